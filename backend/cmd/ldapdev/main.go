@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"stars-admin/internal/ldap/ldaptest"
+	"time"
+)
+
+// ldapdev 本地开发用的 LDAP 服务器，目录数据保存在内存中，不支持 TLS 和写操作
+// 只实现登录和同步所需的简单绑定与查询，用户在 ou=people、组在 ou=groups（groupOfNames）下
+// 指定 -data 时每秒检查该文件，修改文件（如删除用户）后无需重启即可验证同步
+func main() {
+	addr := flag.String("addr", "127.0.0.1:3389", "监听地址")
+	data := flag.String("data", "", "目录数据（JSON）文件，为空时使用内置的示例目录")
+	dump := flag.Bool("dump", false, "输出内置的示例目录后退出，可作为 -data 文件的模板")
+	flag.Parse()
+
+	if *dump {
+		out, _ := json.MarshalIndent(sampleDirectory, "", "  ")
+		fmt.Println(string(out))
+		return
+	}
+	dir, err := loadDirectory(*data)
+	if err != nil {
+		log.Fatal("Failed to load directory:", err)
+	}
+
+	srv, err := ldaptest.Listen(*addr, dir)
+	if err != nil {
+		log.Fatal("Failed to listen:", err)
+	}
+	srv.Logf = log.Printf
+	log.Printf("Development LDAP server listening on %s, base DN %s", srv.URL, dir.BaseDN)
+
+	if *data == "" {
+		select {}
+	}
+	for range time.Tick(time.Second) {
+		dir, err := loadDirectory(*data)
+		if err != nil {
+			log.Println("Failed to load directory:", err)
+			continue
+		}
+		srv.SetDirectory(dir)
+	}
+}
+
+// sampleDirectory 内置的示例目录，服务账号 cn=admin,dc=example,dc=org / admin
+var sampleDirectory = &ldaptest.Directory{
+	BaseDN:       "dc=example,dc=org",
+	BindDN:       "cn=admin,dc=example,dc=org",
+	BindPassword: "admin",
+	Users: []ldaptest.User{
+		{UID: "alice", Password: "alice123", Mail: "alice@example.org", CN: "Alice Liddell", Groups: []string{"stars-admins", "staff"}},
+		{UID: "bob", Password: "bob123", Mail: "bob@example.org", CN: "Bob Builder", Groups: []string{"staff"}},
+		{UID: "carol", Password: "carol123", Mail: "", CN: "Carol (no email)", Groups: []string{}},
+	},
+}
+
+// loadDirectory 读取目录数据，path 为空时使用内置的示例目录
+func loadDirectory(path string) (*ldaptest.Directory, error) {
+	if path == "" {
+		return sampleDirectory, nil
+	}
+	return ldaptest.LoadDirectory(path)
+}
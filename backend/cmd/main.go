@@ -1,14 +1,26 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"log"
+	"net/http"
+	"os/signal"
 	"stars-admin/internal/config"
 	"stars-admin/internal/database"
 	"stars-admin/internal/api/routes"
 	"stars-admin/internal/api/middleware"
+	"stars-admin/internal/i18n"
+	"stars-admin/internal/lifecycle"
+	"stars-admin/internal/logger"
+	"stars-admin/internal/metrics"
+	"stars-admin/internal/tracing"
+	"stars-admin/internal/utils"
+	"syscall"
+	"time"
 	
 	"github.com/gin-gonic/gin"
-	"github.com/gin-contrib/cors"
+	"github.com/sirupsen/logrus"
 )
 
 // @title Stars Admin API
@@ -23,46 +35,146 @@ func main() {
 		log.Fatal("Failed to load config:", err)
 	}
 
+	// 初始化日志
+	if err := logger.Init(cfg.Log); err != nil {
+		log.Fatal("Failed to initialize logger:", err)
+	}
+
+	// 初始化多语言消息
+	if err := i18n.Init(cfg.I18n.DefaultLocale, cfg.I18n.Dir); err != nil {
+		log.Fatal("Failed to initialize i18n:", err)
+	}
+
+	// 初始化令牌参数
+	utils.InitJWT(cfg)
+
+	// 监听配置文件，日志级别可热更新
+	watcher := config.NewWatcher(cfg)
+	watcher.Subscribe(config.Subscriber{
+		Name: "log",
+		Apply: func(old, new *config.Config) {
+			if err := logger.SetLevel(new.Log.Level); err != nil {
+				logrus.WithError(err).Error("Failed to apply log level")
+			}
+		},
+	})
+
+	// 组件按注册的相反顺序关闭：HTTP服务 → 后台任务 → 存储 → 数据库 → 日志
+	lc := lifecycle.New()
+	lc.OnStop("log", logger.Close)
+
+	// 初始化链路追踪
+	shutdownTracing, err := tracing.Init(cfg.Tracing)
+	if err != nil {
+		log.Fatal("Failed to initialize tracing:", err)
+	}
+	lc.OnStop("tracing", shutdownTracing)
+
 	// 初始化数据库
 	db, err := database.InitDB(cfg)
 	if err != nil {
 		log.Fatal("Failed to initialize database:", err)
 	}
+	lc.OnStop("database", func(context.Context) error {
+		return database.Close(db)
+	})
+	metrics.RegisterDBStats(func() map[string]sql.DBStats {
+		return database.PoolStats(db)
+	})
 
-	// 初始化Redis
-	rdb, err := database.InitRedis(cfg)
+	// 初始化令牌与缓存存储
+	st, err := database.InitStore(cfg)
 	if err != nil {
-		log.Fatal("Failed to initialize Redis:", err)
+		log.Fatal("Failed to initialize store:", err)
 	}
+	lc.OnStop("store", func(context.Context) error {
+		return st.Close()
+	})
 
 	// 设置Gin模式
-	if cfg.Server.Mode == "production" {
+	if cfg.IsProduction() {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
-	// 创建路由
-	r := gin.Default()
+	// 创建路由，访问日志由 middleware.Logger 输出
+	r := gin.New()
+	r.Use(gin.Recovery())
 
-	// 添加CORS中间件
-	r.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"http://localhost:3000", "http://localhost:5173"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		AllowCredentials: true,
-	}))
+	// 只信任配置的反向代理转发的客户端 IP，避免伪造 X-Forwarded-For 绕过 IP 访问控制和限流
+	if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		log.Fatal("Failed to set trusted proxies:", err)
+	}
+
+	// 添加CORS和安全响应头中间件，配置可热更新
+	corsPolicy := middleware.NewCORSPolicy(cfg.Security.CORS)
+	headersPolicy := middleware.NewSecurityHeadersPolicy(cfg.Security.Headers)
+	watcher.Subscribe(config.Subscriber{
+		Name: "security",
+		Apply: func(old, new *config.Config) {
+			corsPolicy.Set(new.Security.CORS)
+			headersPolicy.Set(new.Security.Headers)
+		},
+	})
+	r.Use(middleware.CORS(corsPolicy))
+	r.Use(middleware.SecurityHeaders(headersPolicy))
+
+	// 添加链路追踪中间件，需在其他中间件之前以便后续调用沿用请求链路
+	r.Use(middleware.Tracing())
+
+	// 添加请求ID中间件
+	r.Use(middleware.RequestID())
+
+	// 添加语言协商中间件，登录用户的偏好语言在认证中间件中覆盖
+	r.Use(middleware.Locale())
 
 	// 添加日志中间件
 	r.Use(middleware.Logger())
 
+	// 添加指标中间件
+	r.Use(middleware.Metrics())
+
 	// 添加错误处理中间件
 	r.Use(middleware.ErrorHandler())
 
 	// 注册路由
-	routes.RegisterRoutes(r, db, rdb)
+	if err := routes.RegisterRoutes(r, watcher, db, st, lc); err != nil {
+		log.Fatal("Failed to register routes:", err)
+	}
+	watcher.Start()
 
 	// 启动服务器
+	lc.Register(lifecycle.NewHTTPServer(&http.Server{
+		Addr:              ":" + cfg.Server.Port,
+		Handler:           r,
+		ReadTimeout:       seconds(cfg.Server.ReadTimeout),
+		ReadHeaderTimeout: seconds(cfg.Server.ReadHeaderTimeout),
+		WriteTimeout:      seconds(cfg.Server.WriteTimeout),
+		IdleTimeout:       seconds(cfg.Server.IdleTimeout),
+	}))
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
 	log.Printf("Server starting on port %s", cfg.Server.Port)
-	if err := r.Run(":" + cfg.Server.Port); err != nil {
+	if err := lc.Start(ctx); err != nil {
 		log.Fatal("Failed to start server:", err)
 	}
-}
\ No newline at end of file
+
+	// 等待退出信号，再次收到信号时立即退出
+	<-ctx.Done()
+	stop()
+	logrus.Info("Shutdown signal received")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), seconds(cfg.Server.DrainPeriod+cfg.Server.ShutdownTimeout))
+	defer cancel()
+
+	if err := lc.Shutdown(shutdownCtx, seconds(cfg.Server.DrainPeriod)); err != nil {
+		log.Fatal("Shutdown completed with errors:", err)
+	}
+	log.Println("Server stopped")
+}
+
+// seconds 将以秒为单位的配置转换为时长
+func seconds(n int) time.Duration {
+	return time.Duration(n) * time.Second
+}
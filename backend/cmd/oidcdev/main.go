@@ -0,0 +1,111 @@
+package main
+
+import (
+	"flag"
+	"html/template"
+	"log"
+	"net/http"
+	"stars-admin/internal/oidc/oidctest"
+	"strings"
+)
+
+// oidcdev 本地开发用的 OpenID Connect 身份提供方，支持授权码 + PKCE（S256），不校验用户密码
+// 默认在授权页面填写要登录的用户信息，-auto 时直接以命令行参数指定的用户登录
+// 配合 oidc.providers.dev.issuer: http://127.0.0.1:9000、client_id: stars-admin 使用
+func main() {
+	addr := flag.String("addr", "127.0.0.1:9000", "监听地址")
+	issuer := flag.String("issuer", "", "issuer，为空时为 http://<addr>")
+	clientID := flag.String("client-id", "stars-admin", "客户端 ID")
+	clientSecret := flag.String("client-secret", "", "客户端密钥，为空时不校验")
+	auto := flag.Bool("auto", false, "不显示授权页面，直接以下列用户登录")
+	flag.StringVar(&defaultUser.Subject, "sub", "dev-user-1", "用户的 sub")
+	flag.StringVar(&defaultUser.Email, "email", "dev@example.com", "用户的邮箱")
+	flag.BoolVar(&defaultUser.EmailVerified, "email-verified", true, "邮箱是否已验证")
+	flag.StringVar(&defaultUser.Name, "name", "Dev User", "用户的姓名")
+	flag.StringVar(&defaultUser.Username, "username", "", "preferred_username")
+	groups := flag.String("groups", "", "groups 声明，逗号分隔")
+	flag.Parse()
+
+	if *issuer == "" {
+		*issuer = "http://" + *addr
+	}
+	defaultUser.Groups = splitGroups(*groups)
+
+	p, err := oidctest.NewProvider(*issuer, *clientID, *clientSecret)
+	if err != nil {
+		log.Fatal("Failed to generate signing key:", err)
+	}
+	p.Logf = log.Printf
+	p.Login = func(w http.ResponseWriter, r *http.Request) (*oidctest.User, error) {
+		return login(w, r, *auto)
+	}
+
+	log.Printf("Development OIDC provider listening on %s, issuer %s", *addr, p.Issuer)
+	log.Fatal(http.ListenAndServe(*addr, p))
+}
+
+// defaultUser 授权页面的默认值，-auto 时登录的用户
+var defaultUser oidctest.User
+
+// authorizePage 授权页面，提交后以填写的用户登录
+var authorizePage = template.Must(template.New("authorize").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>oidcdev</title></head>
+<body style="font-family: sans-serif; max-width: 420px; margin: 40px auto">
+<h2>oidcdev sign in</h2>
+<p>Client: <code>{{.Query.client_id}}</code></p>
+<form method="post" action="/authorize">
+{{range $k, $v := .Query}}<input type="hidden" name="{{$k}}" value="{{$v}}">
+{{end}}
+<p><label>sub<br><input name="login_sub" value="{{.User.Subject}}" size="40"></label></p>
+<p><label>email<br><input name="login_email" value="{{.User.Email}}" size="40"></label></p>
+<p><label><input type="checkbox" name="login_email_verified" value="true"{{if .User.EmailVerified}} checked{{end}}> email_verified</label></p>
+<p><label>name<br><input name="login_name" value="{{.User.Name}}" size="40"></label></p>
+<p><label>preferred_username<br><input name="login_username" value="{{.User.Username}}" size="40"></label></p>
+<p><label>groups (comma separated)<br><input name="login_groups" value="{{.Groups}}" size="40"></label></p>
+<p><button type="submit">Sign in</button> <button type="submit" name="deny" value="1">Deny</button></p>
+</form>
+</body></html>`))
+
+// login 提交授权页面时以填写的用户登录，-auto 时直接以默认用户登录，否则显示授权页面
+func login(w http.ResponseWriter, r *http.Request, auto bool) (*oidctest.User, error) {
+	form := r.Form
+	switch {
+	case r.Method == http.MethodPost && form.Get("deny") != "":
+		return nil, oidctest.ErrAccessDenied
+	case r.Method == http.MethodPost:
+		return &oidctest.User{
+			Subject:       form.Get("login_sub"),
+			Email:         form.Get("login_email"),
+			EmailVerified: form.Get("login_email_verified") == "true",
+			Name:          form.Get("login_name"),
+			Username:      form.Get("login_username"),
+			Groups:        splitGroups(form.Get("login_groups")),
+		}, nil
+	case auto:
+		u := defaultUser
+		return &u, nil
+	default:
+		query := make(map[string]string)
+		for k := range r.URL.Query() {
+			query[k] = r.URL.Query().Get(k)
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		authorizePage.Execute(w, map[string]interface{}{
+			"Query":  query,
+			"User":   defaultUser,
+			"Groups": strings.Join(defaultUser.Groups, ","),
+		})
+		return nil, nil
+	}
+}
+
+// splitGroups 拆分逗号分隔的组名
+func splitGroups(s string) []string {
+	groups := []string{}
+	for _, g := range strings.Split(s, ",") {
+		if g = strings.TrimSpace(g); g != "" {
+			groups = append(groups, g)
+		}
+	}
+	return groups
+}
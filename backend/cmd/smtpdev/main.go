@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// smtpdev 本地开发用的 SMTP 服务器，接收任意邮件并输出到终端，不会真正投递
+// 配合 email.driver: smtp、email.smtp_host: 127.0.0.1、email.smtp_port: 1025、email.encryption: none、email.username 留空使用
+func main() {
+	addr := flag.String("addr", "127.0.0.1:1025", "监听地址")
+	dir := flag.String("dir", "", "保存收到的邮件（.eml）的目录，为空时不保存")
+	flag.Parse()
+
+	if *dir != "" {
+		if err := os.MkdirAll(*dir, 0o755); err != nil {
+			log.Fatal("Failed to create mail directory:", err)
+		}
+	}
+
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Fatal("Failed to listen:", err)
+	}
+	log.Printf("Development SMTP server listening on %s", *addr)
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			log.Println("Accept failed:", err)
+			continue
+		}
+		go serve(conn, *dir)
+	}
+}
+
+// serve 处理一个 SMTP 会话，只实现发信所需的最少命令，不支持认证和加密
+func serve(conn net.Conn, dir string) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Minute))
+
+	r := bufio.NewReader(conn)
+	reply := func(format string, args ...interface{}) {
+		fmt.Fprintf(conn, format+"\r\n", args...)
+	}
+
+	reply("220 localhost smtpdev ready")
+	var from string
+	var to []string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+
+		switch verb {
+		case "EHLO", "HELO":
+			reply("250 localhost")
+		case "MAIL":
+			from, to = argument(line), nil
+			reply("250 OK")
+		case "RCPT":
+			to = append(to, argument(line))
+			reply("250 OK")
+		case "DATA":
+			if len(to) == 0 {
+				reply("503 RCPT first")
+				continue
+			}
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			data, err := readData(r)
+			if err != nil {
+				return
+			}
+			show(from, to, data)
+			if dir != "" {
+				save(dir, data)
+			}
+			reply("250 OK")
+		case "RSET":
+			from, to = "", nil
+			reply("250 OK")
+		case "NOOP":
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+// argument 取出 MAIL FROM:<a@b> 中的地址
+func argument(line string) string {
+	if i := strings.Index(line, ":"); i >= 0 {
+		line = line[i+1:]
+	}
+	line = strings.TrimSpace(line)
+	if i := strings.Index(line, " "); i >= 0 {
+		line = line[:i]
+	}
+	return strings.Trim(line, "<>")
+}
+
+// readData 读取以单独一行 "." 结束的邮件内容
+func readData(r *bufio.Reader) ([]byte, error) {
+	var buf bytes.Buffer
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimRight(line, "\r\n") == "." {
+			return buf.Bytes(), nil
+		}
+		buf.WriteString(strings.TrimPrefix(line, "."))
+	}
+}
+
+// show 输出收件人、主题和纯文本正文
+func show(from string, to []string, data []byte) {
+	fmt.Println(strings.Repeat("=", 72))
+	fmt.Printf("From:    %s\nTo:      %s\n", from, strings.Join(to, ", "))
+
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		fmt.Printf("(unparsable message: %v)\n%s\n", err, data)
+		return
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		subject = msg.Header.Get("Subject")
+	}
+	fmt.Printf("Subject: %s\n\n%s\n", subject, textBody(msg.Header, msg.Body))
+}
+
+// textBody 返回纯文本正文，multipart 邮件取第一个 text/plain 部分
+func textBody(header mail.Header, body io.Reader) string {
+	mediaType, params, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextRawPart()
+			if err != nil {
+				return "(no text/plain part)"
+			}
+			if strings.HasPrefix(part.Header.Get("Content-Type"), "text/plain") {
+				return decode(part.Header.Get("Content-Transfer-Encoding"), part)
+			}
+		}
+	}
+	return decode(header.Get("Content-Transfer-Encoding"), body)
+}
+
+// decode 按传输编码解码正文
+func decode(encoding string, r io.Reader) string {
+	if strings.EqualFold(encoding, "quoted-printable") {
+		r = quotedprintable.NewReader(r)
+	}
+	content, _ := io.ReadAll(r)
+	return string(content)
+}
+
+// save 将原始邮件保存为 .eml 文件
+func save(dir string, data []byte) {
+	name := filepath.Join(dir, time.Now().Format("20060102-150405.000000000")+".eml")
+	if err := os.WriteFile(name, data, 0o644); err != nil {
+		log.Println("Failed to save message:", err)
+	}
+}
@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"stars-admin/internal/apperrors"
+	"stars-admin/internal/models"
+	"stars-admin/internal/services"
+	"stars-admin/internal/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHandler API 密钥与服务账号处理器
+type APIKeyHandler struct {
+	service *services.APIKeyService
+}
+
+// NewAPIKeyHandler 创建 API 密钥与服务账号处理器
+func NewAPIKeyHandler(service *services.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{service: service}
+}
+
+// ListMine 获取当前用户的 API 密钥
+// @Summary 获取我的API密钥
+// @Description 返回当前用户的全部 API 密钥，包括已过期和已吊销的，不包含密钥本身
+// @Tags 认证
+// @Produce json
+// @Security BearerToken
+// @Success 200 {object} utils.Response{data=[]models.APIKey}
+// @Router /auth/api-keys [get]
+func (h *APIKeyHandler) ListMine(c *gin.Context) {
+	keys, err := h.service.WithContext(c.Request.Context()).ListKeys(c.GetUint("user_id"))
+	if err != nil {
+		utils.Fail(c, err)
+		return
+	}
+
+	utils.Success(c, keys)
+}
+
+// CreateMine 为当前用户创建 API 密钥
+// @Summary 创建我的API密钥
+// @Description 角色和权限必须是当前用户角色和权限的子集；密钥只在创建时返回一次，请求时放在 X-API-Key 头或 Authorization: Bearer 中
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Security BearerToken
+// @Param request body services.CreateAPIKeyRequest true "名称、角色、权限和有效期"
+// @Success 200 {object} utils.Response{data=services.APIKeyResponse}
+// @Router /auth/api-keys [post]
+func (h *APIKeyHandler) CreateMine(c *gin.Context) {
+	var req services.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	resp, err := h.service.WithContext(c.Request.Context()).CreateKey(userID, &req, userID)
+	if err != nil {
+		utils.Fail(c, err)
+		return
+	}
+
+	utils.Success(c, resp)
+}
+
+// RevokeMine 吊销当前用户的 API 密钥
+// @Summary 吊销我的API密钥
+// @Tags 认证
+// @Produce json
+// @Security BearerToken
+// @Param id path int true "密钥ID"
+// @Success 200 {object} utils.Response
+// @Router /auth/api-keys/{id} [delete]
+func (h *APIKeyHandler) RevokeMine(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.Fail(c, apperrors.ErrAPIKeyNotFound)
+		return
+	}
+
+	if err := h.service.WithContext(c.Request.Context()).RevokeKey(c.GetUint("user_id"), uint(id)); err != nil {
+		utils.Fail(c, err)
+		return
+	}
+
+	utils.Success(c, nil)
+}
+
+// ListServiceAccounts 获取服务账号列表
+// @Summary 获取服务账号列表
+// @Tags 服务账号
+// @Produce json
+// @Security BearerToken
+// @Success 200 {object} utils.Response{data=[]models.User}
+// @Router /service-accounts [get]
+func (h *APIKeyHandler) ListServiceAccounts(c *gin.Context) {
+	accounts, err := h.service.WithContext(c.Request.Context()).ListServiceAccounts()
+	if err != nil {
+		utils.Fail(c, err)
+		return
+	}
+
+	utils.Success(c, accounts)
+}
+
+// CreateServiceAccount 创建服务账号
+// @Summary 创建服务账号
+// @Description 服务账号没有密码，不能登录，只能通过 API 密钥访问
+// @Tags 服务账号
+// @Accept json
+// @Produce json
+// @Security BearerToken
+// @Param request body services.CreateServiceAccountRequest true "用户名和角色编码"
+// @Success 200 {object} utils.Response{data=models.User}
+// @Router /service-accounts [post]
+func (h *APIKeyHandler) CreateServiceAccount(c *gin.Context) {
+	var req services.CreateServiceAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	account, err := h.service.WithContext(c.Request.Context()).CreateServiceAccount(&req, c.GetUint("user_id"))
+	if err != nil {
+		utils.Fail(c, err)
+		return
+	}
+
+	utils.Success(c, account)
+}
+
+// DeleteServiceAccount 删除服务账号
+// @Summary 删除服务账号
+// @Description 删除服务账号并吊销其全部 API 密钥
+// @Tags 服务账号
+// @Produce json
+// @Security BearerToken
+// @Param id path int true "服务账号ID"
+// @Success 200 {object} utils.Response
+// @Router /service-accounts/{id} [delete]
+func (h *APIKeyHandler) DeleteServiceAccount(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.Fail(c, apperrors.ErrServiceAccountNotFound)
+		return
+	}
+
+	if err := h.service.WithContext(c.Request.Context()).DeleteServiceAccount(uint(id)); err != nil {
+		utils.Fail(c, err)
+		return
+	}
+
+	utils.Success(c, nil)
+}
+
+// ListServiceAccountKeys 获取服务账号的 API 密钥
+// @Summary 获取服务账号的API密钥
+// @Tags 服务账号
+// @Produce json
+// @Security BearerToken
+// @Param id path int true "服务账号ID"
+// @Success 200 {object} utils.Response{data=[]models.APIKey}
+// @Router /service-accounts/{id}/api-keys [get]
+func (h *APIKeyHandler) ListServiceAccountKeys(c *gin.Context) {
+	service := h.service.WithContext(c.Request.Context())
+	account, ok := serviceAccount(c, service)
+	if !ok {
+		return
+	}
+
+	keys, err := service.ListKeys(account.ID)
+	if err != nil {
+		utils.Fail(c, err)
+		return
+	}
+
+	utils.Success(c, keys)
+}
+
+// CreateServiceAccountKey 为服务账号创建 API 密钥
+// @Summary 为服务账号创建API密钥
+// @Description 角色和权限必须是服务账号角色和权限的子集；密钥只在创建时返回一次
+// @Tags 服务账号
+// @Accept json
+// @Produce json
+// @Security BearerToken
+// @Param id path int true "服务账号ID"
+// @Param request body services.CreateAPIKeyRequest true "名称、角色、权限和有效期"
+// @Success 200 {object} utils.Response{data=services.APIKeyResponse}
+// @Router /service-accounts/{id}/api-keys [post]
+func (h *APIKeyHandler) CreateServiceAccountKey(c *gin.Context) {
+	var req services.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	service := h.service.WithContext(c.Request.Context())
+	account, ok := serviceAccount(c, service)
+	if !ok {
+		return
+	}
+
+	resp, err := service.CreateKey(account.ID, &req, c.GetUint("user_id"))
+	if err != nil {
+		utils.Fail(c, err)
+		return
+	}
+
+	utils.Success(c, resp)
+}
+
+// RevokeServiceAccountKey 吊销服务账号的 API 密钥
+// @Summary 吊销服务账号的API密钥
+// @Tags 服务账号
+// @Produce json
+// @Security BearerToken
+// @Param id path int true "服务账号ID"
+// @Param key_id path int true "密钥ID"
+// @Success 200 {object} utils.Response
+// @Router /service-accounts/{id}/api-keys/{key_id} [delete]
+func (h *APIKeyHandler) RevokeServiceAccountKey(c *gin.Context) {
+	keyID, err := strconv.ParseUint(c.Param("key_id"), 10, 64)
+	if err != nil {
+		utils.Fail(c, apperrors.ErrAPIKeyNotFound)
+		return
+	}
+
+	service := h.service.WithContext(c.Request.Context())
+	account, ok := serviceAccount(c, service)
+	if !ok {
+		return
+	}
+
+	if err := service.RevokeKey(account.ID, uint(keyID)); err != nil {
+		utils.Fail(c, err)
+		return
+	}
+
+	utils.Success(c, nil)
+}
+
+// serviceAccount 按路径参数 id 查询服务账号，失败时已输出错误
+func serviceAccount(c *gin.Context, service *services.APIKeyService) (*models.User, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.Fail(c, apperrors.ErrServiceAccountNotFound)
+		return nil, false
+	}
+
+	account, err := service.ServiceAccount(uint(id))
+	if err != nil {
+		utils.Fail(c, err)
+		return nil, false
+	}
+	return account, true
+}
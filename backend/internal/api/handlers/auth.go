@@ -1,12 +1,15 @@
 package handlers
 
 import (
+	"stars-admin/internal/apperrors"
+	"stars-admin/internal/i18n"
 	"strings"
 	"stars-admin/internal/services"
+	"stars-admin/internal/settings"
+	"stars-admin/internal/store"
 	"stars-admin/internal/utils"
 
 	"github.com/gin-gonic/gin"
-	"github.com/go-redis/redis/v8"
 	"gorm.io/gorm"
 )
 
@@ -16,9 +19,9 @@ type AuthHandler struct {
 }
 
 // NewAuthHandler 创建认证处理器
-func NewAuthHandler(db *gorm.DB, rdb *redis.Client) *AuthHandler {
+func NewAuthHandler(db *gorm.DB, st store.Store, sm *settings.Manager, securityLogs *services.SecurityLogWriter, authenticators []services.Authenticator) *AuthHandler {
 	return &AuthHandler{
-		authService: services.NewAuthService(db, rdb),
+		authService: services.NewAuthService(db, st, sm, securityLogs).WithAuthenticators(authenticators),
 	}
 }
 
@@ -37,10 +40,12 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		utils.ValidateError(c, err)
 		return
 	}
+	req.IP = c.ClientIP()
+	req.UserAgent = c.Request.UserAgent()
 
-	resp, err := h.authService.Login(&req)
+	resp, err := h.authService.WithContext(c.Request.Context()).Login(&req)
 	if err != nil {
-		utils.Error(c, 400, err.Error())
+		utils.Fail(c, err)
 		return
 	}
 
@@ -62,10 +67,12 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 		utils.ValidateError(c, err)
 		return
 	}
+	req.IP = c.ClientIP()
+	req.UserAgent = c.Request.UserAgent()
 
-	resp, err := h.authService.RefreshToken(&req)
+	resp, err := h.authService.WithContext(c.Request.Context()).RefreshToken(&req)
 	if err != nil {
-		utils.Error(c, 400, err.Error())
+		utils.Fail(c, err)
 		return
 	}
 
@@ -84,7 +91,7 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 func (h *AuthHandler) Logout(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		utils.Unauthorized(c, "用户未登录")
+		utils.Fail(c, apperrors.ErrUnauthenticated)
 		return
 	}
 
@@ -92,8 +99,8 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 	authHeader := c.GetHeader("Authorization")
 	token := strings.TrimPrefix(authHeader, "Bearer ")
 
-	if err := h.authService.Logout(userID.(uint), token); err != nil {
-		utils.Error(c, 500, err.Error())
+	if err := h.authService.WithContext(c.Request.Context()).Logout(userID.(uint), token); err != nil {
+		utils.Fail(c, err)
 		return
 	}
 
@@ -112,13 +119,13 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 func (h *AuthHandler) GetUserInfo(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		utils.Unauthorized(c, "用户未登录")
+		utils.Fail(c, apperrors.ErrUnauthenticated)
 		return
 	}
 
-	userInfo, err := h.authService.GetUserInfo(userID.(uint))
+	userInfo, err := h.authService.WithContext(c.Request.Context()).GetUserInfo(userID.(uint))
 	if err != nil {
-		utils.Error(c, 500, err.Error())
+		utils.Fail(c, err)
 		return
 	}
 
@@ -138,7 +145,7 @@ func (h *AuthHandler) GetUserInfo(c *gin.Context) {
 func (h *AuthHandler) UpdatePassword(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		utils.Unauthorized(c, "用户未登录")
+		utils.Fail(c, apperrors.ErrUnauthenticated)
 		return
 	}
 
@@ -148,16 +155,63 @@ func (h *AuthHandler) UpdatePassword(c *gin.Context) {
 		return
 	}
 
-	if err := h.authService.UpdatePassword(userID.(uint), req.OldPassword, req.NewPassword); err != nil {
-		utils.Error(c, 400, err.Error())
+	if err := h.authService.WithContext(c.Request.Context()).UpdatePassword(userID.(uint), req.OldPassword, req.NewPassword); err != nil {
+		utils.Fail(c, err)
 		return
 	}
 
-	utils.SuccessWithMessage(c, "密码更新成功", nil)
+	utils.SuccessWithMessage(c, i18n.T(c.Request.Context(), "message.password_updated", nil), nil)
+}
+
+// UpdatePreferences 更新偏好设置
+// @Summary 更新偏好设置
+// @Description 更新当前用户的偏好语言，为空表示跟随浏览器，重新登录或刷新令牌后生效
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Security BearerToken
+// @Param request body UpdatePreferencesRequest true "偏好设置"
+// @Success 200 {object} utils.Response
+// @Router /auth/preferences [put]
+func (h *AuthHandler) UpdatePreferences(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Fail(c, apperrors.ErrUnauthenticated)
+		return
+	}
+
+	var req UpdatePreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	if err := h.authService.WithContext(c.Request.Context()).UpdatePreferences(userID.(uint), req.Locale); err != nil {
+		utils.Fail(c, err)
+		return
+	}
+
+	utils.SuccessWithMessage(c, i18n.T(c.Request.Context(), "message.preferences_updated", nil), nil)
+}
+
+// PasswordPolicy 获取密码策略
+// @Summary 获取密码策略
+// @Description 返回当前的密码策略，供前端在设置密码时提示规则，无需登录
+// @Tags 认证
+// @Produce json
+// @Success 200 {object} utils.Response{data=settings.PasswordPolicy}
+// @Router /auth/password-policy [get]
+func (h *AuthHandler) PasswordPolicy(c *gin.Context) {
+	utils.Success(c, h.authService.WithContext(c.Request.Context()).PasswordPolicy())
 }
 
 // UpdatePasswordRequest 更新密码请求
 type UpdatePasswordRequest struct {
 	OldPassword string `json:"old_password" binding:"required"`
-	NewPassword string `json:"new_password" binding:"required,min=6"`
-}
\ No newline at end of file
+	NewPassword string `json:"new_password" binding:"required"` // 长度和复杂度按系统配置中的密码策略校验
+}
+
+// UpdatePreferencesRequest 更新偏好设置请求
+type UpdatePreferencesRequest struct {
+	Locale string `json:"locale" binding:"max=20"`
+}
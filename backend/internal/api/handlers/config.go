@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"stars-admin/internal/config"
+	"stars-admin/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+// ConfigHandler 配置文件处理器
+type ConfigHandler struct {
+	watcher *config.Watcher
+}
+
+// NewConfigHandler 创建配置文件处理器
+func NewConfigHandler(watcher *config.Watcher) *ConfigHandler {
+	return &ConfigHandler{watcher: watcher}
+}
+
+// EffectiveConfig 生效配置
+type EffectiveConfig struct {
+	File       string                 `json:"file"`       // 使用的配置文件，为空表示仅使用默认值和环境变量
+	Reloadable []string               `json:"reloadable"` // 修改配置文件后无需重启即可生效的配置项
+	Config     map[string]interface{} `json:"config"`
+}
+
+// Effective 获取生效配置
+// @Summary 获取生效配置
+// @Description 返回配置文件、环境变量和默认值合并后当前生效的配置，密码、密钥等敏感字段已脱敏
+// @Tags 系统管理
+// @Produce json
+// @Security BearerToken
+// @Success 200 {object} utils.Response{data=EffectiveConfig}
+// @Router /system/config/effective [get]
+func (h *ConfigHandler) Effective(c *gin.Context) {
+	utils.Success(c, EffectiveConfig{
+		File:       viper.ConfigFileUsed(),
+		Reloadable: config.ReloadableKeys(),
+		Config:     config.Redact(h.watcher.Current()),
+	})
+}
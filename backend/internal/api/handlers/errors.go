@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"stars-admin/internal/apperrors"
+	"stars-admin/internal/i18n"
+	"stars-admin/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrorCatalog 错误码目录
+// @Summary 错误码目录
+// @Description 返回全部业务错误码及其类别、HTTP状态码和按请求语言翻译的提示信息，供前端生成错误码映射
+// @Tags 系统
+// @Produce json
+// @Success 200 {object} utils.Response{data=[]apperrors.Entry}
+// @Router /errors [get]
+func ErrorCatalog(c *gin.Context) {
+	entries := apperrors.Catalog()
+	for i := range entries {
+		if message, ok := i18n.Lookup(c.Request.Context(), "error."+entries[i].Key, nil); ok {
+			entries[i].Message = message
+		}
+	}
+
+	utils.Success(c, entries)
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"stars-admin/internal/database"
+	"stars-admin/internal/health"
+	"stars-admin/internal/lifecycle"
+	"stars-admin/internal/store"
+	"stars-admin/internal/utils"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// HealthHandler 健康检查处理器
+type HealthHandler struct {
+	checker *health.Checker
+	lc      *lifecycle.Registry
+}
+
+// NewHealthHandler 创建健康检查处理器
+func NewHealthHandler(db *gorm.DB, st store.Store, lc *lifecycle.Registry, timeout time.Duration) *HealthHandler {
+	checker := health.NewChecker(timeout)
+
+	// 主库连接池
+	checker.Add(health.Check{
+		Name:     "database",
+		Critical: true,
+		Run: func(ctx context.Context) (interface{}, error) {
+			sqlDB, err := db.DB()
+			if err != nil {
+				return nil, err
+			}
+			return sqlDB.Stats(), sqlDB.PingContext(ctx)
+		},
+	})
+
+	// 数据表迁移状态，迁移完成后不会再回退，首次通过后不再查询数据库
+	checker.Add(health.Check{
+		Name:     "migrations",
+		Critical: true,
+		Run: health.Once(func(ctx context.Context) (interface{}, error) {
+			pending, err := database.PendingMigrations(ctx, db)
+			if err != nil {
+				return nil, err
+			}
+			details := gin.H{"pending": pending}
+			if len(pending) > 0 {
+				return details, fmt.Errorf("pending migrations: %s", strings.Join(pending, ", "))
+			}
+			return details, nil
+		}),
+	})
+
+	// 只读副本，不可用时读请求回落到主库，只标记为降级
+	if nodes := database.Nodes(db); len(nodes) > 1 {
+		checker.Add(health.Check{
+			Name: "replicas",
+			Run: func(ctx context.Context) (interface{}, error) {
+				nodes := database.Nodes(db)
+				var unhealthy []string
+				for _, n := range nodes {
+					if !n.Primary && !n.Healthy {
+						unhealthy = append(unhealthy, n.Name)
+					}
+				}
+				if len(unhealthy) > 0 {
+					return nodes, fmt.Errorf("unhealthy replicas: %s", strings.Join(unhealthy, ", "))
+				}
+				return nodes, nil
+			},
+		})
+	}
+
+	// 令牌与缓存存储，吊销和刷新检查都允许放行时不影响就绪
+	checker.Add(health.Check{
+		Name:     "store",
+		Critical: !store.FailOpenFor(st, store.CheckBlacklist) || !store.FailOpenFor(st, store.CheckRefresh),
+		Run: func(ctx context.Context) (interface{}, error) {
+			err := st.Ping(ctx)
+			return store.HealthOf(st), err
+		},
+	})
+
+	return &HealthHandler{
+		checker: checker,
+		lc:      lc,
+	}
+}
+
+// Livez 存活检查，只要进程能处理请求即返回成功
+// @Summary 存活检查
+// @Tags 健康检查
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Router /livez [get]
+func (h *HealthHandler) Livez(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": health.StatusUp})
+}
+
+// Readyz 就绪检查，关键依赖不可用或停机摘流时返回 503
+// @Summary 就绪检查
+// @Tags 健康检查
+// @Produce json
+// @Success 200 {object} health.Report
+// @Failure 503 {object} health.Report
+// @Router /readyz [get]
+func (h *HealthHandler) Readyz(c *gin.Context) {
+	if h.lc.Draining() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":  "draining",
+			"message": "Service is shutting down",
+		})
+		return
+	}
+
+	report := h.checker.Run(c.Request.Context())
+
+	status := http.StatusOK
+	if !report.Ready() {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report.Public())
+}
+
+// Detail 依赖健康详情，包含错误信息和连接池统计
+// @Summary 依赖健康详情
+// @Tags 系统管理
+// @Produce json
+// @Security BearerToken
+// @Success 200 {object} utils.Response{data=health.Report}
+// @Router /system/health [get]
+func (h *HealthHandler) Detail(c *gin.Context) {
+	utils.Success(c, h.checker.Run(c.Request.Context()))
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"stars-admin/internal/services"
+	"stars-admin/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LDAPHandler LDAP 目录同步处理器
+type LDAPHandler struct {
+	sync *services.LDAPSync
+}
+
+// NewLDAPHandler 创建 LDAP 目录同步处理器
+func NewLDAPHandler(sync *services.LDAPSync) *LDAPHandler {
+	return &LDAPHandler{sync: sync}
+}
+
+// Sync 立即同步目录
+// @Summary 立即同步LDAP目录
+// @Description 禁用目录中已不存在的 LDAP 用户，与定期同步相同；目录不可用或没有返回任何用户时不做处理
+// @Tags 用户管理
+// @Produce json
+// @Security BearerToken
+// @Success 200 {object} utils.Response{data=services.LDAPSyncResult}
+// @Router /users/ldap-sync [post]
+func (h *LDAPHandler) Sync(c *gin.Context) {
+	result, err := h.sync.Sync(c.Request.Context())
+	if err != nil {
+		utils.Fail(c, err)
+		return
+	}
+
+	utils.Success(c, result)
+}
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"stars-admin/internal/apperrors"
+	"stars-admin/internal/ipfilter"
+	"stars-admin/internal/models"
+	"stars-admin/internal/services"
+	"stars-admin/internal/store"
+	"stars-admin/internal/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// NetworkHandler 网络访问控制处理器
+type NetworkHandler struct {
+	db      *gorm.DB
+	st      store.Store
+	manager *ipfilter.Manager
+}
+
+// NewNetworkHandler 创建网络访问控制处理器
+func NewNetworkHandler(db *gorm.DB, st store.Store, manager *ipfilter.Manager) *NetworkHandler {
+	return &NetworkHandler{db: db, st: st, manager: manager}
+}
+
+// CreateIPRuleRequest 添加 IP 规则请求
+type CreateIPRuleRequest struct {
+	CIDR        string `json:"cidr" binding:"required"` // IP 或 CIDR 网段，如 10.0.0.0/8
+	Action      string `json:"action" binding:"required,oneof=allow deny"`
+	Scope       string `json:"scope" binding:"required,oneof=global system"`
+	Description string `json:"description" binding:"max=255"`
+}
+
+// UpdateAllowedIPsRequest 设置用户允许的 IP 请求
+type UpdateAllowedIPsRequest struct {
+	// AllowedIPs IP 或 CIDR 网段，为空时不限制
+	AllowedIPs []string `json:"allowed_ips" binding:"max=50"`
+}
+
+// ListIPRules 获取 IP 规则
+// @Summary 获取IP规则
+// @Description 返回 IP 访问控制规则，同一作用范围内拒绝规则优先，存在允许规则时只放行命中允许规则的 IP
+// @Tags 系统管理
+// @Produce json
+// @Security BearerToken
+// @Param scope query string false "作用范围：global、system"
+// @Success 200 {object} utils.Response{data=[]models.IPRule}
+// @Router /system/network/ip-rules [get]
+func (h *NetworkHandler) ListIPRules(c *gin.Context) {
+	rules, err := h.manager.List(c.Request.Context(), c.Query("scope"))
+	if err != nil {
+		utils.Fail(c, err)
+		return
+	}
+
+	utils.Success(c, rules)
+}
+
+// CreateIPRule 添加 IP 规则
+// @Summary 添加IP规则
+// @Description 添加 IP 访问控制规则，立即对所有实例生效；会导致当前 IP 无法访问时拒绝添加
+// @Tags 系统管理
+// @Accept json
+// @Produce json
+// @Security BearerToken
+// @Param request body CreateIPRuleRequest true "规则"
+// @Success 200 {object} utils.Response{data=models.IPRule}
+// @Router /system/network/ip-rules [post]
+func (h *NetworkHandler) CreateIPRule(c *gin.Context) {
+	var req CreateIPRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	rule := models.IPRule{
+		CIDR:        req.CIDR,
+		Action:      req.Action,
+		Scope:       req.Scope,
+		Description: req.Description,
+	}
+	if err := h.manager.Create(c.Request.Context(), &rule, networkOperator(c)); err != nil {
+		utils.Fail(c, err)
+		return
+	}
+
+	utils.Success(c, rule)
+}
+
+// DeleteIPRule 删除 IP 规则
+// @Summary 删除IP规则
+// @Description 删除 IP 访问控制规则，会导致当前 IP 无法访问时拒绝删除
+// @Tags 系统管理
+// @Produce json
+// @Security BearerToken
+// @Param id path int true "规则ID"
+// @Success 200 {object} utils.Response
+// @Router /system/network/ip-rules/{id} [delete]
+func (h *NetworkHandler) DeleteIPRule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.Fail(c, apperrors.ErrIPRuleNotFound)
+		return
+	}
+
+	if err := h.manager.Delete(c.Request.Context(), uint(id), networkOperator(c)); err != nil {
+		utils.Fail(c, err)
+		return
+	}
+
+	utils.Success(c, nil)
+}
+
+// UpdateUserAllowedIPs 设置用户允许的 IP
+// @Summary 设置用户允许的IP
+// @Description 用户只能从这些 IP 登录和访问，为空时不限制；已签发的访问令牌在过期前仍按旧范围检查
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerToken
+// @Param id path int true "用户ID"
+// @Param request body UpdateAllowedIPsRequest true "允许的IP"
+// @Success 200 {object} utils.Response{data=UpdateAllowedIPsRequest}
+// @Router /users/{id}/allowed-ips [put]
+func (h *NetworkHandler) UpdateUserAllowedIPs(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.Fail(c, apperrors.ErrUserNotFound)
+		return
+	}
+
+	var req UpdateAllowedIPsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	ips, err := services.SetUserAllowedIPs(c.Request.Context(), h.db, h.st, uint(id), req.AllowedIPs, c.GetUint("user_id"), c.ClientIP())
+	if err != nil {
+		utils.Fail(c, err)
+		return
+	}
+
+	utils.Success(c, UpdateAllowedIPsRequest{AllowedIPs: ips})
+}
+
+// SecurityLogs 获取安全日志
+// @Summary 获取安全日志
+// @Description 分页返回被拦截的访问等安全事件，按时间倒序
+// @Tags 系统管理
+// @Produce json
+// @Security BearerToken
+// @Param event query string false "事件类型：ip_blocked、user_ip_blocked"
+// @Param ip query string false "IP"
+// @Param user_id query int false "用户ID"
+// @Param since query string false "起始时间，RFC 3339 格式"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} utils.Response{data=utils.PageResponse}
+// @Router /system/security-logs [get]
+func (h *NetworkHandler) SecurityLogs(c *gin.Context) {
+	var query services.SecurityLogQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+	if query.Page == 0 {
+		query.Page = 1
+	}
+	if query.PageSize == 0 {
+		query.PageSize = 20
+	}
+
+	logs, total, err := services.ListSecurityLogs(c.Request.Context(), h.db, query)
+	if err != nil {
+		utils.Fail(c, err)
+		return
+	}
+
+	utils.PageSuccess(c, logs, total, query.Page, query.PageSize)
+}
+
+// networkOperator 返回当前登录用户和 IP，用于记录规则修改人和防止锁定自己
+func networkOperator(c *gin.Context) ipfilter.Operator {
+	return ipfilter.Operator{
+		ID:       c.GetUint("user_id"),
+		Username: c.GetString("username"),
+		IP:       c.ClientIP(),
+	}
+}
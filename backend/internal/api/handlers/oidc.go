@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"net/http"
+	"stars-admin/internal/config"
+	"stars-admin/internal/oidc"
+	"stars-admin/internal/services"
+	"stars-admin/internal/settings"
+	"stars-admin/internal/store"
+	"stars-admin/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// OIDCHandler OpenID Connect 登录处理器
+type OIDCHandler struct {
+	service *services.OIDCService
+}
+
+// NewOIDCHandler 创建 OpenID Connect 登录处理器，回调的授权码由 authenticators 中的 oidc 认证方式校验
+func NewOIDCHandler(db *gorm.DB, st store.Store, sm *settings.Manager, securityLogs *services.SecurityLogWriter, authenticators []services.Authenticator, providers *oidc.Registry, cfg config.OIDCConfig) *OIDCHandler {
+	auth := services.NewAuthService(db, st, sm, securityLogs).WithAuthenticators(authenticators)
+	return &OIDCHandler{
+		service: services.NewOIDCService(db, st, auth, providers, securityLogs, cfg),
+	}
+}
+
+// Providers 获取身份提供方
+// @Summary 获取OIDC身份提供方
+// @Description 返回登录页可用的 OpenID Connect 身份提供方，未配置时为空列表
+// @Tags 认证
+// @Produce json
+// @Success 200 {object} utils.Response{data=[]oidc.ProviderInfo}
+// @Router /auth/oidc/providers [get]
+func (h *OIDCHandler) Providers(c *gin.Context) {
+	utils.Success(c, h.service.Providers())
+}
+
+// Authorize 跳转到身份提供方登录
+// @Summary 跳转到OIDC身份提供方登录
+// @Description 生成 state、nonce 和 PKCE 参数后重定向到身份提供方的授权页面
+// @Tags 认证
+// @Param provider path string true "身份提供方标识"
+// @Success 302
+// @Router /auth/oidc/{provider}/authorize [get]
+func (h *OIDCHandler) Authorize(c *gin.Context) {
+	location, err := h.service.WithContext(c.Request.Context()).Authorize(c.Param("provider"))
+	if err != nil {
+		utils.Fail(c, err)
+		return
+	}
+
+	c.Redirect(http.StatusFound, location)
+}
+
+// Callback 身份提供方回调
+// @Summary OIDC身份提供方回调
+// @Description 校验 state 并用授权码换取 ID 令牌，确定用户后重定向到前端登录回调页面：成功时附加一次性 code，失败时附加 error（错误 key）
+// @Tags 认证
+// @Param provider path string true "身份提供方标识"
+// @Param code query string false "授权码"
+// @Param state query string false "state"
+// @Param error query string false "身份提供方返回的错误"
+// @Success 302
+// @Router /auth/oidc/{provider}/callback [get]
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	var req services.OIDCCallbackRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+	req.Provider = c.Param("provider")
+	req.IP = c.ClientIP()
+	req.UserAgent = c.Request.UserAgent()
+
+	c.Redirect(http.StatusFound, h.service.WithContext(c.Request.Context()).Callback(&req))
+}
+
+// Exchange 换取令牌
+// @Summary OIDC登录换取令牌
+// @Description 使用前端登录回调页面收到的一次性 code 换取访问令牌和刷新令牌，code 一分钟内有效且只能使用一次
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param request body services.OIDCExchangeRequest true "一次性 code"
+// @Success 200 {object} utils.Response{data=services.LoginResponse}
+// @Router /auth/oidc/exchange [post]
+func (h *OIDCHandler) Exchange(c *gin.Context) {
+	var req services.OIDCExchangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	resp, err := h.service.WithContext(c.Request.Context()).Exchange(&req)
+	if err != nil {
+		utils.Fail(c, err)
+		return
+	}
+
+	utils.Success(c, resp)
+}
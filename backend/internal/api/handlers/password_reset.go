@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"stars-admin/internal/config"
+	"stars-admin/internal/i18n"
+	"stars-admin/internal/mail"
+	"stars-admin/internal/services"
+	"stars-admin/internal/settings"
+	"stars-admin/internal/store"
+	"stars-admin/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// PasswordResetHandler 自助重置密码处理器
+type PasswordResetHandler struct {
+	service *services.PasswordResetService
+}
+
+// NewPasswordResetHandler 创建自助重置密码处理器
+func NewPasswordResetHandler(db *gorm.DB, st store.Store, sm *settings.Manager, mailer *mail.Mailer, securityLogs *services.SecurityLogWriter, cfg config.PasswordResetConfig) *PasswordResetHandler {
+	return &PasswordResetHandler{
+		service: services.NewPasswordResetService(db, st, sm, mailer, securityLogs, cfg),
+	}
+}
+
+// Forgot 申请重置密码
+// @Summary 申请重置密码
+// @Description 向邮箱发送重置密码链接，无论邮箱是否注册都返回成功；同一账号在限制窗口内只发送有限次数
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param request body services.ForgotPasswordRequest true "邮箱"
+// @Success 200 {object} utils.Response
+// @Router /auth/forgot-password [post]
+func (h *PasswordResetHandler) Forgot(c *gin.Context) {
+	var req services.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+	req.IP = c.ClientIP()
+	req.UserAgent = c.Request.UserAgent()
+
+	if err := h.service.WithContext(c.Request.Context()).Forgot(&req); err != nil {
+		utils.Fail(c, err)
+		return
+	}
+
+	utils.SuccessWithMessage(c, i18n.T(c.Request.Context(), "message.password_reset_requested", nil), nil)
+}
+
+// Reset 重置密码
+// @Summary 重置密码
+// @Description 使用重置链接中的令牌设置新密码，令牌只能使用一次，成功后需要重新登录
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param request body services.ResetPasswordRequest true "令牌和新密码"
+// @Success 200 {object} utils.Response
+// @Router /auth/reset-password [post]
+func (h *PasswordResetHandler) Reset(c *gin.Context) {
+	var req services.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+	req.IP = c.ClientIP()
+	req.UserAgent = c.Request.UserAgent()
+
+	if err := h.service.WithContext(c.Request.Context()).Reset(&req); err != nil {
+		utils.Fail(c, err)
+		return
+	}
+
+	utils.SuccessWithMessage(c, i18n.T(c.Request.Context(), "message.password_reset", nil), nil)
+}
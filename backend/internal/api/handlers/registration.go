@@ -0,0 +1,299 @@
+package handlers
+
+import (
+	"stars-admin/internal/apperrors"
+	"stars-admin/internal/config"
+	"stars-admin/internal/i18n"
+	"stars-admin/internal/mail"
+	"stars-admin/internal/services"
+	"stars-admin/internal/settings"
+	"stars-admin/internal/store"
+	"stars-admin/internal/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// RegistrationHandler 用户邀请与自助注册处理器
+type RegistrationHandler struct {
+	service *services.RegistrationService
+}
+
+// NewRegistrationHandler 创建用户邀请与自助注册处理器
+func NewRegistrationHandler(db *gorm.DB, st store.Store, sm *settings.Manager, mailer *mail.Mailer, securityLogs *services.SecurityLogWriter, cfg config.RegistrationConfig) *RegistrationHandler {
+	return &RegistrationHandler{
+		service: services.NewRegistrationService(db, st, sm, mailer, securityLogs, cfg),
+	}
+}
+
+// Register 自助注册
+// @Summary 自助注册
+// @Description 系统配置开放注册时可用，邮箱域名需在允许列表内；注册后需验证邮箱，按注册策略还需管理员审核
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param request body services.RegisterRequest true "注册信息"
+// @Success 200 {object} utils.Response
+// @Router /auth/register [post]
+func (h *RegistrationHandler) Register(c *gin.Context) {
+	var req services.RegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+	req.IP = c.ClientIP()
+	req.UserAgent = c.Request.UserAgent()
+
+	if err := h.service.WithContext(c.Request.Context()).Register(&req); err != nil {
+		utils.Fail(c, err)
+		return
+	}
+
+	utils.SuccessWithMessage(c, i18n.T(c.Request.Context(), "message.registered", nil), nil)
+}
+
+// VerifyEmail 验证邮箱
+// @Summary 验证邮箱
+// @Description 使用验证邮件中的令牌验证邮箱，令牌只能使用一次；pending_approval 为 true 时还需管理员审核
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param request body services.VerifyEmailRequest true "令牌"
+// @Success 200 {object} utils.Response{data=services.VerifyEmailResponse}
+// @Router /auth/verify-email [post]
+func (h *RegistrationHandler) VerifyEmail(c *gin.Context) {
+	var req services.VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+	req.IP = c.ClientIP()
+	req.UserAgent = c.Request.UserAgent()
+
+	resp, err := h.service.WithContext(c.Request.Context()).VerifyEmail(&req)
+	if err != nil {
+		utils.Fail(c, err)
+		return
+	}
+
+	key := "message.email_verified"
+	if resp.PendingApproval {
+		key = "message.pending_approval"
+	}
+	utils.SuccessWithMessage(c, i18n.T(c.Request.Context(), key, nil), resp)
+}
+
+// ResendVerification 重新发送验证邮件
+// @Summary 重新发送验证邮件
+// @Description 向未验证邮箱的用户重新发送验证邮件，无论邮箱是否注册都返回成功
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param request body services.ResendVerificationRequest true "邮箱"
+// @Success 200 {object} utils.Response
+// @Router /auth/verify-email/resend [post]
+func (h *RegistrationHandler) ResendVerification(c *gin.Context) {
+	var req services.ResendVerificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	if err := h.service.WithContext(c.Request.Context()).ResendVerification(&req); err != nil {
+		utils.Fail(c, err)
+		return
+	}
+
+	utils.SuccessWithMessage(c, i18n.T(c.Request.Context(), "message.verification_sent", nil), nil)
+}
+
+// AcceptInvitation 接受邀请
+// @Summary 接受邀请
+// @Description 使用邀请邮件中的令牌设置用户名和密码，创建后即可登录
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param request body services.AcceptInvitationRequest true "令牌和账号信息"
+// @Success 200 {object} utils.Response
+// @Router /auth/invitations/accept [post]
+func (h *RegistrationHandler) AcceptInvitation(c *gin.Context) {
+	var req services.AcceptInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+	req.IP = c.ClientIP()
+	req.UserAgent = c.Request.UserAgent()
+
+	if err := h.service.WithContext(c.Request.Context()).AcceptInvitation(&req); err != nil {
+		utils.Fail(c, err)
+		return
+	}
+
+	utils.SuccessWithMessage(c, i18n.T(c.Request.Context(), "message.invitation_accepted", nil), nil)
+}
+
+// Invite 邀请用户
+// @Summary 邀请用户
+// @Description 向邮箱发送邀请链接并预先分配角色，同一邮箱之前未接受的邀请随之撤销；链接只在创建时返回一次
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerToken
+// @Param request body services.InviteRequest true "邮箱和角色编码"
+// @Success 200 {object} utils.Response{data=services.InvitationResponse}
+// @Router /users/invitations [post]
+func (h *RegistrationHandler) Invite(c *gin.Context) {
+	var req services.InviteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	resp, err := h.service.WithContext(c.Request.Context()).Invite(&req, c.GetUint("user_id"))
+	if err != nil {
+		utils.Fail(c, err)
+		return
+	}
+
+	utils.SuccessWithMessage(c, i18n.T(c.Request.Context(), "message.invitation_sent", nil), resp)
+}
+
+// ListInvitations 获取邀请列表
+// @Summary 获取邀请列表
+// @Description 分页返回邀请，按创建时间倒序
+// @Tags 用户管理
+// @Produce json
+// @Security BearerToken
+// @Param status query string false "状态：pending、accepted、revoked、expired"
+// @Param email query string false "邮箱"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} utils.Response{data=utils.PageResponse}
+// @Router /users/invitations [get]
+func (h *RegistrationHandler) ListInvitations(c *gin.Context) {
+	var query services.InvitationQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+	if query.Page == 0 {
+		query.Page = 1
+	}
+	if query.PageSize == 0 {
+		query.PageSize = 20
+	}
+
+	invitations, total, err := h.service.WithContext(c.Request.Context()).ListInvitations(query)
+	if err != nil {
+		utils.Fail(c, err)
+		return
+	}
+
+	utils.PageSuccess(c, invitations, total, query.Page, query.PageSize)
+}
+
+// RevokeInvitation 撤销邀请
+// @Summary 撤销邀请
+// @Description 撤销未接受的邀请，邀请链接随之失效
+// @Tags 用户管理
+// @Produce json
+// @Security BearerToken
+// @Param id path int true "邀请ID"
+// @Success 200 {object} utils.Response
+// @Router /users/invitations/{id} [delete]
+func (h *RegistrationHandler) RevokeInvitation(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.Fail(c, apperrors.ErrInvitationNotFound)
+		return
+	}
+
+	if err := h.service.WithContext(c.Request.Context()).RevokeInvitation(uint(id)); err != nil {
+		utils.Fail(c, err)
+		return
+	}
+
+	utils.Success(c, nil)
+}
+
+// ListPendingUsers 获取待审核用户
+// @Summary 获取待审核用户
+// @Description 分页返回已验证邮箱、等待管理员审核的自助注册用户
+// @Tags 用户管理
+// @Produce json
+// @Security BearerToken
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} utils.Response{data=utils.PageResponse}
+// @Router /users/pending [get]
+func (h *RegistrationHandler) ListPendingUsers(c *gin.Context) {
+	var query services.PendingUserQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+	if query.Page == 0 {
+		query.Page = 1
+	}
+	if query.PageSize == 0 {
+		query.PageSize = 20
+	}
+
+	users, total, err := h.service.WithContext(c.Request.Context()).ListPendingUsers(query)
+	if err != nil {
+		utils.Fail(c, err)
+		return
+	}
+
+	utils.PageSuccess(c, users, total, query.Page, query.PageSize)
+}
+
+// Approve 审核通过注册
+// @Summary 审核通过注册
+// @Description 启用待审核的用户，并发送邮件通知用户可以登录
+// @Tags 用户管理
+// @Produce json
+// @Security BearerToken
+// @Param id path int true "用户ID"
+// @Success 200 {object} utils.Response
+// @Router /users/{id}/approve [post]
+func (h *RegistrationHandler) Approve(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.Fail(c, apperrors.ErrUserNotFound)
+		return
+	}
+
+	if err := h.service.WithContext(c.Request.Context()).Approve(uint(id)); err != nil {
+		utils.Fail(c, err)
+		return
+	}
+
+	utils.Success(c, nil)
+}
+
+// Reject 拒绝注册
+// @Summary 拒绝注册
+// @Description 删除待审核或未验证邮箱的用户，用户名和邮箱可以重新注册
+// @Tags 用户管理
+// @Produce json
+// @Security BearerToken
+// @Param id path int true "用户ID"
+// @Success 200 {object} utils.Response
+// @Router /users/{id}/reject [post]
+func (h *RegistrationHandler) Reject(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.Fail(c, apperrors.ErrUserNotFound)
+		return
+	}
+
+	if err := h.service.WithContext(c.Request.Context()).Reject(uint(id)); err != nil {
+		utils.Fail(c, err)
+		return
+	}
+
+	utils.Success(c, nil)
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"encoding/json"
+	"stars-admin/internal/apperrors"
+	"stars-admin/internal/i18n"
+	"stars-admin/internal/settings"
+	"stars-admin/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SettingsHandler 系统配置处理器
+type SettingsHandler struct {
+	manager *settings.Manager
+}
+
+// NewSettingsHandler 创建系统配置处理器
+func NewSettingsHandler(manager *settings.Manager) *SettingsHandler {
+	return &SettingsHandler{manager: manager}
+}
+
+// UpdateSettingsRequest 修改系统配置请求
+type UpdateSettingsRequest struct {
+	// Values 键为配置项，值的 JSON 类型需与配置类型一致
+	Values map[string]json.RawMessage `json:"values" binding:"required"`
+}
+
+// SettingHistoryQuery 配置变更历史查询参数
+type SettingHistoryQuery struct {
+	Key      string `form:"key"`
+	Page     int    `form:"page" binding:"omitempty,min=1"`
+	PageSize int    `form:"page_size" binding:"omitempty,min=1,max=100"`
+}
+
+// List 获取系统配置
+// @Summary 获取系统配置
+// @Description 按分组返回全部配置项的当前值、默认值和校验规则
+// @Tags 系统管理
+// @Produce json
+// @Security BearerToken
+// @Param group query string false "分组，如 general、security、session"
+// @Success 200 {object} utils.Response{data=[]settings.Group}
+// @Router /system/config [get]
+func (h *SettingsHandler) List(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	groups, err := h.manager.List(ctx, c.Query("group"))
+	if err != nil {
+		utils.Fail(c, err)
+		return
+	}
+
+	for i := range groups {
+		for j := range groups[i].Items {
+			item := &groups[i].Items[j]
+			if description, ok := i18n.Lookup(ctx, "setting."+item.Key, nil); ok {
+				item.Description = description
+			}
+		}
+	}
+
+	utils.Success(c, groups)
+}
+
+// Update 修改系统配置
+// @Summary 修改系统配置
+// @Description 批量修改配置项，全部校验通过后才会写入，修改立即对所有实例生效
+// @Tags 系统管理
+// @Accept json
+// @Produce json
+// @Security BearerToken
+// @Param request body UpdateSettingsRequest true "配置值"
+// @Success 200 {object} utils.Response
+// @Router /system/config [put]
+func (h *SettingsHandler) Update(c *gin.Context) {
+	var req UpdateSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	if err := h.manager.Update(c.Request.Context(), req.Values, settingsOperator(c)); err != nil {
+		utils.Fail(c, err)
+		return
+	}
+
+	utils.Success(c, nil)
+}
+
+// Reset 恢复默认值
+// @Summary 恢复配置默认值
+// @Description 删除配置项的覆盖值，恢复为配置文件中的默认值
+// @Tags 系统管理
+// @Produce json
+// @Security BearerToken
+// @Param key path string true "配置项"
+// @Success 200 {object} utils.Response
+// @Router /system/config/{key} [delete]
+func (h *SettingsHandler) Reset(c *gin.Context) {
+	if err := h.manager.Reset(c.Request.Context(), c.Param("key"), settingsOperator(c)); err != nil {
+		utils.Fail(c, err)
+		return
+	}
+
+	utils.Success(c, nil)
+}
+
+// History 获取配置变更历史
+// @Summary 获取配置变更历史
+// @Description 分页返回配置变更历史，按时间倒序
+// @Tags 系统管理
+// @Produce json
+// @Security BearerToken
+// @Param key query string false "配置项"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} utils.Response{data=utils.PageResponse}
+// @Router /system/config/history [get]
+func (h *SettingsHandler) History(c *gin.Context) {
+	var query SettingHistoryQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+	if query.Page == 0 {
+		query.Page = 1
+	}
+	if query.PageSize == 0 {
+		query.PageSize = 20
+	}
+	if query.Key != "" {
+		if _, ok := h.manager.Definition(query.Key); !ok {
+			utils.Fail(c, apperrors.ErrSettingNotFound.WithDetails(map[string]string{"key": query.Key}))
+			return
+		}
+	}
+
+	histories, total, err := h.manager.History(c.Request.Context(), query.Key, query.Page, query.PageSize)
+	if err != nil {
+		utils.Fail(c, err)
+		return
+	}
+
+	utils.PageSuccess(c, histories, total, query.Page, query.PageSize)
+}
+
+// settingsOperator 返回当前登录用户，用于记录变更历史
+func settingsOperator(c *gin.Context) settings.Operator {
+	return settings.Operator{
+		ID:       c.GetUint("user_id"),
+		Username: c.GetString("username"),
+	}
+}
@@ -1,65 +1,85 @@
 package middleware
 
 import (
-	"net/http"
+	"stars-admin/internal/apperrors"
+	"stars-admin/internal/database"
+	"stars-admin/internal/i18n"
+	"stars-admin/internal/ipfilter"
 	"strings"
+	"stars-admin/internal/metrics"
+	"stars-admin/internal/models"
+	"stars-admin/internal/services"
+	"stars-admin/internal/store"
 	"stars-admin/internal/utils"
 
 	"github.com/gin-gonic/gin"
-	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 )
 
-// AuthMiddleware JWT认证中间件
-func AuthMiddleware(db *gorm.DB, rdb *redis.Client) gin.HandlerFunc {
+// passwordChangeExemptPaths 强制修改密码期间仍可访问的接口
+var passwordChangeExemptPaths = map[string]bool{
+	"/api/v1/auth/password": true,
+	"/api/v1/auth/logout":   true,
+	"/api/v1/auth/user":     true,
+}
+
+// apiKeyDeniedPaths API 密钥不能访问的接口前缀，避免密钥被用来签发新密钥或修改所有者的账号
+var apiKeyDeniedPaths = []string{
+	"/api/v1/auth/logout",
+	"/api/v1/auth/password",
+	"/api/v1/auth/api-keys",
+	"/api/v1/service-accounts",
+}
+
+// AuthMiddleware 认证中间件，支持 JWT 访问令牌和 API 密钥
+// API 密钥通过 X-API-Key 头或以 sak_ 开头的 Bearer 令牌传递
+// 用户设置了允许的 IP 时，从其他 IP 发起的请求被拒绝并记录到安全日志
+// API 密钥由 auth 的认证方式链校验，与登录使用同一流程检查所有者
+func AuthMiddleware(db *gorm.DB, st store.Store, logs *services.SecurityLogWriter, auth *services.AuthService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// 获取Authorization头
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"code":    401,
-				"message": "Authorization header is required",
-				"data":    nil,
-			})
-			c.Abort()
-			return
+		var claims *utils.JWTClaims
+		if key, ok := apiKeyFromRequest(c); ok {
+			keyClaims, apiKey, err := auth.WithContext(c.Request.Context()).AuthenticateAPIKey(key, securityLog(c, "", ""))
+			if err != nil {
+				utils.Fail(c, err)
+				return
+			}
+			if apiKeyDenied(c.FullPath()) {
+				utils.Fail(c, apperrors.ErrAPIKeyForbidden)
+				return
+			}
+			claims = keyClaims
+			c.Set("api_key_id", apiKey.ID)
+		} else {
+			tokenClaims, err := bearerClaims(c, st)
+			if err != nil {
+				utils.Fail(c, err)
+				return
+			}
+			claims = tokenClaims
 		}
 
-		// 检查Bearer前缀
-		if !strings.HasPrefix(authHeader, "Bearer ") {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"code":    401,
-				"message": "Invalid authorization format",
-				"data":    nil,
-			})
-			c.Abort()
-			return
+		// 检查用户允许的 IP，令牌中的范围在签发时确定，API 密钥使用所有者当前的范围
+		if len(claims.AllowedIPs) > 0 {
+			if prefixes, err := ipfilter.ParseList(strings.Join(claims.AllowedIPs, ",")); err != nil || !ipfilter.Contains(prefixes, c.ClientIP()) {
+				entry := securityLog(c, services.SecurityEventUserIPBlocked, "token used outside allowed IPs")
+				entry.UserID, entry.Username = claims.UserID, claims.Username
+				logs.Record(entry)
+				utils.Fail(c, apperrors.ErrIPBlocked)
+				return
+			}
 		}
 
-		// 提取token
-		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-		
-		// 验证token
-		claims, err := utils.ValidateJWT(tokenString)
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"code":    401,
-				"message": "Invalid token",
-				"data":    nil,
-			})
-			c.Abort()
+		// 检查是否需要先修改初始密码，令牌签发后已修改的以数据库为准
+		if claims.MustChangePassword && !passwordChangeExemptPaths[c.FullPath()] && mustChangePassword(db.WithContext(c.Request.Context()), claims.UserID) {
+			utils.Fail(c, apperrors.ErrPasswordChangeRequired)
 			return
 		}
 
-		// 检查token是否在黑名单中
-		if utils.IsTokenBlacklisted(rdb, tokenString) {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"code":    401,
-				"message": "Token is blacklisted",
-				"data":    nil,
-			})
-			c.Abort()
-			return
+		// 用户设置了偏好语言时优先于 Accept-Language
+		if claims.Locale != "" && i18n.Default.Supported(claims.Locale) {
+			setLocale(c, claims.Locale)
 		}
 
 		// 将用户信息存储到上下文中
@@ -71,40 +91,97 @@ func AuthMiddleware(db *gorm.DB, rdb *redis.Client) gin.HandlerFunc {
 	}
 }
 
+// bearerClaims 校验 Authorization 头中的 JWT 访问令牌
+func bearerClaims(c *gin.Context, st store.Store) (*utils.JWTClaims, error) {
+	// 检查Bearer前缀
+	tokenString, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok || tokenString == "" {
+		return nil, apperrors.ErrAuthHeaderInvalid
+	}
+
+	// 验证token
+	claims, err := utils.ValidateJWT(tokenString)
+	if err != nil {
+		return nil, apperrors.ErrTokenInvalid.Wrap(err)
+	}
+
+	// 检查token是否在黑名单中，存储不可用时按故障策略处理
+	blacklisted, err := utils.IsTokenBlacklisted(c.Request.Context(), st, tokenString)
+	if err != nil {
+		if !store.FailOpenFor(st, store.CheckBlacklist) {
+			return nil, apperrors.ErrAuthUnavailable.Wrap(err)
+		}
+		logrus.WithError(err).Warn("Blacklist check skipped: store unavailable")
+	}
+	if blacklisted {
+		metrics.BlacklistHits.Inc()
+		return nil, apperrors.ErrTokenRevoked
+	}
+
+	// 检查用户的令牌是否已被整体吊销（如重置密码），与黑名单使用相同的故障策略
+	revoked, err := utils.IsUserTokenRevoked(c.Request.Context(), st, claims)
+	if err != nil {
+		if !store.FailOpenFor(st, store.CheckBlacklist) {
+			return nil, apperrors.ErrAuthUnavailable.Wrap(err)
+		}
+		logrus.WithError(err).Warn("User token revocation check skipped: store unavailable")
+	}
+	if revoked {
+		return nil, apperrors.ErrTokenRevoked
+	}
+
+	return claims, nil
+}
+
+// apiKeyFromRequest 返回请求携带的 API 密钥，X-API-Key 头优先
+func apiKeyFromRequest(c *gin.Context) (string, bool) {
+	if key := c.GetHeader(APIKeyHeader); key != "" {
+		return key, true
+	}
+	if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok && strings.HasPrefix(token, services.APIKeyPrefix) {
+		return token, true
+	}
+	return "", false
+}
+
+// apiKeyDenied 检查接口是否禁止使用 API 密钥访问
+func apiKeyDenied(path string) bool {
+	for _, prefix := range apiKeyDeniedPaths {
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// mustChangePassword 查询用户当前是否仍需修改密码
+func mustChangePassword(db *gorm.DB, userID uint) bool {
+	var user models.User
+	if err := database.UsePrimary(db).Select("must_change_password").First(&user, userID).Error; err != nil {
+		return true
+	}
+	return user.MustChangePassword
+}
+
 // RequirePermission 权限验证中间件
 func RequirePermission(permission string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 获取用户信息
 		user, exists := c.Get("user")
 		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"code":    401,
-				"message": "User not authenticated",
-				"data":    nil,
-			})
-			c.Abort()
+			utils.Fail(c, apperrors.ErrUnauthenticated)
 			return
 		}
 
 		claims, ok := user.(*utils.JWTClaims)
 		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"code":    401,
-				"message": "Invalid user claims",
-				"data":    nil,
-			})
-			c.Abort()
+			utils.Fail(c, apperrors.ErrUnauthenticated)
 			return
 		}
 
 		// 检查用户权限
 		if !hasPermission(claims.Permissions, permission) {
-			c.JSON(http.StatusForbidden, gin.H{
-				"code":    403,
-				"message": "Permission denied",
-				"data":    nil,
-			})
-			c.Abort()
+			utils.Fail(c, apperrors.ErrPermissionDenied)
 			return
 		}
 
@@ -115,7 +192,7 @@ func RequirePermission(permission string) gin.HandlerFunc {
 // hasPermission 检查用户是否有指定权限
 func hasPermission(userPermissions []string, required string) bool {
 	for _, permission := range userPermissions {
-		if permission == required || permission == "*" {
+		if permission == required || permission == models.PermissionAll {
 			return true
 		}
 	}
@@ -128,34 +205,19 @@ func RequireRole(role string) gin.HandlerFunc {
 		// 获取用户信息
 		user, exists := c.Get("user")
 		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"code":    401,
-				"message": "User not authenticated",
-				"data":    nil,
-			})
-			c.Abort()
+			utils.Fail(c, apperrors.ErrUnauthenticated)
 			return
 		}
 
 		claims, ok := user.(*utils.JWTClaims)
 		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"code":    401,
-				"message": "Invalid user claims",
-				"data":    nil,
-			})
-			c.Abort()
+			utils.Fail(c, apperrors.ErrUnauthenticated)
 			return
 		}
 
 		// 检查用户角色
 		if !hasRole(claims.Roles, role) {
-			c.JSON(http.StatusForbidden, gin.H{
-				"code":    403,
-				"message": "Role denied",
-				"data":    nil,
-			})
-			c.Abort()
+			utils.Fail(c, apperrors.ErrRoleDenied)
 			return
 		}
 
@@ -166,7 +228,7 @@ func RequireRole(role string) gin.HandlerFunc {
 // hasRole 检查用户是否有指定角色
 func hasRole(userRoles []string, required string) bool {
 	for _, role := range userRoles {
-		if role == required || role == "admin" {
+		if role == required || role == models.RoleAdmin {
 			return true
 		}
 	}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"stars-admin/internal/apperrors"
+	"stars-admin/internal/store"
+	"stars-admin/internal/utils"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestBearerClaimsRevocation(t *testing.T) {
+	st := store.NewMemoryStore()
+	defer st.Close()
+	ctx := context.Background()
+
+	claimsFor := func(token string) (*utils.JWTClaims, error) {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/auth/user", nil)
+		c.Request.Header.Set("Authorization", "Bearer "+token)
+		return bearerClaims(c, st)
+	}
+
+	token, err := utils.GenerateJWT(utils.JWTClaims{UserID: 1, Username: "alice"}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := utils.GenerateJWT(utils.JWTClaims{UserID: 2, Username: "bob"}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims, err := claimsFor(token); err != nil || claims.UserID != 1 {
+		t.Fatalf("valid token: got %+v, %v", claims, err)
+	}
+
+	// 整体吊销后用户此前签发的令牌失效，其他用户不受影响
+	if err := utils.RevokeUserTokens(ctx, st, 1, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := claimsFor(token); !errors.Is(err, apperrors.ErrTokenRevoked) {
+		t.Errorf("revoked user token: got %v", err)
+	}
+	if _, err := claimsFor(other); err != nil {
+		t.Errorf("other user token: %v", err)
+	}
+
+	// 单个令牌加入黑名单
+	if err := utils.BlacklistToken(ctx, st, other, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := claimsFor(other); !errors.Is(err, apperrors.ErrTokenRevoked) {
+		t.Errorf("blacklisted token: got %v", err)
+	}
+}
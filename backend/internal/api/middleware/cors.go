@@ -0,0 +1,177 @@
+package middleware
+
+import (
+	"net/http"
+	"net/url"
+	"stars-admin/internal/config"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// corsRequestHeaders 服务自身使用的请求头，总是允许跨域携带
+var corsRequestHeaders = []string{"Origin", "Content-Type", "Authorization", "Accept-Language", "traceparent", "tracestate", RequestIDHeader, APIKeyHeader}
+
+// corsExposedHeaders 服务自身返回的响应头，总是暴露给前端
+var corsExposedHeaders = []string{TraceIDHeader, RequestIDHeader, "Content-Language", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After"}
+
+// corsRules 解析后的跨域配置
+type corsRules struct {
+	anyOrigin        bool
+	origins          map[string]bool
+	wildcards        []wildcardOrigin
+	methods          string
+	headers          string
+	exposed          string
+	allowCredentials bool
+	maxAge           string
+}
+
+// wildcardOrigin 通配子域名的来源，如 https://*.example.com
+type wildcardOrigin struct {
+	scheme string
+	suffix string // .example.com，含端口时为 .example.com:8443
+}
+
+// CORSPolicy 可在运行时替换的跨域配置
+type CORSPolicy struct {
+	rules atomic.Pointer[corsRules]
+}
+
+// NewCORSPolicy 创建跨域配置，配置应已通过 config.Validate 校验
+func NewCORSPolicy(cfg config.CORSConfig) *CORSPolicy {
+	p := &CORSPolicy{}
+	p.Set(cfg)
+	return p
+}
+
+// Set 替换跨域配置，不合法的来源会被忽略
+func (p *CORSPolicy) Set(cfg config.CORSConfig) {
+	rules := &corsRules{
+		origins:          make(map[string]bool),
+		methods:          strings.Join(normalizeTokens(cfg.AllowedMethods, strings.ToUpper), ", "),
+		headers:          strings.Join(mergeTokens(corsRequestHeaders, cfg.AllowedHeaders), ", "),
+		exposed:          strings.Join(mergeTokens(corsExposedHeaders, cfg.ExposedHeaders), ", "),
+		allowCredentials: cfg.AllowCredentials,
+	}
+	if cfg.MaxAge > 0 {
+		rules.maxAge = strconv.Itoa(cfg.MaxAge)
+	}
+
+	for _, origin := range cfg.AllowedOrigins {
+		if origin == "*" {
+			rules.anyOrigin = true
+			continue
+		}
+		u, err := url.Parse(strings.ToLower(origin))
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			continue
+		}
+		if strings.HasPrefix(u.Host, "*.") {
+			rules.wildcards = append(rules.wildcards, wildcardOrigin{scheme: u.Scheme, suffix: u.Host[1:]})
+			continue
+		}
+		rules.origins[u.Scheme+"://"+u.Host] = true
+	}
+
+	p.rules.Store(rules)
+}
+
+// allowed 判断来源是否允许跨域访问
+func (r *corsRules) allowed(origin string) bool {
+	if r.anyOrigin {
+		return true
+	}
+
+	origin = strings.ToLower(origin)
+	if r.origins[origin] {
+		return true
+	}
+
+	scheme, host, ok := strings.Cut(origin, "://")
+	if !ok {
+		return false
+	}
+	for _, w := range r.wildcards {
+		// 通配只匹配子域名，不匹配域名本身
+		if scheme == w.scheme && len(host) > len(w.suffix) && strings.HasSuffix(host, w.suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// CORS 跨域中间件
+// 来源不在允许列表中时不返回跨域响应头，由浏览器拦截；预检请求直接返回 403
+// 允许携带凭据时回显请求的来源而不是 *，每个请求读取当前配置，配置热更新后立即生效
+func CORS(policy *CORSPolicy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+
+		rules := policy.rules.Load()
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+		c.Writer.Header().Add("Vary", "Origin")
+
+		if !rules.allowed(origin) {
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		if rules.anyOrigin && !rules.allowCredentials {
+			c.Header("Access-Control-Allow-Origin", "*")
+		} else {
+			c.Header("Access-Control-Allow-Origin", origin)
+		}
+		if rules.allowCredentials {
+			c.Header("Access-Control-Allow-Credentials", "true")
+		}
+
+		if preflight {
+			c.Writer.Header().Add("Vary", "Access-Control-Request-Method")
+			c.Writer.Header().Add("Vary", "Access-Control-Request-Headers")
+			c.Header("Access-Control-Allow-Methods", rules.methods)
+			c.Header("Access-Control-Allow-Headers", rules.headers)
+			if rules.maxAge != "" {
+				c.Header("Access-Control-Max-Age", rules.maxAge)
+			}
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		c.Header("Access-Control-Expose-Headers", rules.exposed)
+		c.Next()
+	}
+}
+
+// mergeTokens 合并内置和配置的请求头，忽略大小写去重
+func mergeTokens(builtin, extra []string) []string {
+	return normalizeTokens(append(append([]string{}, builtin...), extra...), nil)
+}
+
+// normalizeTokens 去除空白和重复项，transform 不为空时先转换
+func normalizeTokens(tokens []string, transform func(string) string) []string {
+	seen := make(map[string]bool, len(tokens))
+	out := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		token = strings.TrimSpace(token)
+		if transform != nil {
+			token = transform(token)
+		}
+		if token == "" || seen[strings.ToLower(token)] {
+			continue
+		}
+		seen[strings.ToLower(token)] = true
+		out = append(out, token)
+	}
+	return out
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"fmt"
+	"stars-admin/internal/apperrors"
+	"stars-admin/internal/ipfilter"
+	"stars-admin/internal/models"
+	"stars-admin/internal/services"
+	"stars-admin/internal/tracing"
+	"stars-admin/internal/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IPFilter IP 访问控制中间件，按作用范围检查客户端 IP，被拦截的请求记录到安全日志
+// 客户端 IP 取自 c.ClientIP()，部署在反向代理之后时需配置 server.trusted_proxies
+func IPFilter(manager *ipfilter.Manager, scope string, logs *services.SecurityLogWriter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ip := c.ClientIP()
+		decision := manager.Check(c.Request.Context(), scope, ip)
+		if decision.Allowed {
+			c.Next()
+			return
+		}
+
+		detail := fmt.Sprintf("scope=%s reason=%s", scope, decision.Reason)
+		if decision.RuleID != 0 {
+			detail += fmt.Sprintf(" rule=%d", decision.RuleID)
+		}
+		logs.Record(securityLog(c, services.SecurityEventIPBlocked, detail))
+
+		utils.Fail(c, apperrors.ErrIPBlocked)
+	}
+}
+
+// securityLog 根据请求构建安全日志，已登录时带上用户信息
+func securityLog(c *gin.Context, event, detail string) models.SecurityLog {
+	entry := models.SecurityLog{
+		Event:     event,
+		IP:        c.ClientIP(),
+		Method:    c.Request.Method,
+		Path:      c.Request.URL.Path,
+		UserAgent: c.Request.UserAgent(),
+		Detail:    detail,
+		TraceID:   tracing.TraceID(c.Request.Context()),
+		CreatedAt: time.Now(),
+	}
+	if claims, ok := c.Get("user"); ok {
+		if userClaims, ok := claims.(*utils.JWTClaims); ok {
+			entry.UserID = userClaims.UserID
+			entry.Username = userClaims.Username
+		}
+	}
+	return entry
+}
@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"stars-admin/internal/i18n"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Locale 语言协商中间件，按 Accept-Language 选择已加载的语言
+// 登录用户设置了偏好语言时由 AuthMiddleware 覆盖
+func Locale() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		setLocale(c, i18n.Default.Match(c.GetHeader("Accept-Language")))
+		c.Next()
+	}
+}
+
+// setLocale 设置本次请求使用的语言
+func setLocale(c *gin.Context, locale string) {
+	c.Set("locale", locale)
+	c.Header("Content-Language", locale)
+	c.Request = c.Request.WithContext(i18n.WithLocale(c.Request.Context(), locale))
+}
@@ -2,25 +2,74 @@ package middleware
 
 import (
 	"bytes"
+	"context"
 	"io"
+	"net/http"
+	"runtime/debug"
+	"stars-admin/internal/apperrors"
+	"stars-admin/internal/i18n"
 	"time"
+	"stars-admin/internal/logger"
 	"stars-admin/internal/models"
+	"stars-admin/internal/services"
+	"stars-admin/internal/tracing"
 	"stars-admin/internal/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
-	"gorm.io/gorm"
 )
 
-// Logger 日志中间件
+// Logger 访问日志中间件，每个请求输出一行结构化日志
 func Logger() gin.HandlerFunc {
-	return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
-		return ""
-	})
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		status := c.Writer.Status()
+		fields := logrus.Fields{
+			"method":     c.Request.Method,
+			"route":      c.FullPath(),
+			"path":       c.Request.URL.Path,
+			"status":     status,
+			"latency_ms": float64(time.Since(start).Microseconds()) / 1000,
+			"client_ip":  c.ClientIP(),
+			"user_agent": c.Request.UserAgent(),
+			"size":       c.Writer.Size(),
+		}
+		if userID, exists := c.Get("user_id"); exists {
+			fields["user_id"] = userID
+		}
+		if len(c.Errors) > 0 {
+			fields["errors"] = c.Errors.String()
+		}
+
+		entry := logger.FromContext(c.Request.Context()).WithFields(fields)
+		switch {
+		case status >= 500:
+			entry.Error("HTTP request")
+		case status >= 400:
+			entry.Warn("HTTP request")
+		default:
+			entry.Info("HTTP request")
+		}
+	}
+}
+
+// omitResponseLogKey 上下文中不记录响应体的标记
+const omitResponseLogKey = "operation_log_omit_response"
+
+// OmitResponseLog 操作日志不记录响应体，用于响应中包含只返回一次的密钥等敏感信息的接口
+func OmitResponseLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(omitResponseLogKey, true)
+		c.Next()
+	}
 }
 
 // OperationLogger 操作日志中间件
-func OperationLogger(db *gorm.DB) gin.HandlerFunc {
+// 日志在请求结束时同步构建，再交给写入器异步落库，避免后台协程读取已复用的 gin.Context
+func OperationLogger(writer *services.OperationLogWriter) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
 		
@@ -38,38 +87,36 @@ func OperationLogger(db *gorm.DB) gin.HandlerFunc {
 		// 处理请求
 		c.Next()
 
-		// 记录操作日志
-		go func() {
-			latency := time.Since(start).Milliseconds()
-			
-			// 获取用户信息
-			var userID uint
-			var username string
-			if claims, exists := c.Get("user"); exists {
-				if userClaims, ok := claims.(*utils.JWTClaims); ok {
-					userID = userClaims.UserID
-					username = userClaims.Username
-				}
+		// 获取用户信息
+		var userID uint
+		var username string
+		if claims, exists := c.Get("user"); exists {
+			if userClaims, ok := claims.(*utils.JWTClaims); ok {
+				userID = userClaims.UserID
+				username = userClaims.Username
 			}
+		}
 
-			// 创建操作日志
-			operationLog := models.OperationLog{
-				UserID:    userID,
-				Username:  username,
-				Method:    c.Request.Method,
-				Path:      c.Request.URL.Path,
-				IP:        c.ClientIP(),
-				UserAgent: c.Request.UserAgent(),
-				Status:    c.Writer.Status(),
-				Latency:   latency,
-				Request:   string(requestBody),
-				Response:  blw.body.String(),
-				CreatedAt: time.Now(),
-			}
+		response := blw.body.String()
+		if c.GetBool(omitResponseLogKey) {
+			response = ""
+		}
 
-			// 保存到数据库
-			db.Create(&operationLog)
-		}()
+		// 记录操作日志
+		writer.Write(models.OperationLog{
+			UserID:    userID,
+			Username:  username,
+			Method:    c.Request.Method,
+			Path:      c.Request.URL.Path,
+			IP:        c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+			Status:    c.Writer.Status(),
+			Latency:   time.Since(start).Milliseconds(),
+			Request:   string(requestBody),
+			Response:  response,
+			TraceID:   tracing.TraceID(c.Request.Context()),
+			CreatedAt: time.Now(),
+		})
 	}
 }
 
@@ -85,51 +132,86 @@ func (w bodyLogWriter) Write(b []byte) (int, error) {
 }
 
 // ErrorHandler 错误处理中间件
+// 统一输出处理过程中通过 c.Error 记录的错误和 panic，客户端只能看到错误码和安全的提示信息
 func ErrorHandler() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		defer func() {
 			if err := recover(); err != nil {
-				logrus.WithFields(logrus.Fields{
-					"error": err,
-					"path":  c.Request.URL.Path,
+				logger.FromContext(c.Request.Context()).WithFields(logrus.Fields{
+					"error":  err,
+					"path":   c.Request.URL.Path,
 					"method": c.Request.Method,
+					"stack":  string(debug.Stack()),
 				}).Error("Panic recovered")
-				
-				c.JSON(500, gin.H{
-					"code":    500,
-					"message": "Internal Server Error",
-					"data":    nil,
-				})
+
+				renderError(c, apperrors.ErrInternal)
 				c.Abort()
 			}
 		}()
-		
+
 		c.Next()
+
+		if len(c.Errors) > 0 && !c.Writer.Written() {
+			renderError(c, c.Errors.Last().Err)
+		}
 	}
 }
 
-// CORS 跨域中间件
-func CORS() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Authorization")
-		c.Header("Access-Control-Allow-Credentials", "true")
-
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
-			return
-		}
+// renderError 按应用错误输出响应，提示信息按请求语言翻译，内部原因只写入日志
+func renderError(c *gin.Context, err error) {
+	appErr := apperrors.From(err)
+	ctx := c.Request.Context()
 
-		c.Next()
+	entry := logger.FromContext(c.Request.Context()).WithFields(logrus.Fields{
+		"code":   appErr.Code,
+		"key":    appErr.Key,
+		"path":   c.Request.URL.Path,
+		"method": c.Request.Method,
+	})
+	if cause := appErr.Unwrap(); cause != nil {
+		entry = entry.WithError(cause)
+	}
+	if appErr.Status() >= http.StatusInternalServerError {
+		entry.Error("Request failed")
+	} else {
+		entry.Debug("Request rejected")
+	}
+
+	message, ok := i18n.Lookup(ctx, "error."+appErr.Key, nil)
+	if !ok {
+		message = appErr.Message
 	}
+
+	details := appErr.Details
+	if fields, ok := details.([]apperrors.FieldError); ok {
+		details = translateFieldErrors(ctx, fields)
+	}
+
+	c.JSON(appErr.Status(), utils.Response{
+		Code:    appErr.Code,
+		Message: message,
+		Data:    details,
+	})
 }
 
-// RateLimiter 限流中间件
-func RateLimiter() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// 这里可以实现基于Redis的限流逻辑
-		// 暂时跳过
-		c.Next()
+// translateFieldErrors 按请求语言生成字段校验错误的提示信息
+func translateFieldErrors(ctx context.Context, fields []apperrors.FieldError) []apperrors.FieldError {
+	translated := make([]apperrors.FieldError, len(fields))
+	for i, fe := range fields {
+		field, ok := i18n.Lookup(ctx, "field."+fe.Field, nil)
+		if !ok {
+			field = fe.Field
+		}
+
+		args := i18n.Args{"field": field, "param": fe.Param}
+		message, ok := i18n.Lookup(ctx, "validation."+fe.Rule, args)
+		if !ok {
+			message = i18n.T(ctx, "validation.default", args)
+		}
+
+		fe.Message = message
+		translated[i] = fe
 	}
-}
\ No newline at end of file
+
+	return translated
+}
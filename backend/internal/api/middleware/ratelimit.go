@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"stars-admin/internal/apperrors"
+	"stars-admin/internal/ratelimit"
+	"stars-admin/internal/store"
+	"stars-admin/internal/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHeader API Key 请求头
+const APIKeyHeader = "X-API-Key"
+
+// RateLimiter 限流中间件
+// 规则按路由前缀匹配，所有匹配的规则同时生效：全部通过才放行并消耗配额，任一规则拒绝时不消耗其他规则的配额；
+// 存储不可用时按故障策略降级到进程内限流或拒绝请求
+// 每个请求读取规则集的当前规则，配置热更新后立即生效
+func RateLimiter(st store.Store, rules *ratelimit.RuleSet) gin.HandlerFunc {
+	limiter := ratelimit.New(st)
+	fallback := ratelimit.NewMemoryLimiter()
+
+	return func(c *gin.Context) {
+		var checks []ratelimit.Check
+		for _, rule := range rules.Rules() {
+			if !strings.HasPrefix(c.Request.URL.Path, rule.Prefix) {
+				continue
+			}
+
+			identity, ok := rateLimitIdentity(c, rule.By)
+			if !ok {
+				continue
+			}
+			checks = append(checks, ratelimit.Check{
+				Key:  fmt.Sprintf("%s:%s:%s", rule.Name, rule.By, identity),
+				Rule: rule,
+			})
+		}
+		if len(checks) == 0 {
+			c.Next()
+			return
+		}
+
+		results, err := limiter.Allow(c.Request.Context(), checks)
+		if err != nil {
+			if !store.FailOpenFor(st, store.CheckRateLimit) {
+				utils.Fail(c, apperrors.ErrRateLimitUnavailable.Wrap(err))
+				return
+			}
+			results, _ = fallback.Allow(context.Background(), checks)
+		}
+
+		result := reportedResult(results)
+		setRateLimitHeaders(c, result)
+		if !result.Allowed {
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			utils.Fail(c, apperrors.ErrRateLimited)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// reportedResult 选择写入响应头的结果：被拒绝时取需要等待最久的规则，放行时取剩余配额最少的规则
+func reportedResult(results []ratelimit.Result) ratelimit.Result {
+	var denied, tightest *ratelimit.Result
+	for i := range results {
+		r := &results[i]
+		if !r.Allowed {
+			if denied == nil || r.RetryAfter > denied.RetryAfter {
+				denied = r
+			}
+			continue
+		}
+		if tightest == nil || r.Remaining < tightest.Remaining {
+			tightest = r
+		}
+	}
+	if denied != nil {
+		return *denied
+	}
+	return *tightest
+}
+
+// rateLimitIdentity 获取限流维度对应的标识，无法识别时跳过该规则
+func rateLimitIdentity(c *gin.Context, by string) (string, bool) {
+	switch by {
+	case ratelimit.ByUser:
+		if userID, exists := c.Get("user_id"); exists {
+			return fmt.Sprintf("%v", userID), true
+		}
+		return "", false
+	case ratelimit.ByAPIKey:
+		if key, ok := apiKeyFromRequest(c); ok {
+			return utils.GetTokenHash(key), true
+		}
+		return "", false
+	default:
+		return c.ClientIP(), true
+	}
+}
+
+// setRateLimitHeaders 设置标准 RateLimit-* 响应头
+func setRateLimitHeaders(c *gin.Context, result ratelimit.Result) {
+	c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+	c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter)))
+}
+
+// ceilSeconds 向上取整到秒
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"stars-admin/internal/apperrors"
+	"stars-admin/internal/ratelimit"
+	"stars-admin/internal/store"
+	"stars-admin/internal/utils"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+)
+
+// newRateLimitRouter 创建使用限流中间件的路由，X-Test-User 请求头模拟已登录用户
+func newRateLimitRouter(st store.Store, rules []ratelimit.Rule) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(ErrorHandler())
+	r.Use(func(c *gin.Context) {
+		if user := c.GetHeader("X-Test-User"); user != "" {
+			c.Set("user_id", user)
+		}
+	})
+	r.Use(RateLimiter(st, ratelimit.NewRuleSet(rules)))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	r.GET("/api/v1/auth/login", ok)
+	r.GET("/api/v1/users", ok)
+	r.GET("/healthz", ok)
+	return r
+}
+
+func serve(r *gin.Engine, path, user string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if user != "" {
+		req.Header.Set("X-Test-User", user)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// assertLimited 断言请求被限流
+func assertLimited(t *testing.T, w *httptest.ResponseRecorder) {
+	t.Helper()
+	var resp utils.Response
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusTooManyRequests || resp.Code != apperrors.ErrRateLimited.Code {
+		t.Errorf("got %d %+v, want rate limited", w.Code, resp)
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	st := store.NewMemoryStore()
+	defer st.Close()
+	r := newRateLimitRouter(st, []ratelimit.Rule{
+		{Name: "global", Prefix: "/api", By: ratelimit.ByIP, Algorithm: ratelimit.AlgorithmTokenBucket, Limit: 10, Window: time.Minute, Burst: 10},
+		{Name: "login", Prefix: "/api/v1/auth", By: ratelimit.ByIP, Algorithm: ratelimit.AlgorithmSlidingWindow, Limit: 2, Window: time.Minute},
+		{Name: "user", Prefix: "/api/v1/users", By: ratelimit.ByUser, Algorithm: ratelimit.AlgorithmSlidingWindow, Limit: 1, Window: time.Minute},
+	})
+
+	// 响应头取剩余配额最少的规则
+	for _, remaining := range []string{"1", "0"} {
+		w := serve(r, "/api/v1/auth/login", "")
+		if w.Code != http.StatusOK || w.Header().Get("RateLimit-Limit") != "2" || w.Header().Get("RateLimit-Remaining") != remaining {
+			t.Errorf("login: got %d %v", w.Code, w.Header())
+		}
+		if reset, _ := strconv.Atoi(w.Header().Get("RateLimit-Reset")); reset <= 0 || reset > 60 {
+			t.Errorf("RateLimit-Reset: got %d", reset)
+		}
+	}
+
+	w := serve(r, "/api/v1/auth/login", "")
+	assertLimited(t, w)
+	if w.Header().Get("RateLimit-Remaining") != "0" || w.Header().Get("Retry-After") == "" || w.Header().Get("Retry-After") == "0" {
+		t.Errorf("limited response headers: %v", w.Header())
+	}
+
+	// 被拒绝的请求不消耗全局规则的配额，未登录时跳过按用户限流的规则
+	w = serve(r, "/api/v1/users", "")
+	if w.Code != http.StatusOK || w.Header().Get("RateLimit-Limit") != "10" || w.Header().Get("RateLimit-Remaining") != "7" {
+		t.Errorf("users: got %d %v", w.Code, w.Header())
+	}
+
+	// 按用户限流的规则各用户独立计数
+	if w := serve(r, "/api/v1/users", "1"); w.Code != http.StatusOK || w.Header().Get("RateLimit-Remaining") != "0" {
+		t.Errorf("user 1: got %d %v", w.Code, w.Header())
+	}
+	assertLimited(t, serve(r, "/api/v1/users", "1"))
+	if w := serve(r, "/api/v1/users", "2"); w.Code != http.StatusOK {
+		t.Errorf("user 2: got %d", w.Code)
+	}
+
+	// 没有匹配的规则时不限流也不输出响应头
+	if w := serve(r, "/healthz", ""); w.Code != http.StatusOK || w.Header().Get("RateLimit-Limit") != "" {
+		t.Errorf("unmatched path: got %d %v", w.Code, w.Header())
+	}
+}
+
+func TestRateLimiterStoreFailure(t *testing.T) {
+	rules := []ratelimit.Rule{{Name: "global", By: ratelimit.ByIP, Algorithm: ratelimit.AlgorithmSlidingWindow, Limit: 1, Window: time.Minute}}
+	// unavailableStore 返回底层 Redis 已停止的容错存储
+	unavailableStore := func(policy string) store.Store {
+		m := miniredis.RunT(t)
+		rs := store.NewRedisStore(redis.NewClient(&redis.Options{Addr: m.Addr(), MaxRetries: -1}))
+		t.Cleanup(func() { rs.Close() })
+		m.Close()
+		return store.NewResilient(rs, store.ResilientOptions{
+			Timeout:  time.Second,
+			Policies: map[string]string{store.CheckRateLimit: policy},
+		})
+	}
+
+	// 故障关闭时拒绝请求
+	r := newRateLimitRouter(unavailableStore(store.FailClosed), rules)
+	w := serve(r, "/api/v1/users", "")
+	var resp utils.Response
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusServiceUnavailable || resp.Code != apperrors.ErrRateLimitUnavailable.Code {
+		t.Errorf("fail closed: got %d %+v", w.Code, resp)
+	}
+
+	// 故障开放时降级到进程内限流
+	r = newRateLimitRouter(unavailableStore(store.FailOpen), rules)
+	if w := serve(r, "/api/v1/users", ""); w.Code != http.StatusOK || w.Header().Get("RateLimit-Remaining") != "0" {
+		t.Errorf("fail open: got %d %v", w.Code, w.Header())
+	}
+	assertLimited(t, serve(r, "/api/v1/users", ""))
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"regexp"
+	"stars-admin/internal/logger"
+	"stars-admin/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDHeader 请求ID请求头
+const RequestIDHeader = "X-Request-ID"
+
+// validRequestID 允许沿用的上游请求ID格式，避免任意内容写入日志
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,64}$`)
+
+// RequestID 请求ID中间件，沿用上游传入的请求ID，没有时生成新的
+// 请求ID写入响应头、gin 上下文和请求上下文，后续日志和数据库日志都会带上
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID.MatchString(requestID) {
+			requestID, _ = utils.GenerateRandomString(16)
+		}
+
+		c.Set("request_id", requestID)
+		c.Header(RequestIDHeader, requestID)
+		c.Request = c.Request.WithContext(logger.WithRequestID(c.Request.Context(), requestID))
+
+		trace.SpanFromContext(c.Request.Context()).SetAttributes(attribute.String("request.id", requestID))
+
+		c.Next()
+	}
+}
@@ -0,0 +1,143 @@
+package middleware
+
+import (
+	"sort"
+	"stars-admin/internal/config"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// headerUnset 路由覆盖中表示不发送该响应头
+const headerUnset = "-"
+
+// securityHeaders 一组安全响应头，值为空时不发送
+type securityHeaders struct {
+	csp            string
+	frameOptions   string
+	referrerPolicy string
+}
+
+// securityRoute 按路由前缀覆盖的安全响应头
+type securityRoute struct {
+	prefix  string
+	headers securityHeaders
+}
+
+// securityRules 解析后的安全响应头配置
+type securityRules struct {
+	enabled  bool
+	hsts     string
+	nosniff  bool
+	defaults securityHeaders
+	routes   []securityRoute // 按前缀长度降序
+}
+
+// SecurityHeadersPolicy 可在运行时替换的安全响应头配置
+type SecurityHeadersPolicy struct {
+	rules atomic.Pointer[securityRules]
+}
+
+// NewSecurityHeadersPolicy 创建安全响应头配置
+func NewSecurityHeadersPolicy(cfg config.HeadersConfig) *SecurityHeadersPolicy {
+	p := &SecurityHeadersPolicy{}
+	p.Set(cfg)
+	return p
+}
+
+// Set 替换安全响应头配置
+func (p *SecurityHeadersPolicy) Set(cfg config.HeadersConfig) {
+	rules := &securityRules{
+		enabled: cfg.Enabled,
+		nosniff: cfg.ContentTypeNosniff,
+		defaults: securityHeaders{
+			csp:            cfg.ContentSecurityPolicy,
+			frameOptions:   cfg.FrameOptions,
+			referrerPolicy: cfg.ReferrerPolicy,
+		},
+	}
+
+	if cfg.HSTS.Enabled {
+		hsts := "max-age=" + strconv.Itoa(cfg.HSTS.MaxAge)
+		if cfg.HSTS.IncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+		if cfg.HSTS.Preload {
+			hsts += "; preload"
+		}
+		rules.hsts = hsts
+	}
+
+	for _, route := range cfg.Routes {
+		rules.routes = append(rules.routes, securityRoute{
+			prefix: route.Prefix,
+			headers: securityHeaders{
+				csp:            override(rules.defaults.csp, route.ContentSecurityPolicy),
+				frameOptions:   override(rules.defaults.frameOptions, route.FrameOptions),
+				referrerPolicy: override(rules.defaults.referrerPolicy, route.ReferrerPolicy),
+			},
+		})
+	}
+	sort.SliceStable(rules.routes, func(i, j int) bool {
+		return len(rules.routes[i].prefix) > len(rules.routes[j].prefix)
+	})
+
+	p.rules.Store(rules)
+}
+
+// headersFor 返回请求路径适用的响应头，匹配多条覆盖规则时使用前缀最长的一条
+func (r *securityRules) headersFor(path string) securityHeaders {
+	for _, route := range r.routes {
+		if strings.HasPrefix(path, route.prefix) {
+			return route.headers
+		}
+	}
+	return r.defaults
+}
+
+// override 计算路由覆盖后的值，为空沿用全局值，为 "-" 时不发送
+func override(base, value string) string {
+	switch value {
+	case "":
+		return base
+	case headerUnset:
+		return ""
+	default:
+		return value
+	}
+}
+
+// SecurityHeaders 安全响应头中间件
+// HSTS 只在 HTTPS 请求（含反向代理转发的 X-Forwarded-Proto: https）中发送
+// 每个请求读取当前配置，配置热更新后立即生效
+func SecurityHeaders(policy *SecurityHeadersPolicy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rules := policy.rules.Load()
+		if !rules.enabled {
+			c.Next()
+			return
+		}
+
+		if rules.hsts != "" && (c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https") {
+			c.Header("Strict-Transport-Security", rules.hsts)
+		}
+		if rules.nosniff {
+			c.Header("X-Content-Type-Options", "nosniff")
+		}
+
+		headers := rules.headersFor(c.Request.URL.Path)
+		if headers.csp != "" {
+			c.Header("Content-Security-Policy", headers.csp)
+		}
+		if headers.frameOptions != "" {
+			c.Header("X-Frame-Options", headers.frameOptions)
+		}
+		if headers.referrerPolicy != "" {
+			c.Header("Referrer-Policy", headers.referrerPolicy)
+		}
+
+		c.Next()
+	}
+}
@@ -2,44 +2,174 @@ package routes
 
 import (
 	"stars-admin/internal/api/handlers"
+	"stars-admin/internal/apperrors"
 	"stars-admin/internal/api/middleware"
+	"stars-admin/internal/config"
+	"stars-admin/internal/ipfilter"
+	"stars-admin/internal/lifecycle"
+	"stars-admin/internal/mail"
+	"stars-admin/internal/metrics"
+	"stars-admin/internal/oidc"
+	"stars-admin/internal/ratelimit"
+	"stars-admin/internal/services"
+	"stars-admin/internal/settings"
+	"stars-admin/internal/store"
+	"stars-admin/internal/utils"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/go-redis/redis/v8"
 	"gorm.io/gorm"
 )
 
 // RegisterRoutes 注册路由
-func RegisterRoutes(r *gin.Engine, db *gorm.DB, rdb *redis.Client) {
+// 需要随服务启停的后台组件注册到 lc，支持热更新的组件订阅 watcher
+func RegisterRoutes(r *gin.Engine, watcher *config.Watcher, db *gorm.DB, st store.Store, lc *lifecycle.Registry) error {
+	cfg := watcher.Current()
+
+	// 系统配置，默认值取自配置文件
+	settingsManager, err := settings.NewManager(db, st, settings.Definitions(cfg))
+	if err != nil {
+		return err
+	}
+	lc.Register(settingsManager)
+	watcher.Subscribe(config.Subscriber{
+		Name: "settings",
+		Apply: func(old, new *config.Config) {
+			settingsManager.SetDefaults(settings.Definitions(new))
+		},
+	})
+
+	// 安全日志异步写入，与操作日志共用队列长度配置
+	securityLogs := services.NewSecurityLogWriter(db, cfg.Server.OperationLogQueue)
+	lc.Register(securityLogs)
+	metrics.RegisterQueue("security_log", securityLogs.Len)
+
+	// IP 访问控制，规则保存在数据库中，启动时加载
+	ipRules := ipfilter.NewManager(db, st)
+	lc.Register(ipRules)
+
+	// 邮件异步发送，email.driver 为 none 时不发送
+	mailer, err := mail.New(cfg.Email, cfg.I18n.DefaultLocale)
+	if err != nil {
+		return err
+	}
+	lc.Register(mailer)
+	metrics.RegisterQueue("mail", mailer.Len)
+
+	// API 密钥与服务账号管理
+	apiKeys := services.NewAPIKeyService(db)
+
+	// LDAP 目录同步，禁用目录中已不存在的用户，ldap.enabled 为 false 时不启用
+	ldapSync := services.NewLDAPSync(db, st, securityLogs, cfg.LDAP)
+	if ldapSync != nil {
+		lc.Register(ldapSync)
+	}
+
+	// 认证方式链：用户名密码登录按 security.authenticators 的顺序尝试，OIDC 回调和 API 密钥由各自的认证方式处理
+	oidcProviders := oidc.NewRegistry(cfg.OIDC)
+	authenticators, err := services.NewAuthenticators(db, cfg, securityLogs, oidcProviders)
+	if err != nil {
+		return err
+	}
+	// 认证中间件校验 API 密钥，与登录共用认证方式链
+	authService := services.NewAuthService(db, st, settingsManager, securityLogs).WithAuthenticators(authenticators)
+
 	// 创建处理器
-	authHandler := handlers.NewAuthHandler(db, rdb)
+	authHandler := handlers.NewAuthHandler(db, st, settingsManager, securityLogs, authenticators)
+	settingsHandler := handlers.NewSettingsHandler(settingsManager)
+	configHandler := handlers.NewConfigHandler(watcher)
+	networkHandler := handlers.NewNetworkHandler(db, st, ipRules)
+	passwordResetHandler := handlers.NewPasswordResetHandler(db, st, settingsManager, mailer, securityLogs, cfg.Security.PasswordReset)
+	registrationHandler := handlers.NewRegistrationHandler(db, st, settingsManager, mailer, securityLogs, cfg.Security.Registration)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeys)
+	oidcHandler := handlers.NewOIDCHandler(db, st, settingsManager, securityLogs, authenticators, oidcProviders, cfg.OIDC)
+	healthHandler := handlers.NewHealthHandler(db, st, lc, time.Duration(cfg.Server.HealthTimeout)*time.Second)
+
+	// 操作日志异步写入
+	operationLogs := services.NewOperationLogWriter(db, cfg.Server.OperationLogQueue)
+	lc.Register(operationLogs)
+	metrics.RegisterQueue("operation_log", operationLogs.Len)
+
+	// 限流规则，私有路由在认证之后限流以便按用户维度计数
+	rateLimitRules, err := ratelimit.RulesFromConfig(cfg.Security.RateLimit)
+	if err != nil {
+		return err
+	}
+	rateLimitRuleSet := ratelimit.NewRuleSet(rateLimitRules)
+	rateLimiter := middleware.RateLimiter(st, rateLimitRuleSet)
+	watcher.Subscribe(config.Subscriber{
+		Name: "rate_limit",
+		Validate: func(cfg *config.Config) error {
+			_, err := ratelimit.RulesFromConfig(cfg.Security.RateLimit)
+			return err
+		},
+		Apply: func(old, new *config.Config) {
+			rules, _ := ratelimit.RulesFromConfig(new.Security.RateLimit)
+			rateLimitRuleSet.Set(rules)
+		},
+	})
 	
+	// 全局 IP 访问控制，需在注册路由之前添加
+	r.Use(middleware.IPFilter(ipRules, ipfilter.ScopeGlobal, securityLogs))
+
+	// Prometheus 指标
+	if cfg.Monitoring.Enabled {
+		var handlers []gin.HandlerFunc
+		if cfg.Monitoring.Username != "" {
+			handlers = append(handlers, gin.BasicAuth(gin.Accounts{cfg.Monitoring.Username: cfg.Monitoring.Password}))
+		}
+		handlers = append(handlers, gin.WrapH(metrics.Handler()))
+		r.GET(cfg.Monitoring.MetricsPath, handlers...)
+	}
+
+	// 未匹配的路由统一返回错误码
+	r.NoRoute(func(c *gin.Context) {
+		utils.Fail(c, apperrors.ErrNotFound)
+	})
+
+	// 存活与就绪探针
+	r.GET("/livez", healthHandler.Livez)
+	r.GET("/readyz", healthHandler.Readyz)
+
 	// API路由组
 	api := r.Group("/api/v1")
 	
 	// 公共路由（不需要认证）
 	public := api.Group("")
+	public.Use(rateLimiter)
 	{
 		// 认证相关路由
 		auth := public.Group("/auth")
 		{
 			auth.POST("/login", authHandler.Login)
 			auth.POST("/refresh", authHandler.RefreshToken)
+			auth.GET("/password-policy", authHandler.PasswordPolicy)
+			auth.POST("/forgot-password", passwordResetHandler.Forgot)
+			auth.POST("/reset-password", passwordResetHandler.Reset)
+			auth.POST("/register", registrationHandler.Register)
+			auth.POST("/verify-email", registrationHandler.VerifyEmail)
+			auth.POST("/verify-email/resend", registrationHandler.ResendVerification)
+			auth.POST("/invitations/accept", registrationHandler.AcceptInvitation)
+
+			// OpenID Connect 登录
+			auth.GET("/oidc/providers", oidcHandler.Providers)
+			auth.GET("/oidc/:provider/authorize", oidcHandler.Authorize)
+			auth.GET("/oidc/:provider/callback", oidcHandler.Callback)
+			auth.POST("/oidc/exchange", oidcHandler.Exchange)
 		}
 		
-		// 健康检查
-		public.GET("/health", func(c *gin.Context) {
-			c.JSON(200, gin.H{
-				"status": "ok",
-				"message": "Service is running",
-			})
-		})
+		// 错误码目录
+		public.GET("/errors", handlers.ErrorCatalog)
+
+		// 健康检查，与 /readyz 相同
+		public.GET("/health", healthHandler.Readyz)
 	}
 	
 	// 私有路由（需要认证）
 	private := api.Group("")
-	private.Use(middleware.AuthMiddleware(db, rdb))
-	private.Use(middleware.OperationLogger(db))
+	private.Use(middleware.AuthMiddleware(db, st, securityLogs, authService))
+	private.Use(rateLimiter)
+	private.Use(middleware.OperationLogger(operationLogs))
 	{
 		// 认证相关路由
 		auth := private.Group("/auth")
@@ -47,6 +177,12 @@ func RegisterRoutes(r *gin.Engine, db *gorm.DB, rdb *redis.Client) {
 			auth.POST("/logout", authHandler.Logout)
 			auth.GET("/user", authHandler.GetUserInfo)
 			auth.PUT("/password", authHandler.UpdatePassword)
+			auth.PUT("/preferences", authHandler.UpdatePreferences)
+
+			// 个人 API 密钥，不能通过 API 密钥访问
+			auth.GET("/api-keys", apiKeyHandler.ListMine)
+			auth.POST("/api-keys", middleware.OmitResponseLog(), apiKeyHandler.CreateMine)
+			auth.DELETE("/api-keys/:id", apiKeyHandler.RevokeMine)
 		}
 		
 		// 用户管理路由
@@ -67,8 +203,31 @@ func RegisterRoutes(r *gin.Engine, db *gorm.DB, rdb *redis.Client) {
 			users.DELETE("/:id", func(c *gin.Context) {
 				c.JSON(200, gin.H{"message": "删除用户"})
 			})
+			users.PUT("/:id/allowed-ips", middleware.RequireRole("admin"), networkHandler.UpdateUserAllowedIPs)
+
+			// 邀请与注册审核
+			users.GET("/invitations", middleware.RequireRole("admin"), registrationHandler.ListInvitations)
+			users.POST("/invitations", middleware.RequireRole("admin"), middleware.OmitResponseLog(), registrationHandler.Invite)
+			users.DELETE("/invitations/:id", middleware.RequireRole("admin"), registrationHandler.RevokeInvitation)
+			users.GET("/pending", middleware.RequireRole("admin"), registrationHandler.ListPendingUsers)
+			users.POST("/:id/approve", middleware.RequireRole("admin"), registrationHandler.Approve)
+			users.POST("/:id/reject", middleware.RequireRole("admin"), registrationHandler.Reject)
+			if ldapSync != nil {
+				users.POST("/ldap-sync", middleware.RequireRole("admin"), handlers.NewLDAPHandler(ldapSync).Sync)
+			}
 		}
 		
+		// 服务账号管理路由，不能通过 API 密钥访问
+		serviceAccounts := private.Group("/service-accounts", middleware.RequireRole("admin"))
+		{
+			serviceAccounts.GET("", apiKeyHandler.ListServiceAccounts)
+			serviceAccounts.POST("", apiKeyHandler.CreateServiceAccount)
+			serviceAccounts.DELETE("/:id", apiKeyHandler.DeleteServiceAccount)
+			serviceAccounts.GET("/:id/api-keys", apiKeyHandler.ListServiceAccountKeys)
+			serviceAccounts.POST("/:id/api-keys", middleware.OmitResponseLog(), apiKeyHandler.CreateServiceAccountKey)
+			serviceAccounts.DELETE("/:id/api-keys/:key_id", apiKeyHandler.RevokeServiceAccountKey)
+		}
+
 		// 角色管理路由
 		roles := private.Group("/roles")
 		{
@@ -110,20 +269,38 @@ func RegisterRoutes(r *gin.Engine, db *gorm.DB, rdb *redis.Client) {
 		}
 		
 		// 系统管理路由
-		system := private.Group("/system")
+		system := private.Group("/system", middleware.IPFilter(ipRules, ipfilter.ScopeSystem, securityLogs))
 		{
 			// 操作日志
 			system.GET("/logs", func(c *gin.Context) {
 				c.JSON(200, gin.H{"message": "操作日志"})
 			})
 			
+			// 依赖健康详情
+			system.GET("/health", middleware.RequireRole("admin"), healthHandler.Detail)
+
 			// 系统配置
-			system.GET("/config", func(c *gin.Context) {
-				c.JSON(200, gin.H{"message": "系统配置"})
-			})
-			system.PUT("/config", func(c *gin.Context) {
-				c.JSON(200, gin.H{"message": "更新系统配置"})
-			})
+			configs := system.Group("/config", middleware.RequireRole("admin"))
+			{
+				configs.GET("", settingsHandler.List)
+				configs.PUT("", settingsHandler.Update)
+				configs.GET("/history", settingsHandler.History)
+				configs.GET("/effective", configHandler.Effective)
+				configs.DELETE("/:key", settingsHandler.Reset)
+			}
+
+			// 网络访问控制
+			network := system.Group("/network", middleware.RequireRole("admin"))
+			{
+				network.GET("/ip-rules", networkHandler.ListIPRules)
+				network.POST("/ip-rules", networkHandler.CreateIPRule)
+				network.DELETE("/ip-rules/:id", networkHandler.DeleteIPRule)
+			}
+
+			// 安全日志
+			system.GET("/security-logs", middleware.RequireRole("admin"), networkHandler.SecurityLogs)
 		}
 	}
-}
\ No newline at end of file
+
+	return nil
+}
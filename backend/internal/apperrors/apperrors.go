@@ -0,0 +1,153 @@
+package apperrors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// Category 错误类别，决定响应的HTTP状态码
+type Category string
+
+// 错误类别
+const (
+	CategoryBadRequest       Category = "bad_request"
+	CategoryUnauthenticated  Category = "unauthenticated"
+	CategoryPermissionDenied Category = "permission_denied"
+	CategoryNotFound         Category = "not_found"
+	CategoryConflict         Category = "conflict"
+	CategoryRateLimited      Category = "rate_limited"
+	CategoryInternal         Category = "internal"
+	CategoryUnavailable      Category = "unavailable"
+)
+
+// categoryStatus 各类别对应的HTTP状态码
+var categoryStatus = map[Category]int{
+	CategoryBadRequest:       http.StatusBadRequest,
+	CategoryUnauthenticated:  http.StatusUnauthorized,
+	CategoryPermissionDenied: http.StatusForbidden,
+	CategoryNotFound:         http.StatusNotFound,
+	CategoryConflict:         http.StatusConflict,
+	CategoryRateLimited:      http.StatusTooManyRequests,
+	CategoryInternal:         http.StatusInternalServerError,
+	CategoryUnavailable:      http.StatusServiceUnavailable,
+}
+
+// HTTPStatus 类别对应的HTTP状态码
+func (c Category) HTTPStatus() int {
+	if status, ok := categoryStatus[c]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// Error 应用错误
+// Message 和 Details 会返回给客户端，cause 为内部原因，只写入日志
+// 返回给客户端的提示信息按 Key 翻译，Message 为找不到翻译时的兜底
+type Error struct {
+	Code     int
+	Key      string
+	Category Category
+	Message  string
+	Details  interface{}
+	cause    error
+}
+
+// Error 实现 error
+func (e *Error) Error() string {
+	if e.cause != nil {
+		return fmt.Sprintf("%s(%d): %s: %v", e.Key, e.Code, e.Message, e.cause)
+	}
+	return fmt.Sprintf("%s(%d): %s", e.Key, e.Code, e.Message)
+}
+
+// Unwrap 返回内部原因
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Is 错误码相同即视为同一错误，便于 errors.Is 匹配带原因的副本
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// Status 响应的HTTP状态码
+func (e *Error) Status() int {
+	return e.Category.HTTPStatus()
+}
+
+// Wrap 返回附带内部原因的副本
+func (e *Error) Wrap(cause error) *Error {
+	clone := *e
+	clone.cause = cause
+	return &clone
+}
+
+// WithDetails 返回附带补充信息的副本
+func (e *Error) WithDetails(details interface{}) *Error {
+	clone := *e
+	clone.Details = details
+	return &clone
+}
+
+// From 将任意错误转换为应用错误，未定义的错误视为内部错误，原始信息不会返回给客户端
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return ErrInternal.Wrap(err)
+}
+
+// Entry 错误码目录条目
+type Entry struct {
+	Code     int      `json:"code"`
+	Key      string   `json:"key"`
+	Category Category `json:"category"`
+	Status   int      `json:"status"`
+	Message  string   `json:"message"`
+}
+
+var (
+	catalogMu sync.RWMutex
+	catalog   = make(map[int]*Error)
+)
+
+// New 定义错误码，错误码必须唯一，应在包初始化时调用
+func New(code int, key string, category Category, message string) *Error {
+	catalogMu.Lock()
+	defer catalogMu.Unlock()
+
+	if existing, ok := catalog[code]; ok {
+		panic(fmt.Sprintf("apperrors: code %d already defined as %s", code, existing.Key))
+	}
+
+	e := &Error{Code: code, Key: key, Category: category, Message: message}
+	catalog[code] = e
+	return e
+}
+
+// Catalog 返回按错误码排序的错误码目录
+func Catalog() []Entry {
+	catalogMu.RLock()
+	defer catalogMu.RUnlock()
+
+	entries := make([]Entry, 0, len(catalog))
+	for _, e := range catalog {
+		entries = append(entries, Entry{
+			Code:     e.Code,
+			Key:      e.Key,
+			Category: e.Category,
+			Status:   e.Status(),
+			Message:  e.Message,
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Code < entries[j].Code
+	})
+
+	return entries
+}
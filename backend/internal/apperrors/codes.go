@@ -0,0 +1,92 @@
+package apperrors
+
+// 错误码为五位数字，前三位与HTTP状态码一致，后两位区分具体原因
+// 错误码一经发布不再修改含义，废弃的错误码不再复用
+// 这里的提示信息为默认语言的兜底，各语言的翻译见 i18n 语言包中的 error.<Key>
+
+// 通用错误
+var (
+	ErrBadRequest       = New(40000, "BAD_REQUEST", CategoryBadRequest, "请求参数错误")
+	ErrValidation       = New(40001, "VALIDATION_FAILED", CategoryBadRequest, "参数校验失败")
+	ErrMalformedBody    = New(40002, "MALFORMED_BODY", CategoryBadRequest, "请求体格式错误")
+	ErrUnauthenticated  = New(40100, "UNAUTHENTICATED", CategoryUnauthenticated, "用户未登录")
+	ErrPermissionDenied = New(40300, "PERMISSION_DENIED", CategoryPermissionDenied, "没有权限访问该资源")
+	ErrNotFound         = New(40400, "NOT_FOUND", CategoryNotFound, "请求的资源不存在")
+	ErrConflict         = New(40900, "CONFLICT", CategoryConflict, "资源已存在或状态冲突")
+	ErrRateLimited      = New(42900, "RATE_LIMITED", CategoryRateLimited, "请求过于频繁，请稍后重试")
+	ErrInternal         = New(50000, "INTERNAL_ERROR", CategoryInternal, "服务器内部错误")
+	ErrUnavailable      = New(50300, "SERVICE_UNAVAILABLE", CategoryUnavailable, "服务暂不可用，请稍后重试")
+)
+
+// 认证与授权
+var (
+	ErrOldPasswordIncorrect   = New(40010, "OLD_PASSWORD_INCORRECT", CategoryBadRequest, "旧密码错误")
+	ErrUnsupportedLocale      = New(40011, "UNSUPPORTED_LOCALE", CategoryBadRequest, "不支持的语言")
+	ErrPasswordPolicy         = New(40012, "PASSWORD_POLICY_VIOLATION", CategoryBadRequest, "新密码不符合密码策略")
+	ErrResetTokenInvalid      = New(40013, "RESET_TOKEN_INVALID", CategoryBadRequest, "重置链接无效或已过期，请重新申请")
+	ErrInvalidCredentials     = New(40101, "INVALID_CREDENTIALS", CategoryUnauthenticated, "用户名或密码错误")
+	ErrAuthHeaderInvalid      = New(40102, "AUTH_HEADER_INVALID", CategoryUnauthenticated, "缺少或无效的认证信息")
+	ErrTokenInvalid           = New(40103, "TOKEN_INVALID", CategoryUnauthenticated, "令牌无效或已过期")
+	ErrTokenRevoked           = New(40104, "TOKEN_REVOKED", CategoryUnauthenticated, "令牌已注销")
+	ErrRefreshTokenInvalid    = New(40105, "REFRESH_TOKEN_INVALID", CategoryUnauthenticated, "刷新令牌无效或已过期")
+	ErrRoleDenied             = New(40301, "ROLE_DENIED", CategoryPermissionDenied, "当前角色无权访问该资源")
+	ErrUserDisabled           = New(40302, "USER_DISABLED", CategoryPermissionDenied, "用户已被禁用")
+	ErrPasswordChangeRequired = New(40303, "PASSWORD_CHANGE_REQUIRED", CategoryPermissionDenied, "请先修改密码")
+	ErrPasswordResetDisabled  = New(40305, "PASSWORD_RESET_DISABLED", CategoryPermissionDenied, "未开启自助重置密码，请联系管理员")
+	ErrUserNotFound           = New(40401, "USER_NOT_FOUND", CategoryNotFound, "用户不存在")
+	ErrAuthUnavailable        = New(50301, "AUTH_UNAVAILABLE", CategoryUnavailable, "认证服务暂不可用，请稍后重试")
+	ErrRateLimitUnavailable   = New(50302, "RATE_LIMIT_UNAVAILABLE", CategoryUnavailable, "限流服务暂不可用，请稍后重试")
+)
+
+// 用户注册与邀请
+var (
+	ErrInvitationInvalid     = New(40014, "INVITATION_INVALID", CategoryBadRequest, "邀请链接无效或已过期")
+	ErrVerificationInvalid   = New(40015, "VERIFICATION_TOKEN_INVALID", CategoryBadRequest, "验证链接无效或已过期，请重新发送")
+	ErrEmailDomainNotAllowed = New(40016, "EMAIL_DOMAIN_NOT_ALLOWED", CategoryBadRequest, "该邮箱域名不允许注册")
+	ErrRegistrationDisabled  = New(40306, "REGISTRATION_DISABLED", CategoryPermissionDenied, "未开放注册，请联系管理员邀请")
+	ErrEmailNotVerified      = New(40307, "EMAIL_NOT_VERIFIED", CategoryPermissionDenied, "请先验证邮箱")
+	ErrUserPendingApproval   = New(40308, "USER_PENDING_APPROVAL", CategoryPermissionDenied, "账号正在等待管理员审核")
+	ErrRoleNotFound          = New(40404, "ROLE_NOT_FOUND", CategoryNotFound, "角色不存在")
+	ErrInvitationNotFound    = New(40405, "INVITATION_NOT_FOUND", CategoryNotFound, "邀请不存在")
+	ErrUsernameTaken         = New(40901, "USERNAME_TAKEN", CategoryConflict, "用户名已被使用")
+	ErrEmailTaken            = New(40902, "EMAIL_TAKEN", CategoryConflict, "邮箱已被使用")
+	ErrInvitationNotPending  = New(40903, "INVITATION_NOT_PENDING", CategoryConflict, "邀请已被接受、撤销或已过期")
+	ErrUserNotPending        = New(40904, "USER_NOT_PENDING", CategoryConflict, "用户不在待审核状态")
+)
+
+// API 密钥
+var (
+	ErrAPIKeyScopeExceeded    = New(40017, "API_KEY_SCOPE_EXCEEDED", CategoryBadRequest, "API 密钥的角色或权限超出了所有者的范围")
+	ErrAPIKeyPrivilegedScope  = New(40019, "API_KEY_PRIVILEGED_SCOPE", CategoryBadRequest, "API 密钥不能包含超级管理员角色或全部权限")
+	ErrAPIKeyInvalid          = New(40106, "API_KEY_INVALID", CategoryUnauthenticated, "API 密钥无效、已过期或已吊销")
+	ErrAPIKeyForbidden        = New(40309, "API_KEY_FORBIDDEN", CategoryPermissionDenied, "API 密钥不能访问该接口")
+	ErrAPIKeyNotFound         = New(40406, "API_KEY_NOT_FOUND", CategoryNotFound, "API 密钥不存在")
+	ErrServiceAccountNotFound = New(40407, "SERVICE_ACCOUNT_NOT_FOUND", CategoryNotFound, "服务账号不存在")
+)
+
+// OpenID Connect 登录
+var (
+	ErrOIDCStateInvalid     = New(40018, "OIDC_STATE_INVALID", CategoryBadRequest, "登录请求无效或已过期，请重新登录")
+	ErrOIDCLoginFailed      = New(40107, "OIDC_LOGIN_FAILED", CategoryUnauthenticated, "身份提供方登录失败")
+	ErrOIDCAccountNotLinked = New(40310, "OIDC_ACCOUNT_NOT_LINKED", CategoryPermissionDenied, "该身份未关联用户，请联系管理员")
+	ErrOIDCProviderNotFound = New(40408, "OIDC_PROVIDER_NOT_FOUND", CategoryNotFound, "身份提供方不存在")
+	ErrOIDCUnavailable      = New(50303, "OIDC_UNAVAILABLE", CategoryUnavailable, "身份提供方暂不可用，请稍后重试")
+)
+
+// LDAP 登录
+var (
+	ErrLDAPUnavailable = New(50304, "LDAP_UNAVAILABLE", CategoryUnavailable, "目录服务暂不可用，请稍后重试")
+)
+
+// 系统配置
+var (
+	ErrInvalidSetting  = New(40020, "INVALID_SETTING", CategoryBadRequest, "配置值不符合要求")
+	ErrSettingNotFound = New(40402, "SETTING_NOT_FOUND", CategoryNotFound, "配置项不存在")
+)
+
+// 网络访问控制
+var (
+	ErrIPRuleLockout  = New(40030, "IP_RULE_LOCKOUT", CategoryBadRequest, "该操作会导致当前IP无法访问，请先添加允许当前IP的规则")
+	ErrIPBlocked      = New(40304, "IP_BLOCKED", CategoryPermissionDenied, "当前网络不允许访问")
+	ErrIPRuleNotFound = New(40403, "IP_RULE_NOT_FOUND", CategoryNotFound, "IP规则不存在")
+)
@@ -1,57 +1,341 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+
 	"github.com/spf13/viper"
 )
 
 // Config 应用配置结构
 type Config struct {
-	Server   ServerConfig   `mapstructure:"server"`
-	Database DatabaseConfig `mapstructure:"database"`
-	Redis    RedisConfig    `mapstructure:"redis"`
-	JWT      JWTConfig      `mapstructure:"jwt"`
-	Log      LogConfig      `mapstructure:"log"`
+	Server     ServerConfig     `mapstructure:"server"`
+	Database   DatabaseConfig   `mapstructure:"database"`
+	Redis      RedisConfig      `mapstructure:"redis"`
+	Store      StoreConfig      `mapstructure:"store"`
+	JWT        JWTConfig        `mapstructure:"jwt"`
+	Log        LogConfig        `mapstructure:"log"`
+	Security   SecurityConfig   `mapstructure:"security"`
+	Monitoring MonitoringConfig `mapstructure:"monitoring"`
+	Tracing    TracingConfig    `mapstructure:"tracing"`
+	I18n       I18nConfig       `mapstructure:"i18n"`
+	Email      EmailConfig      `mapstructure:"email"`
+	OIDC       OIDCConfig       `mapstructure:"oidc"`
+	LDAP       LDAPConfig       `mapstructure:"ldap"`
 }
 
 // ServerConfig 服务器配置
 type ServerConfig struct {
 	Port string `mapstructure:"port"`
 	Mode string `mapstructure:"mode"`
+
+	ReadTimeout       int `mapstructure:"read_timeout"`        // 读取请求超时（秒）
+	ReadHeaderTimeout int `mapstructure:"read_header_timeout"` // 读取请求头超时（秒）
+	WriteTimeout      int `mapstructure:"write_timeout"`       // 写响应超时（秒）
+	IdleTimeout       int `mapstructure:"idle_timeout"`        // 长连接空闲超时（秒）
+	DrainPeriod       int `mapstructure:"drain_period"`        // 收到退出信号后就绪检查失败、继续处理请求的时长（秒）
+	ShutdownTimeout   int `mapstructure:"shutdown_timeout"`    // 等待进行中请求和后台任务完成的最长时间（秒）
+	OperationLogQueue int `mapstructure:"operation_log_queue"` // 操作日志写入队列长度
+	HealthTimeout     int `mapstructure:"health_timeout"`      // 就绪检查中单项依赖检查的超时（秒）
+
+	// TrustedProxies 受信任的反向代理地址或网段，只有来自这些地址的请求才会读取 X-Forwarded-For 获取客户端 IP
+	// 为空时不信任任何代理，客户端 IP 取 TCP 连接的对端地址
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
 }
 
 // DatabaseConfig 数据库配置
 type DatabaseConfig struct {
+	Driver   string `mapstructure:"driver"` // mysql, postgres, sqlite
 	Host     string `mapstructure:"host"`
 	Port     string `mapstructure:"port"`
-	Database string `mapstructure:"database"`
+	Database string `mapstructure:"database"` // sqlite 时为数据库文件路径，":memory:" 为内存库
 	Username string `mapstructure:"username"`
-	Password string `mapstructure:"password"`
-	Charset  string `mapstructure:"charset"`
+	Password string `mapstructure:"password" secret:"true"`
+	Charset  string `mapstructure:"charset"`  // 仅 mysql
+	SSLMode  string `mapstructure:"ssl_mode"` // 仅 postgres
+	TimeZone string `mapstructure:"timezone"` // 仅 postgres
+
+	PoolConfig          `mapstructure:",squash"`
+	Replicas            []ReplicaConfig `mapstructure:"replicas"`
+	ReplicaHealthCheck  int             `mapstructure:"replica_health_check"`  // 只读副本健康检查间隔（秒）
+	ReplicaCheckTimeout int             `mapstructure:"replica_check_timeout"` // 只读副本健康检查超时（秒）
+}
+
+// PoolConfig 连接池配置
+type PoolConfig struct {
+	MaxIdleConns int `mapstructure:"max_idle_conns"`
+	MaxOpenConns int `mapstructure:"max_open_conns"`
+	MaxLifetime  int `mapstructure:"max_lifetime"` // 秒
+}
+
+// ReplicaConfig 只读副本配置，连接池参数为 0 时沿用主库配置
+type ReplicaConfig struct {
+	Name       string `mapstructure:"name"`
+	DSN        string `mapstructure:"dsn" secret:"true"`
+	PoolConfig `mapstructure:",squash"`
 }
 
 // RedisConfig Redis配置
 type RedisConfig struct {
 	Host     string `mapstructure:"host"`
 	Port     string `mapstructure:"port"`
-	Password string `mapstructure:"password"`
+	Password string `mapstructure:"password" secret:"true"`
 	DB       int    `mapstructure:"db"`
 }
 
+// StoreConfig 令牌与缓存存储配置
+type StoreConfig struct {
+	Driver             string              `mapstructure:"driver"`               // redis, memory
+	Timeout            int                 `mapstructure:"timeout"`              // 单次调用超时（毫秒）
+	BreakerThreshold   int                 `mapstructure:"breaker_threshold"`    // 连续失败多少次后熔断
+	BreakerCooldown    int                 `mapstructure:"breaker_cooldown"`     // 熔断后重试间隔（秒）
+	RevocationCacheTTL int                 `mapstructure:"revocation_cache_ttl"` // 本地吊销缓存时长（秒）
+	FailurePolicy      FailurePolicyConfig `mapstructure:"failure_policy"`
+}
+
+// FailurePolicyConfig 存储不可用时各检查项的处理策略
+// open: 放行，closed: 拒绝
+type FailurePolicyConfig struct {
+	Blacklist string `mapstructure:"blacklist"`
+	Refresh   string `mapstructure:"refresh"`
+	RateLimit string `mapstructure:"rate_limit"`
+}
+
 // JWTConfig JWT配置
 type JWTConfig struct {
-	SecretKey      string `mapstructure:"secret_key"`
-	ExpireHours    int    `mapstructure:"expire_hours"`
-	RefreshExpire  int    `mapstructure:"refresh_expire"`
+	SecretKey     string `mapstructure:"secret_key" secret:"true"`
+	ExpireHours   int    `mapstructure:"expire_hours"`
+	RefreshExpire int    `mapstructure:"refresh_expire"`
+}
+
+// SecurityConfig 安全配置
+type SecurityConfig struct {
+	RateLimit RateLimitConfig `mapstructure:"rate_limit"`
+	CORS      CORSConfig      `mapstructure:"cors"`
+	Headers   HeadersConfig   `mapstructure:"headers"`
+
+	PasswordReset PasswordResetConfig `mapstructure:"password_reset"`
+	Registration  RegistrationConfig  `mapstructure:"registration"`
+
+	// Authenticators 用户名密码登录依次尝试的认证方式（local、ldap），未启用的认证方式跳过
+	Authenticators []string `mapstructure:"authenticators"`
+}
+
+// PasswordResetConfig 自助重置密码配置，email.driver 为 none 时不可用
+type PasswordResetConfig struct {
+	URL         string `mapstructure:"url"`          // 前端重置密码页面地址，令牌以 token 查询参数附加
+	TokenTTL    int    `mapstructure:"token_ttl"`    // 重置链接有效期（分钟）
+	MaxRequests int    `mapstructure:"max_requests"` // 每个账号在窗口内最多发送的重置邮件数
+	Window      int    `mapstructure:"window"`       // 限制窗口（秒）
+}
+
+// CORSConfig 跨域配置
+type CORSConfig struct {
+	AllowedOrigins   []string `mapstructure:"allowed_origins"`   // 允许的来源，如 https://admin.example.com，https://*.example.com 匹配所有子域名
+	AllowedMethods   []string `mapstructure:"allowed_methods"`   // 允许的请求方法
+	AllowedHeaders   []string `mapstructure:"allowed_headers"`   // 额外允许的请求头，服务自身使用的请求头总是允许
+	ExposedHeaders   []string `mapstructure:"exposed_headers"`   // 额外暴露的响应头，服务自身使用的响应头总是暴露
+	AllowCredentials bool     `mapstructure:"allow_credentials"` // 是否允许携带 Cookie 等凭据，开启时来源不能为 *
+	MaxAge           int      `mapstructure:"max_age"`           // 预检请求缓存时间（秒）
+}
+
+// HeadersConfig 安全响应头配置，值为空时不发送对应的响应头
+type HeadersConfig struct {
+	Enabled               bool               `mapstructure:"enabled"`
+	HSTS                  HSTSConfig         `mapstructure:"hsts"`
+	ContentSecurityPolicy string             `mapstructure:"content_security_policy"`
+	FrameOptions          string             `mapstructure:"frame_options"` // DENY, SAMEORIGIN
+	ReferrerPolicy        string             `mapstructure:"referrer_policy"`
+	ContentTypeNosniff    bool               `mapstructure:"content_type_nosniff"`
+	Routes                []HeadersRouteRule `mapstructure:"routes"`
+}
+
+// HSTSConfig Strict-Transport-Security 配置，仅应在全站 HTTPS 时开启
+type HSTSConfig struct {
+	Enabled           bool `mapstructure:"enabled"`
+	MaxAge            int  `mapstructure:"max_age"` // 秒
+	IncludeSubdomains bool `mapstructure:"include_subdomains"`
+	Preload           bool `mapstructure:"preload"`
+}
+
+// HeadersRouteRule 按路由前缀覆盖安全响应头，匹配多条时使用前缀最长的一条
+// 字段为空时沿用全局值，为 "-" 时不发送该响应头
+type HeadersRouteRule struct {
+	Prefix                string `mapstructure:"prefix"`
+	ContentSecurityPolicy string `mapstructure:"content_security_policy"`
+	FrameOptions          string `mapstructure:"frame_options"`
+	ReferrerPolicy        string `mapstructure:"referrer_policy"`
+}
+
+// RateLimitConfig 限流配置
+type RateLimitConfig struct {
+	Enabled           bool            `mapstructure:"enabled"`
+	Algorithm         string          `mapstructure:"algorithm"`           // 默认算法：token_bucket, sliding_window
+	RequestsPerMinute int             `mapstructure:"requests_per_minute"` // 未配置规则时按IP限流的默认值
+	Rules             []RateLimitRule `mapstructure:"rules"`
+}
+
+// RateLimitRule 限流规则，请求路径匹配 Prefix 的所有规则同时生效
+type RateLimitRule struct {
+	Name      string `mapstructure:"name"`
+	Prefix    string `mapstructure:"prefix"`    // 路由前缀，为空匹配所有请求
+	By        string `mapstructure:"by"`        // ip, user, api_key
+	Algorithm string `mapstructure:"algorithm"` // 为空时使用默认算法
+	Limit     int    `mapstructure:"limit"`     // 窗口内允许的请求数
+	Window    int    `mapstructure:"window"`    // 窗口长度（秒）
+	Burst     int    `mapstructure:"burst"`     // 令牌桶容量，为空时等于 Limit
+}
+
+// MonitoringConfig 监控配置，配置了用户名时指标接口使用 Basic Auth 保护
+type MonitoringConfig struct {
+	Enabled     bool   `mapstructure:"enabled"`
+	MetricsPath string `mapstructure:"metrics_path"`
+	Username    string `mapstructure:"username"`
+	Password    string `mapstructure:"password" secret:"true"`
+}
+
+// TracingConfig 链路追踪配置
+type TracingConfig struct {
+	Exporter    string            `mapstructure:"exporter"` // none, stdout, otlp
+	ServiceName string            `mapstructure:"service_name"`
+	SampleRatio float64           `mapstructure:"sample_ratio"`          // 无上游链路时的采样比例，0-1
+	Endpoint    string            `mapstructure:"endpoint"`              // OTLP/HTTP 地址，如 localhost:4318
+	URLPath     string            `mapstructure:"url_path"`              // 为空时使用 /v1/traces
+	Insecure    bool              `mapstructure:"insecure"`              // 不使用 TLS
+	Headers     map[string]string `mapstructure:"headers" secret:"true"` // 附加请求头，如认证信息
 }
 
 // LogConfig 日志配置
 type LogConfig struct {
-	Level  string `mapstructure:"level"`
-	Format string `mapstructure:"format"`
-	Output string `mapstructure:"output"`
+	Level         string `mapstructure:"level"`          // debug, info, warn, error
+	Format        string `mapstructure:"format"`         // json, text
+	Output        string `mapstructure:"output"`         // stdout, stderr, file, both
+	FilePath      string `mapstructure:"file_path"`      // 输出到文件时的路径
+	MaxSize       int    `mapstructure:"max_size"`       // 单个文件大小上限（MB）
+	MaxBackups    int    `mapstructure:"max_backups"`    // 保留的历史文件数
+	MaxAge        int    `mapstructure:"max_age"`        // 历史文件保留天数
+	Compress      bool   `mapstructure:"compress"`       // 是否压缩历史文件
+	SlowThreshold int    `mapstructure:"slow_threshold"` // 慢查询阈值（毫秒），0 表示不记录
+}
+
+// I18nConfig 国际化配置
+type I18nConfig struct {
+	DefaultLocale string `mapstructure:"default_locale"` // 无法匹配请求语言时使用的语言
+	Dir           string `mapstructure:"dir"`            // 额外语言文件目录，同名语言覆盖内置消息
+}
+
+// RegistrationConfig 邀请和自助注册邮件中的前端页面地址，令牌以 token 查询参数附加
+// 是否开放注册、域名白名单等策略为系统配置 security.registration
+type RegistrationConfig struct {
+	InvitationURL   string `mapstructure:"invitation_url"`   // 接受邀请页面
+	VerificationURL string `mapstructure:"verification_url"` // 验证邮箱页面
+}
+
+// OIDCConfig OpenID Connect 登录配置
+type OIDCConfig struct {
+	// FrontendURL 前端登录回调页面，登录完成后附加一次性 code 或 error 查询参数跳转到该页面
+	FrontendURL string `mapstructure:"frontend_url"`
+	StateTTL    int    `mapstructure:"state_ttl"` // 从跳转到身份提供方到回调的最长时间（秒）
+	Timeout     int    `mapstructure:"timeout"`   // 请求身份提供方的超时（秒）
+	// Providers 身份提供方，键为提供方标识，用于登录和回调地址
+	Providers map[string]OIDCProviderConfig `mapstructure:"providers"`
+}
+
+// OIDCProviderConfig OpenID Connect 身份提供方
+type OIDCProviderConfig struct {
+	DisplayName  string   `mapstructure:"display_name"` // 登录页按钮上显示的名称
+	Issuer       string   `mapstructure:"issuer"`       // 通过 <issuer>/.well-known/openid-configuration 发现端点
+	ClientID     string   `mapstructure:"client_id"`
+	ClientSecret string   `mapstructure:"client_secret" secret:"true"` // 公共客户端只使用 PKCE 时留空
+	RedirectURL  string   `mapstructure:"redirect_url"`                // 后端回调地址，即 /api/v1/auth/oidc/<标识>/callback 的完整 URL
+	Scopes       []string `mapstructure:"scopes"`                      // 为空时使用 openid profile email
+
+	// UsernameClaim 自动创建用户时作为用户名的声明，为空时使用 preferred_username，缺失时使用邮箱前缀
+	UsernameClaim string `mapstructure:"username_claim"`
+	// TrustEmail 为 true 时不检查 email_verified 声明，用于不返回该声明的身份提供方
+	TrustEmail bool `mapstructure:"trust_email"`
+	// AutoProvision 为 true 时，邮箱未关联任何用户的外部账号首次登录自动创建用户
+	AutoProvision bool     `mapstructure:"auto_provision"`
+	DefaultRoles  []string `mapstructure:"default_roles"` // 自动创建的用户分配的角色编码，不能包含 admin
+	// RoleClaim 用于映射角色的声明，值为字符串或字符串数组，如 groups
+	RoleClaim   string            `mapstructure:"role_claim"`
+	RoleMapping []OIDCRoleMapping `mapstructure:"role_mapping"`
 }
 
-// LoadConfig 加载配置文件
+// OIDCRoleMapping 声明值到角色编码的映射
+// 映射中出现的角色每次登录时按声明同步，其他角色不受影响
+type OIDCRoleMapping struct {
+	Value string `mapstructure:"value"` // 声明中的值，如组名
+	Role  string `mapstructure:"role"`  // 角色编码，不能为 admin
+}
+
+// LDAPConfig LDAP / Active Directory 登录配置
+// 先用服务账号按 user_filter 查找用户，再以用户的 DN 和密码绑定验证密码
+type LDAPConfig struct {
+	Enabled            bool   `mapstructure:"enabled"`
+	URL                string `mapstructure:"url"`                  // ldap://host:389 或 ldaps://host:636
+	StartTLS           bool   `mapstructure:"start_tls"`            // ldap:// 连接后升级为 TLS
+	InsecureSkipVerify bool   `mapstructure:"insecure_skip_verify"` // 不校验服务器证书，仅用于测试
+	Timeout            int    `mapstructure:"timeout"`              // 连接和请求超时（秒）
+
+	BindDN       string `mapstructure:"bind_dn"` // 查找用户和组的服务账号
+	BindPassword string `mapstructure:"bind_password" secret:"true"`
+
+	BaseDN string `mapstructure:"base_dn"` // 查找用户的起点
+	// UserFilter 查找用户的过滤器，%s 替换为转义后的用户名，AD 一般为 (&(objectClass=user)(sAMAccountName=%s))
+	UserFilter        string `mapstructure:"user_filter"`
+	UsernameAttribute string `mapstructure:"username_attribute"` // 用户名属性，AD 一般为 sAMAccountName
+	EmailAttribute    string `mapstructure:"email_attribute"`
+	NameAttribute     string `mapstructure:"name_attribute"` // 作为昵称的属性
+
+	GroupBaseDN string `mapstructure:"group_base_dn"` // 查找组的起点，为空时使用 base_dn
+	// GroupFilter 查找用户所属组的过滤器，%s 替换为转义后的用户 DN
+	GroupFilter        string `mapstructure:"group_filter"`
+	GroupNameAttribute string `mapstructure:"group_name_attribute"` // 组名属性
+
+	// LinkByEmail 为 true 时，未关联的目录账号首次登录关联同邮箱的已有用户
+	// 目录中的邮箱通常可由用户或目录管理员修改，有本地密码或超级管理员角色的用户不会被关联
+	LinkByEmail bool `mapstructure:"link_by_email"`
+	// AutoProvision 为 true 时，目录中存在但未关联用户的账号首次登录自动创建用户
+	AutoProvision bool     `mapstructure:"auto_provision"`
+	DefaultRoles  []string `mapstructure:"default_roles"` // 自动创建的用户分配的角色编码，不能包含 admin
+	// RoleMapping 组到角色编码的映射，映射中出现的角色每次登录时按所属组同步，不能映射到 admin
+	RoleMapping []LDAPRoleMapping `mapstructure:"role_mapping"`
+
+	// SyncInterval 定期同步的间隔（分钟），目录中已不存在的用户将被禁用，为 0 时不同步
+	SyncInterval int `mapstructure:"sync_interval"`
+}
+
+// LDAPRoleMapping 组到角色编码的映射
+type LDAPRoleMapping struct {
+	Group string `mapstructure:"group"` // 组名或组的 DN，不区分大小写
+	Role  string `mapstructure:"role"`  // 角色编码
+}
+
+// EmailConfig 邮件配置
+type EmailConfig struct {
+	Driver      string `mapstructure:"driver"` // none: 不发送邮件, smtp, log: 只输出到日志，用于本地开发
+	SMTPHost    string `mapstructure:"smtp_host"`
+	SMTPPort    int    `mapstructure:"smtp_port"`
+	Username    string `mapstructure:"username"` // 为空时不认证
+	Password    string `mapstructure:"password" secret:"true"`
+	From        string `mapstructure:"from"` // 发件地址，为空时使用 username
+	FromName    string `mapstructure:"from_name"`
+	Encryption  string `mapstructure:"encryption"`   // none, starttls, tls
+	Timeout     int    `mapstructure:"timeout"`      // 连接和发送超时（秒）
+	Queue       int    `mapstructure:"queue"`        // 异步发送队列长度
+	TemplateDir string `mapstructure:"template_dir"` // 邮件模板目录，同名模板覆盖内置模板
+}
+
+// Sender 返回发件地址，未配置 from 时使用 SMTP 用户名
+func (c EmailConfig) Sender() string {
+	if c.From != "" {
+		return c.From
+	}
+	return c.Username
+}
+
+// LoadConfig 加载配置，优先级从高到低为 STARS_*_FILE 密钥文件、STARS_ 环境变量、配置文件、默认值
 func LoadConfig() (*Config, error) {
 	viper.SetConfigName("config")
 	viper.SetConfigType("yaml")
@@ -62,19 +346,46 @@ func LoadConfig() (*Config, error) {
 	// 设置默认值
 	setDefaults()
 
-	// 允许使用环境变量
-	viper.AutomaticEnv()
+	// 允许使用 STARS_ 前缀的环境变量覆盖配置
+	if err := bindEnv(); err != nil {
+		return nil, err
+	}
 
-	// 读取配置文件
+	// 读取配置文件，没有配置文件时只使用默认值和环境变量
 	if err := viper.ReadInConfig(); err != nil {
+		var notFound viper.ConfigFileNotFoundError
+		if !errors.As(err, &notFound) {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+	}
+
+	// 配置文件中以名称为键的配置项读取后才能确定，再次绑定以便通过环境变量设置其中的密钥
+	if err := bindEnv(); err != nil {
+		return nil, err
+	}
+
+	// 读取 STARS_*_FILE 指向的密钥文件
+	if err := loadSecretFiles(); err != nil {
+		return nil, err
+	}
+
+	config, err := unmarshal()
+	if err != nil {
 		return nil, err
 	}
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config:\n%w", err)
+	}
 
+	return config, nil
+}
+
+// unmarshal 将 viper 中合并后的配置解析为结构体，启动和热更新共用
+func unmarshal() (*Config, error) {
 	var config Config
 	if err := viper.Unmarshal(&config); err != nil {
 		return nil, err
 	}
-
 	return &config, nil
 }
 
@@ -83,14 +394,30 @@ func setDefaults() {
 	// 服务器默认配置
 	viper.SetDefault("server.port", "8080")
 	viper.SetDefault("server.mode", "development")
+	viper.SetDefault("server.read_timeout", 30)
+	viper.SetDefault("server.read_header_timeout", 10)
+	viper.SetDefault("server.write_timeout", 30)
+	viper.SetDefault("server.idle_timeout", 120)
+	viper.SetDefault("server.drain_period", 5)
+	viper.SetDefault("server.shutdown_timeout", 30)
+	viper.SetDefault("server.operation_log_queue", 1000)
+	viper.SetDefault("server.health_timeout", 2)
 
-	// 数据库默认配置
+	// 数据库默认配置，端口为空时按驱动取默认端口
+	viper.SetDefault("database.driver", "mysql")
 	viper.SetDefault("database.host", "localhost")
-	viper.SetDefault("database.port", "3306")
+	viper.SetDefault("database.port", "")
 	viper.SetDefault("database.database", "stars_admin")
 	viper.SetDefault("database.username", "root")
 	viper.SetDefault("database.password", "")
 	viper.SetDefault("database.charset", "utf8mb4")
+	viper.SetDefault("database.ssl_mode", "disable")
+	viper.SetDefault("database.timezone", "Asia/Shanghai")
+	viper.SetDefault("database.max_idle_conns", 10)
+	viper.SetDefault("database.max_open_conns", 100)
+	viper.SetDefault("database.max_lifetime", 3600)
+	viper.SetDefault("database.replica_health_check", 10)
+	viper.SetDefault("database.replica_check_timeout", 2)
 
 	// Redis默认配置
 	viper.SetDefault("redis.host", "localhost")
@@ -98,13 +425,99 @@ func setDefaults() {
 	viper.SetDefault("redis.password", "")
 	viper.SetDefault("redis.db", 0)
 
+	// 存储默认配置
+	viper.SetDefault("store.driver", "redis")
+	viper.SetDefault("store.timeout", 500)
+	viper.SetDefault("store.breaker_threshold", 5)
+	viper.SetDefault("store.breaker_cooldown", 10)
+	viper.SetDefault("store.revocation_cache_ttl", 300)
+	viper.SetDefault("store.failure_policy.blacklist", "closed")
+	viper.SetDefault("store.failure_policy.refresh", "closed")
+	viper.SetDefault("store.failure_policy.rate_limit", "open")
+
 	// JWT默认配置
 	viper.SetDefault("jwt.secret_key", "your-secret-key")
 	viper.SetDefault("jwt.expire_hours", 24)
 	viper.SetDefault("jwt.refresh_expire", 168)
 
+	// 限流默认配置
+	viper.SetDefault("security.rate_limit.enabled", true)
+	viper.SetDefault("security.rate_limit.algorithm", "sliding_window")
+	viper.SetDefault("security.rate_limit.requests_per_minute", 100)
+
+	// 跨域默认配置
+	viper.SetDefault("security.cors.allowed_origins", []string{"http://localhost:3000", "http://localhost:5173"})
+	viper.SetDefault("security.cors.allowed_methods", []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"})
+	viper.SetDefault("security.cors.allow_credentials", true)
+	viper.SetDefault("security.cors.max_age", 600)
+
+	// 安全响应头默认配置，接口只返回 JSON，默认禁止加载任何资源和被嵌入页面
+	viper.SetDefault("security.headers.enabled", true)
+	viper.SetDefault("security.headers.hsts.enabled", false)
+	viper.SetDefault("security.headers.hsts.max_age", 31536000)
+	viper.SetDefault("security.headers.hsts.include_subdomains", true)
+	viper.SetDefault("security.headers.content_security_policy", "default-src 'none'; frame-ancestors 'none'")
+	viper.SetDefault("security.headers.frame_options", "DENY")
+	viper.SetDefault("security.headers.referrer_policy", "no-referrer")
+	viper.SetDefault("security.headers.content_type_nosniff", true)
+
+	// 自助重置密码默认配置
+	viper.SetDefault("security.authenticators", []string{"ldap", "local"})
+	viper.SetDefault("security.password_reset.url", "http://localhost:5173/reset-password")
+	viper.SetDefault("security.password_reset.token_ttl", 30)
+	viper.SetDefault("security.password_reset.max_requests", 3)
+	viper.SetDefault("security.password_reset.window", 3600)
+	viper.SetDefault("security.registration.invitation_url", "http://localhost:5173/accept-invitation")
+	viper.SetDefault("security.registration.verification_url", "http://localhost:5173/verify-email")
+
+	// 邮件默认配置，默认不发送邮件
+	viper.SetDefault("email.driver", "none")
+	viper.SetDefault("email.smtp_port", 587)
+	viper.SetDefault("email.from_name", "Stars Admin")
+	viper.SetDefault("email.encryption", "starttls")
+	viper.SetDefault("email.timeout", 10)
+	viper.SetDefault("email.queue", 100)
+	viper.SetDefault("email.template_dir", "./templates/email")
+
+	// OpenID Connect
+	viper.SetDefault("oidc.frontend_url", "http://localhost:5173/oidc/callback")
+	viper.SetDefault("oidc.state_ttl", 600)
+	viper.SetDefault("oidc.timeout", 10)
+
+	// LDAP
+	viper.SetDefault("ldap.enabled", false)
+	viper.SetDefault("ldap.timeout", 10)
+	viper.SetDefault("ldap.user_filter", "(&(objectClass=person)(uid=%s))")
+	viper.SetDefault("ldap.username_attribute", "uid")
+	viper.SetDefault("ldap.email_attribute", "mail")
+	viper.SetDefault("ldap.name_attribute", "cn")
+	viper.SetDefault("ldap.group_filter", "(&(objectClass=groupOfNames)(member=%s))")
+	viper.SetDefault("ldap.group_name_attribute", "cn")
+	viper.SetDefault("ldap.link_by_email", false)
+	viper.SetDefault("ldap.sync_interval", 60)
+
+	// 监控默认配置
+	viper.SetDefault("monitoring.enabled", true)
+	viper.SetDefault("monitoring.metrics_path", "/metrics")
+
+	// 链路追踪默认配置
+	viper.SetDefault("tracing.exporter", "none")
+	viper.SetDefault("tracing.service_name", "stars-admin")
+	viper.SetDefault("tracing.sample_ratio", 1.0)
+	viper.SetDefault("tracing.endpoint", "localhost:4318")
+
 	// 日志默认配置
 	viper.SetDefault("log.level", "info")
 	viper.SetDefault("log.format", "json")
 	viper.SetDefault("log.output", "stdout")
-}
\ No newline at end of file
+	viper.SetDefault("log.file_path", "./logs/app.log")
+	viper.SetDefault("log.max_size", 100)
+	viper.SetDefault("log.max_backups", 3)
+	viper.SetDefault("log.max_age", 7)
+	viper.SetDefault("log.compress", true)
+	viper.SetDefault("log.slow_threshold", 200)
+
+	// 国际化默认配置
+	viper.SetDefault("i18n.default_locale", "zh-CN")
+	viper.SetDefault("i18n.dir", "./locales")
+}
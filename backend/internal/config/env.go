@@ -0,0 +1,76 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// EnvPrefix 环境变量前缀，配置项 database.password 对应 STARS_DATABASE_PASSWORD
+const EnvPrefix = "STARS"
+
+// secretFileSuffix 密钥文件环境变量的后缀，如 STARS_DATABASE_PASSWORD_FILE
+const secretFileSuffix = "_FILE"
+
+// bindEnv 为全部配置项绑定环境变量，没有默认值的配置项也能通过环境变量设置
+func bindEnv() error {
+	viper.SetEnvPrefix(EnvPrefix)
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	viper.AutomaticEnv()
+
+	for _, key := range configKeys() {
+		if err := viper.BindEnv(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadSecretFiles 读取 STARS_<KEY>_FILE 指向的文件作为配置值，用于容器中挂载的密钥
+// 文件末尾的换行会被去掉；同时设置了环境变量和对应的 _FILE 时视为配置错误
+func loadSecretFiles() error {
+	for _, key := range configKeys() {
+		env := EnvName(key)
+		path := os.Getenv(env + secretFileSuffix)
+		if path == "" {
+			continue
+		}
+		if _, ok := os.LookupEnv(env); ok {
+			return fmt.Errorf("both %s and %s%s are set", env, env, secretFileSuffix)
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("%s%s: %w", env, secretFileSuffix, err)
+		}
+		viper.Set(key, strings.TrimRight(string(content), "\r\n"))
+	}
+	return nil
+}
+
+// EnvName 返回配置项对应的环境变量名
+func EnvName(key string) string {
+	return EnvPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// configKeys 返回配置结构中的全部配置项
+func configKeys() []string {
+	flat := make(map[string]interface{})
+	flatten("", toMap(reflect.ValueOf(Config{}), false), flat)
+
+	// 以名称为键的配置（如 OIDC 身份提供方）按配置文件中已有的名称补充配置项
+	for name := range viper.GetStringMap("oidc.providers") {
+		flatten("oidc.providers."+name, toMap(reflect.ValueOf(OIDCProviderConfig{}), false), flat)
+	}
+
+	keys := make([]string, 0, len(flat))
+	for key := range flat {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// redactedValue 脱敏后显示的值
+const redactedValue = "******"
+
+// Redact 返回以配置键组织的配置内容，标记了 secret 的非空字段会被脱敏
+func Redact(cfg *Config) map[string]interface{} {
+	m, _ := toMap(reflect.ValueOf(*cfg), true).(map[string]interface{})
+	return m
+}
+
+// toMap 按 mapstructure 标签将配置转换为嵌套的 map，redact 为 true 时脱敏
+func toMap(v reflect.Value, redact bool) interface{} {
+	switch v.Kind() {
+	case reflect.Struct:
+		out := make(map[string]interface{})
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, squash := mapstructureName(field)
+			value := toMap(v.Field(i), redact)
+
+			if squash {
+				if nested, ok := value.(map[string]interface{}); ok {
+					for k, nv := range nested {
+						out[k] = nv
+					}
+				}
+				continue
+			}
+			if redact && field.Tag.Get("secret") == "true" {
+				value = redactValue(v.Field(i))
+			}
+			out[name] = value
+		}
+		return out
+
+	case reflect.Slice:
+		if v.IsNil() {
+			return []interface{}{}
+		}
+		out := make([]interface{}, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			out[i] = toMap(v.Index(i), redact)
+		}
+		return out
+
+	case reflect.Map:
+		out := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			out[iter.Key().String()] = toMap(iter.Value(), redact)
+		}
+		return out
+
+	default:
+		return v.Interface()
+	}
+}
+
+// redactValue 脱敏单个字段，空值保持为空以便看出是否已配置
+func redactValue(v reflect.Value) interface{} {
+	switch v.Kind() {
+	case reflect.Map:
+		out := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			out[iter.Key().String()] = redactedValue
+		}
+		return out
+	case reflect.String:
+		if v.String() == "" {
+			return ""
+		}
+	}
+	return redactedValue
+}
+
+// mapstructureName 返回字段的配置键以及是否展开到上一级
+func mapstructureName(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("mapstructure")
+	parts := strings.Split(tag, ",")
+	for _, opt := range parts[1:] {
+		if opt == "squash" {
+			return "", true
+		}
+	}
+	if parts[0] == "" {
+		return strings.ToLower(field.Name), false
+	}
+	return parts[0], false
+}
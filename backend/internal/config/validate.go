@@ -0,0 +1,301 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"sort"
+	"stars-admin/internal/models"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// defaultJWTSecrets 默认值和示例配置中的 JWT 密钥，生产环境不允许使用
+var defaultJWTSecrets = []string{"", "your-secret-key", "your-secret-key-here", "your-jwt-secret-key-here"}
+
+// providerName OIDC 身份提供方标识，用于回调地址
+var providerName = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+// IsProduction 是否为生产环境
+func (c *Config) IsProduction() bool {
+	return c.Server.Mode == "production" || c.Server.Mode == "release"
+}
+
+// Validate 校验配置，启动和热更新时调用，返回全部不合法的配置项
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(key, format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%s (%s): %s", key, EnvName(key), fmt.Sprintf(format, args...)))
+	}
+	oneOf := func(key, value string, allowed ...string) {
+		for _, a := range allowed {
+			if value == a {
+				return
+			}
+		}
+		fail(key, "must be one of %s, got %q", strings.Join(allowed, ", "), value)
+	}
+
+	// 服务
+	if port, err := strconv.Atoi(c.Server.Port); err != nil || port <= 0 || port > 65535 {
+		fail("server.port", "must be a port number between 1 and 65535, got %q", c.Server.Port)
+	}
+
+	for _, proxy := range c.Server.TrustedProxies {
+		if !validIPOrCIDR(proxy) {
+			fail("server.trusted_proxies", "invalid IP or CIDR %q", proxy)
+		}
+	}
+
+	// 数据库与存储
+	oneOf("database.driver", c.Database.Driver, "mysql", "postgres", "sqlite")
+	if c.Database.Database == "" {
+		fail("database.database", "is required")
+	}
+	oneOf("store.driver", c.Store.Driver, "redis", "memory")
+	oneOf("store.failure_policy.blacklist", c.Store.FailurePolicy.Blacklist, "open", "closed")
+	oneOf("store.failure_policy.refresh", c.Store.FailurePolicy.Refresh, "open", "closed")
+	oneOf("store.failure_policy.rate_limit", c.Store.FailurePolicy.RateLimit, "open", "closed")
+
+	// 令牌
+	if c.JWT.ExpireHours <= 0 {
+		fail("jwt.expire_hours", "must be positive")
+	}
+	if c.JWT.RefreshExpire <= 0 {
+		fail("jwt.refresh_expire", "must be positive")
+	}
+
+	// 日志
+	if _, err := logrus.ParseLevel(c.Log.Level); err != nil {
+		fail("log.level", "%v", err)
+	}
+	oneOf("log.format", c.Log.Format, "json", "text")
+	oneOf("log.output", c.Log.Output, "stdout", "stderr", "file", "both")
+
+	// 安全
+	if c.Security.RateLimit.RequestsPerMinute < 0 {
+		fail("security.rate_limit.requests_per_minute", "must not be negative")
+	}
+	for _, origin := range c.Security.CORS.AllowedOrigins {
+		if origin == "*" {
+			if c.Security.CORS.AllowCredentials {
+				fail("security.cors.allowed_origins", "* is not allowed when allow_credentials is enabled")
+			}
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || u.Scheme == "" || u.Host == "" || u.Path != "" || strings.Contains(strings.TrimPrefix(u.Host, "*."), "*") {
+			fail("security.cors.allowed_origins", "invalid origin %q, expected scheme://host[:port] or scheme://*.domain", origin)
+		}
+	}
+	if len(c.Security.CORS.AllowedMethods) == 0 {
+		fail("security.cors.allowed_methods", "must not be empty")
+	}
+	if c.Security.CORS.MaxAge < 0 {
+		fail("security.cors.max_age", "must not be negative")
+	}
+	if c.Security.Headers.HSTS.MaxAge < 0 {
+		fail("security.headers.hsts.max_age", "must not be negative")
+	}
+	if fo := c.Security.Headers.FrameOptions; fo != "" {
+		oneOf("security.headers.frame_options", fo, "DENY", "SAMEORIGIN")
+	}
+	for i, route := range c.Security.Headers.Routes {
+		if !strings.HasPrefix(route.Prefix, "/") {
+			fail("security.headers.routes", "rule %d: prefix must start with /, got %q", i, route.Prefix)
+		}
+		switch route.FrameOptions {
+		case "", "-", "DENY", "SAMEORIGIN":
+		default:
+			fail("security.headers.routes", "rule %d: frame_options must be one of DENY, SAMEORIGIN, -, got %q", i, route.FrameOptions)
+		}
+	}
+
+	if c.Security.PasswordReset.TokenTTL <= 0 {
+		fail("security.password_reset.token_ttl", "must be positive")
+	}
+	if c.Security.PasswordReset.MaxRequests <= 0 {
+		fail("security.password_reset.max_requests", "must be positive")
+	}
+	if c.Security.PasswordReset.Window <= 0 {
+		fail("security.password_reset.window", "must be positive")
+	}
+	seen := make(map[string]bool)
+	for _, name := range c.Security.Authenticators {
+		oneOf("security.authenticators", name, "local", "ldap")
+		if seen[name] {
+			fail("security.authenticators", "duplicate authenticator %q", name)
+		}
+		seen[name] = true
+	}
+
+	// 邮件
+	oneOf("email.driver", c.Email.Driver, "none", "smtp", "log")
+	if c.Email.Driver == "smtp" {
+		if c.Email.SMTPHost == "" {
+			fail("email.smtp_host", "is required when email.driver is smtp")
+		}
+		if c.Email.SMTPPort <= 0 || c.Email.SMTPPort > 65535 {
+			fail("email.smtp_port", "must be a port number between 1 and 65535, got %d", c.Email.SMTPPort)
+		}
+		if _, err := mail.ParseAddress(c.Email.Sender()); err != nil {
+			fail("email.from", "must be a valid email address (defaults to email.username), got %q", c.Email.Sender())
+		}
+		oneOf("email.encryption", c.Email.Encryption, "none", "starttls", "tls")
+	}
+	if c.Email.Driver != "none" {
+		for _, link := range []struct{ key, value string }{
+			{"security.password_reset.url", c.Security.PasswordReset.URL},
+			{"security.registration.invitation_url", c.Security.Registration.InvitationURL},
+			{"security.registration.verification_url", c.Security.Registration.VerificationURL},
+		} {
+			if u, err := url.Parse(link.value); err != nil || u.Scheme == "" || u.Host == "" {
+				fail(link.key, "must be an absolute URL, got %q", link.value)
+			}
+		}
+	}
+
+	// OpenID Connect
+	if len(c.OIDC.Providers) > 0 {
+		if u, err := url.Parse(c.OIDC.FrontendURL); err != nil || u.Scheme == "" || u.Host == "" {
+			fail("oidc.frontend_url", "must be an absolute URL, got %q", c.OIDC.FrontendURL)
+		}
+		if c.OIDC.StateTTL <= 0 {
+			fail("oidc.state_ttl", "must be positive")
+		}
+		if c.OIDC.Timeout <= 0 {
+			fail("oidc.timeout", "must be positive")
+		}
+	}
+	for _, name := range sortedKeys(c.OIDC.Providers) {
+		p := c.OIDC.Providers[name]
+		key := "oidc.providers." + name
+		if !providerName.MatchString(name) {
+			fail(key, "name must contain only lowercase letters, digits, - and _")
+		}
+		if u, err := url.Parse(p.Issuer); err != nil || u.Scheme == "" || u.Host == "" {
+			fail(key+".issuer", "must be an absolute URL, got %q", p.Issuer)
+		}
+		if p.ClientID == "" {
+			fail(key+".client_id", "is required")
+		}
+		if u, err := url.Parse(p.RedirectURL); err != nil || u.Scheme == "" || u.Host == "" {
+			fail(key+".redirect_url", "must be an absolute URL, got %q", p.RedirectURL)
+		}
+		if len(p.RoleMapping) > 0 && p.RoleClaim == "" {
+			fail(key+".role_claim", "is required when role_mapping is set")
+		}
+		for i, m := range p.RoleMapping {
+			if m.Value == "" || m.Role == "" {
+				fail(key+".role_mapping", "entry %d: value and role are required", i)
+			}
+			if m.Role == models.RoleAdmin {
+				fail(key+".role_mapping", "entry %d: role %s cannot be granted by an identity provider", i, m.Role)
+			}
+		}
+		for _, role := range p.DefaultRoles {
+			if role == models.RoleAdmin {
+				fail(key+".default_roles", "role %s cannot be granted by an identity provider", role)
+			}
+		}
+	}
+
+	// LDAP
+	if c.LDAP.Enabled {
+		if u, err := url.Parse(c.LDAP.URL); err != nil || (u.Scheme != "ldap" && u.Scheme != "ldaps") || u.Host == "" {
+			fail("ldap.url", "must be an ldap:// or ldaps:// URL, got %q", c.LDAP.URL)
+		} else if u.Scheme == "ldaps" && c.LDAP.StartTLS {
+			fail("ldap.start_tls", "cannot be used with ldaps://")
+		}
+		if c.LDAP.Timeout <= 0 {
+			fail("ldap.timeout", "must be positive")
+		}
+		if c.LDAP.BaseDN == "" {
+			fail("ldap.base_dn", "is required")
+		}
+		if strings.Count(c.LDAP.UserFilter, "%s") != 1 {
+			fail("ldap.user_filter", "must contain exactly one %%s, got %q", c.LDAP.UserFilter)
+		}
+		if c.LDAP.UsernameAttribute == "" {
+			fail("ldap.username_attribute", "is required")
+		}
+		if len(c.LDAP.RoleMapping) > 0 {
+			if strings.Count(c.LDAP.GroupFilter, "%s") != 1 {
+				fail("ldap.group_filter", "must contain exactly one %%s when role_mapping is set, got %q", c.LDAP.GroupFilter)
+			}
+			if c.LDAP.GroupNameAttribute == "" {
+				fail("ldap.group_name_attribute", "is required when role_mapping is set")
+			}
+		}
+		for i, m := range c.LDAP.RoleMapping {
+			if m.Group == "" || m.Role == "" {
+				fail("ldap.role_mapping", "entry %d: group and role are required", i)
+			}
+			if m.Role == models.RoleAdmin {
+				fail("ldap.role_mapping", "entry %d: role %s cannot be granted by the directory", i, m.Role)
+			}
+		}
+		for _, role := range c.LDAP.DefaultRoles {
+			if role == models.RoleAdmin {
+				fail("ldap.default_roles", "role %s cannot be granted by the directory", role)
+			}
+		}
+		if c.LDAP.SyncInterval < 0 {
+			fail("ldap.sync_interval", "must not be negative")
+		}
+	}
+
+	// 监控与链路追踪
+	if c.Monitoring.Enabled && !strings.HasPrefix(c.Monitoring.MetricsPath, "/") {
+		fail("monitoring.metrics_path", "must start with /, got %q", c.Monitoring.MetricsPath)
+	}
+	oneOf("tracing.exporter", c.Tracing.Exporter, "none", "stdout", "otlp")
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		fail("tracing.sample_ratio", "must be between 0 and 1, got %v", c.Tracing.SampleRatio)
+	}
+
+	// 生产环境不允许使用默认密钥和空密码
+	if c.IsProduction() {
+		for _, secret := range defaultJWTSecrets {
+			if c.JWT.SecretKey == secret {
+				fail("jwt.secret_key", "must be changed from the default in production")
+				break
+			}
+		}
+		if c.Database.Driver != "sqlite" && c.Database.Password == "" {
+			fail("database.password", "must not be empty in production")
+		}
+		if c.LDAP.Enabled && c.LDAP.InsecureSkipVerify {
+			fail("ldap.insecure_skip_verify", "must not be enabled in production")
+		}
+		if c.Email.Driver == "log" {
+			fail("email.driver", "log writes password reset links to the log and must not be used in production")
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// validIPOrCIDR 判断是否为 IP 地址或 CIDR 网段
+func validIPOrCIDR(s string) bool {
+	if _, err := netip.ParseAddr(s); err == nil {
+		return true
+	}
+	_, err := netip.ParsePrefix(s)
+	return err == nil
+}
+
+// sortedKeys 返回排序后的键，使校验错误的顺序固定
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,195 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// reloadableItem 可热更新的配置项，apply 将新值复制到生效的配置中
+type reloadableItem struct {
+	key   string
+	apply func(dst, src *Config)
+}
+
+// reloadable 可在运行时热更新的配置项，其余配置修改后需重启服务才能生效
+var reloadable = []reloadableItem{
+	{"log.level", func(dst, src *Config) { dst.Log.Level = src.Log.Level }},
+	{"security.cors", func(dst, src *Config) { dst.Security.CORS = src.Security.CORS }},
+	{"security.headers", func(dst, src *Config) { dst.Security.Headers = src.Security.Headers }},
+	{"security.rate_limit", func(dst, src *Config) { dst.Security.RateLimit = src.Security.RateLimit }},
+	{"jwt.expire_hours", func(dst, src *Config) { dst.JWT.ExpireHours = src.JWT.ExpireHours }},
+	{"jwt.refresh_expire", func(dst, src *Config) { dst.JWT.RefreshExpire = src.JWT.RefreshExpire }},
+}
+
+// ReloadableKeys 返回可热更新的配置项
+func ReloadableKeys() []string {
+	keys := make([]string, len(reloadable))
+	for i, r := range reloadable {
+		keys[i] = r.key
+	}
+	return keys
+}
+
+// Subscriber 配置变更订阅者
+type Subscriber struct {
+	Name string
+	// Validate 校验新配置，任一订阅者返回错误时放弃本次变更，可为空
+	Validate func(cfg *Config) error
+	// Apply 应用新配置，只会在全部校验通过后调用
+	Apply func(old, new *Config)
+}
+
+// Watcher 监听配置文件变化，校验通过后更新当前配置并通知订阅者
+type Watcher struct {
+	current     atomic.Pointer[Config]
+	mu          sync.Mutex
+	subscribers []Subscriber
+}
+
+// NewWatcher 创建配置监听器
+func NewWatcher(cfg *Config) *Watcher {
+	w := &Watcher{}
+	w.current.Store(cfg)
+	return w
+}
+
+// Current 返回当前生效的配置，返回值不可修改
+func (w *Watcher) Current() *Config {
+	return w.current.Load()
+}
+
+// Subscribe 订阅配置变更
+func (w *Watcher) Subscribe(s Subscriber) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.subscribers = append(w.subscribers, s)
+}
+
+// Start 开始监听配置文件，未使用配置文件时不做任何事
+func (w *Watcher) Start() {
+	if viper.ConfigFileUsed() == "" {
+		return
+	}
+
+	viper.OnConfigChange(func(e fsnotify.Event) {
+		if err := w.Reload(); err != nil {
+			logrus.WithError(err).WithField("file", e.Name).Error("Config change rejected")
+		}
+	})
+	viper.WatchConfig()
+}
+
+// Reload 重新读取配置，只应用可热更新的配置项
+// 配置文件无法解析或新配置校验失败时保持当前配置不变
+func (w *Watcher) Reload() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// viper 在配置文件解析失败时沿用旧值且不返回错误，这里单独解析一次以便记录
+	if file := viper.ConfigFileUsed(); file != "" {
+		check := viper.New()
+		check.SetConfigFile(file)
+		if err := check.ReadInConfig(); err != nil {
+			return fmt.Errorf("failed to parse config file: %w", err)
+		}
+	}
+
+	next, err := unmarshal()
+	if err != nil {
+		return err
+	}
+
+	old := w.Current()
+	applied := *old
+	var changed, ignored []string
+	for _, key := range diff(old, next) {
+		if r := reloadableFor(key); r != nil {
+			r.apply(&applied, next)
+			changed = append(changed, key)
+		} else {
+			ignored = append(ignored, key)
+		}
+	}
+	if len(ignored) > 0 {
+		logrus.WithField("keys", ignored).Warn("Config changes require a restart and were not applied")
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+
+	if err := applied.Validate(); err != nil {
+		return err
+	}
+	for _, s := range w.subscribers {
+		if s.Validate == nil {
+			continue
+		}
+		if err := s.Validate(&applied); err != nil {
+			return fmt.Errorf("%s: %w", s.Name, err)
+		}
+	}
+
+	w.current.Store(&applied)
+	for _, s := range w.subscribers {
+		s.Apply(old, &applied)
+	}
+	logrus.WithField("keys", changed).Info("Config reloaded")
+
+	return nil
+}
+
+// reloadableFor 返回配置项所属的可热更新项
+func reloadableFor(key string) *reloadableItem {
+	for i := range reloadable {
+		r := &reloadable[i]
+		if key == r.key || strings.HasPrefix(key, r.key+".") {
+			return r
+		}
+	}
+	return nil
+}
+
+// diff 返回两份配置中取值不同的配置项
+func diff(a, b *Config) []string {
+	left, right := make(map[string]interface{}), make(map[string]interface{})
+	flatten("", toMap(reflect.ValueOf(*a), false), left)
+	flatten("", toMap(reflect.ValueOf(*b), false), right)
+
+	var keys []string
+	for key, value := range left {
+		if !reflect.DeepEqual(value, right[key]) {
+			keys = append(keys, key)
+		}
+	}
+	for key := range right {
+		if _, ok := left[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// flatten 将嵌套配置展开为以点分隔的配置项，列表作为整体比较
+func flatten(prefix string, value interface{}, out map[string]interface{}) {
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		out[prefix] = value
+		return
+	}
+	for key, v := range m {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		flatten(key, v, out)
+	}
+}
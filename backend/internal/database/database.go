@@ -3,35 +3,45 @@ package database
 import (
 	"fmt"
 	"context"
+	"database/sql"
 	"time"
 	"stars-admin/internal/config"
+	"stars-admin/internal/logger"
+	"stars-admin/internal/metrics"
 	"stars-admin/internal/models"
+	"stars-admin/internal/store"
+	"stars-admin/internal/tracing"
 
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 	"github.com/go-redis/redis/v8"
 )
 
+// 支持的数据库驱动
+const (
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
 // InitDB 初始化数据库连接
 func InitDB(cfg *config.Config) (*gorm.DB, error) {
-	// 构建数据库连接字符串
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=%s&parseTime=True&loc=Local",
-		cfg.Database.Username,
-		cfg.Database.Password,
-		cfg.Database.Host,
-		cfg.Database.Port,
-		cfg.Database.Database,
-		cfg.Database.Charset,
-	)
-
-	// 配置GORM
+	dialector, err := newDialector(&cfg.Database)
+	if err != nil {
+		return nil, err
+	}
+
+	// 配置GORM，日志输出到 logrus
+	// 菜单以 parent_id = 0 表示顶级菜单，不创建外键约束以保证各数据库行为一致
 	gormConfig := &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+		Logger:                                   logger.NewGorm(time.Duration(cfg.Log.SlowThreshold) * time.Millisecond),
+		DisableForeignKeyConstraintWhenMigrating: true,
 	}
 
 	// 连接数据库
-	db, err := gorm.Open(mysql.Open(dsn), gormConfig)
+	db, err := gorm.Open(dialector, gormConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
@@ -43,18 +53,94 @@ func InitDB(cfg *config.Config) (*gorm.DB, error) {
 	}
 
 	// 设置连接池参数
-	sqlDB.SetMaxIdleConns(10)
-	sqlDB.SetMaxOpenConns(100)
-	sqlDB.SetConnMaxLifetime(time.Hour)
+	applyPool(sqlDB, cfg.Database.Driver, cfg.Database.PoolConfig)
 
-	// 自动迁移数据库表
+	// 自动迁移数据库表，需在注册副本之前执行以保证结构检查走主库
 	if err := autoMigrate(db); err != nil {
 		return nil, fmt.Errorf("failed to auto migrate: %w", err)
 	}
 
+	// 注册只读副本，读请求默认路由到副本，写请求和事务使用主库
+	if err := registerReplicas(db, sqlDB, &cfg.Database); err != nil {
+		return nil, fmt.Errorf("failed to register replicas: %w", err)
+	}
+
+	// 为请求内的查询创建链路 span
+	if err := db.Use(tracing.GormPlugin{}); err != nil {
+		return nil, fmt.Errorf("failed to register tracing plugin: %w", err)
+	}
+
 	return db, nil
 }
 
+// newDialector 根据驱动构建主库的GORM方言
+func newDialector(cfg *config.DatabaseConfig) (gorm.Dialector, error) {
+	return openDialector(cfg.Driver, buildDSN(cfg))
+}
+
+// buildDSN 根据驱动拼接主库的DSN
+func buildDSN(cfg *config.DatabaseConfig) string {
+	switch cfg.Driver {
+	case DriverMySQL, "":
+		return fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=%s&parseTime=True&loc=Local",
+			cfg.Username,
+			cfg.Password,
+			cfg.Host,
+			portOrDefault(cfg.Port, "3306"),
+			cfg.Database,
+			cfg.Charset,
+		)
+	case DriverPostgres, "postgresql":
+		return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s TimeZone=%s",
+			cfg.Host,
+			portOrDefault(cfg.Port, "5432"),
+			cfg.Username,
+			cfg.Password,
+			cfg.Database,
+			cfg.SSLMode,
+			cfg.TimeZone,
+		)
+	case DriverSQLite:
+		return cfg.Database + "?_pragma=busy_timeout(5000)"
+	default:
+		return ""
+	}
+}
+
+// openDialector 根据驱动和DSN构建GORM方言
+func openDialector(driver, dsn string) (gorm.Dialector, error) {
+	switch driver {
+	case DriverMySQL, "":
+		return mysql.Open(dsn), nil
+	case DriverPostgres, "postgresql":
+		return postgres.Open(dsn), nil
+	case DriverSQLite:
+		return sqlite.Open(dsn), nil
+	default:
+		return nil, fmt.Errorf("unsupported database driver: %s", driver)
+	}
+}
+
+// connDialector 基于已建立的连接池构建GORM方言
+func connDialector(driver string, conn *sql.DB) gorm.Dialector {
+	switch driver {
+	case DriverPostgres, "postgresql":
+		return postgres.New(postgres.Config{Conn: conn})
+	case DriverSQLite:
+		return &sqlite.Dialector{Conn: conn}
+	default:
+		return mysql.New(mysql.Config{Conn: conn})
+	}
+}
+
+// portOrDefault 端口未配置时使用驱动默认端口
+func portOrDefault(port, def string) string {
+	if port == "" {
+		return def
+	}
+	return port
+}
+
 // InitRedis 初始化Redis连接
 func InitRedis(cfg *config.Config) (*redis.Client, error) {
 	rdb := redis.NewClient(&redis.Options{
@@ -63,6 +149,10 @@ func InitRedis(cfg *config.Config) (*redis.Client, error) {
 		DB:       cfg.Redis.DB,
 	})
 
+	// 记录命令耗时和链路
+	rdb.AddHook(metrics.RedisHook{})
+	rdb.AddHook(tracing.RedisHook{})
+
 	// 测试连接
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -74,15 +164,81 @@ func InitRedis(cfg *config.Config) (*redis.Client, error) {
 	return rdb, nil
 }
 
+// InitStore 初始化令牌与缓存存储，统一包装超时、熔断和故障策略
+func InitStore(cfg *config.Config) (store.Store, error) {
+	var inner store.Store
+	switch cfg.Store.Driver {
+	case store.DriverMemory:
+		inner = store.NewMemoryStore()
+	case store.DriverRedis, "":
+		rdb, err := InitRedis(cfg)
+		if err != nil {
+			return nil, err
+		}
+		inner = store.NewRedisStore(rdb)
+	default:
+		return nil, fmt.Errorf("unsupported store driver: %s", cfg.Store.Driver)
+	}
+
+	return store.NewResilient(inner, store.ResilientOptions{
+		Timeout:          time.Duration(cfg.Store.Timeout) * time.Millisecond,
+		FailureThreshold: cfg.Store.BreakerThreshold,
+		Cooldown:         time.Duration(cfg.Store.BreakerCooldown) * time.Second,
+		Policies: map[string]string{
+			store.CheckBlacklist: cfg.Store.FailurePolicy.Blacklist,
+			store.CheckRefresh:   cfg.Store.FailurePolicy.Refresh,
+			store.CheckRateLimit: cfg.Store.FailurePolicy.RateLimit,
+		},
+	}), nil
+}
+
+// migrationModels 需要自动迁移的模型
+var migrationModels = []interface{}{
+	&models.User{},
+	&models.Role{},
+	&models.Menu{},
+	&models.Permission{},
+	&models.UserRole{},
+	&models.RoleMenu{},
+	&models.OperationLog{},
+	&models.SystemSetting{},
+	&models.SystemSettingHistory{},
+	&models.PasswordHistory{},
+	&models.IPRule{},
+	&models.SecurityLog{},
+	&models.Invitation{},
+	&models.APIKey{},
+	&models.UserIdentity{},
+}
+
 // autoMigrate 自动迁移数据库表
 func autoMigrate(db *gorm.DB) error {
-	return db.AutoMigrate(
-		&models.User{},
-		&models.Role{},
-		&models.Menu{},
-		&models.Permission{},
-		&models.UserRole{},
-		&models.RoleMenu{},
-		&models.OperationLog{},
-	)
-}
\ No newline at end of file
+	return db.AutoMigrate(migrationModels...)
+}
+
+// PendingMigrations 返回尚未创建的数据表
+func PendingMigrations(ctx context.Context, db *gorm.DB) ([]string, error) {
+	tx := UsePrimary(db.WithContext(ctx))
+
+	tables, err := tx.Migrator().GetTables()
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[string]bool, len(tables))
+	for _, table := range tables {
+		existing[table] = true
+	}
+
+	var pending []string
+	for _, model := range migrationModels {
+		stmt := &gorm.Statement{DB: tx}
+		if err := stmt.Parse(model); err != nil {
+			return nil, err
+		}
+		if !existing[stmt.Table] {
+			pending = append(pending, stmt.Table)
+		}
+	}
+
+	return pending, nil
+}
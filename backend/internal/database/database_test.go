@@ -0,0 +1,119 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"stars-admin/internal/config"
+	"stars-admin/internal/models"
+	"testing"
+)
+
+func TestDialector(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.DatabaseConfig
+		dialect string
+		dsn     string
+	}{
+		{
+			name:    "mysql",
+			cfg:     config.DatabaseConfig{Driver: DriverMySQL, Host: "db", Username: "root", Password: "secret", Database: "stars", Charset: "utf8mb4"},
+			dialect: "mysql",
+			dsn:     "root:secret@tcp(db:3306)/stars?charset=utf8mb4&parseTime=True&loc=Local",
+		},
+		{
+			name:    "empty driver defaults to mysql",
+			cfg:     config.DatabaseConfig{Host: "db", Port: "3307", Username: "root", Database: "stars", Charset: "utf8mb4"},
+			dialect: "mysql",
+			dsn:     "root:@tcp(db:3307)/stars?charset=utf8mb4&parseTime=True&loc=Local",
+		},
+		{
+			name:    "postgres",
+			cfg:     config.DatabaseConfig{Driver: DriverPostgres, Host: "db", Username: "stars", Password: "secret", Database: "stars", SSLMode: "disable", TimeZone: "UTC"},
+			dialect: "postgres",
+			dsn:     "host=db port=5432 user=stars password=secret dbname=stars sslmode=disable TimeZone=UTC",
+		},
+		{
+			name:    "postgresql alias",
+			cfg:     config.DatabaseConfig{Driver: "postgresql", Host: "db", Port: "6432", Database: "stars"},
+			dialect: "postgres",
+			dsn:     "host=db port=6432 user= password= dbname=stars sslmode= TimeZone=",
+		},
+		{
+			name:    "sqlite",
+			cfg:     config.DatabaseConfig{Driver: DriverSQLite, Database: "data/stars.db"},
+			dialect: "sqlite",
+			dsn:     "data/stars.db?_pragma=busy_timeout(5000)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if dsn := buildDSN(&tt.cfg); dsn != tt.dsn {
+				t.Errorf("dsn: got %q, want %q", dsn, tt.dsn)
+			}
+			dialector, err := newDialector(&tt.cfg)
+			if err != nil {
+				t.Fatalf("newDialector: %v", err)
+			}
+			if dialector.Name() != tt.dialect {
+				t.Errorf("dialect: got %s, want %s", dialector.Name(), tt.dialect)
+			}
+		})
+	}
+
+	if _, err := newDialector(&config.DatabaseConfig{Driver: "oracle"}); err == nil {
+		t.Error("unsupported driver should be rejected")
+	}
+}
+
+func TestInitSQLite(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Database.Driver = DriverSQLite
+	cfg.Database.Database = filepath.Join(t.TempDir(), "test.db")
+	cfg.Database.MaxOpenConns = 20
+	db, err := InitDB(cfg)
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	defer Close(db)
+
+	// SQLite 忽略连接池配置，只允许单连接
+	if stats := PoolStats(db)["primary"]; stats.MaxOpenConnections != 1 {
+		t.Errorf("sqlite max open conns: got %d, want 1", stats.MaxOpenConnections)
+	}
+
+	pending, err := PendingMigrations(context.Background(), db)
+	if err != nil {
+		t.Fatalf("PendingMigrations: %v", err)
+	}
+	if len(pending) != 0 {
+		t.Errorf("all tables should be migrated, pending %v", pending)
+	}
+	if err := db.Create(&models.Role{Name: "user", Code: "user"}).Error; err != nil {
+		t.Fatalf("create: %v", err)
+	}
+}
+
+func TestApplyPool(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Database.Driver = DriverSQLite
+	cfg.Database.Database = filepath.Join(t.TempDir(), "test.db")
+	db, err := InitDB(cfg)
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	defer Close(db)
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	applyPool(sqlDB, DriverMySQL, config.PoolConfig{MaxIdleConns: 2, MaxOpenConns: 7, MaxLifetime: 60})
+	if stats := sqlDB.Stats(); stats.MaxOpenConnections != 7 {
+		t.Errorf("max open conns: got %d, want 7", stats.MaxOpenConnections)
+	}
+	applyPool(sqlDB, DriverSQLite, config.PoolConfig{MaxOpenConns: 7})
+	if stats := sqlDB.Stats(); stats.MaxOpenConnections != 1 {
+		t.Errorf("sqlite max open conns: got %d, want 1", stats.MaxOpenConnections)
+	}
+}
@@ -0,0 +1,37 @@
+// Package databasetest 为测试提供已迁移的 SQLite 数据库，无需启动任何数据库服务
+package databasetest
+
+import (
+	"path/filepath"
+	"stars-admin/internal/config"
+	"stars-admin/internal/database"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// Config 返回使用测试临时目录中 SQLite 文件的配置
+func Config(t testing.TB) *config.Config {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.Database.Driver = database.DriverSQLite
+	cfg.Database.Database = filepath.Join(t.TempDir(), "test.db")
+	return cfg
+}
+
+// Open 打开并迁移测试用的 SQLite 数据库，测试结束时关闭
+func Open(t testing.TB) *gorm.DB {
+	t.Helper()
+	return OpenConfig(t, Config(t))
+}
+
+// OpenConfig 按配置打开并迁移数据库，测试结束时关闭
+func OpenConfig(t testing.TB, cfg *config.Config) *gorm.DB {
+	t.Helper()
+	db, err := database.InitDB(cfg)
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(func() { database.Close(db) })
+	return db
+}
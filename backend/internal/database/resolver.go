@@ -0,0 +1,302 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"stars-admin/internal/config"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/plugin/dbresolver"
+)
+
+// clusterPluginName 数据库节点管理插件名称
+const clusterPluginName = "stars:cluster"
+
+// NodeStatus 数据库节点状态
+type NodeStatus struct {
+	Name      string      `json:"name"`
+	Primary   bool        `json:"primary"`
+	Healthy   bool        `json:"healthy"`
+	LastError string      `json:"last_error,omitempty"`
+	CheckedAt time.Time   `json:"checked_at"`
+	Stats     sql.DBStats `json:"stats"`
+}
+
+// node 数据库节点
+type node struct {
+	name    string
+	primary bool
+	db      *sql.DB
+
+	healthy   atomic.Bool
+	mu        sync.RWMutex
+	lastError string
+	checkedAt time.Time
+}
+
+// cluster 管理主库和只读副本，作为GORM插件挂载在 *gorm.DB 上
+type cluster struct {
+	primary  *node
+	replicas []*node
+	byPool   map[gorm.ConnPool]*node
+
+	interval time.Duration
+	timeout  time.Duration
+	stop     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// Name 实现 gorm.Plugin
+func (c *cluster) Name() string {
+	return clusterPluginName
+}
+
+// Initialize 实现 gorm.Plugin
+func (c *cluster) Initialize(*gorm.DB) error {
+	return nil
+}
+
+// Resolve 实现 dbresolver.Policy，在健康的副本中随机选择，全部不可用时回退到主库
+func (c *cluster) Resolve(pools []gorm.ConnPool) gorm.ConnPool {
+	healthy := make([]gorm.ConnPool, 0, len(pools))
+	for _, pool := range pools {
+		if n, ok := c.byPool[pool]; ok && !n.primary && n.healthy.Load() {
+			healthy = append(healthy, pool)
+		}
+	}
+
+	if len(healthy) == 0 {
+		return c.primary.db
+	}
+	return healthy[rand.Intn(len(healthy))]
+}
+
+// registerReplicas 打开只读副本并注册读写分离
+func registerReplicas(db *gorm.DB, primary *sql.DB, cfg *config.DatabaseConfig) error {
+	c := &cluster{
+		primary:  &node{name: "primary", primary: true, db: primary, checkedAt: time.Now()},
+		byPool:   make(map[gorm.ConnPool]*node),
+		interval: time.Duration(cfg.ReplicaHealthCheck) * time.Second,
+		timeout:  time.Duration(cfg.ReplicaCheckTimeout) * time.Second,
+		stop:     make(chan struct{}),
+	}
+	c.primary.healthy.Store(true)
+	c.byPool[primary] = c.primary
+
+	if len(cfg.Replicas) > 0 {
+		// 主库作为最后一个候选，保证只有一个副本时也会经过健康检查策略
+		dialectors := []gorm.Dialector{}
+		for i, rc := range cfg.Replicas {
+			name := rc.Name
+			if name == "" {
+				name = fmt.Sprintf("replica-%d", i+1)
+			}
+
+			sqlDB, err := openReplica(cfg, rc)
+			if err != nil {
+				c.closeReplicas()
+				return fmt.Errorf("%s: %w", name, err)
+			}
+
+			n := &node{name: name, db: sqlDB}
+			c.replicas = append(c.replicas, n)
+			c.byPool[sqlDB] = n
+			dialectors = append(dialectors, connDialector(cfg.Driver, sqlDB))
+		}
+		dialectors = append(dialectors, connDialector(cfg.Driver, primary))
+
+		if err := db.Use(dbresolver.Register(dbresolver.Config{
+			Replicas: dialectors,
+			Policy:   c,
+		})); err != nil {
+			c.closeReplicas()
+			return err
+		}
+
+		c.checkReplicas()
+		if c.interval > 0 {
+			c.wg.Add(1)
+			go c.watch()
+		}
+	}
+
+	return db.Use(c)
+}
+
+// openReplica 打开只读副本连接池
+func openReplica(cfg *config.DatabaseConfig, rc config.ReplicaConfig) (*sql.DB, error) {
+	dialector, err := openDialector(cfg.Driver, rc.DSN)
+	if err != nil {
+		return nil, err
+	}
+
+	replica, err := gorm.Open(dialector, &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := replica.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	pool := rc.PoolConfig
+	if pool.MaxIdleConns == 0 {
+		pool.MaxIdleConns = cfg.MaxIdleConns
+	}
+	if pool.MaxOpenConns == 0 {
+		pool.MaxOpenConns = cfg.MaxOpenConns
+	}
+	if pool.MaxLifetime == 0 {
+		pool.MaxLifetime = cfg.MaxLifetime
+	}
+	applyPool(sqlDB, cfg.Driver, pool)
+
+	return sqlDB, nil
+}
+
+// applyPool 设置连接池参数，SQLite 只允许单连接以避免写锁冲突和内存库分裂
+func applyPool(sqlDB *sql.DB, driver string, pool config.PoolConfig) {
+	if driver == DriverSQLite {
+		sqlDB.SetMaxOpenConns(1)
+		return
+	}
+
+	sqlDB.SetMaxIdleConns(pool.MaxIdleConns)
+	sqlDB.SetMaxOpenConns(pool.MaxOpenConns)
+	sqlDB.SetConnMaxLifetime(time.Duration(pool.MaxLifetime) * time.Second)
+}
+
+// watch 定期检查副本健康状态
+func (c *cluster) watch() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.checkReplicas()
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+// checkReplicas 检查所有副本
+func (c *cluster) checkReplicas() {
+	for _, n := range c.replicas {
+		ctx := context.Background()
+		cancel := func() {}
+		if c.timeout > 0 {
+			ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		}
+		err := n.db.PingContext(ctx)
+		cancel()
+
+		n.mu.Lock()
+		n.checkedAt = time.Now()
+		if err != nil {
+			n.lastError = err.Error()
+		} else {
+			n.lastError = ""
+		}
+		n.mu.Unlock()
+		n.healthy.Store(err == nil)
+	}
+}
+
+// closeReplicas 关闭所有副本连接
+func (c *cluster) closeReplicas() {
+	for _, n := range c.replicas {
+		n.db.Close()
+	}
+}
+
+// status 节点状态快照
+func (n *node) status() NodeStatus {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	return NodeStatus{
+		Name:      n.name,
+		Primary:   n.primary,
+		Healthy:   n.healthy.Load(),
+		LastError: n.lastError,
+		CheckedAt: n.checkedAt,
+		Stats:     n.db.Stats(),
+	}
+}
+
+// getCluster 获取挂载在 *gorm.DB 上的节点管理插件
+func getCluster(db *gorm.DB) *cluster {
+	if plugin, ok := db.Config.Plugins[clusterPluginName]; ok {
+		return plugin.(*cluster)
+	}
+	return nil
+}
+
+// Nodes 返回主库和各只读副本的状态
+func Nodes(db *gorm.DB) []NodeStatus {
+	c := getCluster(db)
+	if c == nil {
+		return nil
+	}
+
+	nodes := []NodeStatus{c.primary.status()}
+	for _, n := range c.replicas {
+		nodes = append(nodes, n.status())
+	}
+	return nodes
+}
+
+// PoolStats 返回各节点的连接池统计，未配置副本时只有主库
+func PoolStats(db *gorm.DB) map[string]sql.DBStats {
+	stats := make(map[string]sql.DBStats)
+	if nodes := Nodes(db); nodes != nil {
+		for _, n := range nodes {
+			stats[n.Name] = n.Stats
+		}
+		return stats
+	}
+
+	if sqlDB, err := db.DB(); err == nil {
+		stats["primary"] = sqlDB.Stats()
+	}
+	return stats
+}
+
+// UsePrimary 强制本次调用使用主库，用于写后立即读等场景
+func UsePrimary(db *gorm.DB) *gorm.DB {
+	return db.Clauses(dbresolver.Write)
+}
+
+// UseReplica 显式指定本次调用使用只读副本
+func UseReplica(db *gorm.DB) *gorm.DB {
+	return db.Clauses(dbresolver.Read)
+}
+
+// Close 停止副本健康检查并关闭所有数据库连接
+func Close(db *gorm.DB) error {
+	c := getCluster(db)
+	if c == nil {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.Close()
+	}
+
+	close(c.stop)
+	c.wg.Wait()
+	c.closeReplicas()
+
+	return c.primary.db.Close()
+}
@@ -0,0 +1,102 @@
+package database
+
+import (
+	"path/filepath"
+	"stars-admin/internal/config"
+	"stars-admin/internal/models"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// openWithReplica 打开 SQLite 主库和一个未迁移的 SQLite 副本，副本上没有数据表，读请求是否落到副本可以由查询是否报错区分
+func openWithReplica(t *testing.T) *gorm.DB {
+	t.Helper()
+	dir := t.TempDir()
+	cfg := &config.Config{}
+	cfg.Database.Driver = DriverSQLite
+	cfg.Database.Database = filepath.Join(dir, "primary.db")
+	cfg.Database.Replicas = []config.ReplicaConfig{{Name: "replica", DSN: filepath.Join(dir, "replica.db")}}
+	db, err := InitDB(cfg)
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(func() { Close(db) })
+
+	if err := db.Create(&models.Role{Name: "user", Code: "user"}).Error; err != nil {
+		t.Fatalf("create on primary: %v", err)
+	}
+	return db
+}
+
+func TestReplicaRouting(t *testing.T) {
+	db := openWithReplica(t)
+
+	nodes := Nodes(db)
+	if len(nodes) != 2 || !nodes[0].Primary || nodes[1].Name != "replica" || !nodes[1].Healthy {
+		t.Fatalf("unexpected nodes %+v", nodes)
+	}
+
+	// 读请求默认路由到健康的副本
+	var roles []models.Role
+	if err := db.Find(&roles).Error; err == nil {
+		t.Error("read should go to the replica, which has no tables")
+	}
+	if err := UseReplica(db).Find(&roles).Error; err == nil {
+		t.Error("UseReplica should go to the replica")
+	}
+
+	// UsePrimary 和事务内的读请求使用主库
+	if err := UsePrimary(db).Find(&roles).Error; err != nil || len(roles) != 1 {
+		t.Errorf("UsePrimary: got %v, %v", roles, err)
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return tx.Find(&roles).Error
+	})
+	if err != nil || len(roles) != 1 {
+		t.Errorf("transaction read: got %v, %v", roles, err)
+	}
+}
+
+func TestReplicaFallback(t *testing.T) {
+	db := openWithReplica(t)
+	c := getCluster(db)
+
+	// 副本健康检查失败后读请求回退到主库
+	c.replicas[0].db.Close()
+	c.checkReplicas()
+
+	nodes := Nodes(db)
+	if nodes[1].Healthy || nodes[1].LastError == "" {
+		t.Errorf("replica should be unhealthy, got %+v", nodes[1])
+	}
+	var roles []models.Role
+	if err := db.Find(&roles).Error; err != nil || len(roles) != 1 {
+		t.Errorf("read should fall back to the primary, got %v, %v", roles, err)
+	}
+}
+
+func TestResolvePolicy(t *testing.T) {
+	db := openWithReplica(t)
+	c := getCluster(db)
+	replica, primary := c.replicas[0], c.primary
+	pools := []gorm.ConnPool{replica.db, primary.db}
+
+	// 有健康的副本时不选择主库
+	for i := 0; i < 20; i++ {
+		if pool := c.Resolve(pools); pool != replica.db {
+			t.Fatalf("healthy replica should be chosen, got %v", pool)
+		}
+	}
+
+	replica.healthy.Store(false)
+	if pool := c.Resolve(pools); pool != primary.db {
+		t.Errorf("unhealthy replica should fall back to the primary, got %v", pool)
+	}
+
+	// 候选中只有主库时使用主库
+	replica.healthy.Store(true)
+	if pool := c.Resolve([]gorm.ConnPool{primary.db}); pool != primary.db {
+		t.Errorf("got %v, want primary", pool)
+	}
+}
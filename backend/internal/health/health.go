@@ -0,0 +1,176 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// 检查状态
+const (
+	StatusUp       = "up"
+	StatusDown     = "down"
+	StatusDegraded = "degraded"
+)
+
+// Check 依赖检查项，Critical 为 true 时检查失败会导致服务未就绪
+type Check struct {
+	Name     string
+	Critical bool
+	// Run 执行检查，返回的详情只在管理员视图中展示
+	Run func(ctx context.Context) (interface{}, error)
+}
+
+// Result 单项检查结果
+type Result struct {
+	Status   string      `json:"status"`
+	Critical bool        `json:"critical"`
+	Latency  float64     `json:"latency_ms"`
+	Error    string      `json:"error,omitempty"`
+	Details  interface{} `json:"details,omitempty"`
+}
+
+// Report 检查报告
+type Report struct {
+	Status    string            `json:"status"`
+	Checks    map[string]Result `json:"checks"`
+	CheckedAt time.Time         `json:"checked_at"`
+}
+
+// Ready 关键依赖是否全部可用
+func (r Report) Ready() bool {
+	return r.Status != StatusDown
+}
+
+// Public 去掉错误信息和详情，供未认证的探针使用
+func (r Report) Public() Report {
+	checks := make(map[string]Result, len(r.Checks))
+	for name, result := range r.Checks {
+		checks[name] = Result{
+			Status:   result.Status,
+			Critical: result.Critical,
+			Latency:  result.Latency,
+		}
+	}
+	r.Checks = checks
+	return r
+}
+
+// Once 包装检查函数，首次成功后缓存结果，之后直接返回而不再执行检查
+// 用于成功后在进程生命周期内不会再变化的检查，如数据表迁移状态，避免未认证的探针反复查询依赖
+func Once(run func(ctx context.Context) (interface{}, error)) func(ctx context.Context) (interface{}, error) {
+	var (
+		mu      sync.Mutex
+		done    bool
+		details interface{}
+	)
+	return func(ctx context.Context) (interface{}, error) {
+		mu.Lock()
+		if done {
+			defer mu.Unlock()
+			return details, nil
+		}
+		mu.Unlock()
+
+		result, err := run(ctx)
+		if err == nil {
+			mu.Lock()
+			done, details = true, result
+			mu.Unlock()
+		}
+		return result, err
+	}
+}
+
+// Checker 依赖检查器
+type Checker struct {
+	timeout time.Duration
+	checks  []Check
+}
+
+// NewChecker 创建依赖检查器，timeout 为单项检查的超时时间
+func NewChecker(timeout time.Duration) *Checker {
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	return &Checker{timeout: timeout}
+}
+
+// Add 添加检查项
+func (c *Checker) Add(check Check) {
+	c.checks = append(c.checks, check)
+}
+
+// Run 并发执行所有检查项
+// 关键依赖失败时整体为 down，非关键依赖失败时为 degraded
+func (c *Checker) Run(ctx context.Context) Report {
+	report := Report{
+		Status:    StatusUp,
+		Checks:    make(map[string]Result, len(c.checks)),
+		CheckedAt: time.Now(),
+	}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, check := range c.checks {
+		wg.Add(1)
+		go func(check Check) {
+			defer wg.Done()
+			result := c.run(ctx, check)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[check.Name] = result
+		}(check)
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Status == StatusUp {
+			continue
+		}
+		if result.Critical {
+			report.Status = StatusDown
+		} else if report.Status == StatusUp {
+			report.Status = StatusDegraded
+		}
+	}
+
+	return report
+}
+
+// run 在超时限制内执行单项检查
+func (c *Checker) run(ctx context.Context, check Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	type outcome struct {
+		details interface{}
+		err     error
+	}
+	done := make(chan outcome, 1)
+
+	start := time.Now()
+	go func() {
+		details, err := check.Run(ctx)
+		done <- outcome{details: details, err: err}
+	}()
+
+	result := Result{Status: StatusUp, Critical: check.Critical}
+	select {
+	case o := <-done:
+		result.Details = o.details
+		if o.err != nil {
+			result.Status = StatusDown
+			result.Error = o.err.Error()
+		}
+	case <-ctx.Done():
+		result.Status = StatusDown
+		result.Error = ctx.Err().Error()
+	}
+	result.Latency = float64(time.Since(start).Microseconds()) / 1000
+
+	return result
+}
@@ -0,0 +1,87 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestOnce(t *testing.T) {
+	calls := 0
+	fail := true
+	run := Once(func(context.Context) (interface{}, error) {
+		calls++
+		if fail {
+			return "pending", errors.New("pending migrations")
+		}
+		return "ok", nil
+	})
+
+	// 失败时每次都重新检查
+	for i := 0; i < 2; i++ {
+		if _, err := run(context.Background()); err == nil {
+			t.Fatal("expected failure")
+		}
+	}
+
+	// 首次成功后缓存结果
+	fail = false
+	for i := 0; i < 3; i++ {
+		if details, err := run(context.Background()); err != nil || details != "ok" {
+			t.Errorf("got %v, %v", details, err)
+		}
+	}
+	if calls != 3 {
+		t.Errorf("check should stop running after success, got %d calls", calls)
+	}
+}
+
+func TestCheckerRun(t *testing.T) {
+	up := func(context.Context) (interface{}, error) { return "details", nil }
+	down := func(context.Context) (interface{}, error) { return nil, errors.New("unreachable") }
+	hang := func(ctx context.Context) (interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	tests := []struct {
+		name   string
+		checks []Check
+		want   string
+	}{
+		{"all up", []Check{{Name: "a", Critical: true, Run: up}, {Name: "b", Run: up}}, StatusUp},
+		{"optional down", []Check{{Name: "a", Critical: true, Run: up}, {Name: "b", Run: down}}, StatusDegraded},
+		{"critical down", []Check{{Name: "a", Critical: true, Run: down}, {Name: "b", Run: down}}, StatusDown},
+		{"critical timeout", []Check{{Name: "a", Critical: true, Run: hang}}, StatusDown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewChecker(20 * time.Millisecond)
+			for _, check := range tt.checks {
+				c.Add(check)
+			}
+			report := c.Run(context.Background())
+			if report.Status != tt.want || len(report.Checks) != len(tt.checks) {
+				t.Errorf("got %+v, want %s", report, tt.want)
+			}
+			if report.Ready() != (tt.want != StatusDown) {
+				t.Errorf("Ready: got %v", report.Ready())
+			}
+		})
+	}
+
+	// 公开报告不包含错误和详情
+	c := NewChecker(0)
+	c.Add(Check{Name: "a", Run: up})
+	c.Add(Check{Name: "b", Run: down})
+	report := c.Run(context.Background())
+	if report.Checks["a"].Details == nil || report.Checks["b"].Error == "" {
+		t.Fatalf("full report should include details, got %+v", report)
+	}
+	for name, result := range report.Public().Checks {
+		if result.Details != nil || result.Error != "" {
+			t.Errorf("public result %s: got %+v", name, result)
+		}
+	}
+}
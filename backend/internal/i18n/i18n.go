@@ -0,0 +1,276 @@
+package i18n
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/text/language"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultLocale 默认语言
+const DefaultLocale = "zh-CN"
+
+// builtin 内置的语言包
+//
+//go:embed locales/*.yaml
+var builtin embed.FS
+
+// Args 消息模板参数，模板中以 {name} 引用
+type Args map[string]interface{}
+
+// Bundle 语言包集合
+// 每个语言一个文件，文件名为语言标签（如 zh-CN.yaml、en.yaml），嵌套的键展开为以点分隔的消息键
+type Bundle struct {
+	mu       sync.RWMutex
+	fallback string
+	messages map[string]map[string]string
+	tags     []language.Tag
+
+	// matcher 在语言变化后按需重建
+	supported []language.Tag
+	matcher   language.Matcher
+}
+
+// NewBundle 创建语言包集合，找不到消息时使用 fallback 语言
+func NewBundle(fallback string) *Bundle {
+	return &Bundle{
+		fallback: canonical(fallback),
+		messages: make(map[string]map[string]string),
+	}
+}
+
+// LoadFS 加载文件系统目录下的语言文件，同一语言的消息会合并，后加载的覆盖先加载的
+func (b *Bundle) LoadFS(fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+
+		content, err := fs.ReadFile(fsys, filepath.ToSlash(filepath.Join(dir, entry.Name())))
+		if err != nil {
+			return err
+		}
+		if err := b.add(strings.TrimSuffix(entry.Name(), ext), content); err != nil {
+			return fmt.Errorf("failed to load locale file %s: %w", entry.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+// LoadDir 加载本地目录下的语言文件
+func (b *Bundle) LoadDir(dir string) error {
+	return b.LoadFS(os.DirFS(dir), ".")
+}
+
+// add 解析并合并一个语言文件
+func (b *Bundle) add(name string, content []byte) error {
+	tag, err := language.Parse(name)
+	if err != nil {
+		return fmt.Errorf("invalid locale %q: %w", name, err)
+	}
+
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(content, &raw); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	locale := tag.String()
+	messages, ok := b.messages[locale]
+	if !ok {
+		messages = make(map[string]string)
+		b.messages[locale] = messages
+		b.tags = append(b.tags, tag)
+		b.matcher = nil
+	}
+	flatten("", raw, messages)
+
+	return nil
+}
+
+// flatten 将嵌套的消息展开为以点分隔的键
+func flatten(prefix string, raw map[string]interface{}, out map[string]string) {
+	for k, v := range raw {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		switch value := v.(type) {
+		case map[string]interface{}:
+			flatten(key, value, out)
+		default:
+			out[key] = fmt.Sprint(value)
+		}
+	}
+}
+
+// Locales 返回已加载的语言
+func (b *Bundle) Locales() []string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	locales := make([]string, 0, len(b.messages))
+	for locale := range b.messages {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Supported 是否已加载指定语言
+func (b *Bundle) Supported(locale string) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	_, ok := b.messages[canonical(locale)]
+	return ok
+}
+
+// Match 按 Accept-Language 选择最合适的已加载语言，无法匹配时返回默认语言
+func (b *Bundle) Match(acceptLanguage string) string {
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return b.fallback
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.matcher == nil {
+		// 默认语言放在首位，匹配置信度过低时返回默认语言
+		b.supported = []language.Tag{language.Make(b.fallback)}
+		for _, tag := range b.tags {
+			if tag.String() != b.fallback {
+				b.supported = append(b.supported, tag)
+			}
+		}
+		b.matcher = language.NewMatcher(b.supported)
+	}
+
+	_, index, confidence := b.matcher.Match(tags...)
+	if confidence == language.No {
+		return b.fallback
+	}
+	return b.supported[index].String()
+}
+
+// Lookup 查找消息并填充参数，当前语言缺失时回退到默认语言
+func (b *Bundle) Lookup(locale, key string, args Args) (string, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	message, ok := b.messages[canonical(locale)][key]
+	if !ok {
+		message, ok = b.messages[b.fallback][key]
+	}
+	if !ok {
+		return "", false
+	}
+
+	return format(message, args), true
+}
+
+// Translate 查找消息，找不到时返回消息键
+func (b *Bundle) Translate(locale, key string, args Args) string {
+	if message, ok := b.Lookup(locale, key, args); ok {
+		return message
+	}
+	return key
+}
+
+// format 替换模板中的 {name} 参数
+func format(message string, args Args) string {
+	if len(args) == 0 {
+		return message
+	}
+
+	pairs := make([]string, 0, len(args)*2)
+	for name, value := range args {
+		pairs = append(pairs, "{"+name+"}", fmt.Sprint(value))
+	}
+	return strings.NewReplacer(pairs...).Replace(message)
+}
+
+// canonical 规范化语言标签，无法解析时原样返回
+func canonical(locale string) string {
+	tag, err := language.Parse(locale)
+	if err != nil {
+		return locale
+	}
+	return tag.String()
+}
+
+// Default 全局语言包，包含内置的 zh-CN 和 en
+var Default = newDefaultBundle(DefaultLocale)
+
+// newDefaultBundle 创建仅包含内置语言的语言包
+func newDefaultBundle(fallback string) *Bundle {
+	b := NewBundle(fallback)
+	if err := b.LoadFS(builtin, "locales"); err != nil {
+		panic(fmt.Sprintf("i18n: failed to load builtin locales: %v", err))
+	}
+	return b
+}
+
+// Init 设置默认语言并加载外部语言目录，目录中的文件可以新增语言或覆盖内置消息
+func Init(defaultLocale, dir string) error {
+	if defaultLocale == "" {
+		defaultLocale = DefaultLocale
+	}
+
+	b := newDefaultBundle(defaultLocale)
+	if dir != "" {
+		if err := b.LoadDir(dir); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to load locales from %s: %w", dir, err)
+		}
+	}
+	if !b.Supported(defaultLocale) {
+		return fmt.Errorf("default locale %s has no messages", defaultLocale)
+	}
+
+	Default = b
+	return nil
+}
+
+// localeKey 语言在上下文中的键
+type localeKey struct{}
+
+// WithLocale 将语言写入上下文
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeKey{}, locale)
+}
+
+// LocaleFrom 读取上下文中的语言，未设置时返回默认语言
+func LocaleFrom(ctx context.Context) string {
+	if locale, ok := ctx.Value(localeKey{}).(string); ok && locale != "" {
+		return locale
+	}
+	return Default.fallback
+}
+
+// T 按上下文中的语言翻译消息，找不到时返回消息键
+func T(ctx context.Context, key string, args Args) string {
+	return Default.Translate(LocaleFrom(ctx), key, args)
+}
+
+// Lookup 按上下文中的语言查找消息
+func Lookup(ctx context.Context, key string, args Args) (string, bool) {
+	return Default.Lookup(LocaleFrom(ctx), key, args)
+}
@@ -0,0 +1,165 @@
+package ipfilter
+
+import (
+	"fmt"
+	"net/netip"
+	"stars-admin/internal/models"
+	"strings"
+)
+
+// 规则动作
+const (
+	ActionAllow = "allow"
+	ActionDeny  = "deny"
+)
+
+// 规则作用范围
+const (
+	// ScopeGlobal 所有请求
+	ScopeGlobal = "global"
+	// ScopeSystem 系统管理接口 /api/v1/system
+	ScopeSystem = "system"
+)
+
+// Scopes 全部作用范围
+var Scopes = []string{ScopeGlobal, ScopeSystem}
+
+// 拦截原因
+const (
+	// ReasonDenied 命中拒绝规则
+	ReasonDenied = "denied"
+	// ReasonNotAllowed 配置了允许规则但未命中任何一条
+	ReasonNotAllowed = "not_allowed"
+)
+
+// Decision 访问控制的判定结果
+type Decision struct {
+	Allowed bool
+	Reason  string
+	// RuleID 命中的拒绝规则，未命中时为 0
+	RuleID uint
+}
+
+// ParsePrefix 解析 IP 或 CIDR 网段，单个 IP 视为只包含自身的网段
+func ParsePrefix(s string) (netip.Prefix, error) {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		return prefix.Masked(), nil
+	}
+
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// ParseList 解析逗号分隔的 IP 或 CIDR 列表
+func ParseList(s string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, item := range strings.Split(s, ",") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+		prefix, err := ParsePrefix(item)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IP or CIDR %q: %w", strings.TrimSpace(item), err)
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	return prefixes, nil
+}
+
+// Contains 判断 IP 是否在任一网段内，IP 无法解析时返回 false
+func Contains(prefixes []netip.Prefix, ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// rule 解析后的规则
+type rule struct {
+	id     uint
+	prefix netip.Prefix
+}
+
+// scopeRules 同一作用范围内的规则
+type scopeRules struct {
+	allow []rule
+	deny  []rule
+}
+
+// Rules 按作用范围分组的规则
+type Rules struct {
+	scopes map[string]*scopeRules
+}
+
+// Compile 解析规则，忽略网段无法解析或动作未知的规则
+func Compile(rows []models.IPRule) *Rules {
+	r := &Rules{scopes: make(map[string]*scopeRules)}
+	for _, row := range rows {
+		prefix, err := ParsePrefix(row.CIDR)
+		if err != nil {
+			continue
+		}
+
+		s, ok := r.scopes[row.Scope]
+		if !ok {
+			s = &scopeRules{}
+			r.scopes[row.Scope] = s
+		}
+		switch row.Action {
+		case ActionAllow:
+			s.allow = append(s.allow, rule{id: row.ID, prefix: prefix})
+		case ActionDeny:
+			s.deny = append(s.deny, rule{id: row.ID, prefix: prefix})
+		}
+	}
+	return r
+}
+
+// Check 判断 IP 能否访问作用范围内的接口
+// 命中拒绝规则时拒绝；存在允许规则时只放行命中其中一条的 IP；没有任何规则时放行
+func (r *Rules) Check(scope, ip string) Decision {
+	s, ok := r.scopes[scope]
+	if !ok {
+		return Decision{Allowed: true}
+	}
+
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		if len(s.allow) > 0 {
+			return Decision{Reason: ReasonNotAllowed}
+		}
+		return Decision{Allowed: true}
+	}
+	addr = addr.Unmap()
+
+	for _, d := range s.deny {
+		if d.prefix.Contains(addr) {
+			return Decision{Reason: ReasonDenied, RuleID: d.id}
+		}
+	}
+	if len(s.allow) == 0 {
+		return Decision{Allowed: true}
+	}
+	for _, a := range s.allow {
+		if a.prefix.Contains(addr) {
+			return Decision{Allowed: true}
+		}
+	}
+	return Decision{Reason: ReasonNotAllowed}
+}
@@ -0,0 +1,297 @@
+package ipfilter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"stars-admin/internal/apperrors"
+	"stars-admin/internal/database"
+	"stars-admin/internal/models"
+	"stars-admin/internal/store"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	// cacheKey 存储中缓存全部规则的键
+	cacheKey = "ipfilter:rules"
+	// invalidateChannel 规则变更后通知其他实例的频道
+	invalidateChannel = "ipfilter:invalidate"
+	// cacheTTL 存储中缓存的有效期
+	cacheTTL = 10 * time.Minute
+	// localTTL 本地缓存的有效期，未收到变更通知时最迟在该时间后重新读取
+	localTTL = 30 * time.Second
+)
+
+// Operator 规则修改人，IP 用于防止管理员把自己拦在外面
+type Operator struct {
+	ID       uint
+	Username string
+	IP       string
+}
+
+// Manager IP 访问控制规则管理器
+// 规则保存在数据库中，读取经过本地缓存和存储缓存，修改后通过 Redis 发布订阅通知其他实例；
+// 启动时必须能读到规则，之后读取失败时沿用上次的规则
+type Manager struct {
+	db  *gorm.DB
+	st  store.Store
+	rdb *redis.Client
+
+	mu       sync.RWMutex
+	rules    *Rules
+	loadedAt time.Time
+
+	pubsub *redis.PubSub
+	done   chan struct{}
+}
+
+// NewManager 创建 IP 访问控制规则管理器，st 为 Redis 存储时启用跨实例失效通知
+func NewManager(db *gorm.DB, st store.Store) *Manager {
+	return &Manager{
+		db:  db,
+		st:  st,
+		rdb: store.RedisClient(st),
+	}
+}
+
+// Name 组件名称
+func (m *Manager) Name() string {
+	return "ipfilter"
+}
+
+// Start 加载规则并订阅变更通知
+func (m *Manager) Start(ctx context.Context) error {
+	rows, err := m.fromDB(ctx)
+	if err != nil {
+		return err
+	}
+	m.setLocal(rows)
+
+	if m.rdb == nil {
+		return nil
+	}
+
+	m.pubsub = m.rdb.Subscribe(context.Background(), invalidateChannel)
+	m.done = make(chan struct{})
+	go func() {
+		defer close(m.done)
+		for range m.pubsub.Channel() {
+			logrus.Debug("IP rules changed on another instance")
+			m.invalidate()
+		}
+	}()
+
+	return nil
+}
+
+// Stop 取消订阅
+func (m *Manager) Stop(ctx context.Context) error {
+	if m.pubsub == nil {
+		return nil
+	}
+
+	err := m.pubsub.Close()
+	select {
+	case <-m.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return err
+}
+
+// Check 判断 IP 能否访问作用范围内的接口
+func (m *Manager) Check(ctx context.Context, scope, ip string) Decision {
+	return m.load(ctx).Check(scope, ip)
+}
+
+// load 返回当前规则，依次读取本地缓存、存储缓存和数据库，读取失败时沿用上次的规则
+func (m *Manager) load(ctx context.Context) *Rules {
+	m.mu.RLock()
+	rules, loadedAt := m.rules, m.loadedAt
+	m.mu.RUnlock()
+	if time.Since(loadedAt) < localTTL {
+		return rules
+	}
+
+	raw, err := m.st.Get(ctx, cacheKey)
+	if err == nil {
+		var cached []models.IPRule
+		if err := json.Unmarshal([]byte(raw), &cached); err == nil {
+			return m.setLocal(cached)
+		}
+	} else if !errors.Is(err, store.ErrNotFound) {
+		logrus.WithError(err).Warn("IP rules cache unavailable, reading from database")
+	}
+
+	rows, err := m.fromDB(ctx)
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to reload IP rules, using stale rules")
+		return rules
+	}
+
+	// 只在缓存不存在时写入，避免覆盖修改规则时刚写入的新值
+	if encoded, err := json.Marshal(rows); err == nil {
+		if _, err := m.st.SetNX(ctx, cacheKey, string(encoded), cacheTTL); err != nil {
+			logrus.WithError(err).Debug("Failed to populate IP rules cache")
+		}
+	}
+
+	return m.setLocal(rows)
+}
+
+// fromDB 从主库读取全部规则
+func (m *Manager) fromDB(ctx context.Context) ([]models.IPRule, error) {
+	var rows []models.IPRule
+	if err := database.UsePrimary(m.db.WithContext(ctx)).Order("id").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// setLocal 更新本地缓存
+func (m *Manager) setLocal(rows []models.IPRule) *Rules {
+	rules := Compile(rows)
+	m.mu.Lock()
+	m.rules = rules
+	m.loadedAt = time.Now()
+	m.mu.Unlock()
+	return rules
+}
+
+// invalidate 使本地缓存过期，下次读取时重新加载，加载失败时仍可沿用
+func (m *Manager) invalidate() {
+	m.mu.Lock()
+	m.loadedAt = time.Time{}
+	m.mu.Unlock()
+}
+
+// refresh 规则修改后重新读取数据库，写入存储缓存并通知其他实例
+func (m *Manager) refresh(ctx context.Context) {
+	m.invalidate()
+
+	rows, err := m.fromDB(ctx)
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to reload IP rules after change")
+		if err := m.st.Delete(ctx, cacheKey); err != nil {
+			logrus.WithError(err).Warn("Failed to drop IP rules cache")
+		}
+	} else {
+		encoded, _ := json.Marshal(rows)
+		if err := m.st.Set(ctx, cacheKey, string(encoded), cacheTTL); err != nil {
+			logrus.WithError(err).Warn("Failed to update IP rules cache")
+		}
+		m.setLocal(rows)
+	}
+
+	if m.rdb != nil {
+		err := store.Guard(ctx, m.st, func(ctx context.Context) error {
+			return m.rdb.Publish(ctx, invalidateChannel, "").Err()
+		})
+		if err != nil {
+			logrus.WithError(err).Warn("Failed to publish IP rules change, other instances refresh within the local cache TTL")
+		}
+	}
+}
+
+// List 返回规则，scope 为空时返回全部作用范围
+func (m *Manager) List(ctx context.Context, scope string) ([]models.IPRule, error) {
+	query := database.UsePrimary(m.db.WithContext(ctx)).Order("id")
+	if scope != "" {
+		query = query.Where("scope = ?", scope)
+	}
+
+	var rows []models.IPRule
+	if err := query.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// Create 添加规则，网段统一保存为 CIDR 格式
+// 添加后修改人当前的 IP 将无法访问任一作用范围时拒绝添加
+func (m *Manager) Create(ctx context.Context, rule *models.IPRule, op Operator) error {
+	prefix, err := ParsePrefix(rule.CIDR)
+	if err != nil {
+		return apperrors.ErrValidation.WithDetails([]apperrors.FieldError{{Field: "cidr", Rule: "cidr"}})
+	}
+	rule.CIDR = prefix.String()
+	rule.CreatedBy = op.ID
+
+	rows, err := m.fromDB(ctx)
+	if err != nil {
+		return err
+	}
+	if err := checkLockout(append(rows, *rule), op.IP); err != nil {
+		return err
+	}
+
+	if err := m.db.WithContext(ctx).Create(rule).Error; err != nil {
+		return err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"id":       rule.ID,
+		"cidr":     rule.CIDR,
+		"action":   rule.Action,
+		"scope":    rule.Scope,
+		"operator": op.Username,
+	}).Info("IP rule created")
+	m.refresh(ctx)
+
+	return nil
+}
+
+// Delete 删除规则，删除后修改人当前的 IP 将无法访问任一作用范围时拒绝删除
+func (m *Manager) Delete(ctx context.Context, id uint, op Operator) error {
+	rows, err := m.fromDB(ctx)
+	if err != nil {
+		return err
+	}
+
+	remaining := make([]models.IPRule, 0, len(rows))
+	var deleted *models.IPRule
+	for i := range rows {
+		if rows[i].ID == id {
+			deleted = &rows[i]
+			continue
+		}
+		remaining = append(remaining, rows[i])
+	}
+	if deleted == nil {
+		return apperrors.ErrIPRuleNotFound
+	}
+	if err := checkLockout(remaining, op.IP); err != nil {
+		return err
+	}
+
+	if err := m.db.WithContext(ctx).Delete(&models.IPRule{}, id).Error; err != nil {
+		return err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"id":       id,
+		"cidr":     deleted.CIDR,
+		"action":   deleted.Action,
+		"scope":    deleted.Scope,
+		"operator": op.Username,
+	}).Info("IP rule deleted")
+	m.refresh(ctx)
+
+	return nil
+}
+
+// checkLockout 检查规则生效后 IP 是否仍能访问所有作用范围
+func checkLockout(rows []models.IPRule, ip string) error {
+	rules := Compile(rows)
+	for _, scope := range Scopes {
+		if !rules.Check(scope, ip).Allowed {
+			return apperrors.ErrIPRuleLockout.WithDetails(map[string]string{"ip": ip, "scope": scope})
+		}
+	}
+	return nil
+}
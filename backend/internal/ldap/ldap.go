@@ -0,0 +1,222 @@
+// Package ldap 实现 LDAP / Active Directory 的用户认证和目录查询
+package ldap
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"stars-admin/internal/config"
+	"strings"
+	"time"
+
+	goldap "github.com/go-ldap/ldap/v3"
+)
+
+// syncPageSize 同步时分页查询用户的每页条数，AD 默认最多返回 1000 条
+const syncPageSize = 500
+
+var (
+	// ErrInvalidCredentials 用户名或密码错误
+	ErrInvalidCredentials = errors.New("ldap: invalid credentials")
+	// ErrUserNotFound 目录中没有该用户
+	ErrUserNotFound = errors.New("ldap: user not found")
+)
+
+// Entry 目录中的用户
+type Entry struct {
+	DN       string
+	Username string
+	Email    string
+	Name     string
+	Groups   []Group
+}
+
+// Group 用户所属的组
+type Group struct {
+	DN   string
+	Name string
+}
+
+// Directory LDAP 目录，每次调用使用新的连接，调用结束后关闭
+type Directory struct {
+	cfg config.LDAPConfig
+}
+
+// New 创建 LDAP 目录
+func New(cfg config.LDAPConfig) *Directory {
+	return &Directory{cfg: cfg}
+}
+
+// Authenticate 按用户名查找用户并以其 DN 和密码绑定验证密码，成功后查询所属的组
+func (d *Directory) Authenticate(username, password string) (*Entry, error) {
+	// 密码为空的简单绑定是匿名绑定，服务器会返回成功
+	if username == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := d.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	entry, err := d.findUser(conn, username)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if goldap.IsErrorWithCode(err, goldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("ldap: bind as %s: %w", entry.DN, err)
+	}
+
+	// 用户本身可能没有读取组的权限，改回服务账号查询
+	if err := d.bindService(conn); err != nil {
+		return nil, err
+	}
+	if entry.Groups, err = d.groups(conn, entry.DN); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// Usernames 返回目录中符合 user_filter 的全部用户名（小写），用于同步
+func (d *Directory) Usernames() (map[string]bool, error) {
+	conn, err := d.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	result, err := conn.SearchWithPaging(goldap.NewSearchRequest(
+		d.cfg.BaseDN, goldap.ScopeWholeSubtree, goldap.NeverDerefAliases, 0, 0, false,
+		fmt.Sprintf(d.cfg.UserFilter, "*"),
+		[]string{d.cfg.UsernameAttribute},
+		nil,
+	), syncPageSize)
+	if err != nil {
+		return nil, fmt.Errorf("ldap: search users: %w", err)
+	}
+
+	usernames := make(map[string]bool, len(result.Entries))
+	for _, e := range result.Entries {
+		if username := e.GetAttributeValue(d.cfg.UsernameAttribute); username != "" {
+			usernames[strings.ToLower(username)] = true
+		}
+	}
+	return usernames, nil
+}
+
+// connect 建立连接并以服务账号绑定
+func (d *Directory) connect() (*goldap.Conn, error) {
+	timeout := time.Duration(d.cfg.Timeout) * time.Second
+	u, err := url.Parse(d.cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig := &tls.Config{
+		ServerName:         u.Hostname(),
+		InsecureSkipVerify: d.cfg.InsecureSkipVerify,
+	}
+
+	conn, err := goldap.DialURL(d.cfg.URL,
+		goldap.DialWithDialer(&net.Dialer{Timeout: timeout}),
+		goldap.DialWithTLSConfig(tlsConfig),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ldap: dial %s: %w", d.cfg.URL, err)
+	}
+	conn.SetTimeout(timeout)
+
+	if d.cfg.StartTLS {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("ldap: start tls: %w", err)
+		}
+	}
+	if err := d.bindService(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// bindService 以服务账号绑定，未配置服务账号时使用匿名查询
+func (d *Directory) bindService(conn *goldap.Conn) error {
+	if d.cfg.BindDN == "" {
+		return conn.UnauthenticatedBind("")
+	}
+	if err := conn.Bind(d.cfg.BindDN, d.cfg.BindPassword); err != nil {
+		return fmt.Errorf("ldap: bind as service account: %w", err)
+	}
+	return nil
+}
+
+// findUser 按用户名查找唯一的用户
+func (d *Directory) findUser(conn *goldap.Conn, username string) (*Entry, error) {
+	result, err := conn.Search(goldap.NewSearchRequest(
+		d.cfg.BaseDN, goldap.ScopeWholeSubtree, goldap.NeverDerefAliases, 2, 0, false,
+		fmt.Sprintf(d.cfg.UserFilter, goldap.EscapeFilter(username)),
+		[]string{d.cfg.UsernameAttribute, d.cfg.EmailAttribute, d.cfg.NameAttribute},
+		nil,
+	))
+	if err != nil && !goldap.IsErrorWithCode(err, goldap.LDAPResultSizeLimitExceeded) {
+		return nil, fmt.Errorf("ldap: search user: %w", err)
+	}
+
+	switch {
+	case result == nil || len(result.Entries) == 0:
+		return nil, ErrUserNotFound
+	case len(result.Entries) > 1:
+		return nil, fmt.Errorf("ldap: user filter matched more than one entry for %q", username)
+	}
+
+	e := result.Entries[0]
+	return &Entry{
+		DN:       e.DN,
+		Username: e.GetAttributeValue(d.cfg.UsernameAttribute),
+		Email:    strings.ToLower(strings.TrimSpace(e.GetAttributeValue(d.cfg.EmailAttribute))),
+		Name:     e.GetAttributeValue(d.cfg.NameAttribute),
+	}, nil
+}
+
+// groups 查询用户所属的组，未配置 group_filter 时不查询
+func (d *Directory) groups(conn *goldap.Conn, userDN string) ([]Group, error) {
+	if d.cfg.GroupFilter == "" {
+		return nil, nil
+	}
+	base := d.cfg.GroupBaseDN
+	if base == "" {
+		base = d.cfg.BaseDN
+	}
+
+	result, err := conn.Search(goldap.NewSearchRequest(
+		base, goldap.ScopeWholeSubtree, goldap.NeverDerefAliases, 0, 0, false,
+		fmt.Sprintf(d.cfg.GroupFilter, goldap.EscapeFilter(userDN)),
+		[]string{d.cfg.GroupNameAttribute},
+		nil,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("ldap: search groups: %w", err)
+	}
+
+	groups := make([]Group, 0, len(result.Entries))
+	for _, e := range result.Entries {
+		groups = append(groups, Group{DN: e.DN, Name: e.GetAttributeValue(d.cfg.GroupNameAttribute)})
+	}
+	return groups, nil
+}
+
+// Has 返回用户是否属于名称或 DN 为 name 的组，不区分大小写
+func (e *Entry) Has(name string) bool {
+	for _, g := range e.Groups {
+		if strings.EqualFold(g.Name, name) || strings.EqualFold(g.DN, name) {
+			return true
+		}
+	}
+	return false
+}
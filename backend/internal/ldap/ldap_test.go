@@ -0,0 +1,112 @@
+package ldap
+
+import (
+	"errors"
+	"stars-admin/internal/config"
+	"stars-admin/internal/ldap/ldaptest"
+	"testing"
+)
+
+func testDirectory(t *testing.T) (*Directory, *ldaptest.Server) {
+	t.Helper()
+	srv := ldaptest.NewServer(&ldaptest.Directory{
+		BaseDN:       "dc=example,dc=org",
+		BindDN:       "cn=admin,dc=example,dc=org",
+		BindPassword: "admin",
+		Users: []ldaptest.User{
+			{UID: "alice", Password: "alice123", Mail: " Alice@Example.org ", CN: "Alice", Groups: []string{"stars-ops", "staff"}},
+			{UID: "bob", Password: "bob123", Mail: "bob@example.org", CN: "Bob", Groups: []string{"staff"}},
+			{UID: "dev(ops)*", Password: "devops123", CN: "DevOps"},
+		},
+	})
+	t.Cleanup(srv.Close)
+
+	return New(config.LDAPConfig{
+		URL:                srv.URL,
+		Timeout:            5,
+		BindDN:             "cn=admin,dc=example,dc=org",
+		BindPassword:       "admin",
+		BaseDN:             "dc=example,dc=org",
+		UserFilter:         "(&(objectClass=person)(uid=%s))",
+		UsernameAttribute:  "uid",
+		EmailAttribute:     "mail",
+		NameAttribute:      "cn",
+		GroupFilter:        "(&(objectClass=groupOfNames)(member=%s))",
+		GroupNameAttribute: "cn",
+	}), srv
+}
+
+func TestAuthenticate(t *testing.T) {
+	d, _ := testDirectory(t)
+
+	entry, err := d.Authenticate("alice", "alice123")
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if entry.DN != "uid=alice,ou=people,dc=example,dc=org" || entry.Username != "alice" || entry.Name != "Alice" {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+	if entry.Email != "alice@example.org" {
+		t.Errorf("email should be trimmed and lower-cased, got %q", entry.Email)
+	}
+	if !entry.Has("stars-ops") || !entry.Has("CN=Staff,OU=Groups,DC=Example,DC=Org") || entry.Has("admins") {
+		t.Errorf("unexpected groups %+v", entry.Groups)
+	}
+
+	tests := []struct {
+		name     string
+		username string
+		password string
+		want     error
+	}{
+		{"wrong password", "alice", "wrong", ErrInvalidCredentials},
+		{"empty password", "alice", "", ErrInvalidCredentials},
+		{"unknown user", "mallory", "mallory123", ErrUserNotFound},
+		// 未转义时 * 会匹配全部用户，注入的过滤器会改变查询条件
+		{"wildcard", "*", "alice123", ErrUserNotFound},
+		{"filter injection", "alice)(uid=*", "alice123", ErrUserNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := d.Authenticate(tt.username, tt.password); !errors.Is(err, tt.want) {
+				t.Errorf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	// 用户名中的特殊字符按字面匹配
+	entry, err = d.Authenticate("dev(ops)*", "devops123")
+	if err != nil {
+		t.Fatalf("Authenticate with special characters: %v", err)
+	}
+	if entry.Username != "dev(ops)*" || len(entry.Groups) != 0 {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+}
+
+func TestServiceAccount(t *testing.T) {
+	d, _ := testDirectory(t)
+	d.cfg.BindPassword = "wrong"
+
+	_, err := d.Authenticate("alice", "alice123")
+	if err == nil || errors.Is(err, ErrInvalidCredentials) || errors.Is(err, ErrUserNotFound) {
+		t.Errorf("service account bind failure should be a directory error, got %v", err)
+	}
+}
+
+func TestUsernames(t *testing.T) {
+	d, srv := testDirectory(t)
+
+	usernames, err := d.Usernames()
+	if err != nil {
+		t.Fatalf("Usernames: %v", err)
+	}
+	if len(usernames) != 3 || !usernames["alice"] || !usernames["bob"] || !usernames["dev(ops)*"] {
+		t.Errorf("unexpected usernames %v", usernames)
+	}
+
+	srv.SetDirectory(&ldaptest.Directory{BaseDN: "dc=example,dc=org", BindDN: "cn=admin,dc=example,dc=org", BindPassword: "admin"})
+	if usernames, err = d.Usernames(); err != nil || len(usernames) != 0 {
+		t.Errorf("got %v, %v for an empty directory", usernames, err)
+	}
+}
@@ -0,0 +1,441 @@
+// Package ldaptest 提供内存中的 LDAP 服务器，用于本地开发（cmd/ldapdev）和测试
+// 只实现登录和同步所需的简单绑定与查询，不支持 TLS 和写操作
+// 用户在 uid=<uid>,ou=people,<base_dn> 下，组在 cn=<组名>,ou=groups,<base_dn> 下（groupOfNames）
+package ldaptest
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+)
+
+// Directory 目录数据
+type Directory struct {
+	BaseDN       string `json:"base_dn"`
+	BindDN       string `json:"bind_dn"`
+	BindPassword string `json:"bind_password"`
+	Users        []User `json:"users"`
+}
+
+// User 目录中的用户
+type User struct {
+	UID      string   `json:"uid"`
+	Password string   `json:"password"`
+	Mail     string   `json:"mail"`
+	CN       string   `json:"cn"`
+	Groups   []string `json:"groups"`
+}
+
+// LoadDirectory 读取 JSON 格式的目录数据
+func LoadDirectory(path string) (*Directory, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	d := &Directory{}
+	if err := json.Unmarshal(raw, d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// UserDN 返回用户的 DN
+func (d *Directory) UserDN(uid string) string {
+	return fmt.Sprintf("uid=%s,ou=people,%s", uid, d.BaseDN)
+}
+
+// GroupDN 返回组的 DN
+func (d *Directory) GroupDN(name string) string {
+	return fmt.Sprintf("cn=%s,ou=groups,%s", name, d.BaseDN)
+}
+
+// Server 监听本地端口的 LDAP 服务器，目录可在运行中替换，新的请求使用替换后的目录
+type Server struct {
+	// URL 服务器地址，如 ldap://127.0.0.1:3389
+	URL string
+	// Logf 不为 nil 时输出绑定和查询的结果
+	Logf func(format string, args ...interface{})
+
+	ln  net.Listener
+	mu  sync.RWMutex
+	dir *Directory
+	wg  sync.WaitGroup
+}
+
+// NewServer 在 127.0.0.1 的随机端口启动服务器，测试结束时调用 Close
+func NewServer(dir *Directory) *Server {
+	s, err := Listen("127.0.0.1:0", dir)
+	if err != nil {
+		panic(fmt.Sprintf("ldaptest: failed to listen: %v", err))
+	}
+	return s
+}
+
+// Listen 在 addr 启动服务器
+func Listen(addr string, dir *Directory) (*Server, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{URL: "ldap://" + ln.Addr().String(), ln: ln, dir: dir}
+	s.wg.Add(1)
+	go s.accept()
+	return s, nil
+}
+
+// SetDirectory 替换目录数据
+func (s *Server) SetDirectory(dir *Directory) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dir = dir
+}
+
+// Close 停止监听，已建立的连接在客户端关闭后结束
+func (s *Server) Close() {
+	s.ln.Close()
+	s.wg.Wait()
+}
+
+func (s *Server) accept() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.serve(conn)
+	}
+}
+
+func (s *Server) directory() *Directory {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.dir
+}
+
+func (s *Server) logf(format string, args ...interface{}) {
+	if s.Logf != nil {
+		s.Logf(format, args...)
+	}
+}
+
+// entry 目录条目，属性名为小写
+type entry struct {
+	dn    string
+	attrs map[string][]string
+	names map[string]string // 小写属性名到原始属性名
+}
+
+func newEntry(dn string) *entry {
+	return &entry{dn: dn, attrs: map[string][]string{}, names: map[string]string{}}
+}
+
+func (e *entry) add(name string, values ...string) {
+	key := strings.ToLower(name)
+	e.names[key] = name
+	e.attrs[key] = append(e.attrs[key], values...)
+}
+
+// entries 生成用户和组条目
+func (d *Directory) entries() []*entry {
+	var entries []*entry
+	members := map[string][]string{}
+	var groups []string
+	for _, u := range d.Users {
+		e := newEntry(d.UserDN(u.UID))
+		e.add("objectClass", "top", "person", "organizationalPerson", "inetOrgPerson")
+		e.add("uid", u.UID)
+		e.add("cn", u.CN)
+		e.add("sn", u.UID)
+		if u.Mail != "" {
+			e.add("mail", u.Mail)
+		}
+		entries = append(entries, e)
+		for _, g := range u.Groups {
+			if _, ok := members[g]; !ok {
+				groups = append(groups, g)
+			}
+			members[g] = append(members[g], e.dn)
+		}
+	}
+	for _, g := range groups {
+		e := newEntry(d.GroupDN(g))
+		e.add("objectClass", "top", "groupOfNames")
+		e.add("cn", g)
+		e.add("member", members[g]...)
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// password 返回 DN 对应的密码
+func (d *Directory) password(dn string) (string, bool) {
+	if strings.EqualFold(dn, d.BindDN) {
+		return d.BindPassword, true
+	}
+	for _, u := range d.Users {
+		if strings.EqualFold(dn, d.UserDN(u.UID)) {
+			return u.Password, true
+		}
+	}
+	return "", false
+}
+
+// LDAP 协议操作和结果码
+const (
+	appBindRequest      = 0
+	appBindResponse     = 1
+	appUnbindRequest    = 2
+	appSearchRequest    = 3
+	appSearchEntry      = 4
+	appSearchDone       = 5
+	appAbandonRequest   = 16
+	appExtendedRequest  = 23
+	appExtendedResponse = 24
+
+	resultSuccess            = 0
+	resultProtocolError      = 2
+	resultSizeLimitExceeded  = 4
+	resultInvalidCredentials = 49
+	resultInsufficientAccess = 50
+	resultUnwillingToPerform = 53
+)
+
+// serve 处理一个 LDAP 连接，每个请求使用当前的目录
+func (s *Server) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	bound := false
+
+	for {
+		conn.SetDeadline(time.Now().Add(5 * time.Minute))
+		packet, err := ber.ReadPacket(r)
+		if err != nil {
+			return
+		}
+		if len(packet.Children) < 2 {
+			return
+		}
+		id, _ := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+		dir := s.directory()
+
+		switch op.Tag {
+		case appBindRequest:
+			name, password := bindCredentials(op)
+			code := resultInvalidCredentials
+			if expected, ok := dir.password(name); ok && password != "" && password == expected {
+				code = resultSuccess
+			} else if name == "" && password == "" {
+				code = resultSuccess // 匿名绑定
+			}
+			bound = code == resultSuccess
+			s.logf("BIND %q -> %d", name, code)
+			write(conn, id, result(appBindResponse, code, ""))
+		case appSearchRequest:
+			if !bound {
+				write(conn, id, result(appSearchDone, resultInsufficientAccess, "bind required"))
+				continue
+			}
+			s.search(conn, id, op, dir)
+		case appExtendedRequest:
+			write(conn, id, result(appExtendedResponse, resultProtocolError, "extended operations (StartTLS) are not supported"))
+		case appUnbindRequest:
+			return
+		case appAbandonRequest:
+		default:
+			write(conn, id, result(op.Tag+1, resultUnwillingToPerform, "operation not supported"))
+		}
+	}
+}
+
+// bindCredentials 返回简单绑定的 DN 和密码
+func bindCredentials(op *ber.Packet) (string, string) {
+	if len(op.Children) < 3 {
+		return "", ""
+	}
+	return str(op.Children[1]), str(op.Children[2])
+}
+
+// search 执行查询并返回条目，忽略分页等控制
+func (s *Server) search(conn net.Conn, id int64, op *ber.Packet, dir *Directory) {
+	if len(op.Children) < 8 {
+		write(conn, id, result(appSearchDone, resultProtocolError, "malformed search request"))
+		return
+	}
+	base := strings.ToLower(str(op.Children[0]))
+	scope, _ := op.Children[1].Value.(int64)
+	sizeLimit, _ := op.Children[3].Value.(int64)
+	filter := op.Children[6]
+	var attrs []string
+	for _, a := range op.Children[7].Children {
+		attrs = append(attrs, strings.ToLower(str(a)))
+	}
+
+	sent := 0
+	for _, e := range dir.entries() {
+		dn := strings.ToLower(e.dn)
+		inScope := false
+		switch scope {
+		case 0:
+			inScope = dn == base
+		case 1:
+			inScope = strings.HasSuffix(dn, ","+base) && !strings.Contains(strings.TrimSuffix(dn, ","+base), ",")
+		default:
+			inScope = dn == base || strings.HasSuffix(dn, ","+base)
+		}
+		if !inScope || !match(filter, e) {
+			continue
+		}
+		if sizeLimit > 0 && int64(sent) >= sizeLimit {
+			s.logf("SEARCH %s -> size limit exceeded", base)
+			write(conn, id, result(appSearchDone, resultSizeLimitExceeded, ""))
+			return
+		}
+		write(conn, id, searchEntry(e, attrs))
+		sent++
+	}
+	s.logf("SEARCH %s -> %d entries", base, sent)
+	write(conn, id, result(appSearchDone, resultSuccess, ""))
+}
+
+// match 判断条目是否匹配过滤器，支持 and、or、not、等值、存在和子串匹配，比较不区分大小写
+func match(f *ber.Packet, e *entry) bool {
+	switch f.Tag {
+	case 0: // and
+		for _, c := range f.Children {
+			if !match(c, e) {
+				return false
+			}
+		}
+		return true
+	case 1: // or
+		for _, c := range f.Children {
+			if match(c, e) {
+				return true
+			}
+		}
+		return false
+	case 2: // not
+		return len(f.Children) == 1 && !match(f.Children[0], e)
+	case 3: // equalityMatch
+		if len(f.Children) != 2 {
+			return false
+		}
+		for _, v := range e.attrs[strings.ToLower(str(f.Children[0]))] {
+			if strings.EqualFold(v, str(f.Children[1])) {
+				return true
+			}
+		}
+		return false
+	case 4: // substrings
+		if len(f.Children) != 2 {
+			return false
+		}
+		for _, v := range e.attrs[strings.ToLower(str(f.Children[0]))] {
+			if matchSubstrings(strings.ToLower(v), f.Children[1].Children) {
+				return true
+			}
+		}
+		return false
+	case 7: // present
+		return len(e.attrs[strings.ToLower(f.Data.String())]) > 0
+	default:
+		return false
+	}
+}
+
+// matchSubstrings 按 initial、any、final 顺序匹配子串
+func matchSubstrings(v string, parts []*ber.Packet) bool {
+	for _, p := range parts {
+		s := strings.ToLower(p.Data.String())
+		switch p.Tag {
+		case 0: // initial
+			if !strings.HasPrefix(v, s) {
+				return false
+			}
+			v = v[len(s):]
+		case 1: // any
+			i := strings.Index(v, s)
+			if i < 0 {
+				return false
+			}
+			v = v[i+len(s):]
+		case 2: // final
+			if !strings.HasSuffix(v, s) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// searchEntry 编码查询结果条目，attrs 为空或包含 * 时返回全部属性
+func searchEntry(e *entry, attrs []string) *ber.Packet {
+	all := len(attrs) == 0
+	for _, a := range attrs {
+		if a == "*" {
+			all = true
+		}
+	}
+
+	p := ber.Encode(ber.ClassApplication, ber.TypeConstructed, appSearchEntry, nil, "Search Result Entry")
+	p.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, e.dn, "DN"))
+	list := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attributes")
+	for key, values := range e.attrs {
+		if !all && !contains(attrs, key) {
+			continue
+		}
+		attr := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attribute")
+		attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, e.names[key], "Type"))
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+		for _, v := range values {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, v, "Value"))
+		}
+		attr.AppendChild(set)
+		list.AppendChild(attr)
+	}
+	p.AppendChild(list)
+	return p
+}
+
+// result 编码 LDAPResult
+func result(tag ber.Tag, code int, message string) *ber.Packet {
+	p := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Response")
+	p.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, code, "Result Code"))
+	p.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	p.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, message, "Diagnostic Message"))
+	return p
+}
+
+// write 发送 LDAPMessage
+func write(conn net.Conn, id int64, op *ber.Packet) {
+	msg := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Message")
+	msg.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, "Message ID"))
+	msg.AppendChild(op)
+	conn.Write(msg.Bytes())
+}
+
+// str 返回字符串类型的值
+func str(p *ber.Packet) string {
+	if s, ok := p.Value.(string); ok {
+		return s
+	}
+	return p.Data.String()
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+)
+
+// HTTPServer 将 http.Server 包装为生命周期组件
+type HTTPServer struct {
+	srv *http.Server
+}
+
+// NewHTTPServer 创建HTTP服务组件
+func NewHTTPServer(srv *http.Server) *HTTPServer {
+	return &HTTPServer{srv: srv}
+}
+
+// Name 组件名称
+func (s *HTTPServer) Name() string {
+	return "http-server"
+}
+
+// Start 同步监听端口以便尽早暴露端口占用等错误，然后在后台处理请求
+func (s *HTTPServer) Start(context.Context) error {
+	ln, err := net.Listen("tcp", s.srv.Addr)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		if err := s.srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logrus.WithError(err).Error("HTTP server stopped unexpectedly")
+		}
+	}()
+
+	logrus.WithField("addr", s.srv.Addr).Info("HTTP server started")
+	return nil
+}
+
+// Stop 停止接收新连接并等待进行中的请求完成
+func (s *HTTPServer) Stop(ctx context.Context) error {
+	return s.srv.Shutdown(ctx)
+}
@@ -0,0 +1,122 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Component 受生命周期管理的组件
+type Component interface {
+	Name() string
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+}
+
+// Registry 组件生命周期注册表
+// 组件按注册顺序启动、按相反顺序停止，先注册的依赖（数据库、存储）最后关闭
+type Registry struct {
+	mu         sync.Mutex
+	components []Component
+	started    int
+	draining   atomic.Bool
+	stopped    bool
+}
+
+// New 创建生命周期注册表
+func New() *Registry {
+	return &Registry{}
+}
+
+// Register 注册组件
+func (r *Registry) Register(c Component) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.components = append(r.components, c)
+}
+
+// OnStop 注册只需要在关闭时执行的钩子
+func (r *Registry) OnStop(name string, stop func(ctx context.Context) error) {
+	r.Register(&hook{name: name, stop: stop})
+}
+
+// Start 按注册顺序启动尚未启动的组件，失败时停止已启动的组件
+func (r *Registry) Start(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for r.started < len(r.components) {
+		c := r.components[r.started]
+		if err := c.Start(ctx); err != nil {
+			r.stopLocked(ctx)
+			return fmt.Errorf("failed to start %s: %w", c.Name(), err)
+		}
+		r.started++
+	}
+
+	return nil
+}
+
+// Draining 是否处于摘流阶段，摘流期间就绪检查应返回失败
+func (r *Registry) Draining() bool {
+	return r.draining.Load()
+}
+
+// Shutdown 先进入摘流阶段等待 drain，再按注册的相反顺序停止组件
+func (r *Registry) Shutdown(ctx context.Context, drain time.Duration) error {
+	r.draining.Store(true)
+
+	if drain > 0 {
+		logrus.WithField("drain", drain.String()).Info("Draining before shutdown")
+		select {
+		case <-time.After(drain):
+		case <-ctx.Done():
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.stopLocked(ctx)
+}
+
+// stopLocked 停止已启动的组件，调用方需持有锁
+func (r *Registry) stopLocked(ctx context.Context) error {
+	if r.stopped {
+		return nil
+	}
+	r.stopped = true
+
+	var errs []error
+	for i := r.started - 1; i >= 0; i-- {
+		c := r.components[i]
+		start := time.Now()
+		if err := c.Stop(ctx); err != nil {
+			logrus.WithError(err).WithField("component", c.Name()).Error("Component stop failed")
+			errs = append(errs, fmt.Errorf("%s: %w", c.Name(), err))
+			continue
+		}
+		logrus.WithFields(logrus.Fields{
+			"component": c.Name(),
+			"elapsed":   time.Since(start).String(),
+		}).Info("Component stopped")
+	}
+
+	return errors.Join(errs...)
+}
+
+// hook 只在关闭时执行的组件
+type hook struct {
+	name string
+	stop func(ctx context.Context) error
+}
+
+func (h *hook) Name() string                   { return h.name }
+func (h *hook) Start(context.Context) error    { return nil }
+func (h *hook) Stop(ctx context.Context) error { return h.stop(ctx) }
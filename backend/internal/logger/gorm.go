@@ -0,0 +1,89 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// Gorm 将GORM日志输出到 logrus
+// 出错的语句记为 error，超过慢查询阈值的记为 warn，logrus 为 debug 级别时记录所有语句
+type Gorm struct {
+	SlowThreshold time.Duration
+	level         gormlogger.LogLevel
+}
+
+// NewGorm 创建GORM日志适配器，slowThreshold 为 0 时不记录慢查询
+func NewGorm(slowThreshold time.Duration) *Gorm {
+	return &Gorm{
+		SlowThreshold: slowThreshold,
+		level:         gormlogger.Info,
+	}
+}
+
+// LogMode 实现 gormlogger.Interface
+func (l *Gorm) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	clone := *l
+	clone.level = level
+	return &clone
+}
+
+// Info 实现 gormlogger.Interface
+func (l *Gorm) Info(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Info {
+		FromContext(ctx).Infof(msg, args...)
+	}
+}
+
+// Warn 实现 gormlogger.Interface
+func (l *Gorm) Warn(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Warn {
+		FromContext(ctx).Warnf(msg, args...)
+	}
+}
+
+// Error 实现 gormlogger.Interface
+func (l *Gorm) Error(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Error {
+		FromContext(ctx).Errorf(msg, args...)
+	}
+}
+
+// Trace 实现 gormlogger.Interface，记录每条语句的耗时和影响行数
+func (l *Gorm) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.level <= gormlogger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	slow := l.SlowThreshold > 0 && elapsed > l.SlowThreshold
+	failed := err != nil && !errors.Is(err, gorm.ErrRecordNotFound)
+
+	switch {
+	case failed && l.level >= gormlogger.Error:
+	case slow && l.level >= gormlogger.Warn:
+	case l.level >= gormlogger.Info && logrus.IsLevelEnabled(logrus.DebugLevel):
+	default:
+		return
+	}
+
+	sql, rows := fc()
+	entry := FromContext(ctx).WithFields(logrus.Fields{
+		"sql":        sql,
+		"rows":       rows,
+		"elapsed_ms": float64(elapsed.Microseconds()) / 1000,
+	})
+
+	switch {
+	case failed:
+		entry.WithError(err).Error("SQL error")
+	case slow:
+		entry.WithField("threshold_ms", l.SlowThreshold.Milliseconds()).Warn("Slow SQL")
+	default:
+		entry.Debug("SQL")
+	}
+}
@@ -0,0 +1,118 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"stars-admin/internal/config"
+	"stars-admin/internal/tracing"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// 日志输出目标
+const (
+	OutputStdout = "stdout"
+	OutputStderr = "stderr"
+	OutputFile   = "file"
+	OutputBoth   = "both" // 同时输出到标准输出和文件
+)
+
+// file 当前使用的滚动日志文件
+var file *lumberjack.Logger
+
+// Init 按配置设置 logrus 的级别、格式和输出
+func Init(cfg config.LogConfig) error {
+	level, err := logrus.ParseLevel(cfg.Level)
+	if err != nil {
+		return fmt.Errorf("invalid log level %q: %w", cfg.Level, err)
+	}
+
+	var formatter logrus.Formatter
+	switch cfg.Format {
+	case "json", "":
+		formatter = &logrus.JSONFormatter{}
+	case "text":
+		formatter = &logrus.TextFormatter{FullTimestamp: true}
+	default:
+		return fmt.Errorf("unsupported log format: %s", cfg.Format)
+	}
+
+	var out io.Writer
+	switch cfg.Output {
+	case OutputStdout, "":
+		out = os.Stdout
+	case OutputStderr:
+		out = os.Stderr
+	case OutputFile, OutputBoth:
+		if cfg.FilePath == "" {
+			return fmt.Errorf("log.file_path is required for output %s", cfg.Output)
+		}
+		file = &lumberjack.Logger{
+			Filename:   cfg.FilePath,
+			MaxSize:    cfg.MaxSize,
+			MaxBackups: cfg.MaxBackups,
+			MaxAge:     cfg.MaxAge,
+			Compress:   cfg.Compress,
+			LocalTime:  true,
+		}
+		out = file
+		if cfg.Output == OutputBoth {
+			out = io.MultiWriter(os.Stdout, file)
+		}
+	default:
+		return fmt.Errorf("unsupported log output: %s", cfg.Output)
+	}
+
+	logrus.SetLevel(level)
+	logrus.SetFormatter(formatter)
+	logrus.SetOutput(out)
+
+	return nil
+}
+
+// SetLevel 修改日志级别，用于配置热更新
+func SetLevel(level string) error {
+	parsed, err := logrus.ParseLevel(level)
+	if err != nil {
+		return fmt.Errorf("invalid log level %q: %w", level, err)
+	}
+	logrus.SetLevel(parsed)
+	return nil
+}
+
+// Close 关闭日志文件，未使用文件输出时无操作
+func Close(context.Context) error {
+	if file == nil {
+		return nil
+	}
+	return file.Close()
+}
+
+// requestIDKey 请求ID在上下文中的键
+type requestIDKey struct{}
+
+// WithRequestID 将请求ID写入上下文
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestID 读取上下文中的请求ID
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// FromContext 返回带有请求ID和 trace id 字段的日志条目
+func FromContext(ctx context.Context) *logrus.Entry {
+	entry := logrus.NewEntry(logrus.StandardLogger())
+	if requestID := RequestID(ctx); requestID != "" {
+		entry = entry.WithField("request_id", requestID)
+	}
+	if traceID := tracing.TraceID(ctx); traceID != "" {
+		entry = entry.WithField("trace_id", traceID)
+	}
+	return entry.WithContext(ctx)
+}
@@ -0,0 +1,21 @@
+package mail
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+// LogSender 将邮件输出到日志而不发送，用于本地开发
+// 重置密码等邮件中的链接会出现在日志中，不能用于生产环境
+type LogSender struct{}
+
+// Send 输出邮件的收件人、主题和纯文本正文
+func (LogSender) Send(_ context.Context, msg *Message) error {
+	logrus.WithFields(logrus.Fields{
+		"to":      msg.To,
+		"subject": msg.Subject,
+		"body":    msg.Text,
+	}).Info("Email (log driver, not sent)")
+	return nil
+}
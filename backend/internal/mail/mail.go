@@ -0,0 +1,50 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"stars-admin/internal/config"
+	"time"
+)
+
+// 邮件驱动
+const (
+	DriverNone = "none"
+	DriverSMTP = "smtp"
+	DriverLog  = "log"
+)
+
+// Message 邮件内容，HTML 为空时只发送纯文本
+type Message struct {
+	To      []string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Sender 邮件发送方式
+type Sender interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// NewSender 根据配置创建邮件发送方式，驱动为 none 时返回 nil
+func NewSender(cfg config.EmailConfig) (Sender, error) {
+	switch cfg.Driver {
+	case DriverNone, "":
+		return nil, nil
+	case DriverSMTP:
+		return NewSMTPSender(cfg), nil
+	case DriverLog:
+		return LogSender{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported email driver: %s", cfg.Driver)
+	}
+}
+
+// timeout 返回配置的超时时间，未配置时为 10 秒
+func timeout(cfg config.EmailConfig) time.Duration {
+	if cfg.Timeout <= 0 {
+		return 10 * time.Second
+	}
+	return time.Duration(cfg.Timeout) * time.Second
+}
@@ -0,0 +1,149 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"stars-admin/internal/config"
+	"stars-admin/internal/metrics"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// 发送错误
+var (
+	// ErrDisabled 未配置邮件发送
+	ErrDisabled = errors.New("mail: email delivery is disabled")
+	// ErrQueueFull 发送队列已满或已关闭
+	ErrQueueFull = errors.New("mail: queue full")
+)
+
+// Mailer 邮件服务，渲染模板后加入队列，由后台协程逐封发送，关闭时会发完队列中剩余的邮件
+// 发送失败只记录日志，调用方不会等待 SMTP 服务器，也不会因发送结果不同而暴露账号是否存在
+type Mailer struct {
+	sender    Sender
+	templates *Templates
+	cfg       config.EmailConfig
+	queue     chan *Message
+
+	mu     sync.RWMutex
+	closed bool
+	done   chan struct{}
+}
+
+// New 创建邮件服务，defaultLocale 为找不到用户语言的模板时使用的语言
+func New(cfg config.EmailConfig, defaultLocale string) (*Mailer, error) {
+	sender, err := NewSender(cfg)
+	if err != nil {
+		return nil, err
+	}
+	templates, err := LoadTemplates(cfg.TemplateDir, defaultLocale)
+	if err != nil {
+		return nil, err
+	}
+
+	queueSize := cfg.Queue
+	if queueSize <= 0 {
+		queueSize = 100
+	}
+	return &Mailer{
+		sender:    sender,
+		templates: templates,
+		cfg:       cfg,
+		queue:     make(chan *Message, queueSize),
+		done:      make(chan struct{}),
+	}, nil
+}
+
+// Enabled 是否配置了邮件发送
+func (m *Mailer) Enabled() bool {
+	return m.sender != nil
+}
+
+// Name 组件名称
+func (m *Mailer) Name() string {
+	return "mailer"
+}
+
+// Start 启动后台发送协程
+func (m *Mailer) Start(context.Context) error {
+	go m.run()
+	return nil
+}
+
+// Stop 停止接收新邮件并等待队列发完，超时后放弃剩余邮件
+func (m *Mailer) Stop(ctx context.Context) error {
+	m.mu.Lock()
+	if !m.closed {
+		m.closed = true
+		close(m.queue)
+	}
+	m.mu.Unlock()
+
+	select {
+	case <-m.done:
+		return nil
+	case <-ctx.Done():
+		logrus.WithField("pending", len(m.queue)).Warn("Mailer stopped before queue drained")
+		return ctx.Err()
+	}
+}
+
+// Len 当前队列长度
+func (m *Mailer) Len() int {
+	return len(m.queue)
+}
+
+// Send 将邮件加入发送队列
+func (m *Mailer) Send(msg *Message) error {
+	if !m.Enabled() {
+		return ErrDisabled
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.closed {
+		return ErrQueueFull
+	}
+	select {
+	case m.queue <- msg:
+		return nil
+	default:
+		metrics.MailsSent.WithLabelValues("dropped").Inc()
+		return ErrQueueFull
+	}
+}
+
+// SendTemplate 按收件人的语言渲染模板并加入发送队列
+func (m *Mailer) SendTemplate(to, locale, name string, data interface{}) error {
+	if !m.Enabled() {
+		return ErrDisabled
+	}
+
+	msg, err := m.templates.Render(locale, name, data)
+	if err != nil {
+		return err
+	}
+	msg.To = []string{to}
+	return m.Send(msg)
+}
+
+// run 后台发送协程
+func (m *Mailer) run() {
+	defer close(m.done)
+
+	for msg := range m.queue {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout(m.cfg))
+		err := m.sender.Send(ctx, msg)
+		cancel()
+
+		if err != nil {
+			metrics.MailsSent.WithLabelValues("failed").Inc()
+			logrus.WithError(err).WithFields(logrus.Fields{"to": msg.To, "subject": msg.Subject}).Error("Failed to send email")
+			continue
+		}
+		metrics.MailsSent.WithLabelValues("sent").Inc()
+		logrus.WithFields(logrus.Fields{"to": msg.To, "subject": msg.Subject}).Info("Email sent")
+	}
+}
@@ -0,0 +1,130 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"path"
+	"stars-admin/internal/i18n"
+	"strings"
+	texttemplate "text/template"
+)
+
+// builtin 内置的邮件模板，目录结构为 templates/<语言>/<模板名>.tmpl
+//
+//go:embed templates
+var builtin embed.FS
+
+// templateExt 模板文件扩展名
+const templateExt = ".tmpl"
+
+// template 同一份模板分别按纯文本和 HTML 解析，HTML 正文中的数据会被转义
+type template struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// Templates 邮件模板集合
+// 每个模板文件定义 subject、text 两个块，可选定义 html 块；找不到用户语言的模板时使用默认语言
+type Templates struct {
+	fallback  string
+	templates map[string]*template // 键为 <语言>/<模板名>
+}
+
+// LoadTemplates 加载内置模板，dir 存在时再加载其中的模板，同名模板覆盖内置模板
+func LoadTemplates(dir, fallback string) (*Templates, error) {
+	t := &Templates{fallback: fallback, templates: make(map[string]*template)}
+
+	sub, err := fs.Sub(builtin, "templates")
+	if err != nil {
+		return nil, err
+	}
+	if err := t.load(sub); err != nil {
+		return nil, err
+	}
+
+	if dir != "" {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			if err := t.load(os.DirFS(dir)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return t, nil
+}
+
+// load 加载文件系统中 <语言>/<模板名>.tmpl 格式的模板
+func (t *Templates) load(fsys fs.FS) error {
+	files, err := fs.Glob(fsys, "*/*"+templateExt)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		content, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return err
+		}
+
+		key := strings.TrimSuffix(file, templateExt)
+		text, err := texttemplate.New(key).Parse(string(content))
+		if err != nil {
+			return fmt.Errorf("parse email template %s: %w", file, err)
+		}
+		if text.Lookup("subject") == nil || text.Lookup("text") == nil {
+			return fmt.Errorf("email template %s must define subject and text", file)
+		}
+		html, err := htmltemplate.New(key).Parse(string(content))
+		if err != nil {
+			return fmt.Errorf("parse email template %s: %w", file, err)
+		}
+
+		t.templates[key] = &template{text: text, html: html}
+	}
+	return nil
+}
+
+// Render 按语言渲染模板，找不到时依次使用默认语言和内置的默认语言
+func (t *Templates) Render(locale, name string, data interface{}) (*Message, error) {
+	tmpl := t.lookup(name, locale, t.fallback, i18n.DefaultLocale)
+	if tmpl == nil {
+		return nil, fmt.Errorf("email template %s not found", name)
+	}
+
+	var subject, text, html bytes.Buffer
+	if err := tmpl.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, err
+	}
+	if err := tmpl.text.ExecuteTemplate(&text, "text", data); err != nil {
+		return nil, err
+	}
+	if tmpl.html.Lookup("html") != nil {
+		if err := tmpl.html.ExecuteTemplate(&html, "html", data); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Message{
+		// 主题中不能包含换行
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		Text:    strings.TrimSpace(text.String()) + "\n",
+		HTML:    strings.TrimSpace(html.String()),
+	}, nil
+}
+
+// lookup 返回第一个存在的语言的模板
+func (t *Templates) lookup(name string, locales ...string) *template {
+	for _, locale := range locales {
+		if locale == "" {
+			continue
+		}
+		if tmpl, ok := t.templates[path.Join(locale, name)]; ok {
+			return tmpl
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"database/sql"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// dbStatsCollector 在采集时读取各数据库节点的连接池统计
+type dbStatsCollector struct {
+	stats func() map[string]sql.DBStats
+}
+
+var (
+	dbMaxOpen = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "db", "max_open_connections"),
+		"Maximum number of open connections to the database.", []string{"node"}, nil)
+	dbOpen = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "db", "open_connections"),
+		"The number of established connections both in use and idle.", []string{"node"}, nil)
+	dbInUse = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "db", "in_use_connections"),
+		"The number of connections currently in use.", []string{"node"}, nil)
+	dbIdle = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "db", "idle_connections"),
+		"The number of idle connections.", []string{"node"}, nil)
+	dbWaitCount = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "db", "wait_count_total"),
+		"The total number of connections waited for.", []string{"node"}, nil)
+	dbWaitDuration = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "db", "wait_duration_seconds_total"),
+		"The total time blocked waiting for a new connection.", []string{"node"}, nil)
+	dbMaxIdleClosed = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "db", "max_idle_closed_total"),
+		"The total number of connections closed due to max idle limits.", []string{"node"}, nil)
+	dbMaxLifetimeClosed = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "db", "max_lifetime_closed_total"),
+		"The total number of connections closed due to max lifetime.", []string{"node"}, nil)
+)
+
+// Describe 实现 prometheus.Collector
+func (c *dbStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- dbMaxOpen
+	ch <- dbOpen
+	ch <- dbInUse
+	ch <- dbIdle
+	ch <- dbWaitCount
+	ch <- dbWaitDuration
+	ch <- dbMaxIdleClosed
+	ch <- dbMaxLifetimeClosed
+}
+
+// Collect 实现 prometheus.Collector
+func (c *dbStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	for node, s := range c.stats() {
+		ch <- prometheus.MustNewConstMetric(dbMaxOpen, prometheus.GaugeValue, float64(s.MaxOpenConnections), node)
+		ch <- prometheus.MustNewConstMetric(dbOpen, prometheus.GaugeValue, float64(s.OpenConnections), node)
+		ch <- prometheus.MustNewConstMetric(dbInUse, prometheus.GaugeValue, float64(s.InUse), node)
+		ch <- prometheus.MustNewConstMetric(dbIdle, prometheus.GaugeValue, float64(s.Idle), node)
+		ch <- prometheus.MustNewConstMetric(dbWaitCount, prometheus.CounterValue, float64(s.WaitCount), node)
+		ch <- prometheus.MustNewConstMetric(dbWaitDuration, prometheus.CounterValue, s.WaitDuration.Seconds(), node)
+		ch <- prometheus.MustNewConstMetric(dbMaxIdleClosed, prometheus.CounterValue, float64(s.MaxIdleClosed), node)
+		ch <- prometheus.MustNewConstMetric(dbMaxLifetimeClosed, prometheus.CounterValue, float64(s.MaxLifetimeClosed), node)
+	}
+}
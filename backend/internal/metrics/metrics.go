@@ -0,0 +1,110 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace 指标名前缀
+const namespace = "stars"
+
+// Registry 应用指标注册表，包含Go运行时和进程指标
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// HTTP请求指标，route 为 gin 路由模板，未匹配路由时为空
+var (
+	HTTPRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "HTTP requests processed, by method, route template and status.",
+	}, []string{"method", "route", "status"})
+
+	HTTPDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency, by method, route template and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+)
+
+// 认证指标
+var (
+	// LoginAttempts 登录次数，result: success, invalid_credentials, disabled, ip_blocked, error
+	LoginAttempts = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "auth",
+		Name:      "login_total",
+		Help:      "Login attempts, by result.",
+	}, []string{"result"})
+
+	// TokenRefreshes 刷新令牌次数，result: success, invalid, disabled, ip_blocked, unavailable, error
+	TokenRefreshes = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "auth",
+		Name:      "token_refresh_total",
+		Help:      "Token refresh attempts, by result.",
+	}, []string{"result"})
+
+	// BlacklistHits 携带已注销令牌的请求次数
+	BlacklistHits = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "auth",
+		Name:      "blacklist_hits_total",
+		Help:      "Requests rejected because the access token was revoked.",
+	})
+)
+
+// MailsSent 邮件发送次数，result: sent, failed, dropped（队列已满）
+var MailsSent = factory.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: "mail",
+	Name:      "sent_total",
+	Help:      "Emails processed by the mail queue, by result.",
+}, []string{"result"})
+
+// RedisDuration Redis命令耗时，status: ok, error
+var RedisDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: namespace,
+	Subsystem: "redis",
+	Name:      "command_duration_seconds",
+	Help:      "Redis command latency, by command and status.",
+	Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+}, []string{"command", "status"})
+
+// RegisterQueue 注册队列长度指标
+func RegisterQueue(name string, length func() int) {
+	factory.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace:   namespace,
+		Name:        "queue_length",
+		Help:        "Number of items waiting in an in-process queue.",
+		ConstLabels: prometheus.Labels{"queue": name},
+	}, func() float64 {
+		return float64(length())
+	})
+}
+
+// RegisterDBStats 注册数据库连接池指标，stats 返回各节点名称到连接池统计的映射
+func RegisterDBStats(stats func() map[string]sql.DBStats) {
+	Registry.MustRegister(&dbStatsCollector{stats: stats})
+}
+
+// Handler 指标输出接口
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
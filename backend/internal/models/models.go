@@ -6,20 +6,40 @@ import (
 	"gorm.io/gorm"
 )
 
+// 用户状态
+const (
+	UserStatusDisabled        = 0 // 禁用
+	UserStatusActive          = 1 // 正常
+	UserStatusPendingApproval = 2 // 自助注册并验证邮箱后，等待管理员审核
+	UserStatusUnverified      = 3 // 自助注册后尚未验证邮箱
+)
+
+// RoleAdmin 超级管理员角色编码，拥有全部权限，不能通过自助注册、外部身份或 API 密钥获得
+const RoleAdmin = "admin"
+
+// PermissionAll 拥有全部权限
+const PermissionAll = "*"
+
 // User 用户模型
 type User struct {
-	ID          uint           `gorm:"primaryKey" json:"id"`
-	Username    string         `gorm:"uniqueIndex;size:50;not null" json:"username"`
-	Password    string         `gorm:"size:255;not null" json:"-"`
-	Email       string         `gorm:"uniqueIndex;size:100" json:"email"`
-	Phone       string         `gorm:"size:20" json:"phone"`
-	Nickname    string         `gorm:"size:50" json:"nickname"`
-	Avatar      string         `gorm:"size:255" json:"avatar"`
-	Status      int            `gorm:"default:1" json:"status"` // 1:正常 0:禁用
-	LastLoginAt *time.Time     `json:"last_login_at"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+	ID                 uint           `gorm:"primaryKey" json:"id"`
+	Username           string         `gorm:"uniqueIndex;size:50;not null" json:"username"`
+	Password           string         `gorm:"size:255;not null" json:"-"`
+	Email              string         `gorm:"uniqueIndex;size:100" json:"email"`
+	Phone              string         `gorm:"size:20" json:"phone"`
+	Nickname           string         `gorm:"size:50" json:"nickname"`
+	Avatar             string         `gorm:"size:255" json:"avatar"`
+	Status             int            `gorm:"default:1" json:"status"`                   // 1:正常 0:禁用 2:待审核 3:待验证邮箱
+	MustChangePassword bool           `gorm:"default:false" json:"must_change_password"` // 下次登录后必须先修改密码
+	Locale             string         `gorm:"size:20" json:"locale"`                     // 偏好语言，为空时按 Accept-Language
+	AllowedIPs         string         `gorm:"size:1000" json:"allowed_ips"`              // 允许登录和访问的 IP 或网段，逗号分隔，为空时不限制
+	PasswordChangedAt  *time.Time     `json:"password_changed_at"`                       // 为空时按创建时间计算密码有效期
+	EmailVerifiedAt    *time.Time     `json:"email_verified_at"`                         // 验证邮箱或通过邀请注册的时间
+	ServiceAccount     bool           `gorm:"default:false" json:"service_account"`      // 服务账号没有密码，只能通过 API 密钥访问
+	LastLoginAt        *time.Time     `json:"last_login_at"`
+	CreatedAt          time.Time      `json:"created_at"`
+	UpdatedAt          time.Time      `json:"updated_at"`
+	DeletedAt          gorm.DeletedAt `gorm:"index" json:"-"`
 
 	// 关联关系
 	Roles []Role `gorm:"many2many:xc_user_roles" json:"roles,omitempty"`
@@ -99,9 +119,143 @@ type OperationLog struct {
 	Latency   int64     `json:"latency"` // 响应时间(毫秒)
 	Request   string    `gorm:"type:text" json:"request"`
 	Response  string    `gorm:"type:text" json:"response"`
+	TraceID   string    `gorm:"size:32;index" json:"trace_id"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
+// SystemSetting 系统配置，只保存覆盖了默认值的配置项
+type SystemSetting struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Key       string    `gorm:"column:setting_key;uniqueIndex;size:100;not null" json:"key"`
+	Value     string    `gorm:"type:text" json:"value"`
+	UpdatedBy uint      `json:"updated_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SystemSettingHistory 系统配置变更历史
+type SystemSettingHistory struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	Key        string    `gorm:"column:setting_key;size:100;index;not null" json:"key"`
+	Action     string    `gorm:"size:10" json:"action"` // update:修改 reset:恢复默认
+	OldValue   string    `gorm:"type:text" json:"old_value"`
+	NewValue   string    `gorm:"type:text" json:"new_value"`
+	OperatorID uint      `json:"operator_id"`
+	Operator   string    `gorm:"size:50" json:"operator"`
+	CreatedAt  time.Time `gorm:"index" json:"created_at"`
+}
+
+// PasswordHistory 密码历史，用于禁止重复使用最近的密码
+type PasswordHistory struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"index;not null" json:"user_id"`
+	Password  string    `gorm:"size:255;not null" json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// IPRule IP 访问控制规则
+type IPRule struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	CIDR        string    `gorm:"column:cidr;size:50;not null" json:"cidr"`
+	Action      string    `gorm:"size:10;not null" json:"action"`      // allow:允许 deny:拒绝
+	Scope       string    `gorm:"size:50;index;not null" json:"scope"` // global:所有请求 system:系统管理接口
+	Description string    `gorm:"size:255" json:"description"`
+	CreatedBy   uint      `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// SecurityLog 安全日志，记录被拦截的访问等安全事件
+type SecurityLog struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Event     string    `gorm:"size:50;index" json:"event"`
+	UserID    uint      `gorm:"index" json:"user_id"`
+	Username  string    `gorm:"size:50" json:"username"`
+	IP        string    `gorm:"size:50;index" json:"ip"`
+	Method    string    `gorm:"size:10" json:"method"`
+	Path      string    `gorm:"size:255" json:"path"`
+	UserAgent string    `gorm:"size:255" json:"user_agent"`
+	Detail    string    `gorm:"size:500" json:"detail"`
+	TraceID   string    `gorm:"size:32" json:"trace_id"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
+
+// Invitation 用户邀请，令牌只保存哈希
+type Invitation struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	Email      string     `gorm:"size:100;index;not null" json:"email"`
+	TokenHash  string     `gorm:"uniqueIndex;size:64;not null" json:"-"`
+	Roles      string     `gorm:"size:500" json:"roles"` // 接受邀请后分配的角色编码，逗号分隔
+	InvitedBy  uint       `json:"invited_by"`
+	ExpiresAt  time.Time  `gorm:"index" json:"expires_at"`
+	AcceptedAt *time.Time `json:"accepted_at"`
+	UserID     *uint      `json:"user_id"` // 接受邀请后创建的用户
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+
+	// Status 由 InvitationStatus 计算，不保存
+	Status string `gorm:"-" json:"status"`
+}
+
+// APIKey API 密钥，供脚本和 CI 等机器客户端使用，密钥只保存哈希
+// 密钥的角色和权限是创建时所有者角色和权限的子集，使用时再与所有者当前的角色和权限取交集
+type APIKey struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	Name        string     `gorm:"size:50;not null" json:"name"`
+	Prefix      string     `gorm:"size:20" json:"prefix"` // 密钥开头几位，用于识别密钥
+	KeyHash     string     `gorm:"uniqueIndex;size:64;not null" json:"-"`
+	UserID      uint       `gorm:"index;not null" json:"user_id"` // 所有者，用户或服务账号
+	Roles       string     `gorm:"size:500" json:"roles"`         // 角色编码，逗号分隔
+	Permissions string     `gorm:"type:text" json:"permissions"`  // 权限，逗号分隔
+	ExpiresAt   *time.Time `json:"expires_at"`                    // 为空时不过期
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	LastUsedIP  string     `gorm:"column:last_used_ip;size:50" json:"last_used_ip"`
+	RevokedAt   *time.Time `json:"revoked_at"`
+	CreatedBy   uint       `json:"created_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// Active 返回密钥在 now 时是否可用
+func (k *APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// UserIdentity 用户在外部身份提供方的身份，同一身份只能关联一个用户
+type UserIdentity struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	UserID      uint       `gorm:"index;not null" json:"user_id"`
+	Provider    string     `gorm:"size:50;not null;uniqueIndex:idx_identity_provider_subject" json:"provider"`
+	Subject     string     `gorm:"size:255;not null;uniqueIndex:idx_identity_provider_subject" json:"subject"`
+	Email       string     `gorm:"size:100" json:"email"` // 最近一次登录时身份提供方返回的邮箱
+	LastLoginAt *time.Time `json:"last_login_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// 邀请状态
+const (
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationRevoked  = "revoked"
+	InvitationExpired  = "expired"
+)
+
+// InvitationStatus 返回邀请在 now 时的状态
+func (i *Invitation) InvitationStatus(now time.Time) string {
+	switch {
+	case i.AcceptedAt != nil:
+		return InvitationAccepted
+	case i.RevokedAt != nil:
+		return InvitationRevoked
+	case !now.Before(i.ExpiresAt):
+		return InvitationExpired
+	default:
+		return InvitationPending
+	}
+}
+
 // TableName 设置表名
 func (User) TableName() string {
 	return "xc_users"
@@ -130,3 +284,35 @@ func (RoleMenu) TableName() string {
 func (OperationLog) TableName() string {
 	return "xc_operation_logs"
 }
+
+func (SystemSetting) TableName() string {
+	return "xc_system_settings"
+}
+
+func (SystemSettingHistory) TableName() string {
+	return "xc_system_setting_histories"
+}
+
+func (PasswordHistory) TableName() string {
+	return "xc_password_histories"
+}
+
+func (IPRule) TableName() string {
+	return "xc_ip_rules"
+}
+
+func (SecurityLog) TableName() string {
+	return "xc_security_logs"
+}
+
+func (Invitation) TableName() string {
+	return "xc_invitations"
+}
+
+func (APIKey) TableName() string {
+	return "xc_api_keys"
+}
+
+func (UserIdentity) TableName() string {
+	return "xc_user_identities"
+}
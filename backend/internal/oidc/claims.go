@@ -0,0 +1,55 @@
+package oidc
+
+import (
+	"strings"
+)
+
+// Claims ID 令牌和 UserInfo 中的声明
+type Claims struct {
+	Subject string
+	raw     map[string]interface{}
+}
+
+// String 返回字符串声明，不存在或不是字符串时返回空字符串
+func (c *Claims) String(name string) string {
+	s, _ := c.raw[name].(string)
+	return s
+}
+
+// Strings 返回字符串或字符串数组声明，如 groups
+func (c *Claims) Strings(name string) []string {
+	switch v := c.raw[name].(type) {
+	case string:
+		if v == "" {
+			return nil
+		}
+		return []string{v}
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+// Email 返回小写的邮箱地址
+func (c *Claims) Email() string {
+	return strings.ToLower(strings.TrimSpace(c.String("email")))
+}
+
+// EmailVerified 返回 email_verified 声明，部分身份提供方以字符串返回
+func (c *Claims) EmailVerified() bool {
+	switch v := c.raw["email_verified"].(type) {
+	case bool:
+		return v
+	case string:
+		return strings.EqualFold(v, "true")
+	default:
+		return false
+	}
+}
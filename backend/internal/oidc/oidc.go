@@ -0,0 +1,184 @@
+// Package oidc 实现 OpenID Connect 授权码 + PKCE 登录中与身份提供方交互的部分
+package oidc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"stars-admin/internal/config"
+	"sync"
+	"time"
+
+	gooidc "github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// ErrUnknownProvider 未配置的身份提供方
+var ErrUnknownProvider = errors.New("oidc: unknown provider")
+
+// defaultScopes 未配置 scopes 时请求的范围
+var defaultScopes = []string{gooidc.ScopeOpenID, "profile", "email"}
+
+// ProviderInfo 登录页展示的身份提供方
+type ProviderInfo struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+}
+
+// Registry 身份提供方注册表
+// 端点在首次使用时通过发现文档获取，身份提供方暂时不可用不影响服务启动，发现失败时下次使用再重试
+type Registry struct {
+	configs map[string]config.OIDCProviderConfig
+	client  *http.Client
+
+	mu        sync.Mutex
+	providers map[string]*Provider
+}
+
+// NewRegistry 按配置创建身份提供方注册表
+func NewRegistry(cfg config.OIDCConfig) *Registry {
+	return &Registry{
+		configs:   cfg.Providers,
+		client:    &http.Client{Timeout: time.Duration(cfg.Timeout) * time.Second},
+		providers: make(map[string]*Provider),
+	}
+}
+
+// Providers 返回按标识排序的身份提供方
+func (r *Registry) Providers() []ProviderInfo {
+	infos := make([]ProviderInfo, 0, len(r.configs))
+	for name, cfg := range r.configs {
+		display := cfg.DisplayName
+		if display == "" {
+			display = name
+		}
+		infos = append(infos, ProviderInfo{Name: name, DisplayName: display})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+	return infos
+}
+
+// Get 返回身份提供方，首次调用时获取发现文档
+func (r *Registry) Get(ctx context.Context, name string) (*Provider, error) {
+	cfg, ok := r.configs[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if p, ok := r.providers[name]; ok {
+		return p, nil
+	}
+
+	discovered, err := gooidc.NewProvider(gooidc.ClientContext(ctx, r.client), cfg.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: discover %s: %w", name, err)
+	}
+
+	scopes := cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = defaultScopes
+	}
+	p := &Provider{
+		Name:     name,
+		Config:   cfg,
+		client:   r.client,
+		provider: discovered,
+		oauth2: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			Endpoint:     discovered.Endpoint(),
+			RedirectURL:  cfg.RedirectURL,
+			Scopes:       scopes,
+		},
+		verifier: discovered.Verifier(&gooidc.Config{ClientID: cfg.ClientID}),
+	}
+	r.providers[name] = p
+	return p, nil
+}
+
+// Provider 已获取发现文档的身份提供方
+type Provider struct {
+	Name   string
+	Config config.OIDCProviderConfig
+
+	client   *http.Client
+	provider *gooidc.Provider
+	oauth2   oauth2.Config
+	verifier *gooidc.IDTokenVerifier
+}
+
+// GenerateVerifier 生成 PKCE code_verifier
+func GenerateVerifier() string {
+	return oauth2.GenerateVerifier()
+}
+
+// AuthCodeURL 返回跳转到身份提供方的授权地址，使用 S256 PKCE
+func (p *Provider) AuthCodeURL(state, nonce, verifier string) string {
+	return p.oauth2.AuthCodeURL(state, gooidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier))
+}
+
+// Exchange 用授权码换取令牌并校验 ID 令牌的签名、受众、有效期和 nonce，返回 ID 令牌中的声明
+// ID 令牌缺少邮箱或角色声明时从 UserInfo 端点补充
+func (p *Provider) Exchange(ctx context.Context, code, verifier, nonce string) (*Claims, error) {
+	ctx = gooidc.ClientContext(ctx, p.client)
+
+	token, err := p.oauth2.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("oidc: exchange code: %w", err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, errors.New("oidc: token response has no id_token")
+	}
+
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: verify id_token: %w", err)
+	}
+	if idToken.Nonce != nonce {
+		return nil, errors.New("oidc: id_token nonce mismatch")
+	}
+
+	claims := &Claims{Subject: idToken.Subject, raw: map[string]interface{}{}}
+	if err := idToken.Claims(&claims.raw); err != nil {
+		return nil, fmt.Errorf("oidc: decode id_token claims: %w", err)
+	}
+
+	if claims.String("email") == "" || (p.Config.RoleClaim != "" && claims.raw[p.Config.RoleClaim] == nil) {
+		if err := p.mergeUserInfo(ctx, token, claims); err != nil {
+			return nil, err
+		}
+	}
+	return claims, nil
+}
+
+// mergeUserInfo 从 UserInfo 端点补充 ID 令牌中没有的声明，身份提供方不支持时忽略
+func (p *Provider) mergeUserInfo(ctx context.Context, token *oauth2.Token, claims *Claims) error {
+	if p.provider.UserInfoEndpoint() == "" {
+		return nil
+	}
+	info, err := p.provider.UserInfo(ctx, oauth2.StaticTokenSource(token))
+	if err != nil {
+		return fmt.Errorf("oidc: userinfo: %w", err)
+	}
+	if info.Subject != claims.Subject {
+		return errors.New("oidc: userinfo subject mismatch")
+	}
+
+	var extra map[string]interface{}
+	if err := info.Claims(&extra); err != nil {
+		return fmt.Errorf("oidc: decode userinfo claims: %w", err)
+	}
+	for key, value := range extra {
+		if _, ok := claims.raw[key]; !ok {
+			claims.raw[key] = value
+		}
+	}
+	return nil
+}
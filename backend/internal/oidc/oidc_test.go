@@ -0,0 +1,165 @@
+package oidc
+
+import (
+	"context"
+	"net/url"
+	"reflect"
+	"stars-admin/internal/config"
+	"stars-admin/internal/oidc/oidctest"
+	"strings"
+	"testing"
+)
+
+func newTestProvider(t *testing.T, cfg config.OIDCProviderConfig) (*oidctest.Server, *Provider) {
+	t.Helper()
+	idp := oidctest.NewServer("stars-admin", "secret")
+	t.Cleanup(idp.Close)
+
+	cfg.Issuer = idp.URL
+	cfg.ClientID = "stars-admin"
+	cfg.ClientSecret = "secret"
+	cfg.RedirectURL = "http://localhost:8080/api/v1/auth/oidc/dev/callback"
+	registry := NewRegistry(config.OIDCConfig{Timeout: 5, Providers: map[string]config.OIDCProviderConfig{"dev": cfg}})
+	p, err := registry.Get(context.Background(), "dev")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	return idp, p
+}
+
+// authorize 以身份提供方当前的用户完成授权，返回授权码
+func authorize(t *testing.T, idp *oidctest.Server, p *Provider, state, nonce, verifier string) string {
+	t.Helper()
+	params, err := idp.Authorize(p.AuthCodeURL(state, nonce, verifier))
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	if params.Get("state") != state || params.Get("code") == "" {
+		t.Fatalf("unexpected callback %v", params)
+	}
+	return params.Get("code")
+}
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry(config.OIDCConfig{Providers: map[string]config.OIDCProviderConfig{
+		"b":   {DisplayName: "Corp"},
+		"a":   {},
+		"dev": {Issuer: "http://127.0.0.1:1"},
+	}})
+	want := []ProviderInfo{{Name: "a", DisplayName: "a"}, {Name: "b", DisplayName: "Corp"}, {Name: "dev", DisplayName: "dev"}}
+	if got := registry.Providers(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Providers: got %v, want %v", got, want)
+	}
+
+	if _, err := registry.Get(context.Background(), "missing"); err != ErrUnknownProvider {
+		t.Errorf("unknown provider: got %v", err)
+	}
+	if _, err := registry.Get(context.Background(), "dev"); err == nil || err == ErrUnknownProvider {
+		t.Errorf("unreachable issuer: got %v", err)
+	}
+}
+
+func TestAuthCodeURL(t *testing.T) {
+	_, p := newTestProvider(t, config.OIDCProviderConfig{})
+	verifier := GenerateVerifier()
+
+	u, err := url.Parse(p.AuthCodeURL("state-1", "nonce-1", verifier))
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := u.Query()
+	for key, want := range map[string]string{
+		"client_id":             "stars-admin",
+		"response_type":         "code",
+		"state":                 "state-1",
+		"nonce":                 "nonce-1",
+		"code_challenge_method": "S256",
+		"scope":                 "openid profile email",
+	} {
+		if got := query.Get(key); got != want {
+			t.Errorf("%s: got %q, want %q", key, got, want)
+		}
+	}
+	if challenge := query.Get("code_challenge"); challenge == "" || challenge == verifier {
+		t.Errorf("code_challenge should be the S256 hash of the verifier, got %q", challenge)
+	}
+	if strings.Contains(u.String(), verifier) {
+		t.Error("verifier must not appear in the authorization URL")
+	}
+}
+
+func TestExchange(t *testing.T) {
+	idp, p := newTestProvider(t, config.OIDCProviderConfig{})
+	idp.SetUser(&oidctest.User{Subject: "u-1", Email: " Dave@Example.com ", EmailVerified: true, Name: "Dave", Username: "dave", Groups: []string{"staff", "ops"}})
+	ctx := context.Background()
+
+	verifier := GenerateVerifier()
+	code := authorize(t, idp, p, "state", "nonce", verifier)
+	claims, err := p.Exchange(ctx, code, verifier, "nonce")
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if claims.Subject != "u-1" || claims.Email() != "dave@example.com" || !claims.EmailVerified() || claims.String("preferred_username") != "dave" {
+		t.Errorf("unexpected claims %+v", claims.raw)
+	}
+	if groups := claims.Strings("groups"); !reflect.DeepEqual(groups, []string{"staff", "ops"}) {
+		t.Errorf("groups: got %v", groups)
+	}
+
+	// 授权码只能使用一次
+	if _, err := p.Exchange(ctx, code, verifier, "nonce"); err == nil {
+		t.Error("reused code should be rejected")
+	}
+
+	// PKCE code_verifier 与授权请求不一致
+	code = authorize(t, idp, p, "state", "nonce", verifier)
+	if _, err := p.Exchange(ctx, code, GenerateVerifier(), "nonce"); err == nil {
+		t.Error("verifier mismatch should be rejected")
+	}
+
+	// ID 令牌的 nonce 与本次登录不一致，如重放其他登录的授权码
+	code = authorize(t, idp, p, "state", "other-nonce", verifier)
+	if _, err := p.Exchange(ctx, code, verifier, "nonce"); err == nil || !strings.Contains(err.Error(), "nonce") {
+		t.Errorf("nonce mismatch: got %v", err)
+	}
+}
+
+func TestExchangeUserInfo(t *testing.T) {
+	// ID 令牌中没有 role_claim 时请求 UserInfo 端点补充，UserInfo 中也没有时为空
+	idp, p := newTestProvider(t, config.OIDCProviderConfig{RoleClaim: "roles"})
+	idp.SetUser(&oidctest.User{Subject: "u-1", Email: "dave@example.com"})
+
+	verifier := GenerateVerifier()
+	claims, err := p.Exchange(context.Background(), authorize(t, idp, p, "state", "nonce", verifier), verifier, "nonce")
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if claims.EmailVerified() {
+		t.Error("email_verified should be false")
+	}
+	if claims.Strings("roles") != nil {
+		t.Errorf("roles: got %v", claims.Strings("roles"))
+	}
+}
+
+func TestClaims(t *testing.T) {
+	c := &Claims{raw: map[string]interface{}{
+		"email":          "A@B.com",
+		"email_verified": "TRUE",
+		"group":          "staff",
+		"groups":         []interface{}{"staff", 1, "ops"},
+		"empty":          "",
+	}}
+	if c.Email() != "a@b.com" || !c.EmailVerified() {
+		t.Errorf("email: got %q verified %v", c.Email(), c.EmailVerified())
+	}
+	if got := c.Strings("group"); !reflect.DeepEqual(got, []string{"staff"}) {
+		t.Errorf("string claim: got %v", got)
+	}
+	if got := c.Strings("groups"); !reflect.DeepEqual(got, []string{"staff", "ops"}) {
+		t.Errorf("array claim: got %v", got)
+	}
+	if c.Strings("empty") != nil || c.Strings("missing") != nil || c.String("groups") != "" {
+		t.Error("missing or non-string claims should be empty")
+	}
+}
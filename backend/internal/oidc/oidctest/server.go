@@ -0,0 +1,83 @@
+package oidctest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+)
+
+// Server 测试用的身份提供方，授权请求直接以 SetUser 设置的用户登录
+type Server struct {
+	*Provider
+	// URL 身份提供方地址，即 issuer
+	URL string
+
+	srv  *httptest.Server
+	mu   sync.Mutex
+	user *User
+}
+
+// NewServer 在 127.0.0.1 的随机端口启动身份提供方，测试结束时调用 Close
+func NewServer(clientID, clientSecret string) *Server {
+	s := &Server{}
+	s.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.Provider.ServeHTTP(w, r)
+	}))
+	p, err := NewProvider(s.srv.URL, clientID, clientSecret)
+	if err != nil {
+		s.srv.Close()
+		panic(fmt.Sprintf("oidctest: failed to create provider: %v", err))
+	}
+	p.Login = s.login
+	s.Provider = p
+	s.URL = s.srv.URL
+	return s
+}
+
+// SetUser 设置之后的授权请求登录的用户，nil 表示拒绝授权
+func (s *Server) SetUser(u *User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.user = u
+}
+
+func (s *Server) login(w http.ResponseWriter, r *http.Request) (*User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.user == nil {
+		return nil, ErrAccessDenied
+	}
+	u := *s.user
+	return &u, nil
+}
+
+// Authorize 请求授权地址，返回重定向到回调地址时附加的参数（code 和 state，或 error）
+func (s *Server) Authorize(authURL string) (url.Values, error) {
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		return nil, fmt.Errorf("oidctest: authorize returned %s", resp.Status)
+	}
+	location, err := resp.Location()
+	if err != nil {
+		return nil, err
+	}
+	if location.Query().Get("code") == "" && location.Query().Get("error") == "" {
+		return nil, errors.New("oidctest: redirect has neither code nor error")
+	}
+	return location.Query(), nil
+}
+
+// Close 停止身份提供方
+func (s *Server) Close() {
+	s.srv.Close()
+}
@@ -0,0 +1,163 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// memorySweepInterval 清理闲置限流状态的间隔
+const memorySweepInterval = time.Minute
+
+// bucket 令牌桶状态
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	expires time.Time
+}
+
+// window 滑动窗口状态
+type window struct {
+	hits    []time.Time
+	expires time.Time
+}
+
+// MemoryLimiter 进程内限流器，用于单节点部署或Redis不可用时的降级
+type MemoryLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	windows   map[string]*window
+	lastSweep time.Time
+}
+
+// NewMemoryLimiter 创建进程内限流器
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		buckets:   make(map[string]*bucket),
+		windows:   make(map[string]*window),
+		lastSweep: time.Now(),
+	}
+}
+
+// limitState 单条规则的限流状态，先按当前时间恢复配额，判断全部规则后再统一消耗
+type limitState interface {
+	available(rule Rule) bool
+	consume(now time.Time)
+	result(rule Rule, allowed bool, now time.Time) Result
+}
+
+// Allow 判断请求是否允许通过，所有规则在同一把锁内判断和消耗
+func (l *MemoryLimiter) Allow(_ context.Context, checks []Check) ([]Result, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.sweep(now)
+
+	states := make([]limitState, len(checks))
+	available := make([]bool, len(checks))
+	allowed := true
+	for i, check := range checks {
+		if check.Rule.Algorithm == AlgorithmTokenBucket {
+			states[i] = l.tokenBucket(check.Key, check.Rule, now)
+		} else {
+			states[i] = l.slidingWindow(check.Key, check.Rule, now)
+		}
+		available[i] = states[i].available(check.Rule)
+		allowed = allowed && available[i]
+	}
+
+	results := make([]Result, len(checks))
+	for i, check := range checks {
+		if allowed {
+			states[i].consume(now)
+		}
+		results[i] = states[i].result(check.Rule, available[i], now)
+	}
+	return results, nil
+}
+
+// tokenBucket 获取令牌桶并按经过的时间补充令牌
+func (l *MemoryLimiter) tokenBucket(key string, rule Rule, now time.Time) *bucket {
+	capacity := float64(rule.capacity())
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, updated: now}
+		l.buckets[key] = b
+	}
+
+	elapsed := float64(now.Sub(b.updated).Milliseconds())
+	b.tokens = math.Min(capacity, b.tokens+math.Max(0, elapsed)*rule.rate())
+	b.updated = now
+	b.expires = now.Add(time.Duration(capacity/rule.rate()) * time.Millisecond)
+
+	return b
+}
+
+func (b *bucket) available(Rule) bool {
+	return b.tokens >= 1
+}
+
+func (b *bucket) consume(time.Time) {
+	b.tokens--
+}
+
+func (b *bucket) result(rule Rule, allowed bool, _ time.Time) Result {
+	return tokenBucketResult(rule, allowed, b.tokens)
+}
+
+// slidingWindow 获取滑动窗口并清理窗口外的请求
+func (l *MemoryLimiter) slidingWindow(key string, rule Rule, now time.Time) *window {
+	w, ok := l.windows[key]
+	if !ok {
+		w = &window{}
+		l.windows[key] = w
+	}
+
+	cutoff := now.Add(-rule.Window)
+	i := 0
+	for i < len(w.hits) && !w.hits[i].After(cutoff) {
+		i++
+	}
+	w.hits = w.hits[i:]
+	w.expires = now.Add(rule.Window)
+
+	return w
+}
+
+func (w *window) available(rule Rule) bool {
+	return len(w.hits) < rule.Limit
+}
+
+func (w *window) consume(now time.Time) {
+	w.hits = append(w.hits, now)
+}
+
+func (w *window) result(rule Rule, allowed bool, now time.Time) Result {
+	oldest := now
+	if len(w.hits) > 0 {
+		oldest = w.hits[0]
+	}
+	return slidingWindowResult(rule, allowed, len(w.hits), oldest, now)
+}
+
+// sweep 定期清理已过期的限流状态，调用方需持有锁
+func (l *MemoryLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < memorySweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		if now.After(b.expires) {
+			delete(l.buckets, key)
+		}
+	}
+	for key, w := range l.windows {
+		if now.After(w.expires) {
+			delete(l.windows, key)
+		}
+	}
+}
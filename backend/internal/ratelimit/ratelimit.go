@@ -0,0 +1,216 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"stars-admin/internal/config"
+	"stars-admin/internal/store"
+	"sync/atomic"
+	"time"
+)
+
+// 限流算法
+const (
+	AlgorithmTokenBucket   = "token_bucket"
+	AlgorithmSlidingWindow = "sliding_window"
+)
+
+// 限流维度
+const (
+	ByIP     = "ip"
+	ByUser   = "user"
+	ByAPIKey = "api_key"
+)
+
+// Rule 限流规则
+type Rule struct {
+	Name      string
+	Prefix    string
+	By        string
+	Algorithm string
+	Limit     int
+	Window    time.Duration
+	Burst     int
+}
+
+// Result 限流结果
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	ResetAfter time.Duration // 配额完全恢复的剩余时间
+	RetryAfter time.Duration // 被拒绝时建议的重试间隔
+}
+
+// Check 一次限流检查，Key 为规则与请求标识组成的限流键
+type Check struct {
+	Key  string
+	Rule Rule
+}
+
+// Limiter 限流器
+// 一个请求匹配的所有规则一起判断：全部通过时才各消耗一次配额，任一规则拒绝时不消耗任何配额，
+// 避免被拒绝的请求占用其他规则的额度。返回的结果与 checks 一一对应，Allowed 表示该规则是否还有配额
+type Limiter interface {
+	Allow(ctx context.Context, checks []Check) ([]Result, error)
+}
+
+// RuleSet 可在运行时替换的限流规则
+type RuleSet struct {
+	rules atomic.Pointer[[]Rule]
+}
+
+// NewRuleSet 创建限流规则集
+func NewRuleSet(rules []Rule) *RuleSet {
+	s := &RuleSet{}
+	s.Set(rules)
+	return s
+}
+
+// Rules 返回当前规则
+func (s *RuleSet) Rules() []Rule {
+	return *s.rules.Load()
+}
+
+// Set 替换规则，进行中的请求继续使用旧规则
+func (s *RuleSet) Set(rules []Rule) {
+	s.rules.Store(&rules)
+}
+
+// New 根据存储选择限流器，Redis存储使用Redis限流，其余使用进程内限流
+func New(st store.Store) Limiter {
+	if rdb := store.RedisClient(st); rdb != nil {
+		return NewRedisLimiter(st, rdb)
+	}
+	return NewMemoryLimiter()
+}
+
+// RulesFromConfig 将配置转换为限流规则，未配置规则时按IP使用默认限额
+func RulesFromConfig(cfg config.RateLimitConfig) ([]Rule, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	if len(cfg.Rules) == 0 {
+		if cfg.RequestsPerMinute <= 0 {
+			return nil, nil
+		}
+		return []Rule{{
+			Name:      "default",
+			By:        ByIP,
+			Algorithm: algorithmOrDefault("", cfg.Algorithm),
+			Limit:     cfg.RequestsPerMinute,
+			Window:    time.Minute,
+			Burst:     cfg.RequestsPerMinute,
+		}}, nil
+	}
+
+	rules := make([]Rule, 0, len(cfg.Rules))
+	for i, r := range cfg.Rules {
+		rule := Rule{
+			Name:      r.Name,
+			Prefix:    r.Prefix,
+			By:        r.By,
+			Algorithm: algorithmOrDefault(r.Algorithm, cfg.Algorithm),
+			Limit:     r.Limit,
+			Window:    time.Duration(r.Window) * time.Second,
+			Burst:     r.Burst,
+		}
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule-%d", i+1)
+		}
+		if rule.By == "" {
+			rule.By = ByIP
+		}
+		if rule.Burst <= 0 {
+			rule.Burst = rule.Limit
+		}
+		if err := rule.validate(); err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// validate 校验规则
+func (r Rule) validate() error {
+	if r.Limit <= 0 || r.Window <= 0 {
+		return fmt.Errorf("rate limit rule %s requires positive limit and window", r.Name)
+	}
+	switch r.By {
+	case ByIP, ByUser, ByAPIKey:
+	default:
+		return fmt.Errorf("rate limit rule %s has unknown dimension %s", r.Name, r.By)
+	}
+	switch r.Algorithm {
+	case AlgorithmTokenBucket, AlgorithmSlidingWindow:
+	default:
+		return fmt.Errorf("rate limit rule %s has unknown algorithm %s", r.Name, r.Algorithm)
+	}
+	return nil
+}
+
+// rate 令牌桶每毫秒补充的令牌数
+func (r Rule) rate() float64 {
+	return float64(r.Limit) / float64(r.Window.Milliseconds())
+}
+
+// capacity 令牌桶容量
+func (r Rule) capacity() int {
+	if r.Burst > 0 {
+		return r.Burst
+	}
+	return r.Limit
+}
+
+// algorithmOrDefault 规则未指定算法时使用默认算法
+func algorithmOrDefault(algorithm, def string) string {
+	if algorithm != "" {
+		return algorithm
+	}
+	if def != "" {
+		return def
+	}
+	return AlgorithmSlidingWindow
+}
+
+// tokenBucketResult 根据剩余令牌计算限流结果
+func tokenBucketResult(rule Rule, allowed bool, tokens float64) Result {
+	rate := rule.rate()
+	capacity := rule.capacity()
+
+	result := Result{
+		Allowed:    allowed,
+		Limit:      capacity,
+		Remaining:  int(tokens),
+		ResetAfter: time.Duration((float64(capacity)-tokens)/rate) * time.Millisecond,
+	}
+	if !allowed {
+		result.RetryAfter = time.Duration((1-tokens)/rate) * time.Millisecond
+	}
+	return result
+}
+
+// slidingWindowResult 根据窗口内请求数计算限流结果
+func slidingWindowResult(rule Rule, allowed bool, count int, oldest time.Time, now time.Time) Result {
+	reset := oldest.Add(rule.Window).Sub(now)
+	if reset < 0 {
+		reset = 0
+	}
+
+	result := Result{
+		Allowed:    allowed,
+		Limit:      rule.Limit,
+		Remaining:  rule.Limit - count,
+		ResetAfter: reset,
+	}
+	if result.Remaining < 0 {
+		result.Remaining = 0
+	}
+	if !allowed {
+		result.RetryAfter = reset
+	}
+	return result
+}
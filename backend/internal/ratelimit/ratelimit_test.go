@@ -0,0 +1,194 @@
+package ratelimit
+
+import (
+	"context"
+	"stars-admin/internal/config"
+	"stars-admin/internal/store"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+func TestRulesFromConfig(t *testing.T) {
+	if rules, err := RulesFromConfig(config.RateLimitConfig{RequestsPerMinute: 60}); err != nil || rules != nil {
+		t.Errorf("disabled: got %v, %v", rules, err)
+	}
+	if rules, err := RulesFromConfig(config.RateLimitConfig{Enabled: true}); err != nil || rules != nil {
+		t.Errorf("no rules and no default limit: got %v, %v", rules, err)
+	}
+
+	rules, err := RulesFromConfig(config.RateLimitConfig{Enabled: true, RequestsPerMinute: 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Rule{Name: "default", By: ByIP, Algorithm: AlgorithmSlidingWindow, Limit: 60, Window: time.Minute, Burst: 60}
+	if len(rules) != 1 || rules[0] != want {
+		t.Errorf("default rule: got %+v", rules)
+	}
+
+	rules, err = RulesFromConfig(config.RateLimitConfig{
+		Enabled:   true,
+		Algorithm: AlgorithmTokenBucket,
+		Rules: []config.RateLimitRule{
+			{Prefix: "/api/v1/auth", Limit: 5, Window: 60},
+			{Name: "users", By: ByUser, Algorithm: AlgorithmSlidingWindow, Limit: 100, Window: 1, Burst: 20},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 未填写的名称、维度、算法和容量使用默认值
+	want = Rule{Name: "rule-1", Prefix: "/api/v1/auth", By: ByIP, Algorithm: AlgorithmTokenBucket, Limit: 5, Window: time.Minute, Burst: 5}
+	if len(rules) != 2 || rules[0] != want {
+		t.Errorf("got %+v, want %+v", rules[0], want)
+	}
+	if rules[1].Algorithm != AlgorithmSlidingWindow || rules[1].Burst != 20 || rules[1].Window != time.Second {
+		t.Errorf("got %+v", rules[1])
+	}
+
+	tests := []struct {
+		name string
+		rule config.RateLimitRule
+		want string
+	}{
+		{"zero limit", config.RateLimitRule{Window: 60}, "positive limit"},
+		{"zero window", config.RateLimitRule{Limit: 5}, "positive limit"},
+		{"unknown dimension", config.RateLimitRule{By: "session", Limit: 5, Window: 60}, "unknown dimension"},
+		{"unknown algorithm", config.RateLimitRule{Algorithm: "leaky_bucket", Limit: 5, Window: 60}, "unknown algorithm"},
+	}
+	for _, tt := range tests {
+		cfg := config.RateLimitConfig{Enabled: true, Rules: []config.RateLimitRule{tt.rule}}
+		if _, err := RulesFromConfig(cfg); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: got %v, want error containing %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	mem := store.NewMemoryStore()
+	defer mem.Close()
+	if _, ok := New(mem).(*MemoryLimiter); !ok {
+		t.Error("memory store should use the in-process limiter")
+	}
+
+	m := miniredis.RunT(t)
+	rs := store.NewRedisStore(redis.NewClient(&redis.Options{Addr: m.Addr()}))
+	defer rs.Close()
+	if _, ok := New(store.NewResilient(rs, store.ResilientOptions{})).(*RedisLimiter); !ok {
+		t.Error("redis store should use the redis limiter")
+	}
+}
+
+// limiters 进程内限流器和 Redis 限流器（miniredis）运行相同的用例
+var limiters = []struct {
+	name string
+	open func(t *testing.T) Limiter
+}{
+	{"memory", func(t *testing.T) Limiter { return NewMemoryLimiter() }},
+	{"redis", func(t *testing.T) Limiter {
+		m := miniredis.RunT(t)
+		rs := store.NewRedisStore(redis.NewClient(&redis.Options{Addr: m.Addr()}))
+		t.Cleanup(func() { rs.Close() })
+		return New(rs)
+	}},
+}
+
+// eachLimiter 对每种限流器运行用例
+func eachLimiter(t *testing.T, fn func(t *testing.T, l Limiter)) {
+	for _, tt := range limiters {
+		t.Run(tt.name, func(t *testing.T) { fn(t, tt.open(t)) })
+	}
+}
+
+// allow 对单条规则执行一次检查
+func allow(t *testing.T, l Limiter, key string, rule Rule) Result {
+	t.Helper()
+	results, err := l.Allow(context.Background(), []Check{{Key: key, Rule: rule}})
+	if err != nil {
+		t.Fatalf("Allow: %v", err)
+	}
+	return results[0]
+}
+
+func TestSlidingWindow(t *testing.T) {
+	rule := Rule{Name: "sw", Algorithm: AlgorithmSlidingWindow, Limit: 3, Window: 200 * time.Millisecond}
+	eachLimiter(t, func(t *testing.T, l Limiter) {
+		for i := 1; i <= 3; i++ {
+			r := allow(t, l, "k", rule)
+			if !r.Allowed || r.Limit != 3 || r.Remaining != 3-i || r.ResetAfter <= 0 || r.ResetAfter > rule.Window {
+				t.Errorf("request %d: got %+v", i, r)
+			}
+		}
+		r := allow(t, l, "k", rule)
+		if r.Allowed || r.Remaining != 0 || r.RetryAfter <= 0 || r.RetryAfter > rule.Window {
+			t.Errorf("over limit: got %+v", r)
+		}
+		// 不同的键互不影响
+		if r := allow(t, l, "other", rule); !r.Allowed {
+			t.Errorf("other key: got %+v", r)
+		}
+
+		// 被拒绝的请求不计入窗口，最早的请求移出窗口后恢复
+		time.Sleep(rule.Window + 20*time.Millisecond)
+		if r := allow(t, l, "k", rule); !r.Allowed || r.Remaining != 2 {
+			t.Errorf("after window: got %+v", r)
+		}
+	})
+}
+
+func TestTokenBucket(t *testing.T) {
+	// 每 100ms 补充一个令牌，容量 2
+	rule := Rule{Name: "tb", Algorithm: AlgorithmTokenBucket, Limit: 2, Window: 200 * time.Millisecond, Burst: 2}
+	eachLimiter(t, func(t *testing.T, l Limiter) {
+		for i := 1; i <= 2; i++ {
+			if r := allow(t, l, "k", rule); !r.Allowed || r.Limit != 2 || r.Remaining != 2-i {
+				t.Errorf("request %d: got %+v", i, r)
+			}
+		}
+		r := allow(t, l, "k", rule)
+		if r.Allowed || r.Remaining != 0 || r.RetryAfter <= 0 || r.RetryAfter > 100*time.Millisecond {
+			t.Errorf("empty bucket: got %+v", r)
+		}
+
+		time.Sleep(120 * time.Millisecond)
+		if r := allow(t, l, "k", rule); !r.Allowed {
+			t.Errorf("after refill: got %+v", r)
+		}
+		if r := allow(t, l, "k", rule); r.Allowed {
+			t.Errorf("only one token should be refilled, got %+v", r)
+		}
+	})
+}
+
+func TestMultipleRules(t *testing.T) {
+	strict := Check{Key: "strict", Rule: Rule{Name: "strict", Algorithm: AlgorithmSlidingWindow, Limit: 1, Window: time.Minute}}
+	loose := Check{Key: "loose", Rule: Rule{Name: "loose", Algorithm: AlgorithmTokenBucket, Limit: 5, Window: time.Minute, Burst: 5}}
+	ctx := context.Background()
+
+	eachLimiter(t, func(t *testing.T, l Limiter) {
+		results, err := l.Allow(ctx, []Check{strict, loose})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !results[0].Allowed || !results[1].Allowed || results[0].Remaining != 0 || results[1].Remaining != 4 {
+			t.Errorf("first request: got %+v", results)
+		}
+
+		// 严格规则拒绝时不消耗宽松规则的配额
+		for i := 0; i < 3; i++ {
+			results, err = l.Allow(ctx, []Check{strict, loose})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if results[0].Allowed || !results[1].Allowed || results[1].Remaining != 4 {
+				t.Errorf("denied request: got %+v", results)
+			}
+		}
+		if r := allow(t, l, loose.Key, loose.Rule); !r.Allowed || r.Remaining != 3 {
+			t.Errorf("loose rule alone: got %+v", r)
+		}
+	})
+}
@@ -0,0 +1,152 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"stars-admin/internal/store"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// limitScript 在一次调用中判断请求匹配的所有规则，全部通过时才消耗配额
+// 每条规则占用 4 个参数：算法、令牌补充速率或窗口长度、桶容量或窗口限额、过期时间或请求成员
+// 令牌桶按时间补充令牌，滑动窗口日志清理窗口外的请求，这两步不消耗配额，无论是否放行都会写回
+// 每条规则返回 {是否有配额, 剩余令牌或窗口内请求数, 窗口内最早请求时间}
+var limitScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local states = {}
+local allowed = true
+for i, key in ipairs(KEYS) do
+	local base = 1 + (i - 1) * 4
+	local state = {algorithm = ARGV[base + 1]}
+	if state.algorithm == "token_bucket" then
+		local rate = tonumber(ARGV[base + 2])
+		local capacity = tonumber(ARGV[base + 3])
+		local data = redis.call("HMGET", key, "tokens", "ts")
+		local tokens = tonumber(data[1]) or capacity
+		local ts = tonumber(data[2]) or now
+		state.tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate)
+		state.ttl = tonumber(ARGV[base + 4])
+		state.ok = state.tokens >= 1
+	else
+		state.window = tonumber(ARGV[base + 2])
+		state.member = ARGV[base + 4]
+		redis.call("ZREMRANGEBYSCORE", key, 0, now - state.window)
+		state.count = redis.call("ZCARD", key)
+		state.ok = state.count < tonumber(ARGV[base + 3])
+	end
+	allowed = allowed and state.ok
+	states[i] = state
+end
+
+local reply = {}
+for i, key in ipairs(KEYS) do
+	local state = states[i]
+	local ok = 0
+	if state.ok then
+		ok = 1
+	end
+	if state.algorithm == "token_bucket" then
+		if allowed then
+			state.tokens = state.tokens - 1
+		end
+		redis.call("HSET", key, "tokens", tostring(state.tokens), "ts", now)
+		redis.call("PEXPIRE", key, state.ttl)
+		table.insert(reply, {ok, tostring(state.tokens), now})
+	else
+		if allowed then
+			redis.call("ZADD", key, now, state.member)
+			state.count = state.count + 1
+		end
+		redis.call("PEXPIRE", key, state.window)
+		local oldest = redis.call("ZRANGE", key, 0, 0, "WITHSCORES")
+		local oldestScore = now
+		if oldest[2] then
+			oldestScore = tonumber(oldest[2])
+		end
+		table.insert(reply, {ok, state.count, oldestScore})
+	end
+end
+return reply
+`)
+
+// RedisLimiter 基于Redis的分布式限流器，调用受存储的超时和熔断保护
+type RedisLimiter struct {
+	st  store.Store
+	rdb *redis.Client
+	seq uint64
+}
+
+// NewRedisLimiter 创建Redis限流器
+func NewRedisLimiter(st store.Store, rdb *redis.Client) *RedisLimiter {
+	return &RedisLimiter{st: st, rdb: rdb}
+}
+
+// Allow 判断请求是否允许通过，所有规则由一次脚本调用原子地判断和消耗
+func (l *RedisLimiter) Allow(ctx context.Context, checks []Check) (results []Result, err error) {
+	if len(checks) == 0 {
+		return nil, nil
+	}
+
+	now := time.Now()
+	keys := make([]string, len(checks))
+	args := make([]interface{}, 0, 1+len(checks)*4)
+	args = append(args, now.UnixMilli())
+	for i, check := range checks {
+		keys[i] = "ratelimit:" + check.Key
+		rule := check.Rule
+		switch rule.Algorithm {
+		case AlgorithmTokenBucket:
+			ttl := time.Duration(float64(rule.capacity())/rule.rate())*time.Millisecond + time.Second
+			args = append(args, rule.Algorithm, rule.rate(), rule.capacity(), ttl.Milliseconds())
+		default:
+			member := fmt.Sprintf("%d-%d", now.UnixNano(), atomic.AddUint64(&l.seq, 1))
+			args = append(args, AlgorithmSlidingWindow, rule.Window.Milliseconds(), rule.Limit, member)
+		}
+	}
+
+	err = store.Guard(ctx, l.st, func(ctx context.Context) error {
+		reply, err := limitScript.Run(ctx, l.rdb, keys, args...).Slice()
+		if err != nil {
+			return err
+		}
+		results, err = parseReply(checks, reply, now)
+		return err
+	})
+
+	return results, err
+}
+
+// parseReply 解析脚本返回的每条规则的状态
+func parseReply(checks []Check, reply []interface{}, now time.Time) ([]Result, error) {
+	if len(reply) != len(checks) {
+		return nil, fmt.Errorf("unexpected rate limit reply")
+	}
+
+	results := make([]Result, len(checks))
+	for i, check := range checks {
+		values, ok := reply[i].([]interface{})
+		if !ok || len(values) != 3 {
+			return nil, fmt.Errorf("unexpected rate limit reply")
+		}
+		allowed, _ := values[0].(int64)
+
+		switch check.Rule.Algorithm {
+		case AlgorithmTokenBucket:
+			tokensStr, _ := values[1].(string)
+			tokens, err := strconv.ParseFloat(tokensStr, 64)
+			if err != nil {
+				return nil, err
+			}
+			results[i] = tokenBucketResult(check.Rule, allowed == 1, tokens)
+		default:
+			count, _ := values[1].(int64)
+			oldest, _ := values[2].(int64)
+			results[i] = slidingWindowResult(check.Rule, allowed == 1, int(count), time.UnixMilli(oldest), now)
+		}
+	}
+	return results, nil
+}
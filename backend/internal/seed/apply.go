@@ -0,0 +1,326 @@
+package seed
+
+import (
+	"errors"
+	"os"
+	"stars-admin/internal/models"
+	"stars-admin/internal/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// generatedPasswordLength 自动生成的初始密码长度
+const generatedPasswordLength = 16
+
+// Credential 新建用户的初始凭据
+type Credential struct {
+	Username           string
+	Password           string
+	Generated          bool
+	MustChangePassword bool // 首次登录时需要修改密码
+}
+
+// Apply 幂等地写入种子数据，已存在的记录保持不变
+func Apply(db *gorm.DB, data *Data) ([]Credential, error) {
+	var credentials []Credential
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if credentials, err = applyUsers(tx, data.Users); err != nil {
+			return err
+		}
+		if err := applyRoles(tx, data.Roles); err != nil {
+			return err
+		}
+		if err := applyMenus(tx, data.Menus); err != nil {
+			return err
+		}
+		if err := applyPermissions(tx, data.Permissions); err != nil {
+			return err
+		}
+		return applyAssignments(tx, data.Assignments)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return credentials, nil
+}
+
+// applyUsers 创建缺失的用户
+func applyUsers(tx *gorm.DB, users []UserSeed) ([]Credential, error) {
+	var credentials []Credential
+
+	for _, u := range users {
+		var count int64
+		if err := tx.Model(&models.User{}).Where("username = ?", u.Username).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			continue
+		}
+
+		credential, err := initialPassword(u)
+		if err != nil {
+			return nil, err
+		}
+
+		hashedPassword, err := utils.HashPassword(credential.Password)
+		if err != nil {
+			return nil, err
+		}
+
+		now := time.Now()
+		user := models.User{
+			Username:           u.Username,
+			Password:           hashedPassword,
+			Email:              u.Email,
+			Phone:              u.Phone,
+			Nickname:           u.Nickname,
+			Status:             statusOrDefault(u.Status),
+			MustChangePassword: u.MustChangePassword,
+			PasswordChangedAt:  &now,
+			CreatedAt:          now,
+			UpdatedAt:          now,
+		}
+		if err := tx.Create(&user).Error; err != nil {
+			return nil, err
+		}
+
+		// 初始密码计入密码历史，修改时不能改回初始密码
+		if err := tx.Create(&models.PasswordHistory{UserID: user.ID, Password: hashedPassword}).Error; err != nil {
+			return nil, err
+		}
+
+		credential.MustChangePassword = u.MustChangePassword
+		credentials = append(credentials, credential)
+	}
+
+	return credentials, nil
+}
+
+// initialPassword 从环境变量读取初始密码，未设置时生成随机密码
+func initialPassword(u UserSeed) (Credential, error) {
+	if u.PasswordEnv != "" {
+		if password := os.Getenv(u.PasswordEnv); password != "" {
+			return Credential{Username: u.Username, Password: password}, nil
+		}
+	}
+
+	password, err := utils.GenerateRandomPassword(generatedPasswordLength)
+	if err != nil {
+		return Credential{}, err
+	}
+
+	return Credential{Username: u.Username, Password: password, Generated: true}, nil
+}
+
+// applyRoles 创建缺失的角色
+func applyRoles(tx *gorm.DB, roles []RoleSeed) error {
+	for _, r := range roles {
+		var count int64
+		if err := tx.Model(&models.Role{}).Where("code = ?", r.Code).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+
+		role := models.Role{
+			Name:        r.Name,
+			Code:        r.Code,
+			Description: r.Description,
+			Status:      statusOrDefault(r.Status),
+			CreatedAt:   time.Now(),
+			UpdatedAt:   time.Now(),
+		}
+		if err := tx.Create(&role).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// applyMenus 创建缺失的菜单，并为本次创建的菜单设置父菜单，已存在菜单的父子关系保持不变
+func applyMenus(tx *gorm.DB, menus []MenuSeed) error {
+	created := make(map[string]uint)
+	for _, m := range menus {
+		var count int64
+		if err := tx.Model(&models.Menu{}).Where("path = ?", m.Path).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+
+		menuType := m.Type
+		if menuType == 0 {
+			menuType = 1
+		}
+
+		menu := models.Menu{
+			Name:      m.Name,
+			Path:      m.Path,
+			Component: m.Component,
+			Icon:      m.Icon,
+			Sort:      m.Sort,
+			Type:      menuType,
+			Status:    statusOrDefault(m.Status),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		}
+		if err := tx.Create(&menu).Error; err != nil {
+			return err
+		}
+		created[m.Path] = menu.ID
+	}
+
+	// 父菜单可能在子菜单之后定义，全部创建后再设置父子关系
+	for _, m := range menus {
+		id, ok := created[m.Path]
+		if !ok || m.Parent == "" {
+			continue
+		}
+
+		var parent models.Menu
+		if err := tx.Where("path = ?", m.Parent).First(&parent).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Menu{}).Where("id = ?", id).Update("parent_id", parent.ID).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// applyPermissions 创建缺失的权限
+func applyPermissions(tx *gorm.DB, permissions []PermissionSeed) error {
+	for _, p := range permissions {
+		var count int64
+		if err := tx.Model(&models.Permission{}).Where("code = ?", p.Code).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+
+		permission := models.Permission{
+			Name:        p.Name,
+			Code:        p.Code,
+			Description: p.Description,
+			CreatedAt:   time.Now(),
+			UpdatedAt:   time.Now(),
+		}
+		if err := tx.Create(&permission).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// applyAssignments 补齐用户角色和角色菜单关联
+func applyAssignments(tx *gorm.DB, assignments Assignments) error {
+	for _, ur := range assignments.UserRoles {
+		var user models.User
+		if err := tx.Where("username = ?", ur.User).First(&user).Error; err != nil {
+			return err
+		}
+
+		for _, code := range ur.Roles {
+			var role models.Role
+			if err := tx.Where("code = ?", code).First(&role).Error; err != nil {
+				return err
+			}
+
+			if err := ensureLink(tx, &models.UserRole{}, "user_id = ? AND role_id = ?", &models.UserRole{
+				UserID:    user.ID,
+				RoleID:    role.ID,
+				CreatedAt: time.Now(),
+			}, user.ID, role.ID); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, rm := range assignments.RoleMenus {
+		var role models.Role
+		if err := tx.Where("code = ?", rm.Role).First(&role).Error; err != nil {
+			return err
+		}
+
+		menus, err := resolveMenus(tx, rm.Menus)
+		if err != nil {
+			return err
+		}
+
+		for _, menu := range menus {
+			if err := ensureLink(tx, &models.RoleMenu{}, "role_id = ? AND menu_id = ?", &models.RoleMenu{
+				RoleID:    role.ID,
+				MenuID:    menu.ID,
+				CreatedAt: time.Now(),
+			}, role.ID, menu.ID); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// resolveMenus 按路径查找菜单，"*" 表示全部菜单，重复的路径只查找一次
+func resolveMenus(tx *gorm.DB, paths []string) ([]models.Menu, error) {
+	var menus []models.Menu
+	for _, path := range paths {
+		if path == "*" {
+			if err := tx.Find(&menus).Error; err != nil {
+				return nil, err
+			}
+			return menus, nil
+		}
+	}
+
+	unique := make([]string, 0, len(paths))
+	seen := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		if !seen[path] {
+			seen[path] = true
+			unique = append(unique, path)
+		}
+	}
+
+	if len(unique) == 0 {
+		return nil, nil
+	}
+	if err := tx.Where("path IN ?", unique).Find(&menus).Error; err != nil {
+		return nil, err
+	}
+	if len(menus) != len(unique) {
+		return nil, errors.New("seed assignment references missing menus")
+	}
+
+	return menus, nil
+}
+
+// ensureLink 关联记录不存在时创建
+func ensureLink(tx *gorm.DB, model interface{}, query string, record interface{}, args ...interface{}) error {
+	var count int64
+	if err := tx.Model(model).Where(query, args...).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	return tx.Create(record).Error
+}
+
+// statusOrDefault 未指定状态时默认为正常
+func statusOrDefault(status *int) int {
+	if status == nil {
+		return 1
+	}
+	return *status
+}
@@ -0,0 +1,248 @@
+package seed
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// BaseEnv 所有环境共用的种子目录
+const BaseEnv = "base"
+
+// Data 种子数据定义
+type Data struct {
+	Users       []UserSeed       `yaml:"users" json:"users"`
+	Roles       []RoleSeed       `yaml:"roles" json:"roles"`
+	Menus       []MenuSeed       `yaml:"menus" json:"menus"`
+	Permissions []PermissionSeed `yaml:"permissions" json:"permissions"`
+	Assignments Assignments      `yaml:"assignments" json:"assignments"`
+}
+
+// UserSeed 用户种子
+type UserSeed struct {
+	Username string `yaml:"username" json:"username"`
+	Email    string `yaml:"email" json:"email"`
+	Phone    string `yaml:"phone" json:"phone"`
+	Nickname string `yaml:"nickname" json:"nickname"`
+	Status   *int   `yaml:"status" json:"status"`
+	// PasswordEnv 初始密码所在的环境变量，为空或未设置时自动生成随机密码
+	PasswordEnv        string `yaml:"password_env" json:"password_env"`
+	MustChangePassword bool   `yaml:"must_change_password" json:"must_change_password"`
+}
+
+// RoleSeed 角色种子
+type RoleSeed struct {
+	Code        string `yaml:"code" json:"code"`
+	Name        string `yaml:"name" json:"name"`
+	Description string `yaml:"description" json:"description"`
+	Status      *int   `yaml:"status" json:"status"`
+}
+
+// MenuSeed 菜单种子，Parent 为父菜单的路径
+type MenuSeed struct {
+	Path      string `yaml:"path" json:"path"`
+	Parent    string `yaml:"parent" json:"parent"`
+	Name      string `yaml:"name" json:"name"`
+	Component string `yaml:"component" json:"component"`
+	Icon      string `yaml:"icon" json:"icon"`
+	Sort      int    `yaml:"sort" json:"sort"`
+	Type      int    `yaml:"type" json:"type"`
+	Status    *int   `yaml:"status" json:"status"`
+}
+
+// PermissionSeed 权限种子
+type PermissionSeed struct {
+	Code        string `yaml:"code" json:"code"`
+	Name        string `yaml:"name" json:"name"`
+	Description string `yaml:"description" json:"description"`
+}
+
+// Assignments 关联关系种子
+type Assignments struct {
+	UserRoles []UserRoleSeed `yaml:"user_roles" json:"user_roles"`
+	RoleMenus []RoleMenuSeed `yaml:"role_menus" json:"role_menus"`
+}
+
+// UserRoleSeed 用户角色分配，按用户名和角色编码关联
+type UserRoleSeed struct {
+	User  string   `yaml:"user" json:"user"`
+	Roles []string `yaml:"roles" json:"roles"`
+}
+
+// RoleMenuSeed 角色菜单分配，菜单路径为 "*" 时表示全部菜单
+type RoleMenuSeed struct {
+	Role  string   `yaml:"role" json:"role"`
+	Menus []string `yaml:"menus" json:"menus"`
+}
+
+// Load 加载种子数据，先读取 base 目录，再叠加指定环境目录
+func Load(dir, env string) (*Data, error) {
+	data := &Data{}
+
+	envs := []string{BaseEnv}
+	if env != "" && env != BaseEnv {
+		envs = append(envs, env)
+	}
+
+	for _, e := range envs {
+		files, err := seedFiles(filepath.Join(dir, e))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, file := range files {
+			part, err := loadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			data.merge(part)
+		}
+	}
+
+	if err := data.validate(); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// seedFiles 列出目录下的种子文件，目录不存在时返回空列表
+func seedFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read seed directory %s: %w", dir, err)
+	}
+
+	var files []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".yaml", ".yml", ".json":
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	sort.Strings(files)
+
+	return files, nil
+}
+
+// loadFile 按扩展名解析单个种子文件
+func loadFile(file string) (*Data, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read seed file %s: %w", file, err)
+	}
+
+	var data Data
+	if strings.ToLower(filepath.Ext(file)) == ".json" {
+		err = json.Unmarshal(content, &data)
+	} else {
+		err = yaml.Unmarshal(content, &data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse seed file %s: %w", file, err)
+	}
+
+	return &data, nil
+}
+
+// merge 合并种子数据，相同标识的定义以后加载的为准
+func (d *Data) merge(other *Data) {
+	for _, u := range other.Users {
+		d.Users = upsert(d.Users, u, func(x UserSeed) bool { return x.Username == u.Username })
+	}
+	for _, r := range other.Roles {
+		d.Roles = upsert(d.Roles, r, func(x RoleSeed) bool { return x.Code == r.Code })
+	}
+	for _, m := range other.Menus {
+		d.Menus = upsert(d.Menus, m, func(x MenuSeed) bool { return x.Path == m.Path })
+	}
+	for _, p := range other.Permissions {
+		d.Permissions = upsert(d.Permissions, p, func(x PermissionSeed) bool { return x.Code == p.Code })
+	}
+	d.Assignments.UserRoles = append(d.Assignments.UserRoles, other.Assignments.UserRoles...)
+	d.Assignments.RoleMenus = append(d.Assignments.RoleMenus, other.Assignments.RoleMenus...)
+}
+
+// upsert 替换匹配的元素，不存在时追加
+func upsert[T any](list []T, item T, match func(T) bool) []T {
+	for i := range list {
+		if match(list[i]) {
+			list[i] = item
+			return list
+		}
+	}
+	return append(list, item)
+}
+
+// validate 校验种子数据的必填字段和引用关系
+func (d *Data) validate() error {
+	users := make(map[string]bool)
+	for _, u := range d.Users {
+		if u.Username == "" {
+			return fmt.Errorf("seed user without username")
+		}
+		users[u.Username] = true
+	}
+
+	roles := make(map[string]bool)
+	for _, r := range d.Roles {
+		if r.Code == "" || r.Name == "" {
+			return fmt.Errorf("seed role requires code and name")
+		}
+		roles[r.Code] = true
+	}
+
+	menus := make(map[string]bool)
+	for _, m := range d.Menus {
+		if m.Path == "" || m.Name == "" {
+			return fmt.Errorf("seed menu requires path and name")
+		}
+		menus[m.Path] = true
+	}
+	for _, m := range d.Menus {
+		if m.Parent != "" && !menus[m.Parent] {
+			return fmt.Errorf("seed menu %s references unknown parent %s", m.Path, m.Parent)
+		}
+	}
+
+	for _, p := range d.Permissions {
+		if p.Code == "" || p.Name == "" {
+			return fmt.Errorf("seed permission requires code and name")
+		}
+	}
+
+	for _, ur := range d.Assignments.UserRoles {
+		if !users[ur.User] {
+			return fmt.Errorf("seed assignment references unknown user %s", ur.User)
+		}
+		for _, code := range ur.Roles {
+			if !roles[code] {
+				return fmt.Errorf("seed assignment references unknown role %s", code)
+			}
+		}
+	}
+
+	for _, rm := range d.Assignments.RoleMenus {
+		if !roles[rm.Role] {
+			return fmt.Errorf("seed assignment references unknown role %s", rm.Role)
+		}
+		for _, path := range rm.Menus {
+			if path != "*" && !menus[path] {
+				return fmt.Errorf("seed assignment references unknown menu %s", path)
+			}
+		}
+	}
+
+	return nil
+}
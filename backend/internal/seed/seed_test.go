@@ -0,0 +1,257 @@
+package seed
+
+import (
+	"os"
+	"path/filepath"
+	"stars-admin/internal/database/databasetest"
+	"stars-admin/internal/models"
+	"stars-admin/internal/utils"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// writeSeeds 在 dir 下按“环境/文件名”写入种子文件
+func writeSeeds(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	writeSeeds(t, dir, map[string]string{
+		"base/1-rbac.yaml": `
+roles:
+  - {code: admin, name: Admin}
+  - {code: user, name: User}
+menus:
+  - {path: /system, name: System}
+  - {path: /system/users, parent: /system, name: Users}
+assignments:
+  role_menus:
+    - {role: admin, menus: ["*"]}
+`,
+		"base/2-users.yml": `
+users:
+  - {username: admin, password_env: SEED_TEST_ADMIN}
+assignments:
+  user_roles:
+    - {user: admin, roles: [admin]}
+`,
+		"base/notes.txt": "ignored",
+		"dev/users.json": `{
+  "roles": [{"code": "user", "name": "Member"}],
+  "users": [{"username": "dev", "email": "dev@example.com"}],
+  "assignments": {"user_roles": [{"user": "dev", "roles": ["user"]}]}
+}`,
+	})
+
+	base, err := Load(dir, BaseEnv)
+	if err != nil {
+		t.Fatalf("Load base: %v", err)
+	}
+	if len(base.Users) != 1 || len(base.Roles) != 2 || len(base.Menus) != 2 || len(base.Assignments.UserRoles) != 1 {
+		t.Errorf("unexpected base seeds %+v", base)
+	}
+
+	// 环境目录中的同名定义覆盖 base，关联关系追加
+	dev, err := Load(dir, "dev")
+	if err != nil {
+		t.Fatalf("Load dev: %v", err)
+	}
+	if len(dev.Roles) != 2 || dev.Roles[1].Name != "Member" {
+		t.Errorf("role should be overridden by the environment, got %+v", dev.Roles)
+	}
+	if len(dev.Users) != 2 || len(dev.Assignments.UserRoles) != 2 || len(dev.Assignments.RoleMenus) != 1 {
+		t.Errorf("unexpected merged seeds %+v", dev)
+	}
+
+	// 环境目录不存在时只使用 base
+	if data, err := Load(dir, "missing"); err != nil || len(data.Users) != 1 {
+		t.Errorf("missing environment: got %+v, %v", data, err)
+	}
+
+	writeSeeds(t, dir, map[string]string{"broken/bad.yaml": "roles: ["})
+	if _, err := Load(dir, "broken"); err == nil || !strings.Contains(err.Error(), "bad.yaml") {
+		t.Errorf("invalid file: got %v", err)
+	}
+}
+
+func TestLoadRepositorySeeds(t *testing.T) {
+	for _, env := range []string{"development", "production"} {
+		if _, err := Load("../../seeds", env); err != nil {
+			t.Errorf("seeds for %s: %v", env, err)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := func() *Data {
+		return &Data{
+			Users: []UserSeed{{Username: "admin"}},
+			Roles: []RoleSeed{{Code: "admin", Name: "Admin"}},
+			Menus: []MenuSeed{{Path: "/system", Name: "System"}},
+			Assignments: Assignments{
+				UserRoles: []UserRoleSeed{{User: "admin", Roles: []string{"admin"}}},
+				RoleMenus: []RoleMenuSeed{{Role: "admin", Menus: []string{"/system", "*"}}},
+			},
+		}
+	}
+	if err := valid().validate(); err != nil {
+		t.Fatalf("valid seeds rejected: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*Data)
+		want   string
+	}{
+		{"user without username", func(d *Data) { d.Users = append(d.Users, UserSeed{}) }, "without username"},
+		{"role without name", func(d *Data) { d.Roles = append(d.Roles, RoleSeed{Code: "x"}) }, "role requires"},
+		{"menu without path", func(d *Data) { d.Menus = append(d.Menus, MenuSeed{Name: "x"}) }, "menu requires"},
+		{"unknown parent", func(d *Data) { d.Menus = append(d.Menus, MenuSeed{Path: "/x", Name: "x", Parent: "/missing"}) }, "unknown parent"},
+		{"permission without name", func(d *Data) { d.Permissions = append(d.Permissions, PermissionSeed{Code: "x"}) }, "permission requires"},
+		{"unknown user", func(d *Data) { d.Assignments.UserRoles[0].User = "missing" }, "unknown user"},
+		{"unknown role", func(d *Data) { d.Assignments.UserRoles[0].Roles = []string{"missing"} }, "unknown role"},
+		{"unknown menu", func(d *Data) { d.Assignments.RoleMenus[0].Menus = []string{"/missing"} }, "unknown menu"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := valid()
+			tt.modify(d)
+			if err := d.validate(); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got %v, want error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func testSeeds() *Data {
+	return &Data{
+		Users: []UserSeed{
+			{Username: "admin", Email: "admin@example.com", PasswordEnv: "SEED_TEST_ADMIN", MustChangePassword: true},
+			{Username: "dev"},
+		},
+		Roles: []RoleSeed{{Code: "admin", Name: "Admin"}, {Code: "user", Name: "User"}},
+		// 子菜单在父菜单之前定义
+		Menus: []MenuSeed{
+			{Path: "/system/users", Parent: "/system", Name: "Users"},
+			{Path: "/system", Name: "System"},
+		},
+		Permissions: []PermissionSeed{{Code: "system:user", Name: "Users"}},
+		Assignments: Assignments{
+			UserRoles: []UserRoleSeed{{User: "admin", Roles: []string{"admin", "user"}}},
+			RoleMenus: []RoleMenuSeed{
+				{Role: "admin", Menus: []string{"*"}},
+				{Role: "user", Menus: []string{"/system/users", "/system/users"}},
+			},
+		},
+	}
+}
+
+func count(t *testing.T, db *gorm.DB, model interface{}) int64 {
+	t.Helper()
+	var n int64
+	if err := db.Model(model).Count(&n).Error; err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func menu(t *testing.T, db *gorm.DB, path string) models.Menu {
+	t.Helper()
+	var m models.Menu
+	if err := db.Where("path = ?", path).First(&m).Error; err != nil {
+		t.Fatalf("menu %s: %v", path, err)
+	}
+	return m
+}
+
+func TestApply(t *testing.T) {
+	db := databasetest.Open(t)
+	t.Setenv("SEED_TEST_ADMIN", "Admin123456")
+
+	credentials, err := Apply(db, testSeeds())
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if len(credentials) != 2 {
+		t.Fatalf("got credentials %+v", credentials)
+	}
+	admin, dev := credentials[0], credentials[1]
+	if admin.Password != "Admin123456" || admin.Generated || !admin.MustChangePassword {
+		t.Errorf("admin password should come from the environment, got %+v", admin)
+	}
+	if !dev.Generated || len(dev.Password) != generatedPasswordLength || dev.MustChangePassword {
+		t.Errorf("dev password should be generated, got %+v", dev)
+	}
+
+	var user models.User
+	if err := db.Where("username = ?", "admin").First(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !utils.CheckPassword(user.Password, "Admin123456") || !user.MustChangePassword || user.Status != 1 {
+		t.Errorf("unexpected admin user %+v", user)
+	}
+	if n := count(t, db, &models.PasswordHistory{}); n != 2 {
+		t.Errorf("initial passwords should be in the history, got %d", n)
+	}
+	if system, users := menu(t, db, "/system"), menu(t, db, "/system/users"); users.ParentID != system.ID || users.Type != 1 {
+		t.Errorf("unexpected menus %+v %+v", system, users)
+	}
+	// admin 关联全部菜单，user 的重复路径只关联一次
+	if n := count(t, db, &models.RoleMenu{}); n != 3 {
+		t.Errorf("got %d role menus, want 3", n)
+	}
+	if n := count(t, db, &models.UserRole{}); n != 2 {
+		t.Errorf("got %d user roles, want 2", n)
+	}
+
+	// 管理员修改的数据在再次执行时保持不变
+	system := menu(t, db, "/system")
+	if err := db.Model(&models.Menu{}).Where("path = ?", "/system/users").Updates(map[string]interface{}{"parent_id": 0, "name": "Accounts"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Model(&models.Role{}).Where("code = ?", "user").Update("name", "Member").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	data := testSeeds()
+	data.Menus = append(data.Menus, MenuSeed{Path: "/system/roles", Parent: "/system", Name: "Roles"})
+	credentials, err = Apply(db, data)
+	if err != nil {
+		t.Fatalf("second Apply: %v", err)
+	}
+	if len(credentials) != 0 {
+		t.Errorf("existing users should not get new credentials, got %+v", credentials)
+	}
+	if users := menu(t, db, "/system/users"); users.ParentID != 0 || users.Name != "Accounts" {
+		t.Errorf("existing menu should be left unchanged, got %+v", users)
+	}
+	if roles := menu(t, db, "/system/roles"); roles.ParentID != system.ID {
+		t.Errorf("new menu should get its parent, got %+v", roles)
+	}
+	var role models.Role
+	if err := db.Where("code = ?", "user").First(&role).Error; err != nil || role.Name != "Member" {
+		t.Errorf("existing role should be left unchanged, got %+v, %v", role, err)
+	}
+	if n := count(t, db, &models.User{}); n != 2 {
+		t.Errorf("got %d users, want 2", n)
+	}
+	if n := count(t, db, &models.UserRole{}); n != 2 {
+		t.Errorf("got %d user roles, want 2", n)
+	}
+	// 新菜单按 "*" 关联到 admin
+	if n := count(t, db, &models.RoleMenu{}); n != 4 {
+		t.Errorf("got %d role menus, want 4", n)
+	}
+}
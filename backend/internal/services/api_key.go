@@ -0,0 +1,451 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"stars-admin/internal/apperrors"
+	"stars-admin/internal/database"
+	"stars-admin/internal/models"
+	"stars-admin/internal/utils"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// APIKeyPrefix API 密钥前缀，Authorization 头中以此开头的 Bearer 令牌按 API 密钥认证
+const APIKeyPrefix = "sak_"
+
+// apiKeyTouchInterval 最后使用时间的更新间隔，避免每个请求都写数据库
+const apiKeyTouchInterval = time.Minute
+
+// serviceAccountEmailDomain 服务账号的占位邮箱域名，.invalid 保证不会被真正投递
+const serviceAccountEmailDomain = "service-account.invalid"
+
+// APIKeyService API 密钥与服务账号服务，请求中的密钥由 api_key 认证方式校验
+type APIKeyService struct {
+	db  *gorm.DB
+	ctx context.Context
+}
+
+// NewAPIKeyService 创建 API 密钥与服务账号服务
+func NewAPIKeyService(db *gorm.DB) *APIKeyService {
+	return &APIKeyService{
+		db:  db,
+		ctx: context.Background(),
+	}
+}
+
+// WithContext 返回绑定请求上下文的服务副本
+func (s *APIKeyService) WithContext(ctx context.Context) *APIKeyService {
+	clone := *s
+	clone.db = s.db.WithContext(ctx)
+	clone.ctx = ctx
+	return &clone
+}
+
+// CreateAPIKeyRequest 创建 API 密钥请求
+type CreateAPIKeyRequest struct {
+	Name string `json:"name" binding:"required,max=50"`
+	// Roles 角色编码，必须是所有者当前角色的子集
+	Roles []string `json:"roles" binding:"max=20"`
+	// Permissions 权限，必须是所有者当前权限的子集
+	Permissions []string `json:"permissions" binding:"max=100"`
+	// ExpiresInDays 有效期（天），为 0 时不过期
+	ExpiresInDays int `json:"expires_in_days" binding:"omitempty,min=1,max=3650"`
+}
+
+// APIKeyResponse 创建 API 密钥的结果，密钥只在创建时返回一次
+type APIKeyResponse struct {
+	APIKey *models.APIKey `json:"api_key"`
+	Key    string         `json:"key"`
+}
+
+// CreateServiceAccountRequest 创建服务账号请求
+type CreateServiceAccountRequest struct {
+	Username string   `json:"username" binding:"required,min=3,max=50"`
+	Nickname string   `json:"nickname" binding:"max=50"`
+	Roles    []string `json:"roles" binding:"max=20"` // 角色编码
+}
+
+// CreateKey 为用户或服务账号创建 API 密钥
+func (s *APIKeyService) CreateKey(ownerID uint, req *CreateAPIKeyRequest, operatorID uint) (*APIKeyResponse, error) {
+	var owner models.User
+	if err := database.UsePrimary(s.db).First(&owner, ownerID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.ErrUserNotFound
+		}
+		return nil, err
+	}
+	if err := userStatusError(&owner); err != nil {
+		return nil, err
+	}
+
+	ownerRoles, ownerPermissions, err := userRolesAndPermissions(s.db, owner.ID)
+	if err != nil {
+		return nil, err
+	}
+	roles := splitCodes(strings.Join(req.Roles, ","))
+	permissions := splitCodes(strings.Join(req.Permissions, ","))
+
+	// 密钥可能随 CI 配置等泄露，不能带有超级管理员角色或全部权限，需要时为具体的接口授予对应的角色和权限
+	if privileged := privilegedScope(roles, permissions); len(privileged) > 0 {
+		return nil, apperrors.ErrAPIKeyPrivilegedScope.WithDetails(privileged)
+	}
+
+	exceeded := map[string]interface{}{}
+	if extra := difference(roles, ownerRoles); len(extra) > 0 {
+		exceeded["roles"] = extra
+	}
+	if extra := scopeDifference(permissions, ownerPermissions); len(extra) > 0 {
+		exceeded["permissions"] = extra
+	}
+	if len(exceeded) > 0 {
+		return nil, apperrors.ErrAPIKeyScopeExceeded.WithDetails(exceeded)
+	}
+
+	secret, err := utils.GenerateRandomString(32)
+	if err != nil {
+		return nil, err
+	}
+	key := APIKeyPrefix + secret
+
+	apiKey := models.APIKey{
+		Name:        req.Name,
+		Prefix:      key[:len(APIKeyPrefix)+8],
+		KeyHash:     utils.GetTokenHash(key),
+		UserID:      owner.ID,
+		Roles:       strings.Join(roles, ","),
+		Permissions: strings.Join(permissions, ","),
+		CreatedBy:   operatorID,
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		apiKey.ExpiresAt = &expiresAt
+	}
+	if err := s.db.Create(&apiKey).Error; err != nil {
+		return nil, err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"api_key_id": apiKey.ID,
+		"user_id":    owner.ID,
+		"created_by": operatorID,
+	}).Info("API key created")
+
+	return &APIKeyResponse{APIKey: &apiKey, Key: key}, nil
+}
+
+// ListKeys 返回用户或服务账号的全部 API 密钥，包括已过期和已吊销的
+func (s *APIKeyService) ListKeys(ownerID uint) ([]models.APIKey, error) {
+	var keys []models.APIKey
+	if err := s.db.Where("user_id = ?", ownerID).Order("id DESC").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// RevokeKey 吊销 API 密钥，立即生效
+func (s *APIKeyService) RevokeKey(ownerID, keyID uint) error {
+	var apiKey models.APIKey
+	if err := s.db.Where("id = ? AND user_id = ?", keyID, ownerID).First(&apiKey).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperrors.ErrAPIKeyNotFound
+		}
+		return err
+	}
+	if apiKey.RevokedAt != nil {
+		return nil
+	}
+
+	if err := s.db.Model(&apiKey).Update("revoked_at", time.Now()).Error; err != nil {
+		return err
+	}
+
+	logrus.WithFields(logrus.Fields{"api_key_id": apiKey.ID, "user_id": ownerID}).Info("API key revoked")
+	return nil
+}
+
+// APIKeyCredentials 请求携带的 API 密钥
+type APIKeyCredentials struct {
+	Key string
+	// Request 请求信息，密钥被拒绝时补充事件和原因后写入安全日志
+	Request models.SecurityLog
+}
+
+func (*APIKeyCredentials) credentials() {}
+
+// apiKeyAuthenticator 按哈希查找 API 密钥，确认的身份为密钥的所有者
+type apiKeyAuthenticator struct {
+	db           *gorm.DB
+	securityLogs *SecurityLogWriter
+}
+
+// NewAPIKeyAuthenticator 创建 API 密钥认证方式
+func NewAPIKeyAuthenticator(db *gorm.DB, securityLogs *SecurityLogWriter) Authenticator {
+	return &apiKeyAuthenticator{db: db, securityLogs: securityLogs}
+}
+
+// Name 认证方式名称
+func (a *apiKeyAuthenticator) Name() string {
+	return AuthenticatorAPIKey
+}
+
+// Authenticate 校验密钥未过期、未吊销且所有者存在，返回所有者的身份，无效的密钥写入安全日志
+func (a *apiKeyAuthenticator) Authenticate(ctx context.Context, creds Credentials) (*Identity, error) {
+	c, ok := creds.(*APIKeyCredentials)
+	if !ok {
+		return nil, ErrAuthenticatorSkip
+	}
+	db := a.db.WithContext(ctx)
+	reject := func(user *models.User, detail string) {
+		request := c.Request
+		request.Event = SecurityEventAPIKeyRejected
+		request.Detail = detail
+		if user != nil {
+			request.UserID, request.Username = user.ID, user.Username
+		}
+		a.securityLogs.Record(request)
+	}
+
+	if !strings.HasPrefix(c.Key, APIKeyPrefix) {
+		return nil, apperrors.ErrAPIKeyInvalid
+	}
+
+	var apiKey models.APIKey
+	if err := database.UsePrimary(db).Where("key_hash = ?", utils.GetTokenHash(c.Key)).First(&apiKey).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			reject(nil, "unknown key "+truncate(c.Key, len(APIKeyPrefix)+8))
+			return nil, apperrors.ErrAPIKeyInvalid
+		}
+		return nil, apperrors.ErrAuthUnavailable.Wrap(err)
+	}
+
+	var owner models.User
+	if err := database.UsePrimary(db).First(&owner, apiKey.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			reject(nil, fmt.Sprintf("key %d owner deleted", apiKey.ID))
+			return nil, apperrors.ErrAPIKeyInvalid
+		}
+		return nil, apperrors.ErrAuthUnavailable.Wrap(err)
+	}
+
+	now := time.Now()
+	if !apiKey.Active(now) {
+		reject(&owner, fmt.Sprintf("key %d revoked or expired", apiKey.ID))
+		return nil, apperrors.ErrAPIKeyInvalid
+	}
+
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= apiKeyTouchInterval {
+		if err := db.Model(&apiKey).UpdateColumns(map[string]interface{}{
+			"last_used_at": now,
+			"last_used_ip": c.Request.IP,
+		}).Error; err != nil {
+			logrus.WithError(err).WithField("api_key_id", apiKey.ID).Warn("Failed to update API key last used time")
+		}
+	}
+
+	return &Identity{Authenticator: AuthenticatorAPIKey, UserID: owner.ID, APIKey: &apiKey}, nil
+}
+
+// AuthenticateAPIKey 由认证方式链校验 API 密钥，检查所有者状态后返回以所有者身份访问的声明
+// 角色和权限为密钥与所有者当前范围的交集；request 为请求信息，密钥被拒绝时写入安全日志
+func (s *AuthService) AuthenticateAPIKey(key string, request models.SecurityLog) (*utils.JWTClaims, *models.APIKey, error) {
+	identity, err := s.authenticate(&APIKeyCredentials{Key: key, Request: request})
+	if err != nil {
+		if errors.Is(err, apperrors.ErrInvalidCredentials) {
+			// 认证方式链中没有 API 密钥认证方式
+			return nil, nil, apperrors.ErrAPIKeyInvalid
+		}
+		return nil, nil, err
+	}
+	if identity.APIKey == nil {
+		return nil, nil, fmt.Errorf("authenticator %s returned no API key", identity.Authenticator)
+	}
+
+	src := loginSource{ip: request.IP, userAgent: request.UserAgent, method: request.Method, path: request.Path}
+	owner, err := s.identityUser(identity, src)
+	if err != nil {
+		return nil, nil, apperrors.ErrAuthUnavailable.Wrap(err)
+	}
+	if err := userStatusError(owner); err != nil {
+		s.record(SecurityEventAPIKeyRejected, owner, src, fmt.Sprintf("key %d owner status %d", identity.APIKey.ID, owner.Status))
+		return nil, nil, err
+	}
+	if err := s.syncIdentityRoles(identity, owner); err != nil {
+		return nil, nil, err
+	}
+
+	ownerRoles, ownerPermissions, err := userRolesAndPermissions(s.db, owner.ID)
+	if err != nil {
+		return nil, nil, apperrors.ErrAuthUnavailable.Wrap(err)
+	}
+
+	// 创建时已拒绝特权范围，这里再去掉一次，避免此前创建的密钥继续带有特权
+	apiKey := identity.APIKey
+	roles := difference(intersect(splitCodes(apiKey.Roles), ownerRoles), []string{models.RoleAdmin})
+	permissions := difference(scopeIntersect(splitCodes(apiKey.Permissions), ownerPermissions), []string{models.PermissionAll})
+
+	return &utils.JWTClaims{
+		UserID:      owner.ID,
+		Username:    owner.Username,
+		Roles:       roles,
+		Permissions: permissions,
+		Locale:      owner.Locale,
+		AllowedIPs:  allowedIPs(owner),
+	}, apiKey, nil
+}
+
+// CreateServiceAccount 创建服务账号，服务账号没有密码，只能通过 API 密钥访问
+func (s *APIKeyService) CreateServiceAccount(req *CreateServiceAccountRequest, operatorID uint) (*models.User, error) {
+	if err := checkUsernameAvailable(s.db, req.Username); err != nil {
+		return nil, err
+	}
+	roles, err := activeRoles(s.db, req.Roles)
+	if err != nil {
+		return nil, err
+	}
+
+	account := models.User{
+		Username:       req.Username,
+		Email:          req.Username + "@" + serviceAccountEmailDomain,
+		Nickname:       req.Nickname,
+		Status:         models.UserStatusActive,
+		ServiceAccount: true,
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&account).Error; err != nil {
+			return err
+		}
+		for _, role := range roles {
+			if err := tx.Create(&models.UserRole{UserID: account.ID, RoleID: role.ID}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	account.Roles = roles
+
+	logrus.WithFields(logrus.Fields{
+		"user_id":    account.ID,
+		"username":   account.Username,
+		"created_by": operatorID,
+	}).Info("Service account created")
+
+	return &account, nil
+}
+
+// ListServiceAccounts 返回全部服务账号及其角色
+func (s *APIKeyService) ListServiceAccounts() ([]models.User, error) {
+	var accounts []models.User
+	if err := s.db.Preload("Roles").Where("service_account = ?", true).Order("id DESC").Find(&accounts).Error; err != nil {
+		return nil, err
+	}
+	return accounts, nil
+}
+
+// ServiceAccount 返回服务账号，用户不存在或不是服务账号时返回 ErrServiceAccountNotFound
+func (s *APIKeyService) ServiceAccount(id uint) (*models.User, error) {
+	var account models.User
+	if err := s.db.Where("service_account = ?", true).First(&account, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.ErrServiceAccountNotFound
+		}
+		return nil, err
+	}
+	return &account, nil
+}
+
+// DeleteServiceAccount 删除服务账号并吊销其全部 API 密钥
+func (s *APIKeyService) DeleteServiceAccount(id uint) error {
+	account, err := s.ServiceAccount(id)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.APIKey{}).Where("user_id = ? AND revoked_at IS NULL", account.ID).Update("revoked_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return tx.Delete(account).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	logrus.WithFields(logrus.Fields{"user_id": account.ID, "username": account.Username}).Info("Service account deleted")
+	return nil
+}
+
+// difference 返回 a 中不在 b 中的元素
+func difference(a, b []string) []string {
+	set := make(map[string]bool, len(b))
+	for _, v := range b {
+		set[v] = true
+	}
+	var out []string
+	for _, v := range a {
+		if !set[v] {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// intersect 返回 a 中同时在 b 中的元素
+func intersect(a, b []string) []string {
+	set := make(map[string]bool, len(b))
+	for _, v := range b {
+		set[v] = true
+	}
+	var out []string
+	for _, v := range a {
+		if set[v] {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// scopeDifference 返回 requested 中超出 owner 权限范围的权限，owner 拥有全部权限时不会超出
+func scopeDifference(requested, owner []string) []string {
+	if containsString(owner, models.PermissionAll) {
+		return nil
+	}
+	return difference(requested, owner)
+}
+
+// scopeIntersect 返回 requested 中在 owner 权限范围内的权限，owner 拥有全部权限时全部保留
+func scopeIntersect(requested, owner []string) []string {
+	if containsString(owner, models.PermissionAll) {
+		return requested
+	}
+	return intersect(requested, owner)
+}
+
+// privilegedScope 返回密钥范围中的特权角色和权限
+func privilegedScope(roles, permissions []string) map[string]interface{} {
+	privileged := map[string]interface{}{}
+	if containsString(roles, models.RoleAdmin) {
+		privileged["roles"] = []string{models.RoleAdmin}
+	}
+	if containsString(permissions, models.PermissionAll) {
+		privileged["permissions"] = []string{models.PermissionAll}
+	}
+	return privileged
+}
+
+// containsString 判断 list 是否包含 value
+func containsString(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"stars-admin/internal/apperrors"
+	"stars-admin/internal/models"
+	"stars-admin/internal/utils"
+	"testing"
+)
+
+func TestAPIKeyScope(t *testing.T) {
+	if extra := scopeDifference([]string{"user:list", "role:list"}, []string{"*"}); extra != nil {
+		t.Errorf("owner with * should cover any permission, got extra %v", extra)
+	}
+	if extra := scopeDifference([]string{"user:list", "role:list"}, []string{"user:list"}); !reflect.DeepEqual(extra, []string{"role:list"}) {
+		t.Errorf("got extra %v, want [role:list]", extra)
+	}
+	if kept := scopeIntersect([]string{"user:list"}, []string{"*"}); !reflect.DeepEqual(kept, []string{"user:list"}) {
+		t.Errorf("owner with * should keep key permissions, got %v", kept)
+	}
+	if kept := scopeIntersect([]string{"user:list", "role:list"}, []string{"role:list"}); !reflect.DeepEqual(kept, []string{"role:list"}) {
+		t.Errorf("got %v, want [role:list]", kept)
+	}
+
+	if privileged := privilegedScope([]string{"user"}, []string{"user:list"}); len(privileged) != 0 {
+		t.Errorf("unexpected privileged scope %v", privileged)
+	}
+	privileged := privilegedScope([]string{"user", "admin"}, []string{"*"})
+	if len(privileged) != 2 {
+		t.Errorf("admin role and * should both be privileged, got %v", privileged)
+	}
+}
+
+func apiKeyAuth(env *testEnv) *AuthService {
+	return env.auth.WithAuthenticators([]Authenticator{NewLocalAuthenticator(env.db), NewAPIKeyAuthenticator(env.db, env.logs)})
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	env := newTestEnv(t)
+	keys := NewAPIKeyService(env.db)
+	owner := env.createUser(t, "ci", "ci@example.org", "Ci123456", models.RoleAdmin, "user")
+	auth := apiKeyAuth(env)
+	request := models.SecurityLog{IP: "127.0.0.1", Method: "GET", Path: "/api/v1/auth/user"}
+
+	if _, err := keys.CreateKey(owner.ID, &CreateAPIKeyRequest{Name: "admin", Roles: []string{models.RoleAdmin}}, owner.ID); !errors.Is(err, apperrors.ErrAPIKeyPrivilegedScope) {
+		t.Errorf("admin role in key scope: got %v, want privileged scope error", err)
+	}
+	if _, err := keys.CreateKey(owner.ID, &CreateAPIKeyRequest{Name: "all", Permissions: []string{models.PermissionAll}}, owner.ID); !errors.Is(err, apperrors.ErrAPIKeyPrivilegedScope) {
+		t.Errorf("* in key scope: got %v, want privileged scope error", err)
+	}
+
+	created, err := keys.CreateKey(owner.ID, &CreateAPIKeyRequest{Name: "ci", Roles: []string{"user"}}, owner.ID)
+	if err != nil {
+		t.Fatalf("CreateKey: %v", err)
+	}
+	claims, apiKey, err := auth.AuthenticateAPIKey(created.Key, request)
+	if err != nil {
+		t.Fatalf("AuthenticateAPIKey: %v", err)
+	}
+	if claims.UserID != owner.ID || apiKey.ID != created.APIKey.ID || !reflect.DeepEqual(claims.Roles, []string{"user"}) {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+
+	// 此前创建的带有特权角色的密钥不再获得特权
+	legacy := models.APIKey{Name: "legacy", KeyHash: utils.GetTokenHash(APIKeyPrefix + "legacy"), UserID: owner.ID, Roles: "admin,user", Permissions: "*"}
+	if err := env.db.Create(&legacy).Error; err != nil {
+		t.Fatal(err)
+	}
+	if claims, _, err = auth.AuthenticateAPIKey(APIKeyPrefix+"legacy", request); err != nil {
+		t.Fatalf("AuthenticateAPIKey: %v", err)
+	}
+	if !reflect.DeepEqual(claims.Roles, []string{"user"}) || len(claims.Permissions) != 0 {
+		t.Errorf("privileged scope should be dropped, got roles %v, permissions %v", claims.Roles, claims.Permissions)
+	}
+
+	// API 密钥不能作为密码登录，用户名密码也不会被当作 API 密钥
+	if _, err := auth.Login(&LoginRequest{Username: "ci", Password: created.Key}); !errors.Is(err, apperrors.ErrInvalidCredentials) {
+		t.Errorf("API key used as password: got %v", err)
+	}
+	if _, _, err := env.auth.AuthenticateAPIKey(created.Key, request); !errors.Is(err, apperrors.ErrAPIKeyInvalid) {
+		t.Errorf("chain without API key authenticator: got %v", err)
+	}
+	env.events()
+
+	if _, _, err := auth.AuthenticateAPIKey(APIKeyPrefix+"unknown", request); !errors.Is(err, apperrors.ErrAPIKeyInvalid) {
+		t.Errorf("unknown key: got %v", err)
+	}
+	if err := keys.RevokeKey(owner.ID, created.APIKey.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := auth.AuthenticateAPIKey(created.Key, request); !errors.Is(err, apperrors.ErrAPIKeyInvalid) {
+		t.Errorf("revoked key: got %v", err)
+	}
+	if events := env.events(); len(events) != 2 || events[0] != SecurityEventAPIKeyRejected || events[1] != SecurityEventAPIKeyRejected {
+		t.Errorf("rejected keys should be recorded, got %v", events)
+	}
+
+	// 所有者被禁用后密钥随之失效
+	if err := env.db.Model(owner).Update("status", models.UserStatusDisabled).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := auth.AuthenticateAPIKey(APIKeyPrefix+"legacy", request); !errors.Is(err, apperrors.ErrUserDisabled) {
+		t.Errorf("disabled owner: got %v", err)
+	}
+	if !containsString(env.events(), SecurityEventAPIKeyRejected) {
+		t.Error("disabled owner should be recorded")
+	}
+}
@@ -1,35 +1,84 @@
 package services
 
 import (
+	"context"
 	"errors"
+	"stars-admin/internal/apperrors"
+	"stars-admin/internal/database"
+	"stars-admin/internal/i18n"
+	"stars-admin/internal/ipfilter"
+	"stars-admin/internal/metrics"
 	"stars-admin/internal/models"
+	"stars-admin/internal/settings"
+	"stars-admin/internal/store"
+	"stars-admin/internal/tracing"
 	"stars-admin/internal/utils"
+	"strings"
 	"time"
 
-	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 )
 
 // AuthService 认证服务
 type AuthService struct {
-	db  *gorm.DB
-	rdb *redis.Client
+	db           *gorm.DB
+	st           store.Store
+	settings     *settings.Manager
+	securityLogs *SecurityLogWriter
+	ctx          context.Context
+
+	authenticators []Authenticator // 依次尝试的认证方式
+	secondFactor   SecondFactor    // 第二因素验证，为空时不验证
 }
 
-// NewAuthService 创建认证服务
-func NewAuthService(db *gorm.DB, rdb *redis.Client) *AuthService {
+// NewAuthService 创建认证服务，令牌有效期和密码策略从系统配置实时读取
+// 默认只使用本地密码，其他认证方式通过 WithAuthenticators 设置
+func NewAuthService(db *gorm.DB, st store.Store, sm *settings.Manager, securityLogs *SecurityLogWriter) *AuthService {
 	return &AuthService{
-		db:  db,
-		rdb: rdb,
+		db:             db,
+		st:             st,
+		settings:       sm,
+		securityLogs:   securityLogs,
+		ctx:            context.Background(),
+		authenticators: []Authenticator{NewLocalAuthenticator(db)},
 	}
 }
 
+// WithAuthenticators 返回按顺序使用指定认证方式的服务副本
+func (s *AuthService) WithAuthenticators(authenticators []Authenticator) *AuthService {
+	clone := *s
+	clone.authenticators = authenticators
+	return &clone
+}
+
+// WithSecondFactor 返回在签发令牌前验证第二因素的服务副本
+func (s *AuthService) WithSecondFactor(secondFactor SecondFactor) *AuthService {
+	clone := *s
+	clone.secondFactor = secondFactor
+	return &clone
+}
+
+// WithContext 返回绑定请求上下文的服务副本，数据库和存储调用沿用该上下文的链路和取消信号
+func (s *AuthService) WithContext(ctx context.Context) *AuthService {
+	clone := *s
+	clone.db = s.db.WithContext(ctx)
+	clone.ctx = ctx
+	return &clone
+}
+
 // LoginRequest 登录请求
 type LoginRequest struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
+
+	// 客户端信息，由处理器填充
+	IP        string `json:"-"`
+	UserAgent string `json:"-"`
 }
 
+func (*LoginRequest) credentials() {}
+
 // LoginResponse 登录响应
 type LoginResponse struct {
 	AccessToken  string    `json:"access_token"`
@@ -46,87 +95,242 @@ type UserInfo struct {
 	Nickname string `json:"nickname"`
 	Avatar   string `json:"avatar"`
 	Status   int    `json:"status"`
+	// MustChangePassword 为 true 时前端应引导用户先修改密码
+	MustChangePassword bool   `json:"must_change_password"`
+	Locale             string `json:"locale"`
 }
 
 // RefreshTokenRequest 刷新token请求
 type RefreshTokenRequest struct {
 	RefreshToken string `json:"refresh_token" binding:"required"`
+
+	// 客户端信息，由处理器填充
+	IP        string `json:"-"`
+	UserAgent string `json:"-"`
 }
 
-// Login 用户登录
+// Login 用户登录，按顺序尝试各认证方式验证用户名和密码
 func (s *AuthService) Login(req *LoginRequest) (*LoginResponse, error) {
-	// 查找用户
+	identity, err := s.authenticate(req)
+	if err != nil {
+		if errors.Is(err, apperrors.ErrInvalidCredentials) {
+			metrics.LoginAttempts.WithLabelValues("invalid_credentials").Inc()
+		} else {
+			metrics.LoginAttempts.WithLabelValues("error").Inc()
+		}
+		return nil, err
+	}
+
+	// 密码正确后再检查用户状态和来源 IP，避免泄露账号是否存在
+	_, resp, err := s.loginIdentity(identity, loginSource{
+		ip:        req.IP,
+		userAgent: req.UserAgent,
+		method:    "POST",
+		path:      "/api/v1/auth/login",
+	})
+	if errors.Is(err, errIdentityNotLinked) {
+		metrics.LoginAttempts.WithLabelValues("invalid_credentials").Inc()
+		logrus.WithError(err).WithField("authenticator", identity.Authenticator).Warn("Login identity is not linked to any user")
+		return nil, apperrors.ErrInvalidCredentials
+	}
+	return resp, err
+}
+
+// completeLogin 确认用户身份后检查来源 IP、签发令牌并更新最后登录时间
+func (s *AuthService) completeLogin(user *models.User, ip, userAgent, path string) (*LoginResponse, error) {
+	if !s.checkUserIP(user, ip, userAgent, path) {
+		metrics.LoginAttempts.WithLabelValues("ip_blocked").Inc()
+		return nil, apperrors.ErrIPBlocked
+	}
+
+	resp, err := s.issueTokens(user)
+	if err != nil {
+		metrics.LoginAttempts.WithLabelValues("error").Inc()
+		return nil, err
+	}
+	metrics.LoginAttempts.WithLabelValues("success").Inc()
+
+	// 更新最后登录时间
+	now := time.Now()
+	s.db.Model(user).Update("last_login_at", &now)
+
+	return resp, nil
+}
+
+// RefreshToken 刷新访问令牌，同时轮换刷新令牌
+func (s *AuthService) RefreshToken(req *RefreshTokenRequest) (*LoginResponse, error) {
+	userID, err := utils.ParseRefreshToken(req.RefreshToken)
+	if err != nil {
+		metrics.TokenRefreshes.WithLabelValues("invalid").Inc()
+		return nil, apperrors.ErrRefreshTokenInvalid.Wrap(err)
+	}
+
+	// 校验是否为当前有效的刷新令牌，存储不可用时按故障策略处理
+	valid, err := utils.ValidateRefreshToken(s.ctx, s.st, userID, req.RefreshToken)
+	if err != nil {
+		if !store.FailOpenFor(s.st, store.CheckRefresh) {
+			metrics.TokenRefreshes.WithLabelValues("unavailable").Inc()
+			return nil, apperrors.ErrAuthUnavailable.Wrap(err)
+		}
+		logrus.WithError(err).WithField("user_id", userID).Warn("Refresh token check skipped: store unavailable")
+		valid = true
+	}
+	if !valid {
+		metrics.TokenRefreshes.WithLabelValues("invalid").Inc()
+		return nil, apperrors.ErrRefreshTokenInvalid
+	}
+
 	var user models.User
-	if err := s.db.Where("username = ?", req.Username).First(&user).Error; err != nil {
+	if err := database.UsePrimary(s.db).First(&user, userID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("用户名或密码错误")
+			metrics.TokenRefreshes.WithLabelValues("invalid").Inc()
+			return nil, apperrors.ErrRefreshTokenInvalid
 		}
+		metrics.TokenRefreshes.WithLabelValues("error").Inc()
 		return nil, err
 	}
 
-	// 检查用户状态
-	if user.Status != 1 {
-		return nil, errors.New("用户已被禁用")
+	if user.Status != models.UserStatusActive {
+		metrics.TokenRefreshes.WithLabelValues("disabled").Inc()
+		return nil, apperrors.ErrUserDisabled
+	}
+
+	if !s.checkUserIP(&user, req.IP, req.UserAgent, "/api/v1/auth/refresh") {
+		metrics.TokenRefreshes.WithLabelValues("ip_blocked").Inc()
+		return nil, apperrors.ErrIPBlocked
 	}
 
-	// 验证密码
-	if !utils.CheckPassword(user.Password, req.Password) {
-		return nil, errors.New("用户名或密码错误")
+	resp, err := s.issueTokens(&user)
+	if err != nil {
+		metrics.TokenRefreshes.WithLabelValues("error").Inc()
+		return nil, err
+	}
+	metrics.TokenRefreshes.WithLabelValues("success").Inc()
+
+	return resp, nil
+}
+
+// issueTokens 为用户签发访问令牌和刷新令牌
+func (s *AuthService) issueTokens(user *models.User) (*LoginResponse, error) {
+	// 密码超过有效期时要求先修改密码
+	if !user.MustChangePassword && s.settings.PasswordPolicy(s.ctx).Expired(passwordChangedAt(user)) {
+		if err := s.db.Model(user).Update("must_change_password", true).Error; err != nil {
+			return nil, err
+		}
+		user.MustChangePassword = true
 	}
 
 	// 获取用户角色和权限
-	roles, permissions, err := s.getUserRolesAndPermissions(user.ID)
+	roles, permissions, err := userRolesAndPermissions(s.db, user.ID)
 	if err != nil {
 		return nil, err
 	}
 
+	accessTTL := s.settings.AccessTokenTTL(s.ctx)
+	refreshTTL := s.settings.RefreshTokenTTL(s.ctx)
+
 	// 生成JWT token
-	accessToken, err := utils.GenerateJWT(user.ID, user.Username, roles, permissions)
+	accessToken, err := utils.GenerateJWT(utils.JWTClaims{
+		UserID:             user.ID,
+		Username:           user.Username,
+		Roles:              roles,
+		Permissions:        permissions,
+		MustChangePassword: user.MustChangePassword,
+		Locale:             user.Locale,
+		AllowedIPs:         allowedIPs(user),
+	}, accessTTL)
 	if err != nil {
 		return nil, err
 	}
 
 	// 生成刷新token
-	refreshToken := utils.GenerateRefreshToken()
-	if err := utils.StoreRefreshToken(s.rdb, user.ID, refreshToken, 7*24*time.Hour); err != nil {
+	refreshToken, err := utils.GenerateRefreshToken(user.ID, refreshTTL)
+	if err != nil {
 		return nil, err
 	}
-
-	// 更新最后登录时间
-	now := time.Now()
-	s.db.Model(&user).Update("last_login_at", &now)
+	if err := utils.StoreRefreshToken(s.ctx, s.st, user.ID, refreshToken, refreshTTL); err != nil {
+		if !store.FailOpenFor(s.st, store.CheckRefresh) {
+			return nil, apperrors.ErrAuthUnavailable.Wrap(err)
+		}
+		logrus.WithError(err).WithField("user_id", user.ID).Warn("Refresh token not stored: store unavailable")
+	}
 
 	return &LoginResponse{
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
-		ExpiresIn:    24 * 60 * 60, // 24小时
-		User: &UserInfo{
-			ID:       user.ID,
-			Username: user.Username,
-			Email:    user.Email,
-			Nickname: user.Nickname,
-			Avatar:   user.Avatar,
-			Status:   user.Status,
-		},
+		ExpiresIn:    int64(accessTTL.Seconds()),
+		User:         newUserInfo(user),
 	}, nil
 }
 
-// RefreshToken 刷新访问令牌
-func (s *AuthService) RefreshToken(req *RefreshTokenRequest) (*LoginResponse, error) {
-	// 这里需要从刷新token中获取用户信息
-	// 简化实现，实际应该存储刷新token与用户的关联
-	return nil, errors.New("refresh token功能待实现")
+// checkUserIP 检查用户是否允许从该 IP 访问，不允许时记录安全日志
+// 允许的 IP 无法解析时按不允许处理，避免配置错误导致限制失效
+func (s *AuthService) checkUserIP(user *models.User, ip, userAgent, path string) bool {
+	if user.AllowedIPs == "" {
+		return true
+	}
+
+	prefixes, err := ipfilter.ParseList(user.AllowedIPs)
+	if err != nil {
+		logrus.WithError(err).WithField("user_id", user.ID).Error("Invalid allowed IPs for user")
+	}
+	if err == nil && ipfilter.Contains(prefixes, ip) {
+		return true
+	}
+
+	s.securityLogs.Record(models.SecurityLog{
+		Event:     SecurityEventUserIPBlocked,
+		UserID:    user.ID,
+		Username:  user.Username,
+		IP:        ip,
+		Method:    "POST",
+		Path:      path,
+		UserAgent: userAgent,
+		Detail:    "login outside allowed IPs",
+		TraceID:   tracing.TraceID(s.ctx),
+		CreatedAt: time.Now(),
+	})
+	return false
+}
+
+// userStatusError 返回用户无法登录的原因，状态正常时返回 nil
+func userStatusError(user *models.User) error {
+	switch user.Status {
+	case models.UserStatusActive:
+		return nil
+	case models.UserStatusUnverified:
+		return apperrors.ErrEmailNotVerified
+	case models.UserStatusPendingApproval:
+		return apperrors.ErrUserPendingApproval
+	default:
+		return apperrors.ErrUserDisabled
+	}
+}
+
+// allowedIPs 返回用户允许的 IP 列表，写入令牌供认证中间件检查
+func allowedIPs(user *models.User) []string {
+	var ips []string
+	for _, ip := range strings.Split(user.AllowedIPs, ",") {
+		if ip = strings.TrimSpace(ip); ip != "" {
+			ips = append(ips, ip)
+		}
+	}
+	return ips
 }
 
 // Logout 用户登出
 func (s *AuthService) Logout(userID uint, token string) error {
-	// 将token加入黑名单
-	if err := utils.BlacklistToken(s.rdb, token, 24*time.Hour); err != nil {
+	// 将token加入黑名单，保留到令牌过期为止
+	ttl := s.settings.AccessTokenTTL(s.ctx)
+	if claims, err := utils.ValidateJWT(token); err == nil && claims.ExpiresAt != nil {
+		ttl = time.Until(claims.ExpiresAt.Time)
+	}
+	if err := utils.BlacklistToken(s.ctx, s.st, token, ttl); err != nil {
 		return err
 	}
 
 	// 删除刷新token
-	if err := utils.DeleteRefreshToken(s.rdb, userID); err != nil {
+	if err := utils.DeleteRefreshToken(s.ctx, s.st, userID); err != nil {
 		return err
 	}
 
@@ -137,68 +341,111 @@ func (s *AuthService) Logout(userID uint, token string) error {
 func (s *AuthService) GetUserInfo(userID uint) (*UserInfo, error) {
 	var user models.User
 	if err := s.db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.ErrUserNotFound
+		}
 		return nil, err
 	}
 
-	return &UserInfo{
-		ID:       user.ID,
-		Username: user.Username,
-		Email:    user.Email,
-		Nickname: user.Nickname,
-		Avatar:   user.Avatar,
-		Status:   user.Status,
-	}, nil
+	return newUserInfo(&user), nil
 }
 
 // UpdatePassword 更新密码
 func (s *AuthService) UpdatePassword(userID uint, oldPassword, newPassword string) error {
 	var user models.User
-	if err := s.db.First(&user, userID).Error; err != nil {
+	if err := database.UsePrimary(s.db).First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperrors.ErrUserNotFound
+		}
 		return err
 	}
 
 	// 验证旧密码
 	if !utils.CheckPassword(user.Password, oldPassword) {
-		return errors.New("旧密码错误")
+		return apperrors.ErrOldPasswordIncorrect
 	}
 
-	// 加密新密码
-	hashedPassword, err := utils.HashPassword(newPassword)
-	if err != nil {
+	// 校验密码策略和密码历史
+	if err := validateNewPassword(s.db, s.settings.PasswordPolicy(s.ctx), &user, "new_password", newPassword); err != nil {
+		return err
+	}
+
+	// 更新密码，同时解除强制修改密码标记
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return setPassword(tx, &user, newPassword)
+	})
+}
+
+// PasswordPolicy 返回当前的密码策略
+func (s *AuthService) PasswordPolicy() settings.PasswordPolicy {
+	return s.settings.PasswordPolicy(s.ctx)
+}
+
+// UpdatePreferences 更新用户偏好设置，语言为空表示跟随浏览器
+// 新的偏好在下次签发令牌后生效
+func (s *AuthService) UpdatePreferences(userID uint, locale string) error {
+	if locale != "" && !i18n.Default.Supported(locale) {
+		return apperrors.ErrUnsupportedLocale
+	}
+
+	var user models.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperrors.ErrUserNotFound
+		}
 		return err
 	}
 
-	// 更新密码
-	return s.db.Model(&user).Update("password", hashedPassword).Error
+	return s.db.Model(&user).Update("locale", locale).Error
+}
+
+// newUserInfo 转换用户信息
+func newUserInfo(user *models.User) *UserInfo {
+	return &UserInfo{
+		ID:                 user.ID,
+		Username:           user.Username,
+		Email:              user.Email,
+		Nickname:           user.Nickname,
+		Avatar:             user.Avatar,
+		Status:             user.Status,
+		MustChangePassword: user.MustChangePassword,
+		Locale:             user.Locale,
+	}
 }
 
-// getUserRolesAndPermissions 获取用户角色和权限
-func (s *AuthService) getUserRolesAndPermissions(userID uint) ([]string, []string, error) {
+// userRolesAndPermissions 获取用户启用的角色编码和菜单权限
+// 使用子查询而非手写JOIN，表名和字段引用交由GORM按方言生成
+func userRolesAndPermissions(db *gorm.DB, userID uint) ([]string, []string, error) {
+	roleIDs := db.Model(&models.UserRole{}).Select("role_id").Where("user_id = ?", userID)
+
 	var roles []models.Role
-	if err := s.db.Joins("JOIN xc_user_roles ON xc_user_roles.role_id = xc_roles.id").
-		Where("xc_user_roles.user_id = ? AND xc_roles.status = 1", userID).
-		Find(&roles).Error; err != nil {
+	if err := database.UsePrimary(db).Where("id IN (?) AND status = ?", roleIDs, 1).Find(&roles).Error; err != nil {
 		return nil, nil, err
 	}
 
 	var roleNames []string
-	var permissions []string
-
+	var activeRoleIDs []uint
 	for _, role := range roles {
 		roleNames = append(roleNames, role.Code)
+		activeRoleIDs = append(activeRoleIDs, role.ID)
+	}
 
-		// 获取角色对应的菜单权限
-		var menus []models.Menu
-		if err := s.db.Joins("JOIN xc_role_menus ON xc_role_menus.menu_id = xc_menus.id").
-			Where("xc_role_menus.role_id = ? AND xc_menus.status = 1", role.ID).
-			Find(&menus).Error; err != nil {
-			continue
-		}
+	if len(activeRoleIDs) == 0 {
+		return roleNames, nil, nil
+	}
+
+	// 获取角色对应的菜单权限
+	menuIDs := db.Model(&models.RoleMenu{}).Select("menu_id").Where("role_id IN ?", activeRoleIDs)
+
+	var menus []models.Menu
+	if err := database.UsePrimary(db).Where("id IN (?) AND status = ?", menuIDs, 1).Find(&menus).Error; err != nil {
+		return nil, nil, err
+	}
 
-		for _, menu := range menus {
-			if menu.Path != "" {
-				permissions = append(permissions, menu.Path)
-			}
+	var permissions []string
+	for _, menu := range menus {
+		if menu.Path != "" {
+			permissions = append(permissions, menu.Path)
 		}
 	}
 
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"stars-admin/internal/apperrors"
+	"stars-admin/internal/config"
+	"stars-admin/internal/database"
+	"stars-admin/internal/models"
+	"stars-admin/internal/oidc"
+	"stars-admin/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// 认证方式，local 和 ldap 验证用户名和密码，按 security.authenticators 配置的顺序尝试；
+// oidc 验证身份提供方回调的授权码，api_key 验证请求携带的 API 密钥，只处理各自的凭据，总是启用
+const (
+	AuthenticatorLocal  = "local"
+	AuthenticatorLDAP   = "ldap"
+	AuthenticatorOIDC   = "oidc"
+	AuthenticatorAPIKey = "api_key"
+)
+
+// ErrAuthenticatorSkip 认证方式不处理该凭据或不认识该用户，交给下一个认证方式
+var ErrAuthenticatorSkip = errors.New("authenticator: credentials not handled")
+
+// Credentials 认证凭据：用户名和密码（*LoginRequest）、身份提供方的授权码（*OIDCCredentials）或 API 密钥（*APIKeyCredentials）
+type Credentials interface {
+	credentials()
+}
+
+// Authenticator 认证方式，所有凭据都依次交给认证方式链，由处理该凭据的认证方式确认身份
+type Authenticator interface {
+	// Name 认证方式名称，local 和 ldap 与 security.authenticators 中的名称相同
+	Name() string
+	// Authenticate 验证凭据，返回确认的身份
+	// 不处理该类凭据或不认识该用户时返回 ErrAuthenticatorSkip，其他错误直接作为认证结果返回
+	Authenticate(ctx context.Context, creds Credentials) (*Identity, error)
+}
+
+// Identity 认证方式确认的身份，AuthService 据此确定用户，统一检查用户状态、同步角色，再签发令牌（登录时先验证第二因素）或生成 API 密钥的访问声明
+type Identity struct {
+	Authenticator string // 认证方式名称
+
+	// UserID 认证方式已确定的本地用户，为 0 时按外部身份查找用户，未关联时按邮箱关联或自动创建用户
+	UserID uint
+
+	// 外部身份，按 (Provider, Subject) 关联用户
+	Provider      string
+	Subject       string
+	Username      string // 自动创建用户时的用户名，为空时使用邮箱前缀
+	Email         string
+	EmailVerified bool // 邮箱是否经认证方式确认，只有确认过的邮箱才用于关联或创建用户
+	Name          string
+
+	// LinkByEmail 未关联时按确认过的邮箱关联已有用户，服务账号和超级管理员不会被关联
+	LinkByEmail bool
+	// LinkPasswordUsers 按邮箱关联时允许关联有本地密码的用户，为 false 时只关联外部身份创建的用户
+	LinkPasswordUsers bool
+
+	AutoProvision bool     // 未关联用户时自动创建用户
+	DefaultRoles  []string // 自动创建的用户的角色，超级管理员角色不会授予
+
+	// ManagedRoles 按外部身份同步的角色，其中在 Roles 中的授予、其余撤销，为空时不同步，超级管理员角色不受影响
+	ManagedRoles []string
+	Roles        []string
+
+	// MFA 认证方式已完成多因素认证（如身份提供方的 amr 包含 mfa），不再验证第二因素
+	MFA bool
+
+	// APIKey 通过 API 密钥认证时为使用的密钥，访问范围限定为密钥的角色和权限
+	APIKey *models.APIKey
+}
+
+// String 返回外部身份的描述，用于安全日志
+func (i *Identity) String() string {
+	return fmt.Sprintf("%s identity %s", i.Provider, i.Subject)
+}
+
+// SecondFactor 第二因素验证，登录时在确认用户和用户状态之后、签发令牌之前调用，返回错误时登录失败
+// 对本地密码、LDAP 和 OpenID Connect 登录统一生效，API 密钥不能换取登录令牌，不经过第二因素
+type SecondFactor interface {
+	Verify(ctx context.Context, user *models.User, identity *Identity) error
+}
+
+// NewAuthenticators 创建认证方式链：先按 security.authenticators 的顺序创建用户名密码登录的认证方式，未启用的跳过；
+// 再加入 OpenID Connect 和 API 密钥的认证方式，providers 为 OIDC 登录服务使用的身份提供方
+func NewAuthenticators(db *gorm.DB, cfg *config.Config, securityLogs *SecurityLogWriter, providers *oidc.Registry) ([]Authenticator, error) {
+	var authenticators []Authenticator
+	for _, name := range cfg.Security.Authenticators {
+		switch name {
+		case AuthenticatorLocal:
+			authenticators = append(authenticators, NewLocalAuthenticator(db))
+		case AuthenticatorLDAP:
+			if cfg.LDAP.Enabled {
+				authenticators = append(authenticators, NewLDAPAuthenticator(db, cfg.LDAP))
+			}
+		default:
+			return nil, fmt.Errorf("unsupported authenticator: %s", name)
+		}
+	}
+	authenticators = append(authenticators,
+		NewOIDCAuthenticator(providers),
+		NewAPIKeyAuthenticator(db, securityLogs),
+	)
+	return authenticators, nil
+}
+
+// localAuthenticator 按本地密码（bcrypt）验证
+type localAuthenticator struct {
+	db *gorm.DB
+}
+
+// NewLocalAuthenticator 创建本地密码认证方式
+func NewLocalAuthenticator(db *gorm.DB) Authenticator {
+	return &localAuthenticator{db: db}
+}
+
+// Name 认证方式名称
+func (a *localAuthenticator) Name() string {
+	return AuthenticatorLocal
+}
+
+// Authenticate 验证本地密码，用户不存在或没有本地密码（外部身份创建的用户）时交给下一个认证方式
+func (a *localAuthenticator) Authenticate(ctx context.Context, creds Credentials) (*Identity, error) {
+	req, ok := creds.(*LoginRequest)
+	if !ok {
+		return nil, ErrAuthenticatorSkip
+	}
+
+	var user models.User
+	if err := database.UsePrimary(a.db.WithContext(ctx)).Where("username = ?", req.Username).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAuthenticatorSkip
+		}
+		return nil, err
+	}
+
+	// 服务账号没有密码，不能登录
+	if user.ServiceAccount {
+		return nil, apperrors.ErrInvalidCredentials
+	}
+	if user.Password == "" {
+		return nil, ErrAuthenticatorSkip
+	}
+	if !utils.CheckPassword(user.Password, req.Password) {
+		return nil, apperrors.ErrInvalidCredentials
+	}
+	return &Identity{Authenticator: AuthenticatorLocal, UserID: user.ID}, nil
+}
+
+// authenticate 依次尝试认证方式，都不处理该凭据时返回用户名或密码错误
+func (s *AuthService) authenticate(creds Credentials) (*Identity, error) {
+	for _, a := range s.authenticators {
+		identity, err := a.Authenticate(s.ctx, creds)
+		if errors.Is(err, ErrAuthenticatorSkip) {
+			continue
+		}
+		return identity, err
+	}
+	return nil, apperrors.ErrInvalidCredentials
+}
@@ -0,0 +1,23 @@
+package services
+
+import (
+	"context"
+	"stars-admin/internal/i18n"
+	"stars-admin/internal/models"
+)
+
+// displayName 返回邮件中称呼用户的名字，未设置昵称时使用用户名
+func displayName(user *models.User) string {
+	if user.Nickname != "" {
+		return user.Nickname
+	}
+	return user.Username
+}
+
+// emailLocale 返回发送给用户的邮件语言，未设置偏好语言时使用请求语言
+func emailLocale(ctx context.Context, user *models.User) string {
+	if user.Locale != "" {
+		return user.Locale
+	}
+	return i18n.LocaleFrom(ctx)
+}
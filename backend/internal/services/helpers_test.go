@@ -0,0 +1,114 @@
+package services
+
+import (
+	"sort"
+	"stars-admin/internal/database/databasetest"
+	"stars-admin/internal/models"
+	"stars-admin/internal/settings"
+	"stars-admin/internal/store"
+	"stars-admin/internal/utils"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// testEnv 测试用的 SQLite 数据库、内存存储和认证服务
+// 安全日志不启动写入协程，由 events 从队列中读取
+type testEnv struct {
+	db   *gorm.DB
+	st   *store.MemoryStore
+	sm   *settings.Manager
+	logs *SecurityLogWriter
+	auth *AuthService
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	cfg := databasetest.Config(t)
+	db := databasetest.OpenConfig(t, cfg)
+
+	st := store.NewMemoryStore()
+	t.Cleanup(func() { st.Close() })
+	sm, err := settings.NewManager(db, st, settings.Definitions(cfg))
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+
+	for _, code := range []string{models.RoleAdmin, "user", "ops"} {
+		if err := db.Create(&models.Role{Name: code, Code: code, Status: 1}).Error; err != nil {
+			t.Fatalf("create role %s: %v", code, err)
+		}
+	}
+
+	logs := NewSecurityLogWriter(db, 100)
+	return &testEnv{db: db, st: st, sm: sm, logs: logs, auth: NewAuthService(db, st, sm, logs)}
+}
+
+// createUser 创建正常状态的用户，password 为空时没有本地密码
+func (e *testEnv) createUser(t *testing.T, username, email, password string, roles ...string) *models.User {
+	t.Helper()
+	user := &models.User{Username: username, Email: email, Status: models.UserStatusActive}
+	if password != "" {
+		hash, err := utils.HashPassword(password)
+		if err != nil {
+			t.Fatal(err)
+		}
+		user.Password = hash
+	}
+	if err := e.db.Create(user).Error; err != nil {
+		t.Fatalf("create user %s: %v", username, err)
+	}
+	e.grant(t, user.ID, roles...)
+	return user
+}
+
+// grant 为用户分配角色
+func (e *testEnv) grant(t *testing.T, userID uint, roles ...string) {
+	t.Helper()
+	for _, code := range roles {
+		var role models.Role
+		if err := e.db.Where("code = ?", code).First(&role).Error; err != nil {
+			t.Fatalf("role %s: %v", code, err)
+		}
+		if err := e.db.Create(&models.UserRole{UserID: userID, RoleID: role.ID}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// user 按用户名查询用户，不存在时返回 nil
+func (e *testEnv) user(t *testing.T, username string) *models.User {
+	t.Helper()
+	var users []models.User
+	if err := e.db.Where("username = ?", username).Find(&users).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(users) == 0 {
+		return nil
+	}
+	return &users[0]
+}
+
+// roles 返回用户的角色编码，按字母排序
+func (e *testEnv) roles(t *testing.T, userID uint) []string {
+	t.Helper()
+	roles, _, err := userRolesAndPermissions(e.db, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(roles)
+	return roles
+}
+
+// events 取出队列中的安全日志事件
+func (e *testEnv) events() []string {
+	var events []string
+	for {
+		select {
+		case log := <-e.logs.queue:
+			events = append(events, log.Event)
+		default:
+			return events
+		}
+	}
+}
@@ -0,0 +1,304 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"stars-admin/internal/apperrors"
+	"stars-admin/internal/database"
+	"stars-admin/internal/metrics"
+	"stars-admin/internal/models"
+	"stars-admin/internal/tracing"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// errIdentityNotLinked 外部身份无法关联到用户，调用方转换为各登录方式的错误
+var errIdentityNotLinked = errors.New("identity is not linked to any user")
+
+// loginSource 登录请求的来源，用于安全日志
+type loginSource struct {
+	ip        string
+	userAgent string
+	method    string
+	path      string
+}
+
+// loginIdentity 按认证方式确认的身份确定用户，检查用户状态、验证第二因素、同步角色后签发令牌
+// 确定了用户之后的失败同时返回该用户，供调用方记录日志
+func (s *AuthService) loginIdentity(identity *Identity, src loginSource) (*models.User, *LoginResponse, error) {
+	user, err := s.identityUser(identity, src)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := userStatusError(user); err != nil {
+		metrics.LoginAttempts.WithLabelValues("disabled").Inc()
+		return user, nil, err
+	}
+	if s.secondFactor != nil && !identity.MFA {
+		if err := s.secondFactor.Verify(s.ctx, user, identity); err != nil {
+			metrics.LoginAttempts.WithLabelValues("second_factor").Inc()
+			return user, nil, err
+		}
+	}
+	if err := s.syncIdentityRoles(identity, user); err != nil {
+		return user, nil, err
+	}
+
+	resp, err := s.completeLogin(user, src.ip, src.userAgent, src.path)
+	if err != nil {
+		return user, nil, err
+	}
+
+	if identity.Provider != "" {
+		now := time.Now()
+		s.db.Model(&models.UserIdentity{}).
+			Where("provider = ? AND subject = ?", identity.Provider, identity.Subject).
+			Updates(map[string]interface{}{"email": identity.Email, "last_login_at": &now})
+	}
+	return user, resp, nil
+}
+
+// syncIdentityRoles 按身份映射的角色同步用户的角色，没有映射时不处理
+func (s *AuthService) syncIdentityRoles(identity *Identity, user *models.User) error {
+	if len(identity.ManagedRoles) == 0 {
+		return nil
+	}
+	wanted := make(map[string]bool, len(identity.Roles))
+	for _, role := range identity.Roles {
+		wanted[role] = true
+	}
+	return syncMappedRoles(s.db, user.ID, identity.ManagedRoles, wanted)
+}
+
+// identityUser 返回身份对应的用户，外部身份未关联时按配置以确认过的邮箱关联已有用户，没有时按配置自动创建用户
+func (s *AuthService) identityUser(identity *Identity, src loginSource) (*models.User, error) {
+	var user models.User
+	if identity.UserID != 0 {
+		if err := database.UsePrimary(s.db).First(&user, identity.UserID).Error; err != nil {
+			return nil, err
+		}
+		return &user, nil
+	}
+
+	var linked models.UserIdentity
+	err := database.UsePrimary(s.db).Where("provider = ? AND subject = ?", identity.Provider, identity.Subject).First(&linked).Error
+	if err == nil {
+		err = database.UsePrimary(s.db).First(&user, linked.UserID).Error
+		if err == nil {
+			return &user, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		// 关联的用户已删除，按首次登录重新关联
+		if err := s.db.Delete(&linked).Error; err != nil {
+			return nil, err
+		}
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	// 只使用认证方式确认过的邮箱关联或创建用户，避免通过自行填写的邮箱接管他人账号
+	if identity.Email == "" || !identity.EmailVerified {
+		return nil, fmt.Errorf("%w: %s has no verified email", errIdentityNotLinked, identity)
+	}
+
+	err = database.UsePrimary(s.db).Where("email = ?", identity.Email).First(&user).Error
+	switch {
+	case err == nil:
+		if err := linkableUser(s.db, identity, &user); err != nil {
+			return nil, err
+		}
+		if err := linkIdentity(s.db, user.ID, identity.Provider, identity.Subject, identity.Email); err != nil {
+			return nil, err
+		}
+		// 事件名为 <认证方式>_identity_linked，如 oidc_identity_linked、ldap_identity_linked
+		s.record(identity.Authenticator+"_identity_linked", &user, src,
+			fmt.Sprintf("%s linked by email %s", identity, identity.Email))
+		return &user, nil
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, err
+	}
+
+	if !identity.AutoProvision {
+		return nil, fmt.Errorf("%w: no user with email %s", errIdentityNotLinked, identity.Email)
+	}
+	// 已删除用户的邮箱不能再使用
+	if err := checkEmailAvailable(s.db, identity.Email); err != nil {
+		if errors.Is(err, apperrors.ErrEmailTaken) {
+			return nil, fmt.Errorf("%w: email %s belongs to a deleted user", errIdentityNotLinked, identity.Email)
+		}
+		return nil, err
+	}
+
+	username := identity.Username
+	if username == "" {
+		username, _, _ = strings.Cut(identity.Email, "@")
+	}
+	created := &models.User{Email: identity.Email, Nickname: identity.Name}
+	link := &models.UserIdentity{Provider: identity.Provider, Subject: identity.Subject, Email: identity.Email}
+	if err := provisionUser(s.db, created, username, identity.DefaultRoles, link); err != nil {
+		return nil, err
+	}
+	s.record(SecurityEventUserProvisioned, created, src, identity.String())
+	return created, nil
+}
+
+// linkableUser 检查身份能否按邮箱关联到已有用户，不能时返回 errIdentityNotLinked
+// 同邮箱的用户存在时不会再创建用户，因此不能关联的身份只能由管理员处理
+func linkableUser(db *gorm.DB, identity *Identity, user *models.User) error {
+	if !identity.LinkByEmail {
+		return fmt.Errorf("%w: email %s belongs to user %s and linking by email is disabled", errIdentityNotLinked, identity.Email, user.Username)
+	}
+	if user.ServiceAccount {
+		return fmt.Errorf("%w: email %s belongs to a service account", errIdentityNotLinked, identity.Email)
+	}
+	if user.Password != "" && !identity.LinkPasswordUsers {
+		return fmt.Errorf("%w: email %s belongs to user %s with a local password", errIdentityNotLinked, identity.Email, user.Username)
+	}
+	privileged, err := hasRole(db, user.ID, models.RoleAdmin)
+	if err != nil {
+		return err
+	}
+	if privileged {
+		return fmt.Errorf("%w: email %s belongs to privileged user %s", errIdentityNotLinked, identity.Email, user.Username)
+	}
+	return nil
+}
+
+// hasRole 检查用户是否有指定角色，不论角色是否启用
+func hasRole(db *gorm.DB, userID uint, code string) (bool, error) {
+	roleIDs := db.Model(&models.UserRole{}).Select("role_id").Where("user_id = ?", userID)
+	var count int64
+	if err := database.UsePrimary(db).Model(&models.Role{}).Where("id IN (?) AND code = ?", roleIDs, code).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// record 记录登录过程中的安全事件
+func (s *AuthService) record(event string, user *models.User, src loginSource, detail string) {
+	s.securityLogs.Record(models.SecurityLog{
+		Event:     event,
+		UserID:    user.ID,
+		Username:  user.Username,
+		IP:        src.ip,
+		Method:    src.method,
+		Path:      src.path,
+		UserAgent: src.userAgent,
+		Detail:    detail,
+		TraceID:   tracing.TraceID(s.ctx),
+		CreatedAt: time.Now(),
+	})
+}
+
+// linkIdentity 将外部身份关联到用户
+func linkIdentity(db *gorm.DB, userID uint, provider, subject, email string) error {
+	return db.Create(&models.UserIdentity{
+		UserID:   userID,
+		Provider: provider,
+		Subject:  subject,
+		Email:    email,
+	}).Error
+}
+
+// provisionUser 为外部身份创建用户并分配默认角色，username 已被使用时追加数字后缀
+// 创建的用户没有密码，只能通过外部身份登录，需要时可通过重置密码设置
+func provisionUser(db *gorm.DB, user *models.User, username string, defaultRoles []string, identity *models.UserIdentity) error {
+	var err error
+	if user.Username, err = availableUsername(db, username); err != nil {
+		return err
+	}
+	roles, err := grantableRoles(db, unprivilegedRoles(defaultRoles))
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	user.Nickname = truncate(user.Nickname, 50)
+	user.Status = models.UserStatusActive
+	user.EmailVerifiedAt = &now
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		for _, role := range roles {
+			if err := tx.Create(&models.UserRole{UserID: user.ID, RoleID: role.ID}).Error; err != nil {
+				return err
+			}
+		}
+		identity.UserID = user.ID
+		return tx.Create(identity).Error
+	})
+}
+
+// availableUsername 返回可用的用户名，已被使用时追加数字后缀
+func availableUsername(db *gorm.DB, base string) (string, error) {
+	base = truncate(base, 40)
+	if len(base) < 3 {
+		base = "user_" + base
+	}
+
+	for i := 1; i <= 100; i++ {
+		username := base
+		if i > 1 {
+			username = fmt.Sprintf("%s%d", base, i)
+		}
+		err := checkUsernameAvailable(db, username)
+		if err == nil {
+			return username, nil
+		}
+		if !errors.Is(err, apperrors.ErrUsernameTaken) {
+			return "", err
+		}
+	}
+	return "", apperrors.ErrUsernameTaken
+}
+
+// syncMappedRoles 同步外部身份映射的角色：managed 中 wanted 的角色授予，其余撤销，其他角色和超级管理员角色保持不变
+func syncMappedRoles(db *gorm.DB, userID uint, managed []string, wanted map[string]bool) error {
+	roles, err := grantableRoles(db, unprivilegedRoles(managed))
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var current []uint
+		if err := tx.Model(&models.UserRole{}).Where("user_id = ?", userID).Pluck("role_id", &current).Error; err != nil {
+			return err
+		}
+		has := make(map[uint]bool, len(current))
+		for _, id := range current {
+			has[id] = true
+		}
+
+		var granted, revoked []string
+		for _, role := range roles {
+			switch {
+			case wanted[role.Code] && !has[role.ID]:
+				if err := tx.Create(&models.UserRole{UserID: userID, RoleID: role.ID}).Error; err != nil {
+					return err
+				}
+				granted = append(granted, role.Code)
+			case !wanted[role.Code] && has[role.ID]:
+				if err := tx.Where("user_id = ? AND role_id = ?", userID, role.ID).Delete(&models.UserRole{}).Error; err != nil {
+					return err
+				}
+				revoked = append(revoked, role.Code)
+			}
+		}
+
+		if len(granted) > 0 || len(revoked) > 0 {
+			logrus.WithFields(logrus.Fields{
+				"user_id": userID,
+				"granted": granted,
+				"revoked": revoked,
+			}).Info("Roles synced from identity provider")
+		}
+		return nil
+	})
+}
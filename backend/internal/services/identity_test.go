@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"stars-admin/internal/apperrors"
+	"stars-admin/internal/models"
+	"stars-admin/internal/store"
+	"testing"
+)
+
+var errSecondFactor = errors.New("second factor required")
+
+// recordingFactor 记录第二因素验证的调用，allow 为 false 时拒绝登录
+type recordingFactor struct {
+	allow bool
+	calls []string
+}
+
+func (f *recordingFactor) Verify(_ context.Context, user *models.User, identity *Identity) error {
+	f.calls = append(f.calls, identity.Authenticator+":"+user.Username)
+	if !f.allow {
+		return errSecondFactor
+	}
+	return nil
+}
+
+func TestSecondFactor(t *testing.T) {
+	env := newTestEnv(t)
+	_, ldapCfg := newLDAPTest(t, ldapBob)
+	factor := &recordingFactor{}
+	env.auth = env.auth.WithSecondFactor(factor)
+	idp, oidcSvc := newOIDCTest(t, env, nil)
+
+	carol := env.createUser(t, "carol", "carol@example.org", "Carol123456", "user")
+	disabled := env.createUser(t, "dan", "dan@example.org", "Dan123456")
+	env.db.Model(disabled).Update("status", models.UserStatusDisabled)
+
+	// 第二因素未通过时不签发令牌
+	resp, err := env.auth.Login(&LoginRequest{Username: "carol", Password: "Carol123456", IP: "127.0.0.1"})
+	if !errors.Is(err, errSecondFactor) || resp != nil {
+		t.Fatalf("local login: got %+v, %v", resp, err)
+	}
+	if _, err := env.st.Get(context.Background(), fmt.Sprintf("refresh_token:%d", carol.ID)); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("refresh token should not be issued, got %v", err)
+	}
+	if user := env.user(t, "carol"); user.LastLoginAt != nil {
+		t.Error("last login time should not be updated")
+	}
+
+	// 用户状态先于第二因素检查
+	if _, err := env.auth.Login(&LoginRequest{Username: "dan", Password: "Dan123456", IP: "127.0.0.1"}); !errors.Is(err, apperrors.ErrUserDisabled) {
+		t.Errorf("disabled user: got %v", err)
+	}
+
+	// LDAP 和 OpenID Connect 登录同样验证第二因素
+	if _, err := ldapLogin(env, ldapCfg, "bob", "bob123"); !errors.Is(err, errSecondFactor) {
+		t.Errorf("ldap login: got %v", err)
+	}
+	erin := oidcErin
+	idp.SetUser(&erin)
+	if _, err := oidcLogin(t, idp, oidcSvc); err == nil {
+		t.Error("oidc login should require the second factor")
+	}
+	want := []string{"local:carol", "ldap:bob", "oidc:erin"}
+	if !reflect.DeepEqual(factor.calls, want) {
+		t.Errorf("second factor calls: got %v, want %v", factor.calls, want)
+	}
+
+	// 身份提供方已完成多因素认证时不再验证
+	erin.AMR = []string{"pwd", "mfa"}
+	if resp, err := oidcLogin(t, idp, oidcSvc); err != nil || resp.AccessToken == "" {
+		t.Errorf("oidc login with mfa: got %+v, %v", resp, err)
+	}
+	if len(factor.calls) != len(want) {
+		t.Errorf("second factor should be skipped, got calls %v", factor.calls)
+	}
+
+	factor.allow = true
+	if resp, err := env.auth.Login(&LoginRequest{Username: "carol", Password: "Carol123456", IP: "127.0.0.1"}); err != nil || resp.AccessToken == "" {
+		t.Errorf("verified login: got %+v, %v", resp, err)
+	}
+}
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"stars-admin/internal/apperrors"
+	"stars-admin/internal/config"
+	"stars-admin/internal/database"
+	"stars-admin/internal/ldap"
+	"stars-admin/internal/models"
+	"stars-admin/internal/store"
+	"stars-admin/internal/tracing"
+	"stars-admin/internal/utils"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// ldapProvider 外部身份表中 LDAP 身份的提供方名称，subject 为小写的目录用户名
+const ldapProvider = AuthenticatorLDAP
+
+// ldapAuthenticator 通过 LDAP 目录验证用户名和密码
+// 已关联 LDAP 身份的用户只能通过目录验证；本地存在同名用户且未关联时交给其他认证方式；
+// 都不存在时到目录中验证，目录中也没有该用户时交给其他认证方式
+type ldapAuthenticator struct {
+	db        *gorm.DB
+	directory *ldap.Directory
+	cfg       config.LDAPConfig
+}
+
+// NewLDAPAuthenticator 创建 LDAP 认证方式
+func NewLDAPAuthenticator(db *gorm.DB, cfg config.LDAPConfig) Authenticator {
+	return &ldapAuthenticator{db: db, directory: ldap.New(cfg), cfg: cfg}
+}
+
+// Name 认证方式名称
+func (a *ldapAuthenticator) Name() string {
+	return AuthenticatorLDAP
+}
+
+// Authenticate 绑定验证密码，返回目录用户的身份和按所属的组映射的角色
+func (a *ldapAuthenticator) Authenticate(ctx context.Context, creds Credentials) (*Identity, error) {
+	req, ok := creds.(*LoginRequest)
+	if !ok {
+		return nil, ErrAuthenticatorSkip
+	}
+
+	db := a.db.WithContext(ctx)
+	subject := strings.ToLower(req.Username)
+
+	var linked int64
+	if err := database.UsePrimary(db).Model(&models.UserIdentity{}).Where("provider = ? AND subject = ?", ldapProvider, subject).Count(&linked).Error; err != nil {
+		return nil, err
+	}
+	if linked == 0 {
+		var count int64
+		if err := database.UsePrimary(db).Model(&models.User{}).Where("username = ?", req.Username).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			return nil, ErrAuthenticatorSkip
+		}
+	}
+
+	entry, err := a.directory.Authenticate(req.Username, req.Password)
+	switch {
+	case errors.Is(err, ldap.ErrUserNotFound) && linked == 0:
+		return nil, ErrAuthenticatorSkip
+	case errors.Is(err, ldap.ErrInvalidCredentials) || errors.Is(err, ldap.ErrUserNotFound):
+		return nil, apperrors.ErrInvalidCredentials
+	case err != nil:
+		return nil, apperrors.ErrLDAPUnavailable.Wrap(err)
+	}
+
+	// 目录中的邮箱由管理员维护，视为已确认
+	identity := &Identity{
+		Authenticator: AuthenticatorLDAP,
+		Provider:      ldapProvider,
+		Subject:       strings.ToLower(entry.Username),
+		Username:      entry.Username,
+		Email:         entry.Email,
+		EmailVerified: true, // 目录中的邮箱用于创建用户，是否用于关联已有用户由 link_by_email 决定
+		Name:          entry.Name,
+		LinkByEmail:   a.cfg.LinkByEmail,
+		AutoProvision: a.cfg.AutoProvision,
+		DefaultRoles:  a.cfg.DefaultRoles,
+	}
+	for _, m := range a.cfg.RoleMapping {
+		identity.ManagedRoles = append(identity.ManagedRoles, m.Role)
+		if entry.Has(m.Group) {
+			identity.Roles = append(identity.Roles, m.Role)
+		}
+	}
+	return identity, nil
+}
+
+// LDAPSyncResult 一次目录同步的结果
+type LDAPSyncResult struct {
+	Checked  int      `json:"checked"`  // 检查的 LDAP 用户数
+	Disabled []string `json:"disabled"` // 目录中已不存在而被禁用的用户名
+}
+
+// LDAPSync 定期检查关联了 LDAP 身份的用户，禁用目录中已不存在的用户
+// 只禁用不恢复，用户重新出现在目录中时需要管理员手动启用
+type LDAPSync struct {
+	db           *gorm.DB
+	st           store.Store
+	directory    *ldap.Directory
+	securityLogs *SecurityLogWriter
+	interval     time.Duration
+
+	mu   sync.Mutex // 定期同步和手动同步不并发执行
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewLDAPSync 创建目录同步，LDAP 未启用时返回 nil
+func NewLDAPSync(db *gorm.DB, st store.Store, securityLogs *SecurityLogWriter, cfg config.LDAPConfig) *LDAPSync {
+	if !cfg.Enabled {
+		return nil
+	}
+	return &LDAPSync{
+		db:           db,
+		st:           st,
+		directory:    ldap.New(cfg),
+		securityLogs: securityLogs,
+		interval:     time.Duration(cfg.SyncInterval) * time.Minute,
+		stop:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+}
+
+// Name 组件名称
+func (s *LDAPSync) Name() string {
+	return "ldap-sync"
+}
+
+// Start 启动定期同步，sync_interval 为 0 时只支持手动同步
+func (s *LDAPSync) Start(context.Context) error {
+	if s.interval <= 0 {
+		close(s.done)
+		return nil
+	}
+	go s.run()
+	return nil
+}
+
+// Stop 停止定期同步，等待正在进行的同步结束
+func (s *LDAPSync) Stop(ctx context.Context) error {
+	select {
+	case <-s.stop:
+	default:
+		close(s.stop)
+	}
+
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run 按间隔同步
+func (s *LDAPSync) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := s.Sync(context.Background()); err != nil {
+				logrus.WithError(err).Error("LDAP sync failed")
+			}
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// Sync 立即同步一次
+// 目录查询失败或没有返回任何用户时不做处理，避免配置错误或目录故障导致禁用全部用户
+func (s *LDAPSync) Sync(ctx context.Context) (*LDAPSyncResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	usernames, err := s.directory.Usernames()
+	if err != nil {
+		return nil, apperrors.ErrLDAPUnavailable.Wrap(err)
+	}
+	if len(usernames) == 0 {
+		return nil, apperrors.ErrLDAPUnavailable.Wrap(errors.New("directory returned no users"))
+	}
+
+	db := s.db.WithContext(ctx)
+	var identities []models.UserIdentity
+	if err := db.Where("provider = ?", ldapProvider).Find(&identities).Error; err != nil {
+		return nil, err
+	}
+
+	result := &LDAPSyncResult{Checked: len(identities), Disabled: []string{}}
+	for _, identity := range identities {
+		if usernames[identity.Subject] {
+			continue
+		}
+
+		var user models.User
+		if err := db.First(&user, identity.UserID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			}
+			return result, err
+		}
+		if user.Status == models.UserStatusDisabled {
+			continue
+		}
+
+		if err := db.Model(&user).Update("status", models.UserStatusDisabled).Error; err != nil {
+			return result, err
+		}
+		// 已签发的刷新令牌随之失效，访问令牌在有效期内仍可使用
+		if err := utils.DeleteRefreshToken(ctx, s.st, user.ID); err != nil {
+			logrus.WithError(err).WithField("user_id", user.ID).Warn("Failed to delete refresh token of disabled user")
+		}
+		s.securityLogs.Record(models.SecurityLog{
+			Event:     SecurityEventLDAPUserDisabled,
+			UserID:    user.ID,
+			Username:  user.Username,
+			Detail:    "directory user " + identity.Subject + " no longer exists",
+			TraceID:   tracing.TraceID(ctx),
+			CreatedAt: time.Now(),
+		})
+		result.Disabled = append(result.Disabled, user.Username)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"directory_users": len(usernames),
+		"checked":         result.Checked,
+		"disabled":        len(result.Disabled),
+	}).Info("LDAP sync finished")
+	return result, nil
+}
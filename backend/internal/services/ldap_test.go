@@ -0,0 +1,285 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"stars-admin/internal/apperrors"
+	"stars-admin/internal/config"
+	"stars-admin/internal/ldap/ldaptest"
+	"stars-admin/internal/models"
+	"stars-admin/internal/utils"
+	"testing"
+)
+
+func testLDAPDirectory(users ...ldaptest.User) *ldaptest.Directory {
+	return &ldaptest.Directory{
+		BaseDN:       "dc=example,dc=org",
+		BindDN:       "cn=admin,dc=example,dc=org",
+		BindPassword: "admin",
+		Users:        users,
+	}
+}
+
+var (
+	ldapAlice = ldaptest.User{UID: "alice", Password: "alice123", Mail: "alice@example.org", CN: "Alice", Groups: []string{"ops-team", "staff"}}
+	ldapBob   = ldaptest.User{UID: "bob", Password: "bob123", Mail: "bob@example.org", CN: "Bob", Groups: []string{"staff"}}
+)
+
+// newLDAPTest 启动目录服务器，返回 LDAP 配置，ops-team 组映射到 ops 角色
+func newLDAPTest(t *testing.T, users ...ldaptest.User) (*ldaptest.Server, config.LDAPConfig) {
+	t.Helper()
+	srv := ldaptest.NewServer(testLDAPDirectory(users...))
+	t.Cleanup(srv.Close)
+
+	return srv, config.LDAPConfig{
+		Enabled:            true,
+		URL:                srv.URL,
+		Timeout:            5,
+		BindDN:             "cn=admin,dc=example,dc=org",
+		BindPassword:       "admin",
+		BaseDN:             "dc=example,dc=org",
+		UserFilter:         "(&(objectClass=person)(uid=%s))",
+		UsernameAttribute:  "uid",
+		EmailAttribute:     "mail",
+		NameAttribute:      "cn",
+		GroupFilter:        "(&(objectClass=groupOfNames)(member=%s))",
+		GroupNameAttribute: "cn",
+		AutoProvision:      true,
+		DefaultRoles:       []string{"user"},
+		RoleMapping:        []config.LDAPRoleMapping{{Group: "ops-team", Role: "ops"}},
+	}
+}
+
+func ldapLogin(env *testEnv, cfg config.LDAPConfig, username, password string) (*LoginResponse, error) {
+	auth := env.auth.WithAuthenticators([]Authenticator{NewLDAPAuthenticator(env.db, cfg), NewLocalAuthenticator(env.db)})
+	return auth.Login(&LoginRequest{Username: username, Password: password, IP: "127.0.0.1"})
+}
+
+func TestLDAPAuthenticator(t *testing.T) {
+	env := newTestEnv(t)
+	_, cfg := newLDAPTest(t, ldapAlice)
+	env.createUser(t, "admin", "admin@example.org", "Admin123456", models.RoleAdmin)
+	a := NewLDAPAuthenticator(env.db, cfg)
+
+	identity, err := a.Authenticate(context.Background(), &LoginRequest{Username: "Alice", Password: "alice123"})
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if identity.Provider != ldapProvider || identity.Subject != "alice" || identity.Email != "alice@example.org" || identity.LinkByEmail {
+		t.Errorf("unexpected identity %+v", identity)
+	}
+	if !reflect.DeepEqual(identity.ManagedRoles, []string{"ops"}) || !reflect.DeepEqual(identity.Roles, []string{"ops"}) {
+		t.Errorf("got managed roles %v, roles %v", identity.ManagedRoles, identity.Roles)
+	}
+
+	tests := []struct {
+		name     string
+		username string
+		password string
+		want     error
+	}{
+		{"wrong password", "alice", "wrong", apperrors.ErrInvalidCredentials},
+		{"not in directory", "mallory", "mallory123", ErrAuthenticatorSkip},
+		{"filter injection", "alice)(uid=*", "alice123", ErrAuthenticatorSkip},
+		// 本地同名用户交给 local 验证，目录故障不影响本地管理员
+		{"local user", "admin", "Admin123456", ErrAuthenticatorSkip},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := a.Authenticate(context.Background(), &LoginRequest{Username: tt.username, Password: tt.password}); !errors.Is(err, tt.want) {
+				t.Errorf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	// 已关联目录身份的用户从目录中删除后不再交给其他认证方式
+	if _, err := ldapLogin(env, cfg, "alice", "alice123"); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	cfg.BaseDN = "ou=nobody,dc=example,dc=org"
+	if _, err := NewLDAPAuthenticator(env.db, cfg).Authenticate(context.Background(), &LoginRequest{Username: "alice", Password: "alice123"}); !errors.Is(err, apperrors.ErrInvalidCredentials) {
+		t.Errorf("linked user missing from directory: got %v, want invalid credentials", err)
+	}
+
+	cfg.URL = "ldap://127.0.0.1:1"
+	if _, err := NewLDAPAuthenticator(env.db, cfg).Authenticate(context.Background(), &LoginRequest{Username: "alice", Password: "alice123"}); !errors.Is(err, apperrors.ErrLDAPUnavailable) {
+		t.Errorf("directory down: got %v, want LDAP unavailable", err)
+	}
+}
+
+func TestLDAPLoginChain(t *testing.T) {
+	env := newTestEnv(t)
+	_, cfg := newLDAPTest(t, ldapAlice)
+	env.createUser(t, "admin", "admin@example.org", "Admin123456", models.RoleAdmin)
+
+	if _, err := ldapLogin(env, cfg, "admin", "Admin123456"); err != nil {
+		t.Errorf("local admin should log in through the local authenticator: %v", err)
+	}
+	if _, err := ldapLogin(env, cfg, "mallory", "mallory123"); !errors.Is(err, apperrors.ErrInvalidCredentials) {
+		t.Errorf("unknown user: got %v, want invalid credentials", err)
+	}
+	if _, err := ldapLogin(env, cfg, "alice", "wrong"); !errors.Is(err, apperrors.ErrInvalidCredentials) {
+		t.Errorf("wrong password: got %v, want invalid credentials", err)
+	}
+}
+
+func TestLDAPRoleMapping(t *testing.T) {
+	env := newTestEnv(t)
+	srv, cfg := newLDAPTest(t, ldapAlice)
+
+	resp, err := ldapLogin(env, cfg, "alice", "alice123")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if got := env.roles(t, resp.User.ID); !reflect.DeepEqual(got, []string{"ops", "user"}) {
+		t.Errorf("provisioned roles: got %v, want [ops user]", got)
+	}
+	if !containsString(env.events(), SecurityEventUserProvisioned) {
+		t.Error("provisioning should be recorded")
+	}
+
+	// 移出映射的组后撤销映射的角色，默认角色不受影响
+	moved := ldapAlice
+	moved.Groups = []string{"staff"}
+	srv.SetDirectory(testLDAPDirectory(moved))
+	if _, err := ldapLogin(env, cfg, "alice", "alice123"); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if got := env.roles(t, resp.User.ID); !reflect.DeepEqual(got, []string{"user"}) {
+		t.Errorf("roles after leaving ops-team: got %v, want [user]", got)
+	}
+
+	// 重新加入后再次授予
+	srv.SetDirectory(testLDAPDirectory(ldapAlice))
+	if _, err := ldapLogin(env, cfg, "alice", "alice123"); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if got := env.roles(t, resp.User.ID); !reflect.DeepEqual(got, []string{"ops", "user"}) {
+		t.Errorf("roles after rejoining ops-team: got %v, want [ops user]", got)
+	}
+}
+
+func TestSyncMappedRolesKeepsPrivilegedRoles(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t, "root", "root@example.org", "", models.RoleAdmin, "ops")
+
+	if err := syncMappedRoles(env.db, user.ID, []string{models.RoleAdmin, "ops", "user"}, map[string]bool{"user": true}); err != nil {
+		t.Fatalf("syncMappedRoles: %v", err)
+	}
+	if got := env.roles(t, user.ID); !reflect.DeepEqual(got, []string{models.RoleAdmin, "user"}) {
+		t.Errorf("got %v, want [admin user]", got)
+	}
+
+	if err := syncMappedRoles(env.db, user.ID, []string{models.RoleAdmin}, map[string]bool{models.RoleAdmin: true}); err != nil {
+		t.Fatalf("syncMappedRoles: %v", err)
+	}
+	other := env.createUser(t, "other", "other@example.org", "")
+	if err := syncMappedRoles(env.db, other.ID, []string{models.RoleAdmin}, map[string]bool{models.RoleAdmin: true}); err != nil {
+		t.Fatalf("syncMappedRoles: %v", err)
+	}
+	if got := env.roles(t, other.ID); len(got) != 0 {
+		t.Errorf("admin must not be granted by role mapping, got %v", got)
+	}
+}
+
+func TestLDAPLinkByEmail(t *testing.T) {
+	env := newTestEnv(t)
+	_, cfg := newLDAPTest(t, ldapAlice, ldapBob)
+	// 外部身份创建的用户没有本地密码
+	external := env.createUser(t, "alice.l", "alice@example.org", "")
+	withPassword := env.createUser(t, "bob.b", "bob@example.org", "Bob123456")
+
+	if _, err := ldapLogin(env, cfg, "alice", "alice123"); !errors.Is(err, apperrors.ErrInvalidCredentials) {
+		t.Fatalf("linking by email is disabled by default: got %v", err)
+	}
+	if u := env.user(t, "alice"); u != nil {
+		t.Fatalf("no user should be provisioned for a taken email, got %+v", u)
+	}
+
+	cfg.LinkByEmail = true
+	resp, err := ldapLogin(env, cfg, "alice", "alice123")
+	if err != nil {
+		t.Fatalf("Login with link_by_email: %v", err)
+	}
+	if resp.User.ID != external.ID {
+		t.Errorf("logged in as user %d, want %d", resp.User.ID, external.ID)
+	}
+	if !containsString(env.events(), SecurityEventLDAPIdentityLinked) {
+		t.Error("linking should be recorded")
+	}
+
+	if _, err := ldapLogin(env, cfg, "bob", "bob123"); !errors.Is(err, apperrors.ErrInvalidCredentials) {
+		t.Errorf("user with a local password must not be linked: got %v", err)
+	}
+	var count int64
+	env.db.Model(&models.UserIdentity{}).Where("user_id = ?", withPassword.ID).Count(&count)
+	if count != 0 {
+		t.Error("identity linked to a user with a local password")
+	}
+
+	// 超级管理员即使没有本地密码也不会被关联
+	if err := env.db.Model(withPassword).Update("password", "").Error; err != nil {
+		t.Fatal(err)
+	}
+	env.grant(t, withPassword.ID, models.RoleAdmin)
+	if _, err := ldapLogin(env, cfg, "bob", "bob123"); !errors.Is(err, apperrors.ErrInvalidCredentials) {
+		t.Errorf("admin user must not be linked: got %v", err)
+	}
+}
+
+func TestLDAPSync(t *testing.T) {
+	env := newTestEnv(t)
+	srv, cfg := newLDAPTest(t, ldapAlice, ldapBob)
+	sync := NewLDAPSync(env.db, env.st, env.logs, cfg)
+
+	alice, err := ldapLogin(env, cfg, "alice", "alice123")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	bob, err := ldapLogin(env, cfg, "bob", "bob123")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	local := env.createUser(t, "carol", "carol@example.org", "Carol123456")
+	env.events()
+
+	// 目录没有返回任何用户时不做处理
+	srv.SetDirectory(testLDAPDirectory())
+	if _, err := sync.Sync(context.Background()); !errors.Is(err, apperrors.ErrLDAPUnavailable) {
+		t.Errorf("empty directory: got %v, want LDAP unavailable", err)
+	}
+
+	srv.SetDirectory(testLDAPDirectory(ldapAlice))
+	result, err := sync.Sync(context.Background())
+	if err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if result.Checked != 2 || !reflect.DeepEqual(result.Disabled, []string{"bob"}) {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	if u := env.user(t, "bob"); u.Status != models.UserStatusDisabled {
+		t.Errorf("bob should be disabled, got status %d", u.Status)
+	}
+	if u := env.user(t, "alice"); u.Status != models.UserStatusActive {
+		t.Errorf("alice should stay active, got status %d", u.Status)
+	}
+	if u := env.user(t, local.Username); u.Status != models.UserStatusActive {
+		t.Errorf("local users are not synced, got status %d", u.Status)
+	}
+
+	if ok, _ := utils.ValidateRefreshToken(context.Background(), env.st, bob.User.ID, bob.RefreshToken); ok {
+		t.Error("refresh token of the disabled user should be revoked")
+	}
+	if ok, _ := utils.ValidateRefreshToken(context.Background(), env.st, alice.User.ID, alice.RefreshToken); !ok {
+		t.Error("refresh token of alice should stay valid")
+	}
+	if !containsString(env.events(), SecurityEventLDAPUserDisabled) {
+		t.Error("disabling should be recorded")
+	}
+
+	if _, err := ldapLogin(env, cfg, "bob", "bob123"); !errors.Is(err, apperrors.ErrInvalidCredentials) {
+		t.Errorf("removed user: got %v, want invalid credentials", err)
+	}
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"stars-admin/internal/apperrors"
+	"stars-admin/internal/ipfilter"
+	"stars-admin/internal/models"
+	"stars-admin/internal/store"
+	"stars-admin/internal/utils"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// SetUserAllowedIPs 设置用户允许登录和访问的 IP 或网段，ips 为空时取消限制
+// 网段统一保存为 CIDR 格式；修改后删除用户的刷新令牌，已签发的访问令牌在过期前仍按旧范围检查
+// operatorID 和 operatorIP 用于防止管理员把自己当前的 IP 排除在外
+func SetUserAllowedIPs(ctx context.Context, db *gorm.DB, st store.Store, userID uint, ips []string, operatorID uint, operatorIP string) ([]string, error) {
+	var fields []apperrors.FieldError
+	normalized := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		prefix, err := ipfilter.ParsePrefix(ip)
+		if err != nil {
+			fields = append(fields, apperrors.FieldError{Field: "allowed_ips", Rule: "cidr"})
+			continue
+		}
+		normalized = append(normalized, prefix.String())
+	}
+	if len(fields) > 0 {
+		return nil, apperrors.ErrValidation.WithDetails(fields)
+	}
+
+	value := strings.Join(normalized, ",")
+	if len(value) > 1000 {
+		return nil, apperrors.ErrValidation.WithDetails([]apperrors.FieldError{{Field: "allowed_ips", Rule: "max", Param: "1000"}})
+	}
+
+	if userID == operatorID && value != "" {
+		prefixes, _ := ipfilter.ParseList(value)
+		if !ipfilter.Contains(prefixes, operatorIP) {
+			return nil, apperrors.ErrIPRuleLockout.WithDetails(map[string]string{"ip": operatorIP})
+		}
+	}
+
+	var user models.User
+	if err := db.WithContext(ctx).First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.ErrUserNotFound
+		}
+		return nil, err
+	}
+	if err := db.WithContext(ctx).Model(&user).Update("allowed_ips", value).Error; err != nil {
+		return nil, err
+	}
+
+	if err := utils.DeleteRefreshToken(ctx, st, userID); err != nil {
+		logrus.WithError(err).WithField("user_id", userID).Warn("Failed to revoke refresh token after allowed IPs change")
+	}
+	logrus.WithFields(logrus.Fields{"user_id": userID, "allowed_ips": value, "operator_id": operatorID}).Info("User allowed IPs updated")
+
+	return normalized, nil
+}
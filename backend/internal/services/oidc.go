@@ -0,0 +1,402 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"stars-admin/internal/apperrors"
+	"stars-admin/internal/config"
+	"stars-admin/internal/models"
+	"stars-admin/internal/oidc"
+	"stars-admin/internal/store"
+	"stars-admin/internal/tracing"
+	"stars-admin/internal/utils"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// oidcLoginCodeTTL 登录完成后前端换取令牌的一次性 code 有效期
+const oidcLoginCodeTTL = time.Minute
+
+// OIDCService OpenID Connect 登录服务，使用授权码 + PKCE 流程
+// 回调的授权码交给 AuthService 的认证方式链，由 oidc 认证方式换取并校验 ID 令牌，关联、创建用户和签发令牌由 AuthService 统一处理
+type OIDCService struct {
+	db           *gorm.DB
+	st           store.Store
+	auth         *AuthService
+	providers    *oidc.Registry
+	securityLogs *SecurityLogWriter
+	cfg          config.OIDCConfig
+	ctx          context.Context
+}
+
+// NewOIDCService 创建 OpenID Connect 登录服务，auth 的认证方式链中需包含使用同一 providers 的 oidc 认证方式
+func NewOIDCService(db *gorm.DB, st store.Store, auth *AuthService, providers *oidc.Registry, securityLogs *SecurityLogWriter, cfg config.OIDCConfig) *OIDCService {
+	return &OIDCService{
+		db:           db,
+		st:           st,
+		auth:         auth,
+		providers:    providers,
+		securityLogs: securityLogs,
+		cfg:          cfg,
+		ctx:          context.Background(),
+	}
+}
+
+// WithContext 返回绑定请求上下文的服务副本
+func (s *OIDCService) WithContext(ctx context.Context) *OIDCService {
+	clone := *s
+	clone.db = s.db.WithContext(ctx)
+	clone.auth = s.auth.WithContext(ctx)
+	clone.ctx = ctx
+	return &clone
+}
+
+// oidcState 跳转到身份提供方前保存的登录状态，以 state 参数为键
+type oidcState struct {
+	Provider string `json:"provider"`
+	Verifier string `json:"verifier"`
+	Nonce    string `json:"nonce"`
+}
+
+// OIDCCallbackRequest 身份提供方回调参数
+type OIDCCallbackRequest struct {
+	Provider         string `form:"-"` // 由处理器按路径参数填充
+	Code             string `form:"code"`
+	State            string `form:"state"`
+	Error            string `form:"error"`
+	ErrorDescription string `form:"error_description"`
+
+	// 客户端信息，由处理器填充
+	IP        string `form:"-"`
+	UserAgent string `form:"-"`
+}
+
+// OIDCCredentials 身份提供方回调的授权码，以及跳转前保存的 PKCE code_verifier 和 nonce
+type OIDCCredentials struct {
+	Provider string
+	Code     string
+	Verifier string
+	Nonce    string
+}
+
+func (*OIDCCredentials) credentials() {}
+
+// OIDCExchangeRequest 用回调得到的一次性 code 换取令牌
+type OIDCExchangeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// Providers 返回登录页可用的身份提供方
+func (s *OIDCService) Providers() []oidc.ProviderInfo {
+	return s.providers.Providers()
+}
+
+// Authorize 生成 state、nonce 和 PKCE code_verifier，返回跳转到身份提供方的授权地址
+func (s *OIDCService) Authorize(name string) (string, error) {
+	provider, err := oidcProvider(s.ctx, s.providers, name)
+	if err != nil {
+		return "", err
+	}
+
+	state, err := utils.GenerateRandomString(32)
+	if err != nil {
+		return "", err
+	}
+	nonce, err := utils.GenerateRandomString(32)
+	if err != nil {
+		return "", err
+	}
+	verifier := oidc.GenerateVerifier()
+
+	value, err := json.Marshal(oidcState{Provider: name, Verifier: verifier, Nonce: nonce})
+	if err != nil {
+		return "", err
+	}
+	if err := s.st.Set(s.ctx, oidcStateKey(state), string(value), time.Duration(s.cfg.StateTTL)*time.Second); err != nil {
+		return "", apperrors.ErrAuthUnavailable.Wrap(err)
+	}
+
+	return provider.AuthCodeURL(state, nonce, verifier), nil
+}
+
+// Callback 处理身份提供方回调，返回跳转到前端登录回调页面的地址
+// 登录成功时附加一次性 code 供前端换取令牌，失败时附加错误 key，令牌不出现在地址中
+func (s *OIDCService) Callback(req *OIDCCallbackRequest) string {
+	resp, err := s.login(req)
+	if err == nil {
+		var code string
+		code, err = s.storeLogin(resp)
+		if err == nil {
+			return s.frontendURL("code", code)
+		}
+	}
+
+	appErr := apperrors.From(err)
+	if appErr.Status() >= 500 {
+		logrus.WithError(err).WithField("provider", req.Provider).Error("OIDC login failed")
+	}
+	return s.frontendURL("error", appErr.Key)
+}
+
+// Exchange 用一次性 code 换取登录结果，code 只能使用一次
+func (s *OIDCService) Exchange(req *OIDCExchangeRequest) (*LoginResponse, error) {
+	key := oidcLoginKey(req.Code)
+	value, err := s.st.Get(s.ctx, key)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, apperrors.ErrOIDCStateInvalid
+		}
+		return nil, apperrors.ErrAuthUnavailable.Wrap(err)
+	}
+	consumed, err := s.st.DeleteIfEqual(s.ctx, key, value)
+	if err != nil {
+		return nil, apperrors.ErrAuthUnavailable.Wrap(err)
+	}
+	if !consumed {
+		return nil, apperrors.ErrOIDCStateInvalid
+	}
+
+	var resp LoginResponse
+	if err := json.Unmarshal([]byte(value), &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// login 校验回调，由认证方式链换取并校验 ID 令牌，确定用户后签发令牌
+func (s *OIDCService) login(req *OIDCCallbackRequest) (*LoginResponse, error) {
+	if req.Error != "" {
+		s.record(SecurityEventOIDCLoginFailed, nil, req, fmt.Sprintf("provider returned %s: %s", req.Error, req.ErrorDescription))
+		return nil, apperrors.ErrOIDCLoginFailed
+	}
+
+	state, err := s.consumeState(req.State)
+	if err != nil {
+		return nil, err
+	}
+	if state.Provider != req.Provider {
+		s.record(SecurityEventOIDCLoginFailed, nil, req, "state issued for provider "+state.Provider)
+		return nil, apperrors.ErrOIDCStateInvalid
+	}
+
+	identity, err := s.auth.authenticate(&OIDCCredentials{
+		Provider: req.Provider,
+		Code:     req.Code,
+		Verifier: state.Verifier,
+		Nonce:    state.Nonce,
+	})
+	if err != nil {
+		if errors.Is(err, apperrors.ErrOIDCLoginFailed) {
+			logrus.WithError(err).WithField("provider", req.Provider).Warn("OIDC code exchange failed")
+			s.record(SecurityEventOIDCLoginFailed, nil, req, err.Error())
+		}
+		return nil, err
+	}
+
+	user, resp, err := s.auth.loginIdentity(identity, loginSource{
+		ip:        req.IP,
+		userAgent: req.UserAgent,
+		method:    "GET",
+		path:      oidcCallbackPath(req.Provider),
+	})
+	if err != nil {
+		if errors.Is(err, errIdentityNotLinked) {
+			s.record(SecurityEventOIDCLoginFailed, nil, req, err.Error())
+			return nil, apperrors.ErrOIDCAccountNotLinked.Wrap(err)
+		}
+		if user != nil {
+			s.record(SecurityEventOIDCLoginFailed, user, req, apperrors.From(err).Key)
+		}
+		return nil, err
+	}
+	s.record(SecurityEventOIDCLogin, user, req, "subject "+identity.Subject)
+
+	return resp, nil
+}
+
+// consumeState 读取并作废 state，同一 state 只能回调一次
+func (s *OIDCService) consumeState(state string) (*oidcState, error) {
+	if state == "" {
+		return nil, apperrors.ErrOIDCStateInvalid
+	}
+
+	key := oidcStateKey(state)
+	value, err := s.st.Get(s.ctx, key)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, apperrors.ErrOIDCStateInvalid
+		}
+		return nil, apperrors.ErrAuthUnavailable.Wrap(err)
+	}
+	consumed, err := s.st.DeleteIfEqual(s.ctx, key, value)
+	if err != nil {
+		return nil, apperrors.ErrAuthUnavailable.Wrap(err)
+	}
+	if !consumed {
+		return nil, apperrors.ErrOIDCStateInvalid
+	}
+
+	var st oidcState
+	if err := json.Unmarshal([]byte(value), &st); err != nil {
+		return nil, apperrors.ErrOIDCStateInvalid.Wrap(err)
+	}
+	return &st, nil
+}
+
+// oidcIdentity 将 ID 令牌的声明转换为统一的身份，按 role_claim 映射角色
+func oidcIdentity(provider *oidc.Provider, claims *oidc.Claims) *Identity {
+	cfg := provider.Config
+	claim := cfg.UsernameClaim
+	if claim == "" {
+		claim = "preferred_username"
+	}
+
+	identity := &Identity{
+		Authenticator: AuthenticatorOIDC,
+		Provider:      provider.Name,
+		Subject:       claims.Subject,
+		Username:      strings.TrimSpace(claims.String(claim)),
+		Email:         claims.Email(),
+		EmailVerified: claims.EmailVerified() || cfg.TrustEmail,
+		Name:          claims.String("name"),
+		// 身份提供方确认过的邮箱可以关联有本地密码的用户，超级管理员仍不会被关联
+		LinkByEmail:       true,
+		LinkPasswordUsers: true,
+		AutoProvision:     cfg.AutoProvision,
+		DefaultRoles:      cfg.DefaultRoles,
+	}
+	for _, amr := range claims.Strings("amr") {
+		if amr == "mfa" {
+			identity.MFA = true
+		}
+	}
+
+	if cfg.RoleClaim == "" {
+		return identity
+	}
+	values := make(map[string]bool)
+	for _, value := range claims.Strings(cfg.RoleClaim) {
+		values[value] = true
+	}
+	for _, m := range cfg.RoleMapping {
+		identity.ManagedRoles = append(identity.ManagedRoles, m.Role)
+		if values[m.Value] {
+			identity.Roles = append(identity.Roles, m.Role)
+		}
+	}
+	return identity
+}
+
+// oidcAuthenticator 用身份提供方回调的授权码换取并校验 ID 令牌
+type oidcAuthenticator struct {
+	providers *oidc.Registry
+}
+
+// NewOIDCAuthenticator 创建 OpenID Connect 认证方式
+func NewOIDCAuthenticator(providers *oidc.Registry) Authenticator {
+	return &oidcAuthenticator{providers: providers}
+}
+
+// Name 认证方式名称
+func (a *oidcAuthenticator) Name() string {
+	return AuthenticatorOIDC
+}
+
+// Authenticate 换取并校验 ID 令牌，返回外部身份和按 role_claim 映射的角色，换取或校验失败时返回 ErrOIDCLoginFailed
+func (a *oidcAuthenticator) Authenticate(ctx context.Context, creds Credentials) (*Identity, error) {
+	c, ok := creds.(*OIDCCredentials)
+	if !ok {
+		return nil, ErrAuthenticatorSkip
+	}
+
+	provider, err := oidcProvider(ctx, a.providers, c.Provider)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := provider.Exchange(ctx, c.Code, c.Verifier, c.Nonce)
+	if err != nil {
+		return nil, apperrors.ErrOIDCLoginFailed.Wrap(err)
+	}
+	return oidcIdentity(provider, claims), nil
+}
+
+// oidcProvider 返回身份提供方，发现文档获取失败时返回 ErrOIDCUnavailable
+func oidcProvider(ctx context.Context, providers *oidc.Registry, name string) (*oidc.Provider, error) {
+	provider, err := providers.Get(ctx, name)
+	if err != nil {
+		if errors.Is(err, oidc.ErrUnknownProvider) {
+			return nil, apperrors.ErrOIDCProviderNotFound
+		}
+		return nil, apperrors.ErrOIDCUnavailable.Wrap(err)
+	}
+	return provider, nil
+}
+
+// storeLogin 保存登录结果，返回前端换取令牌的一次性 code
+func (s *OIDCService) storeLogin(resp *LoginResponse) (string, error) {
+	code, err := utils.GenerateRandomString(32)
+	if err != nil {
+		return "", err
+	}
+	value, err := json.Marshal(resp)
+	if err != nil {
+		return "", err
+	}
+	if err := s.st.Set(s.ctx, oidcLoginKey(code), string(value), oidcLoginCodeTTL); err != nil {
+		return "", apperrors.ErrAuthUnavailable.Wrap(err)
+	}
+	return code, nil
+}
+
+// frontendURL 将参数附加到前端登录回调页面地址
+func (s *OIDCService) frontendURL(key, value string) string {
+	u, err := url.Parse(s.cfg.FrontendURL)
+	if err != nil {
+		// 配置校验已保证地址合法
+		return s.cfg.FrontendURL
+	}
+	query := u.Query()
+	query.Set(key, value)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// record 记录身份提供方回调的安全日志，user 为空表示未能确定用户
+func (s *OIDCService) record(event string, user *models.User, req *OIDCCallbackRequest, detail string) {
+	entry := models.SecurityLog{
+		Event:     event,
+		IP:        req.IP,
+		Method:    "GET",
+		Path:      oidcCallbackPath(req.Provider),
+		UserAgent: req.UserAgent,
+		Detail:    detail,
+		TraceID:   tracing.TraceID(s.ctx),
+		CreatedAt: time.Now(),
+	}
+	if user != nil {
+		entry.UserID = user.ID
+		entry.Username = user.Username
+	}
+	s.securityLogs.Record(entry)
+}
+
+// oidcStateKey 登录状态的存储键
+func oidcStateKey(state string) string {
+	return "oidc:state:" + utils.GetTokenHash(state)
+}
+
+// oidcLoginKey 登录结果的存储键
+func oidcLoginKey(code string) string {
+	return "oidc:login:" + utils.GetTokenHash(code)
+}
+
+// oidcCallbackPath 回调接口路径，用于安全日志
+func oidcCallbackPath(provider string) string {
+	return "/api/v1/auth/oidc/" + provider + "/callback"
+}
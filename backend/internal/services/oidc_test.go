@@ -0,0 +1,320 @@
+package services
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"stars-admin/internal/apperrors"
+	"stars-admin/internal/config"
+	"stars-admin/internal/models"
+	"stars-admin/internal/oidc"
+	"stars-admin/internal/oidc/oidctest"
+	"testing"
+)
+
+var (
+	oidcDave = oidctest.User{Subject: "sub-dave", Email: "dave@example.org", EmailVerified: true, Name: "Dave", Username: "dave", Groups: []string{"ops-team"}}
+	oidcErin = oidctest.User{Subject: "sub-erin", Email: "erin@example.org", EmailVerified: true, Name: "Erin"}
+)
+
+// newOIDCTest 启动身份提供方，返回使用 dev 身份提供方的登录服务，ops-team 组映射到 ops 角色
+// configure 不为 nil 时用于修改身份提供方的配置
+func newOIDCTest(t *testing.T, env *testEnv, configure func(*config.OIDCProviderConfig)) (*oidctest.Server, *OIDCService) {
+	t.Helper()
+	idp := oidctest.NewServer("stars-admin", "secret")
+	t.Cleanup(idp.Close)
+
+	provider := config.OIDCProviderConfig{
+		Issuer:        idp.URL,
+		ClientID:      "stars-admin",
+		ClientSecret:  "secret",
+		RedirectURL:   "http://localhost:8080/api/v1/auth/oidc/dev/callback",
+		AutoProvision: true,
+		DefaultRoles:  []string{"user"},
+		RoleClaim:     "groups",
+		RoleMapping:   []config.OIDCRoleMapping{{Value: "ops-team", Role: "ops"}},
+	}
+	if configure != nil {
+		configure(&provider)
+	}
+	other := provider
+	other.RedirectURL = "http://localhost:8080/api/v1/auth/oidc/other/callback"
+	cfg := config.OIDCConfig{
+		FrontendURL: "http://localhost:5173/oidc/callback",
+		StateTTL:    600,
+		Timeout:     5,
+		Providers:   map[string]config.OIDCProviderConfig{"dev": provider, "other": other},
+	}
+
+	providers := oidc.NewRegistry(cfg)
+	auth := env.auth.WithAuthenticators([]Authenticator{NewLocalAuthenticator(env.db), NewOIDCAuthenticator(providers)})
+	return idp, NewOIDCService(env.db, env.st, auth, providers, env.logs, cfg)
+}
+
+// oidcAuthorize 跳转到身份提供方并以其当前用户完成授权，返回回调请求
+func oidcAuthorize(t *testing.T, idp *oidctest.Server, svc *OIDCService, provider string) *OIDCCallbackRequest {
+	t.Helper()
+	authURL, err := svc.Authorize(provider)
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	params, err := idp.Authorize(authURL)
+	if err != nil {
+		t.Fatalf("authorize at provider: %v", err)
+	}
+	return &OIDCCallbackRequest{
+		Provider:         provider,
+		Code:             params.Get("code"),
+		State:            params.Get("state"),
+		Error:            params.Get("error"),
+		ErrorDescription: params.Get("error_description"),
+		IP:               "127.0.0.1",
+	}
+}
+
+// oidcCallback 处理回调，返回前端回调页面地址中的参数
+func oidcCallback(t *testing.T, svc *OIDCService, req *OIDCCallbackRequest) url.Values {
+	t.Helper()
+	u, err := url.Parse(svc.Callback(req))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u.Query()
+}
+
+// oidcLogin 以身份提供方当前的用户完成登录并换取令牌
+func oidcLogin(t *testing.T, idp *oidctest.Server, svc *OIDCService) (*LoginResponse, error) {
+	t.Helper()
+	params := oidcCallback(t, svc, oidcAuthorize(t, idp, svc, "dev"))
+	if key := params.Get("error"); key != "" {
+		return nil, errors.New(key)
+	}
+	return svc.Exchange(&OIDCExchangeRequest{Code: params.Get("code")})
+}
+
+func TestOIDCLogin(t *testing.T) {
+	env := newTestEnv(t)
+	idp, svc := newOIDCTest(t, env, nil)
+	dave := oidcDave
+	idp.SetUser(&dave)
+
+	req := oidcAuthorize(t, idp, svc, "dev")
+	params := oidcCallback(t, svc, req)
+	if params.Get("code") == "" || params.Get("error") != "" {
+		t.Fatalf("unexpected frontend callback %v", params)
+	}
+
+	// 一次性 code 只能换取一次令牌
+	resp, err := svc.Exchange(&OIDCExchangeRequest{Code: params.Get("code")})
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if resp.AccessToken == "" || resp.RefreshToken == "" || resp.User.Username != "dave" {
+		t.Errorf("unexpected login response %+v", resp)
+	}
+	if _, err := svc.Exchange(&OIDCExchangeRequest{Code: params.Get("code")}); !errors.Is(err, apperrors.ErrOIDCStateInvalid) {
+		t.Errorf("reused exchange code: got %v", err)
+	}
+
+	// 首次登录自动创建用户，分配 default_roles 和映射的角色
+	user := env.user(t, "dave")
+	if user == nil || user.Email != "dave@example.org" || user.Nickname != "Dave" || user.Password != "" {
+		t.Fatalf("unexpected provisioned user %+v", user)
+	}
+	if roles := env.roles(t, user.ID); !reflect.DeepEqual(roles, []string{"ops", "user"}) {
+		t.Errorf("got roles %v, want [ops user]", roles)
+	}
+	if events := env.events(); !containsString(events, SecurityEventUserProvisioned) || !containsString(events, SecurityEventOIDCLogin) {
+		t.Errorf("got events %v", events)
+	}
+
+	// 同一 state 只能回调一次
+	if key := oidcCallback(t, svc, req).Get("error"); key != apperrors.ErrOIDCStateInvalid.Key {
+		t.Errorf("replayed state: got %q", key)
+	}
+
+	// 之后按已关联的外部身份确定用户，邮箱变更不影响
+	dave.Email = "dave@new.example.org"
+	if _, err := oidcLogin(t, idp, svc); err != nil {
+		t.Fatalf("second login: %v", err)
+	}
+	var count int64
+	env.db.Model(&models.User{}).Count(&count)
+	if count != 1 {
+		t.Errorf("second login should not create another user, got %d users", count)
+	}
+	var identity models.UserIdentity
+	if err := env.db.Where("provider = ? AND subject = ?", "dev", "sub-dave").First(&identity).Error; err != nil || identity.UserID != user.ID || identity.Email != "dave@new.example.org" {
+		t.Errorf("unexpected identity %+v, err %v", identity, err)
+	}
+}
+
+func TestOIDCCallbackRejected(t *testing.T) {
+	env := newTestEnv(t)
+	idp, svc := newOIDCTest(t, env, nil)
+	dave := oidcDave
+	idp.SetUser(&dave)
+
+	// 未知的 state
+	req := oidcAuthorize(t, idp, svc, "dev")
+	req.State = "unknown"
+	if key := oidcCallback(t, svc, req).Get("error"); key != apperrors.ErrOIDCStateInvalid.Key {
+		t.Errorf("unknown state: got %q", key)
+	}
+
+	// state 属于其他身份提供方
+	req = oidcAuthorize(t, idp, svc, "other")
+	req.Provider = "dev"
+	if key := oidcCallback(t, svc, req).Get("error"); key != apperrors.ErrOIDCStateInvalid.Key {
+		t.Errorf("state of another provider: got %q", key)
+	}
+
+	// 将一次登录的授权码注入另一次登录的回调：PKCE code_verifier 和 nonce 都不匹配
+	first := oidcAuthorize(t, idp, svc, "dev")
+	second := oidcAuthorize(t, idp, svc, "dev")
+	second.Code = first.Code
+	if key := oidcCallback(t, svc, second).Get("error"); key != apperrors.ErrOIDCLoginFailed.Key {
+		t.Errorf("code of another login: got %q", key)
+	}
+	// 授权码在身份提供方已被使用，原登录的回调也随之失败
+	if key := oidcCallback(t, svc, first).Get("error"); key != apperrors.ErrOIDCLoginFailed.Key {
+		t.Errorf("consumed code: got %q", key)
+	}
+
+	// 身份提供方返回错误
+	idp.SetUser(nil)
+	req = oidcAuthorize(t, idp, svc, "dev")
+	if req.Error != "access_denied" {
+		t.Fatalf("provider should deny the request, got %+v", req)
+	}
+	if key := oidcCallback(t, svc, req).Get("error"); key != apperrors.ErrOIDCLoginFailed.Key {
+		t.Errorf("provider error: got %q", key)
+	}
+
+	if env.user(t, "dave") != nil {
+		t.Error("rejected callbacks should not create users")
+	}
+	if events := env.events(); len(events) != 4 || !reflect.DeepEqual(events, []string{
+		SecurityEventOIDCLoginFailed, SecurityEventOIDCLoginFailed, SecurityEventOIDCLoginFailed, SecurityEventOIDCLoginFailed,
+	}) {
+		t.Errorf("got events %v", events)
+	}
+}
+
+func TestOIDCLinkByEmail(t *testing.T) {
+	env := newTestEnv(t)
+	idp, svc := newOIDCTest(t, env, nil)
+	erin := env.createUser(t, "erin", "erin@example.org", "Erin123456", "user")
+
+	// 邮箱未验证时不关联已有用户，也不创建同邮箱的用户
+	unverified := oidcErin
+	unverified.EmailVerified = false
+	idp.SetUser(&unverified)
+	if _, err := oidcLogin(t, idp, svc); err == nil || err.Error() != apperrors.ErrOIDCAccountNotLinked.Key {
+		t.Errorf("unverified email: got %v", err)
+	}
+	env.events()
+
+	// 已验证的邮箱关联有本地密码的已有用户
+	verified := oidcErin
+	idp.SetUser(&verified)
+	resp, err := oidcLogin(t, idp, svc)
+	if err != nil {
+		t.Fatalf("verified email: %v", err)
+	}
+	if resp.User.ID != erin.ID {
+		t.Errorf("should log in as the existing user, got %+v", resp.User)
+	}
+	if !containsString(env.events(), SecurityEventOIDCIdentityLinked) {
+		t.Error("linking should be recorded")
+	}
+
+	// 拥有 admin 角色的用户不会被关联
+	admin := env.createUser(t, "root", "root@example.org", "Root123456", models.RoleAdmin)
+	idp.SetUser(&oidctest.User{Subject: "sub-root", Email: "root@example.org", EmailVerified: true})
+	if _, err := oidcLogin(t, idp, svc); err == nil || err.Error() != apperrors.ErrOIDCAccountNotLinked.Key {
+		t.Errorf("admin user: got %v", err)
+	}
+	var count int64
+	env.db.Model(&models.UserIdentity{}).Where("user_id = ?", admin.ID).Count(&count)
+	if count != 0 {
+		t.Error("admin user should not be linked")
+	}
+}
+
+func TestOIDCTrustEmail(t *testing.T) {
+	env := newTestEnv(t)
+	idp, svc := newOIDCTest(t, env, func(p *config.OIDCProviderConfig) { p.TrustEmail = true })
+	erin := env.createUser(t, "erin", "erin@example.org", "Erin123456")
+
+	// 开启 trust_email 时不检查 email_verified
+	unverified := oidcErin
+	unverified.EmailVerified = false
+	idp.SetUser(&unverified)
+	resp, err := oidcLogin(t, idp, svc)
+	if err != nil {
+		t.Fatalf("trusted email: %v", err)
+	}
+	if resp.User.ID != erin.ID {
+		t.Errorf("should log in as the existing user, got %+v", resp.User)
+	}
+}
+
+func TestOIDCAutoProvisionDisabled(t *testing.T) {
+	env := newTestEnv(t)
+	idp, svc := newOIDCTest(t, env, func(p *config.OIDCProviderConfig) { p.AutoProvision = false })
+	dave := oidcDave
+	idp.SetUser(&dave)
+
+	if _, err := oidcLogin(t, idp, svc); err == nil || err.Error() != apperrors.ErrOIDCAccountNotLinked.Key {
+		t.Errorf("unknown email without auto_provision: got %v", err)
+	}
+	if env.user(t, "dave") != nil {
+		t.Error("user should not be created")
+	}
+}
+
+func TestOIDCRoleMapping(t *testing.T) {
+	env := newTestEnv(t)
+	// 配置校验拒绝 admin，这里绕过校验确认身份提供方仍不能授予 admin
+	idp, svc := newOIDCTest(t, env, func(p *config.OIDCProviderConfig) {
+		p.DefaultRoles = []string{"user", models.RoleAdmin}
+		p.RoleMapping = append(p.RoleMapping, config.OIDCRoleMapping{Value: "root", Role: models.RoleAdmin})
+	})
+	dave := oidcDave
+	dave.Groups = []string{"ops-team", "root"}
+	idp.SetUser(&dave)
+
+	if _, err := oidcLogin(t, idp, svc); err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	user := env.user(t, "dave")
+	if roles := env.roles(t, user.ID); !reflect.DeepEqual(roles, []string{"ops", "user"}) {
+		t.Errorf("got roles %v, want [ops user]", roles)
+	}
+
+	// 声明中没有的映射角色被撤销，未出现在映射中的角色不受影响
+	dave.Groups = nil
+	if _, err := oidcLogin(t, idp, svc); err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if roles := env.roles(t, user.ID); !reflect.DeepEqual(roles, []string{"user"}) {
+		t.Errorf("got roles %v, want [user]", roles)
+	}
+
+	dave.Groups = []string{"ops-team"}
+	if _, err := oidcLogin(t, idp, svc); err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if roles := env.roles(t, user.ID); !reflect.DeepEqual(roles, []string{"ops", "user"}) {
+		t.Errorf("got roles %v, want [ops user]", roles)
+	}
+
+	// 禁用的用户不能登录
+	if err := env.db.Model(user).Update("status", models.UserStatusDisabled).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := oidcLogin(t, idp, svc); err == nil || err.Error() != apperrors.ErrUserDisabled.Key {
+		t.Errorf("disabled user: got %v", err)
+	}
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"stars-admin/internal/models"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// LogWriter 日志异步写入器
+// 日志进入队列后由后台协程写入数据库，关闭时会写完队列中剩余的日志
+type LogWriter[T any] struct {
+	name  string
+	db    *gorm.DB
+	queue chan T
+
+	mu     sync.RWMutex
+	closed bool
+	done   chan struct{}
+}
+
+// OperationLogWriter 操作日志写入器，请求处理完成后写入
+type OperationLogWriter = LogWriter[models.OperationLog]
+
+// NewOperationLogWriter 创建操作日志写入器
+func NewOperationLogWriter(db *gorm.DB, queueSize int) *OperationLogWriter {
+	return newLogWriter[models.OperationLog]("operation-log-writer", db, queueSize)
+}
+
+// newLogWriter 创建日志写入器，name 为组件名称
+func newLogWriter[T any](name string, db *gorm.DB, queueSize int) *LogWriter[T] {
+	if queueSize <= 0 {
+		queueSize = 1000
+	}
+	return &LogWriter[T]{
+		name:  name,
+		db:    db,
+		queue: make(chan T, queueSize),
+		done:  make(chan struct{}),
+	}
+}
+
+// Name 组件名称
+func (w *LogWriter[T]) Name() string {
+	return w.name
+}
+
+// Start 启动后台写入协程
+func (w *LogWriter[T]) Start(context.Context) error {
+	go w.run()
+	return nil
+}
+
+// Stop 停止接收新日志并等待队列写完，超时后放弃剩余日志
+func (w *LogWriter[T]) Stop(ctx context.Context) error {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.queue)
+	}
+	w.mu.Unlock()
+
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		logrus.WithFields(logrus.Fields{"writer": w.name, "pending": len(w.queue)}).Warn("Log writer stopped before queue drained")
+		return ctx.Err()
+	}
+}
+
+// Write 将日志加入队列，队列已满或已关闭时丢弃并返回 false
+func (w *LogWriter[T]) Write(log T) bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.closed {
+		return false
+	}
+
+	select {
+	case w.queue <- log:
+		return true
+	default:
+		logrus.WithField("writer", w.name).Warn("Log queue full, dropping log")
+		return false
+	}
+}
+
+// Len 当前队列长度
+func (w *LogWriter[T]) Len() int {
+	return len(w.queue)
+}
+
+// run 后台写入协程
+func (w *LogWriter[T]) run() {
+	defer close(w.done)
+
+	for log := range w.queue {
+		if err := w.db.Create(&log).Error; err != nil {
+			logrus.WithError(err).WithField("writer", w.name).Error("Failed to save log")
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"stars-admin/internal/apperrors"
+	"stars-admin/internal/models"
+	"stars-admin/internal/settings"
+	"stars-admin/internal/utils"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// validateNewPassword 按密码策略和密码历史校验新密码，field 为错误中使用的字段名
+func validateNewPassword(db *gorm.DB, policy settings.PasswordPolicy, user *models.User, field, password string) error {
+	if violations := policy.Check(field, password, user.Username); len(violations) > 0 {
+		return apperrors.ErrPasswordPolicy.WithDetails(violations)
+	}
+
+	if policy.HistoryCount > 0 {
+		reused, err := passwordReused(db, user, password, policy.HistoryCount)
+		if err != nil {
+			return err
+		}
+		if reused {
+			return apperrors.ErrPasswordPolicy.WithDetails([]apperrors.FieldError{
+				{Field: field, Rule: "history", Param: strconv.Itoa(policy.HistoryCount)},
+			})
+		}
+	}
+
+	return nil
+}
+
+// passwordReused 判断密码是否与当前密码或最近 count 次使用过的密码相同
+func passwordReused(db *gorm.DB, user *models.User, password string, count int) (bool, error) {
+	if user.Password != "" && utils.CheckPassword(user.Password, password) {
+		return true, nil
+	}
+
+	var hashes []string
+	if err := db.Model(&models.PasswordHistory{}).Where("user_id = ?", user.ID).
+		Order("id DESC").Limit(count).Pluck("password", &hashes).Error; err != nil {
+		return false, err
+	}
+	for _, hash := range hashes {
+		if utils.CheckPassword(hash, password) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// setPassword 保存新密码并记录密码历史，同时解除强制修改密码标记，应在事务中调用
+// 每个用户最多保留 settings.PasswordHistoryLimit 条历史，调大 history_count 后仍能校验
+func setPassword(tx *gorm.DB, user *models.User, password string) error {
+	hashedPassword, err := utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if err := tx.Model(user).Updates(map[string]interface{}{
+		"password":             hashedPassword,
+		"password_changed_at":  &now,
+		"must_change_password": false,
+	}).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Create(&models.PasswordHistory{UserID: user.ID, Password: hashedPassword}).Error; err != nil {
+		return err
+	}
+
+	// 删除超出保留条数的旧记录
+	var oldest []uint
+	if err := tx.Model(&models.PasswordHistory{}).Where("user_id = ?", user.ID).
+		Order("id DESC").Offset(settings.PasswordHistoryLimit-1).Limit(1).Pluck("id", &oldest).Error; err != nil {
+		return err
+	}
+	if len(oldest) > 0 {
+		return tx.Where("user_id = ? AND id < ?", user.ID, oldest[0]).Delete(&models.PasswordHistory{}).Error
+	}
+
+	return nil
+}
+
+// passwordChangedAt 返回密码的设置时间，从未修改过时为创建时间
+func passwordChangedAt(user *models.User) time.Time {
+	if user.PasswordChangedAt != nil {
+		return *user.PasswordChangedAt
+	}
+	return user.CreatedAt
+}
@@ -0,0 +1,214 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"stars-admin/internal/apperrors"
+	"stars-admin/internal/config"
+	"stars-admin/internal/database"
+	"stars-admin/internal/mail"
+	"stars-admin/internal/models"
+	"stars-admin/internal/settings"
+	"stars-admin/internal/store"
+	"stars-admin/internal/utils"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// PasswordResetService 自助重置密码服务
+// 令牌只保存哈希，有效期内只能使用一次；无论邮箱是否注册都返回相同的结果，避免探测账号
+type PasswordResetService struct {
+	db           *gorm.DB
+	st           store.Store
+	settings     *settings.Manager
+	mailer       *mail.Mailer
+	securityLogs *SecurityLogWriter
+	cfg          config.PasswordResetConfig
+	tokens       oneTimeToken
+	ctx          context.Context
+}
+
+// NewPasswordResetService 创建自助重置密码服务
+func NewPasswordResetService(db *gorm.DB, st store.Store, sm *settings.Manager, mailer *mail.Mailer, securityLogs *SecurityLogWriter, cfg config.PasswordResetConfig) *PasswordResetService {
+	return &PasswordResetService{
+		db:           db,
+		st:           st,
+		settings:     sm,
+		mailer:       mailer,
+		securityLogs: securityLogs,
+		cfg:          cfg,
+		tokens:       oneTimeToken{st: st, purpose: "password_reset"},
+		ctx:          context.Background(),
+	}
+}
+
+// WithContext 返回绑定请求上下文的服务副本
+func (s *PasswordResetService) WithContext(ctx context.Context) *PasswordResetService {
+	clone := *s
+	clone.db = s.db.WithContext(ctx)
+	clone.ctx = ctx
+	return &clone
+}
+
+// ForgotPasswordRequest 申请重置密码请求
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email,max=100"`
+
+	// 客户端信息，由处理器填充
+	IP        string `json:"-"`
+	UserAgent string `json:"-"`
+}
+
+// ResetPasswordRequest 重置密码请求
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required,max=128"`
+	NewPassword string `json:"new_password" binding:"required"` // 按系统配置中的密码策略校验
+
+	// 客户端信息，由处理器填充
+	IP        string `json:"-"`
+	UserAgent string `json:"-"`
+}
+
+// passwordResetEmail 重置密码邮件模板数据
+type passwordResetEmail struct {
+	SiteName  string
+	Name      string
+	Username  string
+	URL       string
+	ExpiresIn int
+	IP        string
+}
+
+// Forgot 申请重置密码，向邮箱对应的用户发送重置链接
+// 邮箱未注册、用户已禁用、申请过于频繁或存储不可用时不发送邮件，但同样返回成功，只记录安全日志
+func (s *PasswordResetService) Forgot(req *ForgotPasswordRequest) error {
+	if !s.mailer.Enabled() {
+		return apperrors.ErrPasswordResetDisabled
+	}
+
+	const path = "/api/v1/auth/forgot-password"
+	var user models.User
+	if err := database.UsePrimary(s.db).Where("email = ?", req.Email).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			s.record(SecurityEventPasswordResetRequested, nil, req.IP, req.UserAgent, path, "unknown email "+req.Email)
+			return nil
+		}
+		return err
+	}
+	if user.Status != models.UserStatusActive {
+		s.record(SecurityEventPasswordResetRequested, &user, req.IP, req.UserAgent, path, "user disabled, email not sent")
+		return nil
+	}
+	if user.ServiceAccount {
+		s.record(SecurityEventPasswordResetRequested, &user, req.IP, req.UserAgent, path, "service account, email not sent")
+		return nil
+	}
+
+	// 按账号限制申请次数，防止被用来轰炸邮箱
+	// 存储不可用时同样返回成功，避免已注册邮箱因返回错误而被探测出来
+	count, throttled, err := s.tokens.throttle(s.ctx, user.ID, s.cfg.MaxRequests, time.Duration(s.cfg.Window)*time.Second)
+	if err != nil {
+		logrus.WithError(err).WithField("user_id", user.ID).Error("Failed to check password reset throttle")
+		s.record(SecurityEventPasswordResetRequested, &user, req.IP, req.UserAgent, path, "store unavailable, email not sent")
+		return nil
+	}
+	if throttled {
+		s.record(SecurityEventPasswordResetThrottled, &user, req.IP, req.UserAgent, path, fmt.Sprintf("%d requests within %ds", count, s.cfg.Window))
+		return nil
+	}
+
+	token, err := s.tokens.issue(s.ctx, user.ID, time.Duration(s.cfg.TokenTTL)*time.Minute)
+	if err != nil {
+		logrus.WithError(err).WithField("user_id", user.ID).Error("Failed to issue password reset token")
+		s.record(SecurityEventPasswordResetRequested, &user, req.IP, req.UserAgent, path, "store unavailable, email not sent")
+		return nil
+	}
+
+	link, err := tokenURL(s.cfg.URL, token)
+	if err != nil {
+		return err
+	}
+	err = s.mailer.SendTemplate(user.Email, emailLocale(s.ctx, &user), "password_reset", passwordResetEmail{
+		SiteName:  s.settings.String(s.ctx, settings.KeySiteName),
+		Name:      displayName(&user),
+		Username:  user.Username,
+		URL:       link,
+		ExpiresIn: s.cfg.TokenTTL,
+		IP:        req.IP,
+	})
+	if err != nil {
+		// 与其他不发送邮件的情况返回相同的结果，避免据此探测邮箱是否注册
+		logrus.WithError(err).WithField("user_id", user.ID).Error("Failed to queue password reset email")
+		s.record(SecurityEventPasswordResetRequested, &user, req.IP, req.UserAgent, path, "failed to queue reset email")
+		return nil
+	}
+
+	s.record(SecurityEventPasswordResetRequested, &user, req.IP, req.UserAgent, path, "reset email sent")
+	return nil
+}
+
+// Reset 使用重置链接中的令牌设置新密码
+// 新密码不符合策略时令牌仍然有效；设置成功后令牌作废，用户已签发的访问令牌和刷新令牌同时失效，需要重新登录
+func (s *PasswordResetService) Reset(req *ResetPasswordRequest) error {
+	const path = "/api/v1/auth/reset-password"
+
+	userID, err := s.tokens.lookup(s.ctx, req.Token)
+	if errors.Is(err, store.ErrNotFound) {
+		s.record(SecurityEventPasswordResetFailed, nil, req.IP, req.UserAgent, path, "invalid or expired token")
+		return apperrors.ErrResetTokenInvalid
+	}
+	if err != nil {
+		return apperrors.ErrAuthUnavailable.Wrap(err)
+	}
+
+	var user models.User
+	if err := database.UsePrimary(s.db).First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			s.record(SecurityEventPasswordResetFailed, nil, req.IP, req.UserAgent, path, "user not found")
+			return apperrors.ErrResetTokenInvalid
+		}
+		return err
+	}
+	if user.Status != models.UserStatusActive {
+		s.record(SecurityEventPasswordResetFailed, &user, req.IP, req.UserAgent, path, "user disabled")
+		return apperrors.ErrUserDisabled
+	}
+
+	if err := validateNewPassword(s.db, s.settings.PasswordPolicy(s.ctx), &user, "new_password", req.NewPassword); err != nil {
+		return err
+	}
+
+	// 先作废令牌再修改密码，并发使用同一令牌时只有一个请求能成功
+	consumed, err := s.tokens.consume(s.ctx, req.Token, user.ID)
+	if err != nil {
+		return apperrors.ErrAuthUnavailable.Wrap(err)
+	}
+	if !consumed {
+		s.record(SecurityEventPasswordResetFailed, &user, req.IP, req.UserAgent, path, "token already used")
+		return apperrors.ErrResetTokenInvalid
+	}
+
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		return setPassword(tx, &user, req.NewPassword)
+	}); err != nil {
+		return err
+	}
+
+	if err := utils.RevokeUserTokens(s.ctx, s.st, user.ID, s.settings.AccessTokenTTL(s.ctx)); err != nil {
+		logrus.WithError(err).WithField("user_id", user.ID).Error("Failed to revoke access tokens after password reset")
+	}
+	if err := utils.DeleteRefreshToken(s.ctx, s.st, user.ID); err != nil {
+		logrus.WithError(err).WithField("user_id", user.ID).Warn("Failed to revoke refresh token after password reset")
+	}
+
+	s.record(SecurityEventPasswordReset, &user, req.IP, req.UserAgent, path, "password reset via email")
+	return nil
+}
+
+// record 记录重置密码相关的安全日志
+func (s *PasswordResetService) record(event string, user *models.User, ip, userAgent, path, detail string) {
+	recordAuthEvent(s.ctx, s.securityLogs, event, user, ip, userAgent, path, detail)
+}
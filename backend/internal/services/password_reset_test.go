@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"stars-admin/internal/apperrors"
+	"stars-admin/internal/config"
+	"stars-admin/internal/mail"
+	"stars-admin/internal/models"
+	"stars-admin/internal/store"
+	"stars-admin/internal/utils"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var errStoreDown = errors.New("store down")
+
+// failingStore 指定操作失败的存储，其余操作使用内存存储
+type failingStore struct {
+	*store.MemoryStore
+	failIncr bool
+	failSet  bool
+}
+
+func (s *failingStore) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	if s.failIncr {
+		return 0, errStoreDown
+	}
+	return s.MemoryStore.Incr(ctx, key, ttl)
+}
+
+func (s *failingStore) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	if s.failSet {
+		return errStoreDown
+	}
+	return s.MemoryStore.Set(ctx, key, value, ttl)
+}
+
+// newPasswordResetService 创建使用 st 保存令牌的重置密码服务，邮件只进入队列不发送
+func newPasswordResetService(t *testing.T, env *testEnv, st store.Store) (*PasswordResetService, *mail.Mailer) {
+	t.Helper()
+	mailer, err := mail.New(config.EmailConfig{Driver: mail.DriverLog}, "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.PasswordResetConfig{URL: "https://admin.example.org/reset", TokenTTL: 30, MaxRequests: 3, Window: 3600}
+	return NewPasswordResetService(env.db, st, env.sm, mailer, env.logs, cfg), mailer
+}
+
+func TestForgotPassword(t *testing.T) {
+	env := newTestEnv(t)
+	env.createUser(t, "alice", "alice@example.org", "Alice123456")
+
+	tests := []struct {
+		name  string
+		email string
+		store *failingStore
+		sent  bool
+	}{
+		{"registered", "alice@example.org", &failingStore{}, true},
+		{"unknown email", "bob@example.org", &failingStore{}, false},
+		{"throttle unavailable", "alice@example.org", &failingStore{failIncr: true}, false},
+		{"token store unavailable", "alice@example.org", &failingStore{failSet: true}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.store.MemoryStore = store.NewMemoryStore()
+			defer tt.store.Close()
+			svc, mailer := newPasswordResetService(t, env, tt.store)
+
+			// 存储不可用时与邮箱未注册的结果相同，不能据此探测账号
+			if err := svc.Forgot(&ForgotPasswordRequest{Email: tt.email}); err != nil {
+				t.Fatalf("Forgot: %v", err)
+			}
+			if sent := mailer.Len() == 1; sent != tt.sent {
+				t.Errorf("email sent: got %v, want %v", sent, tt.sent)
+			}
+			if events := env.events(); len(events) != 1 || events[0] != SecurityEventPasswordResetRequested {
+				t.Errorf("got events %v", events)
+			}
+		})
+	}
+}
+
+func TestResetPasswordRevokesTokens(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t, "alice", "alice@example.org", "Alice123456")
+	svc, _ := newPasswordResetService(t, env, env.st)
+	ctx := context.Background()
+
+	login, err := env.auth.Login(&LoginRequest{Username: "alice", Password: "Alice123456", IP: "127.0.0.1"})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	claims, err := utils.ValidateJWT(login.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := svc.tokens.issue(ctx, user.ID, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.Reset(&ResetPasswordRequest{Token: token, NewPassword: "Changed2468"}); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+
+	// 重置前签发的访问令牌和刷新令牌都失效
+	if revoked, err := utils.IsUserTokenRevoked(ctx, env.st, claims); err != nil || !revoked {
+		t.Errorf("access token issued before reset: got %v, %v", revoked, err)
+	}
+	if _, err := env.auth.RefreshToken(&RefreshTokenRequest{RefreshToken: login.RefreshToken}); err == nil {
+		t.Error("refresh token issued before reset should be rejected")
+	}
+	later := &utils.JWTClaims{UserID: user.ID, RegisteredClaims: jwt.RegisteredClaims{IssuedAt: jwt.NewNumericDate(time.Now().Add(time.Second))}}
+	if revoked, err := utils.IsUserTokenRevoked(ctx, env.st, later); err != nil || revoked {
+		t.Errorf("token issued after reset: got %v, %v", revoked, err)
+	}
+
+	// 令牌只能使用一次
+	if err := svc.Reset(&ResetPasswordRequest{Token: token, NewPassword: "Another2468"}); !errors.Is(err, apperrors.ErrResetTokenInvalid) {
+		t.Errorf("reused token: got %v", err)
+	}
+	var updated models.User
+	env.db.First(&updated, user.ID)
+	if !utils.CheckPassword(updated.Password, "Changed2468") {
+		t.Error("password should be changed")
+	}
+}
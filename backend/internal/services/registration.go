@@ -0,0 +1,703 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"stars-admin/internal/apperrors"
+	"stars-admin/internal/config"
+	"stars-admin/internal/mail"
+	"stars-admin/internal/models"
+	"stars-admin/internal/settings"
+	"stars-admin/internal/store"
+	"stars-admin/internal/utils"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// 重新发送验证邮件的限制
+const (
+	verificationMaxResends = 3
+	verificationWindow     = time.Hour
+)
+
+// RegistrationService 用户邀请与自助注册服务
+// 管理员邀请的用户接受邀请即可登录；自助注册的用户需验证邮箱，按注册策略还需管理员审核
+type RegistrationService struct {
+	db           *gorm.DB
+	st           store.Store
+	settings     *settings.Manager
+	mailer       *mail.Mailer
+	securityLogs *SecurityLogWriter
+	cfg          config.RegistrationConfig
+	tokens       oneTimeToken
+	ctx          context.Context
+}
+
+// NewRegistrationService 创建用户邀请与自助注册服务
+func NewRegistrationService(db *gorm.DB, st store.Store, sm *settings.Manager, mailer *mail.Mailer, securityLogs *SecurityLogWriter, cfg config.RegistrationConfig) *RegistrationService {
+	return &RegistrationService{
+		db:           db,
+		st:           st,
+		settings:     sm,
+		mailer:       mailer,
+		securityLogs: securityLogs,
+		cfg:          cfg,
+		tokens:       oneTimeToken{st: st, purpose: "email_verification"},
+		ctx:          context.Background(),
+	}
+}
+
+// WithContext 返回绑定请求上下文的服务副本
+func (s *RegistrationService) WithContext(ctx context.Context) *RegistrationService {
+	clone := *s
+	clone.db = s.db.WithContext(ctx)
+	clone.ctx = ctx
+	return &clone
+}
+
+// InviteRequest 邀请用户请求
+type InviteRequest struct {
+	Email string   `json:"email" binding:"required,email,max=100"`
+	Roles []string `json:"roles" binding:"max=20"` // 角色编码
+}
+
+// InvitationResponse 邀请结果，链接只在创建时返回一次
+type InvitationResponse struct {
+	Invitation *models.Invitation `json:"invitation"`
+	URL        string             `json:"url"`
+	// EmailSent 为 false 时未配置邮件发送，需要管理员将链接转交给用户
+	EmailSent bool `json:"email_sent"`
+}
+
+// InvitationQuery 邀请查询条件
+type InvitationQuery struct {
+	Status   string `form:"status" binding:"omitempty,oneof=pending accepted revoked expired"`
+	Email    string `form:"email"`
+	Page     int    `form:"page" binding:"omitempty,min=1"`
+	PageSize int    `form:"page_size" binding:"omitempty,min=1,max=100"`
+}
+
+// AcceptInvitationRequest 接受邀请请求，邮箱为邀请时的邮箱
+type AcceptInvitationRequest struct {
+	Token    string `json:"token" binding:"required,max=128"`
+	Username string `json:"username" binding:"required,min=3,max=50"`
+	Password string `json:"password" binding:"required"` // 按系统配置中的密码策略校验
+	Nickname string `json:"nickname" binding:"max=50"`
+
+	// 客户端信息，由处理器填充
+	IP        string `json:"-"`
+	UserAgent string `json:"-"`
+}
+
+// RegisterRequest 自助注册请求
+type RegisterRequest struct {
+	Username string `json:"username" binding:"required,min=3,max=50"`
+	Email    string `json:"email" binding:"required,email,max=100"`
+	Password string `json:"password" binding:"required"` // 按系统配置中的密码策略校验
+	Nickname string `json:"nickname" binding:"max=50"`
+
+	// 客户端信息，由处理器填充
+	IP        string `json:"-"`
+	UserAgent string `json:"-"`
+}
+
+// VerifyEmailRequest 验证邮箱请求
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required,max=128"`
+
+	// 客户端信息，由处理器填充
+	IP        string `json:"-"`
+	UserAgent string `json:"-"`
+}
+
+// VerifyEmailResponse 验证邮箱结果
+type VerifyEmailResponse struct {
+	// PendingApproval 为 true 时还需管理员审核后才能登录
+	PendingApproval bool `json:"pending_approval"`
+}
+
+// ResendVerificationRequest 重新发送验证邮件请求
+type ResendVerificationRequest struct {
+	Email string `json:"email" binding:"required,email,max=100"`
+}
+
+// PendingUserQuery 待审核用户查询条件
+type PendingUserQuery struct {
+	Page     int `form:"page" binding:"omitempty,min=1"`
+	PageSize int `form:"page_size" binding:"omitempty,min=1,max=100"`
+}
+
+// invitationEmail 邀请邮件模板数据
+type invitationEmail struct {
+	SiteName  string
+	Inviter   string
+	Email     string
+	URL       string
+	ExpiresIn int
+}
+
+// verificationEmail 验证邮箱邮件模板数据
+type verificationEmail struct {
+	SiteName  string
+	Name      string
+	Username  string
+	URL       string
+	ExpiresIn int
+}
+
+// approvedEmail 审核通过邮件模板数据
+type approvedEmail struct {
+	SiteName string
+	Name     string
+	Username string
+}
+
+// Invite 邀请用户，同一邮箱之前未接受的邀请随之撤销
+func (s *RegistrationService) Invite(req *InviteRequest, inviterID uint) (*InvitationResponse, error) {
+	email := strings.TrimSpace(req.Email)
+	if err := checkEmailAvailable(s.db, email); err != nil {
+		return nil, err
+	}
+
+	roles, err := activeRoles(s.db, req.Roles)
+	if err != nil {
+		return nil, err
+	}
+	codes := make([]string, len(roles))
+	for i, role := range roles {
+		codes[i] = role.Code
+	}
+
+	token, err := utils.GenerateRandomString(32)
+	if err != nil {
+		return nil, err
+	}
+	policy := s.settings.Registration(s.ctx)
+	now := time.Now()
+	invitation := models.Invitation{
+		Email:     email,
+		TokenHash: utils.GetTokenHash(token),
+		Roles:     strings.Join(codes, ","),
+		InvitedBy: inviterID,
+		ExpiresAt: now.Add(time.Duration(policy.InvitationTTL) * time.Hour),
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Invitation{}).
+			Where("email = ? AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?", email, now).
+			Update("revoked_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Create(&invitation).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	invitation.Status = invitation.InvitationStatus(now)
+
+	link, err := tokenURL(s.cfg.InvitationURL, token)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &InvitationResponse{Invitation: &invitation, URL: link}
+	if s.mailer.Enabled() {
+		var inviter models.User
+		s.db.Select("username", "nickname").First(&inviter, inviterID)
+		err := s.mailer.SendTemplate(email, emailLocale(s.ctx, &models.User{}), "invitation", invitationEmail{
+			SiteName:  s.settings.String(s.ctx, settings.KeySiteName),
+			Inviter:   displayName(&inviter),
+			Email:     email,
+			URL:       link,
+			ExpiresIn: policy.InvitationTTL,
+		})
+		if err != nil {
+			logrus.WithError(err).WithField("invitation_id", invitation.ID).Error("Failed to queue invitation email")
+		}
+		resp.EmailSent = err == nil
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"invitation_id": invitation.ID,
+		"email":         email,
+		"roles":         invitation.Roles,
+		"invited_by":    inviterID,
+	}).Info("User invited")
+
+	return resp, nil
+}
+
+// ListInvitations 分页查询邀请，按创建时间倒序
+func (s *RegistrationService) ListInvitations(query InvitationQuery) ([]models.Invitation, int64, error) {
+	now := time.Now()
+	tx := s.db.Model(&models.Invitation{})
+	if query.Email != "" {
+		tx = tx.Where("email = ?", query.Email)
+	}
+	switch query.Status {
+	case models.InvitationPending:
+		tx = tx.Where("accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?", now)
+	case models.InvitationAccepted:
+		tx = tx.Where("accepted_at IS NOT NULL")
+	case models.InvitationRevoked:
+		tx = tx.Where("accepted_at IS NULL AND revoked_at IS NOT NULL")
+	case models.InvitationExpired:
+		tx = tx.Where("accepted_at IS NULL AND revoked_at IS NULL AND expires_at <= ?", now)
+	}
+
+	var total int64
+	if err := tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var invitations []models.Invitation
+	if err := tx.Order("id DESC").Offset((query.Page - 1) * query.PageSize).Limit(query.PageSize).Find(&invitations).Error; err != nil {
+		return nil, 0, err
+	}
+	for i := range invitations {
+		invitations[i].Status = invitations[i].InvitationStatus(now)
+	}
+
+	return invitations, total, nil
+}
+
+// RevokeInvitation 撤销未接受的邀请
+func (s *RegistrationService) RevokeInvitation(id uint) error {
+	var invitation models.Invitation
+	if err := s.db.First(&invitation, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperrors.ErrInvitationNotFound
+		}
+		return err
+	}
+
+	now := time.Now()
+	if invitation.InvitationStatus(now) != models.InvitationPending {
+		return apperrors.ErrInvitationNotPending
+	}
+	result := s.db.Model(&invitation).Where("accepted_at IS NULL AND revoked_at IS NULL").Update("revoked_at", now)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return apperrors.ErrInvitationNotPending
+	}
+	return nil
+}
+
+// AcceptInvitation 接受邀请并创建用户，用户的邮箱视为已验证，创建后即可登录
+func (s *RegistrationService) AcceptInvitation(req *AcceptInvitationRequest) error {
+	const path = "/api/v1/auth/invitations/accept"
+
+	var invitation models.Invitation
+	if err := s.db.Where("token_hash = ?", utils.GetTokenHash(req.Token)).First(&invitation).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			s.record(SecurityEventInvitationInvalid, nil, req.IP, req.UserAgent, path, "unknown token")
+			return apperrors.ErrInvitationInvalid
+		}
+		return err
+	}
+	if status := invitation.InvitationStatus(time.Now()); status != models.InvitationPending {
+		s.record(SecurityEventInvitationInvalid, nil, req.IP, req.UserAgent, path, fmt.Sprintf("invitation %d is %s", invitation.ID, status))
+		return apperrors.ErrInvitationInvalid
+	}
+
+	if err := checkUsernameAvailable(s.db, req.Username); err != nil {
+		return err
+	}
+	if err := checkEmailAvailable(s.db, invitation.Email); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	user := models.User{
+		Username:        req.Username,
+		Email:           invitation.Email,
+		Nickname:        req.Nickname,
+		Status:          models.UserStatusActive,
+		EmailVerifiedAt: &now,
+	}
+	if err := validateNewPassword(s.db, s.settings.PasswordPolicy(s.ctx), &user, "password", req.Password); err != nil {
+		return err
+	}
+
+	roles, err := grantableRoles(s.db, splitCodes(invitation.Roles))
+	if err != nil {
+		return err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// 并发接受同一邀请时只有一个请求能成功
+		result := tx.Model(&invitation).Where("accepted_at IS NULL AND revoked_at IS NULL").Update("accepted_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return apperrors.ErrInvitationInvalid
+		}
+
+		if err := createUser(tx, &user, req.Password, roles); err != nil {
+			return err
+		}
+		return tx.Model(&invitation).Update("user_id", user.ID).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	s.record(SecurityEventInvitationAccepted, &user, req.IP, req.UserAgent, path, fmt.Sprintf("invitation %d", invitation.ID))
+	return nil
+}
+
+// Register 自助注册，注册后发送验证邮件，验证邮箱前不能登录
+// 未开放注册或未配置邮件发送时返回 ErrRegistrationDisabled
+func (s *RegistrationService) Register(req *RegisterRequest) error {
+	const path = "/api/v1/auth/register"
+
+	policy := s.settings.Registration(s.ctx)
+	if !policy.Enabled || !s.mailer.Enabled() {
+		return apperrors.ErrRegistrationDisabled
+	}
+	email := strings.TrimSpace(req.Email)
+	if !policy.DomainAllowed(email) {
+		return apperrors.ErrEmailDomainNotAllowed
+	}
+
+	if err := checkUsernameAvailable(s.db, req.Username); err != nil {
+		return err
+	}
+	if err := checkEmailAvailable(s.db, email); err != nil {
+		return err
+	}
+
+	user := models.User{
+		Username: req.Username,
+		Email:    email,
+		Nickname: req.Nickname,
+		Status:   models.UserStatusUnverified,
+	}
+	if err := validateNewPassword(s.db, s.settings.PasswordPolicy(s.ctx), &user, "password", req.Password); err != nil {
+		return err
+	}
+
+	// 默认角色被删除时仍允许注册，由管理员审核时分配角色
+	roles, err := grantableRoles(s.db, unprivilegedRoles(policy.DefaultRoles))
+	if err != nil {
+		return err
+	}
+
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		return createUser(tx, &user, req.Password, roles)
+	}); err != nil {
+		return err
+	}
+
+	s.record(SecurityEventUserRegistered, &user, req.IP, req.UserAgent, path, "self-registration, email not verified")
+	return s.sendVerification(&user, policy)
+}
+
+// VerifyEmail 使用验证链接中的令牌验证邮箱
+// 自助注册的用户验证后按注册策略进入待审核状态或直接启用
+func (s *RegistrationService) VerifyEmail(req *VerifyEmailRequest) (*VerifyEmailResponse, error) {
+	const path = "/api/v1/auth/verify-email"
+
+	userID, err := s.tokens.lookup(s.ctx, req.Token)
+	if errors.Is(err, store.ErrNotFound) {
+		s.record(SecurityEventVerificationFailed, nil, req.IP, req.UserAgent, path, "invalid or expired token")
+		return nil, apperrors.ErrVerificationInvalid
+	}
+	if err != nil {
+		return nil, apperrors.ErrAuthUnavailable.Wrap(err)
+	}
+
+	var user models.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			s.record(SecurityEventVerificationFailed, nil, req.IP, req.UserAgent, path, "user not found")
+			return nil, apperrors.ErrVerificationInvalid
+		}
+		return nil, err
+	}
+
+	consumed, err := s.tokens.consume(s.ctx, req.Token, user.ID)
+	if err != nil {
+		return nil, apperrors.ErrAuthUnavailable.Wrap(err)
+	}
+	if !consumed {
+		s.record(SecurityEventVerificationFailed, &user, req.IP, req.UserAgent, path, "token already used")
+		return nil, apperrors.ErrVerificationInvalid
+	}
+
+	updates := map[string]interface{}{"email_verified_at": time.Now()}
+	if user.Status == models.UserStatusUnverified {
+		user.Status = models.UserStatusActive
+		if s.settings.Registration(s.ctx).RequireApproval {
+			user.Status = models.UserStatusPendingApproval
+		}
+		updates["status"] = user.Status
+	}
+	if err := s.db.Model(&user).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+
+	s.record(SecurityEventEmailVerified, &user, req.IP, req.UserAgent, path, fmt.Sprintf("status=%d", user.Status))
+	return &VerifyEmailResponse{PendingApproval: user.Status == models.UserStatusPendingApproval}, nil
+}
+
+// ResendVerification 重新发送验证邮件，邮箱未注册或已验证时同样返回成功
+func (s *RegistrationService) ResendVerification(req *ResendVerificationRequest) error {
+	if !s.mailer.Enabled() {
+		return apperrors.ErrRegistrationDisabled
+	}
+
+	var user models.User
+	if err := s.db.Where("email = ?", req.Email).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if user.Status != models.UserStatusUnverified {
+		return nil
+	}
+
+	_, throttled, err := s.tokens.throttle(s.ctx, user.ID, verificationMaxResends, verificationWindow)
+	if err != nil {
+		return apperrors.ErrAuthUnavailable.Wrap(err)
+	}
+	if throttled {
+		logrus.WithField("user_id", user.ID).Warn("Verification email throttled")
+		return nil
+	}
+
+	return s.sendVerification(&user, s.settings.Registration(s.ctx))
+}
+
+// ListPendingUsers 分页查询待审核的用户，按注册时间倒序
+func (s *RegistrationService) ListPendingUsers(query PendingUserQuery) ([]models.User, int64, error) {
+	tx := s.db.Model(&models.User{}).Where("status = ?", models.UserStatusPendingApproval)
+
+	var total int64
+	if err := tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var users []models.User
+	if err := tx.Order("id DESC").Offset((query.Page - 1) * query.PageSize).Limit(query.PageSize).Find(&users).Error; err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+// Approve 审核通过自助注册的用户，并通知用户可以登录
+func (s *RegistrationService) Approve(userID uint) error {
+	user, err := s.pendingUser(userID, models.UserStatusPendingApproval)
+	if err != nil {
+		return err
+	}
+
+	result := s.db.Model(user).Where("status = ?", models.UserStatusPendingApproval).Update("status", models.UserStatusActive)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return apperrors.ErrUserNotPending
+	}
+
+	if s.mailer.Enabled() {
+		err := s.mailer.SendTemplate(user.Email, emailLocale(s.ctx, user), "registration_approved", approvedEmail{
+			SiteName: s.settings.String(s.ctx, settings.KeySiteName),
+			Name:     displayName(user),
+			Username: user.Username,
+		})
+		if err != nil {
+			logrus.WithError(err).WithField("user_id", user.ID).Error("Failed to queue registration approved email")
+		}
+	}
+
+	logrus.WithFields(logrus.Fields{"user_id": user.ID, "username": user.Username}).Info("Registration approved")
+	return nil
+}
+
+// Reject 拒绝自助注册的用户，待审核和未验证邮箱的用户会被彻底删除，用户名和邮箱可以重新注册
+func (s *RegistrationService) Reject(userID uint) error {
+	user, err := s.pendingUser(userID, models.UserStatusPendingApproval, models.UserStatusUnverified)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.UserRole{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.PasswordHistory{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(user).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	logrus.WithFields(logrus.Fields{"user_id": user.ID, "username": user.Username}).Info("Registration rejected")
+	return nil
+}
+
+// pendingUser 返回处于指定状态之一的用户
+func (s *RegistrationService) pendingUser(userID uint, statuses ...int) (*models.User, error) {
+	var user models.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.ErrUserNotFound
+		}
+		return nil, err
+	}
+	for _, status := range statuses {
+		if user.Status == status {
+			return &user, nil
+		}
+	}
+	return nil, apperrors.ErrUserNotPending
+}
+
+// sendVerification 生成验证令牌并发送验证邮件
+func (s *RegistrationService) sendVerification(user *models.User, policy settings.RegistrationPolicy) error {
+	token, err := s.tokens.issue(s.ctx, user.ID, time.Duration(policy.VerificationTTL)*time.Hour)
+	if err != nil {
+		return apperrors.ErrAuthUnavailable.Wrap(err)
+	}
+	link, err := tokenURL(s.cfg.VerificationURL, token)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.SendTemplate(user.Email, emailLocale(s.ctx, user), "email_verification", verificationEmail{
+		SiteName:  s.settings.String(s.ctx, settings.KeySiteName),
+		Name:      displayName(user),
+		Username:  user.Username,
+		URL:       link,
+		ExpiresIn: policy.VerificationTTL,
+	})
+}
+
+// checkUsernameAvailable 检查用户名是否可用，已删除用户的用户名同样不能使用
+func checkUsernameAvailable(db *gorm.DB, username string) error {
+	var count int64
+	if err := db.Unscoped().Model(&models.User{}).Where("username = ?", username).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return apperrors.ErrUsernameTaken
+	}
+	return nil
+}
+
+// checkEmailAvailable 检查邮箱是否可用，已删除用户的邮箱同样不能使用
+func checkEmailAvailable(db *gorm.DB, email string) error {
+	var count int64
+	if err := db.Unscoped().Model(&models.User{}).Where("email = ?", email).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return apperrors.ErrEmailTaken
+	}
+	return nil
+}
+
+// activeRoles 按编码查询启用的角色，存在未知或已禁用的编码时返回 ErrRoleNotFound
+func activeRoles(db *gorm.DB, codes []string) ([]models.Role, error) {
+	roles, missing, err := findRoles(db, codes)
+	if err != nil {
+		return nil, err
+	}
+	if len(missing) > 0 {
+		return nil, apperrors.ErrRoleNotFound.WithDetails(map[string]interface{}{"roles": missing})
+	}
+	return roles, nil
+}
+
+// grantableRoles 按编码查询启用的角色，忽略邀请或配置之后被删除、禁用的角色
+func grantableRoles(db *gorm.DB, codes []string) ([]models.Role, error) {
+	roles, missing, err := findRoles(db, codes)
+	if err != nil {
+		return nil, err
+	}
+	if len(missing) > 0 {
+		logrus.WithField("roles", missing).Warn("Skipping unknown or disabled roles")
+	}
+	return roles, nil
+}
+
+// unprivilegedRoles 去掉特权角色，用于不经管理员确认自动授予的角色（自助注册、外部身份）
+// 配置校验已拒绝特权角色，这里防止校验之前写入的配置生效
+func unprivilegedRoles(codes []string) []string {
+	result := make([]string, 0, len(codes))
+	for _, code := range codes {
+		if code == models.RoleAdmin {
+			logrus.WithField("role", code).Warn("Skipping privileged role that cannot be granted automatically")
+			continue
+		}
+		result = append(result, code)
+	}
+	return result
+}
+
+// findRoles 按编码查询启用的角色，同时返回未找到的编码
+func findRoles(db *gorm.DB, codes []string) ([]models.Role, []string, error) {
+	if len(codes) == 0 {
+		return nil, nil, nil
+	}
+
+	var roles []models.Role
+	if err := db.Where("code IN ? AND status = ?", codes, 1).Find(&roles).Error; err != nil {
+		return nil, nil, err
+	}
+	found := make(map[string]bool, len(roles))
+	for _, role := range roles {
+		found[role.Code] = true
+	}
+	var missing []string
+	for _, code := range codes {
+		if !found[code] {
+			missing = append(missing, code)
+			found[code] = true
+		}
+	}
+	return roles, missing, nil
+}
+
+// createUser 创建用户、设置密码并分配角色，应在事务中调用
+func createUser(tx *gorm.DB, user *models.User, password string, roles []models.Role) error {
+	if err := tx.Create(user).Error; err != nil {
+		return err
+	}
+	if err := setPassword(tx, user, password); err != nil {
+		return err
+	}
+	for _, role := range roles {
+		if err := tx.Create(&models.UserRole{UserID: user.ID, RoleID: role.ID}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// record 记录注册相关的安全日志
+func (s *RegistrationService) record(event string, user *models.User, ip, userAgent, path, detail string) {
+	recordAuthEvent(s.ctx, s.securityLogs, event, user, ip, userAgent, path, detail)
+}
+
+// splitCodes 拆分逗号分隔的角色编码
+func splitCodes(s string) []string {
+	var codes []string
+	for _, code := range strings.Split(s, ",") {
+		if code = strings.TrimSpace(code); code != "" {
+			codes = append(codes, code)
+		}
+	}
+	return codes
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"context"
+	"stars-admin/internal/models"
+	"stars-admin/internal/tracing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// 安全事件类型
+const (
+	// SecurityEventIPBlocked 请求来源被 IP 访问控制规则拦截
+	SecurityEventIPBlocked = "ip_blocked"
+	// SecurityEventUserIPBlocked 用户从允许范围以外的 IP 登录或访问
+	SecurityEventUserIPBlocked = "user_ip_blocked"
+	// SecurityEventPasswordResetRequested 申请重置密码，邮箱未注册或用户已禁用时不发送邮件
+	SecurityEventPasswordResetRequested = "password_reset_requested"
+	// SecurityEventPasswordResetThrottled 账号申请重置密码过于频繁，未发送邮件
+	SecurityEventPasswordResetThrottled = "password_reset_throttled"
+	// SecurityEventPasswordResetFailed 使用无效或已过期的重置链接
+	SecurityEventPasswordResetFailed = "password_reset_failed"
+	// SecurityEventPasswordReset 通过重置链接设置了新密码
+	SecurityEventPasswordReset = "password_reset"
+	// SecurityEventUserRegistered 自助注册了新用户
+	SecurityEventUserRegistered = "user_registered"
+	// SecurityEventEmailVerified 验证了邮箱
+	SecurityEventEmailVerified = "email_verified"
+	// SecurityEventVerificationFailed 使用无效或已过期的邮箱验证链接
+	SecurityEventVerificationFailed = "email_verification_failed"
+	// SecurityEventInvitationAccepted 通过邀请注册了新用户
+	SecurityEventInvitationAccepted = "invitation_accepted"
+	// SecurityEventInvitationInvalid 使用无效、已撤销或已过期的邀请链接
+	SecurityEventInvitationInvalid = "invitation_invalid"
+	// SecurityEventAPIKeyRejected 使用未知、已过期或已吊销的 API 密钥，或密钥所有者不可用
+	SecurityEventAPIKeyRejected = "api_key_rejected"
+	// SecurityEventOIDCLogin 通过 OpenID Connect 身份提供方登录
+	SecurityEventOIDCLogin = "oidc_login"
+	// SecurityEventOIDCLoginFailed 身份提供方返回错误、令牌校验失败或外部身份无法关联用户
+	SecurityEventOIDCLoginFailed = "oidc_login_failed"
+	// SecurityEventOIDCIdentityLinked 外部身份按已验证的邮箱关联到已有用户
+	SecurityEventOIDCIdentityLinked = "oidc_identity_linked"
+	// SecurityEventUserProvisioned 外部身份首次登录自动创建了用户
+	SecurityEventUserProvisioned = "user_provisioned"
+	// SecurityEventLDAPIdentityLinked 目录用户首次登录时按邮箱关联到已有用户
+	SecurityEventLDAPIdentityLinked = "ldap_identity_linked"
+	// SecurityEventLDAPUserDisabled 目录中已不存在的用户被同步禁用
+	SecurityEventLDAPUserDisabled = "ldap_user_disabled"
+)
+
+// SecurityLogWriter 安全日志写入器，同时输出一条警告日志便于告警
+type SecurityLogWriter struct {
+	*LogWriter[models.SecurityLog]
+}
+
+// NewSecurityLogWriter 创建安全日志写入器
+func NewSecurityLogWriter(db *gorm.DB, queueSize int) *SecurityLogWriter {
+	return &SecurityLogWriter{newLogWriter[models.SecurityLog]("security-log-writer", db, queueSize)}
+}
+
+// Record 记录安全事件，超出字段长度的请求内容会被截断
+func (w *SecurityLogWriter) Record(entry models.SecurityLog) {
+	entry.Path = truncate(entry.Path, 255)
+	entry.UserAgent = truncate(entry.UserAgent, 255)
+	entry.Detail = truncate(entry.Detail, 500)
+
+	logrus.WithFields(logrus.Fields{
+		"event":    entry.Event,
+		"ip":       entry.IP,
+		"user_id":  entry.UserID,
+		"username": entry.Username,
+		"path":     entry.Path,
+		"detail":   entry.Detail,
+		"trace_id": entry.TraceID,
+	}).Warn("Security event")
+
+	w.Write(entry)
+}
+
+// recordAuthEvent 记录认证相关接口的安全事件，user 为空表示未能确定用户
+func recordAuthEvent(ctx context.Context, logs *SecurityLogWriter, event string, user *models.User, ip, userAgent, path, detail string) {
+	entry := models.SecurityLog{
+		Event:     event,
+		IP:        ip,
+		Method:    "POST",
+		Path:      path,
+		UserAgent: userAgent,
+		Detail:    detail,
+		TraceID:   tracing.TraceID(ctx),
+		CreatedAt: time.Now(),
+	}
+	if user != nil {
+		entry.UserID = user.ID
+		entry.Username = user.Username
+	}
+	logs.Record(entry)
+}
+
+// SecurityLogQuery 安全日志查询条件
+type SecurityLogQuery struct {
+	Event    string    `form:"event"`
+	IP       string    `form:"ip"`
+	UserID   uint      `form:"user_id"`
+	Since    time.Time `form:"since" time_format:"2006-01-02T15:04:05Z07:00"`
+	Page     int       `form:"page" binding:"omitempty,min=1"`
+	PageSize int       `form:"page_size" binding:"omitempty,min=1,max=100"`
+}
+
+// ListSecurityLogs 分页查询安全日志，按时间倒序
+func ListSecurityLogs(ctx context.Context, db *gorm.DB, query SecurityLogQuery) ([]models.SecurityLog, int64, error) {
+	tx := db.WithContext(ctx).Model(&models.SecurityLog{})
+	if query.Event != "" {
+		tx = tx.Where("event = ?", query.Event)
+	}
+	if query.IP != "" {
+		tx = tx.Where("ip = ?", query.IP)
+	}
+	if query.UserID != 0 {
+		tx = tx.Where("user_id = ?", query.UserID)
+	}
+	if !query.Since.IsZero() {
+		tx = tx.Where("created_at >= ?", query.Since)
+	}
+
+	var total int64
+	if err := tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var logs []models.SecurityLog
+	if err := tx.Order("id DESC").Offset((query.Page - 1) * query.PageSize).Limit(query.PageSize).Find(&logs).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return logs, total, nil
+}
+
+// truncate 按字符截断字符串
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n])
+	}
+	return s
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"stars-admin/internal/store"
+	"stars-admin/internal/utils"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// oneTimeToken 邮件链接中使用的一次性令牌，保存在存储中，只保存哈希
+// 每个用户同一用途只有一个有效令牌，生成新令牌后旧令牌失效
+type oneTimeToken struct {
+	st      store.Store
+	purpose string // 存储键前缀，如 password_reset
+}
+
+// tokenKey 令牌哈希到用户 ID
+func (t oneTimeToken) tokenKey(hash string) string {
+	return fmt.Sprintf("%s:token:%s", t.purpose, hash)
+}
+
+// userKey 用户当前有效的令牌哈希
+func (t oneTimeToken) userKey(userID uint) string {
+	return fmt.Sprintf("%s:user:%d", t.purpose, userID)
+}
+
+// issue 为用户生成新令牌，之前生成的令牌随之失效
+func (t oneTimeToken) issue(ctx context.Context, userID uint, ttl time.Duration) (string, error) {
+	token, err := utils.GenerateRandomString(32)
+	if err != nil {
+		return "", err
+	}
+
+	if previous, err := t.st.Get(ctx, t.userKey(userID)); err == nil {
+		if err := t.st.Delete(ctx, t.tokenKey(previous)); err != nil {
+			return "", err
+		}
+	} else if !errors.Is(err, store.ErrNotFound) {
+		return "", err
+	}
+
+	hash := utils.GetTokenHash(token)
+	if err := t.st.Set(ctx, t.tokenKey(hash), strconv.FormatUint(uint64(userID), 10), ttl); err != nil {
+		return "", err
+	}
+	if err := t.st.Set(ctx, t.userKey(userID), hash, ttl); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// lookup 返回令牌所属的用户，令牌不存在或已过期时返回 store.ErrNotFound
+func (t oneTimeToken) lookup(ctx context.Context, token string) (uint, error) {
+	value, err := t.st.Get(ctx, t.tokenKey(utils.GetTokenHash(token)))
+	if err != nil {
+		return 0, err
+	}
+	userID, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, store.ErrNotFound
+	}
+	return uint(userID), nil
+}
+
+// consume 作废令牌，并发使用同一令牌时只有一个调用返回 true
+func (t oneTimeToken) consume(ctx context.Context, token string, userID uint) (bool, error) {
+	consumed, err := t.st.DeleteIfEqual(ctx, t.tokenKey(utils.GetTokenHash(token)), strconv.FormatUint(uint64(userID), 10))
+	if err != nil || !consumed {
+		return false, err
+	}
+	if err := t.st.Delete(ctx, t.userKey(userID)); err != nil {
+		logrus.WithError(err).WithFields(logrus.Fields{"purpose": t.purpose, "user_id": userID}).Warn("Failed to clear one-time token")
+	}
+	return true, nil
+}
+
+// throttle 按用户计数，返回窗口内的次数是否超过 max
+func (t oneTimeToken) throttle(ctx context.Context, userID uint, max int, window time.Duration) (int64, bool, error) {
+	count, err := t.st.Incr(ctx, fmt.Sprintf("%s:limit:%d", t.purpose, userID), window)
+	if err != nil {
+		return 0, false, err
+	}
+	return count, count > int64(max), nil
+}
+
+// tokenURL 将令牌作为 token 查询参数附加到前端页面地址
+func tokenURL(base, token string) (string, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	query := u.Query()
+	query.Set("token", token)
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
@@ -0,0 +1,27 @@
+package settings
+
+import (
+	_ "embed"
+	"strings"
+)
+
+//go:embed common_passwords.txt
+var commonPasswordList string
+
+// commonPasswords 常见弱密码，键为小写
+var commonPasswords = func() map[string]bool {
+	m := make(map[string]bool)
+	for _, line := range strings.Split(commonPasswordList, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		m[strings.ToLower(line)] = true
+	}
+	return m
+}()
+
+// IsCommonPassword 判断是否为常见弱密码，不区分大小写
+func IsCommonPassword(password string) bool {
+	return commonPasswords[strings.ToLower(password)]
+}
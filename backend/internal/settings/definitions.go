@@ -0,0 +1,276 @@
+package settings
+
+import (
+	"context"
+	"encoding/json"
+	"stars-admin/internal/apperrors"
+	"stars-admin/internal/config"
+	"stars-admin/internal/models"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/sirupsen/logrus"
+)
+
+// 配置分组
+const (
+	GroupGeneral  = "general"
+	GroupSecurity = "security"
+	GroupSession  = "session"
+)
+
+// 内置配置项
+const (
+	KeySiteName        = "site.name"
+	KeyPasswordPolicy  = "security.password_policy"
+	KeyRegistration    = "security.registration"
+	KeyAccessTokenTTL  = "session.access_token_ttl"
+	KeyRefreshTokenTTL = "session.refresh_token_ttl"
+)
+
+// PasswordHistoryLimit 每个用户最多保留的密码历史条数，history_count 不能超过该值
+const PasswordHistoryLimit = 24
+
+// maxPasswordBytes bcrypt 只使用密码的前 72 字节，更长的密码一律拒绝
+const maxPasswordBytes = 72
+
+// PasswordPolicy 密码策略
+type PasswordPolicy struct {
+	MinLength        int  `json:"min_length"`
+	MaxLength        int  `json:"max_length"` // 按字节计算，不超过 72
+	RequireUppercase bool `json:"require_uppercase"`
+	RequireLowercase bool `json:"require_lowercase"`
+	RequireDigit     bool `json:"require_digit"`
+	RequireSymbol    bool `json:"require_symbol"`
+	DisallowCommon   bool `json:"disallow_common"`   // 不能是常见弱密码
+	DisallowUsername bool `json:"disallow_username"` // 不能包含用户名
+	HistoryCount     int  `json:"history_count"`     // 不能与最近 N 次使用过的密码相同，0 表示不限制
+	MaxAgeDays       int  `json:"max_age_days"`      // 密码有效期（天），过期后登录须先修改，0 表示永不过期
+}
+
+// Check 校验密码是否符合策略，返回未满足的规则，field 为错误中使用的字段名
+// 密码历史需要查询数据库，不在这里校验
+func (p PasswordPolicy) Check(field, password, username string) []apperrors.FieldError {
+	var upper, lower, digit, symbol bool
+	length := 0
+	for _, r := range password {
+		length++
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			symbol = true
+		}
+	}
+
+	maxLength := p.MaxLength
+	if maxLength <= 0 || maxLength > maxPasswordBytes {
+		maxLength = maxPasswordBytes
+	}
+
+	var violations []apperrors.FieldError
+	if length < p.MinLength {
+		violations = append(violations, apperrors.FieldError{Field: field, Rule: "min", Param: strconv.Itoa(p.MinLength)})
+	}
+	if len(password) > maxLength {
+		violations = append(violations, apperrors.FieldError{Field: field, Rule: "max", Param: strconv.Itoa(maxLength)})
+	}
+	if p.RequireUppercase && !upper {
+		violations = append(violations, apperrors.FieldError{Field: field, Rule: "uppercase"})
+	}
+	if p.RequireLowercase && !lower {
+		violations = append(violations, apperrors.FieldError{Field: field, Rule: "lowercase"})
+	}
+	if p.RequireDigit && !digit {
+		violations = append(violations, apperrors.FieldError{Field: field, Rule: "digit"})
+	}
+	if p.RequireSymbol && !symbol {
+		violations = append(violations, apperrors.FieldError{Field: field, Rule: "symbol"})
+	}
+	if p.DisallowCommon && IsCommonPassword(password) {
+		violations = append(violations, apperrors.FieldError{Field: field, Rule: "common"})
+	}
+	if p.DisallowUsername && username != "" && strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+		violations = append(violations, apperrors.FieldError{Field: field, Rule: "username"})
+	}
+
+	return violations
+}
+
+// Expired 判断在 changedAt 设置的密码是否已超过有效期
+func (p PasswordPolicy) Expired(changedAt time.Time) bool {
+	return p.MaxAgeDays > 0 && time.Since(changedAt) > time.Duration(p.MaxAgeDays)*24*time.Hour
+}
+
+// validatePasswordPolicy 最大长度不能小于最小长度，否则任何密码都无法通过校验
+// 未填写最大长度时使用默认值 72，不会小于最小长度的上限
+func validatePasswordPolicy(value string) []apperrors.FieldError {
+	var policy PasswordPolicy
+	if err := json.Unmarshal([]byte(value), &policy); err != nil {
+		return []apperrors.FieldError{{Field: KeyPasswordPolicy, Rule: "object"}}
+	}
+	if policy.MaxLength > 0 && policy.MaxLength < policy.MinLength {
+		return []apperrors.FieldError{{Field: KeyPasswordPolicy + ".max_length", Rule: "gtefield", Param: "min_length"}}
+	}
+	return nil
+}
+
+// PasswordPolicy 读取当前的密码策略
+// 保存的策略中缺少的字段（如升级后新增的规则）取默认值
+func (m *Manager) PasswordPolicy(ctx context.Context) PasswordPolicy {
+	var policy PasswordPolicy
+	def, _ := m.Definition(KeyPasswordPolicy)
+	_ = json.Unmarshal([]byte(def.Default), &policy)
+
+	if err := m.JSON(ctx, KeyPasswordPolicy, &policy); err != nil {
+		logrus.WithError(err).Warn("Invalid password policy setting, using default")
+		policy = PasswordPolicy{}
+		_ = json.Unmarshal([]byte(def.Default), &policy)
+	}
+	return policy
+}
+
+// RegistrationPolicy 用户注册策略
+type RegistrationPolicy struct {
+	Enabled         bool     `json:"enabled"`          // 是否开放自助注册
+	AllowedDomains  []string `json:"allowed_domains"`  // 允许自助注册的邮箱域名，*.example.com 匹配子域名，为空时不限制
+	RequireApproval bool     `json:"require_approval"` // 自助注册的用户验证邮箱后还需管理员审核
+	DefaultRoles    []string `json:"default_roles"`    // 自助注册用户的角色编码
+	InvitationTTL   int      `json:"invitation_ttl"`   // 邀请链接有效期（小时）
+	VerificationTTL int      `json:"verification_ttl"` // 邮箱验证链接有效期（小时）
+}
+
+// DomainAllowed 判断邮箱域名是否允许自助注册
+func (p RegistrationPolicy) DomainAllowed(email string) bool {
+	if len(p.AllowedDomains) == 0 {
+		return true
+	}
+
+	i := strings.LastIndex(email, "@")
+	if i < 0 {
+		return false
+	}
+	domain := strings.ToLower(email[i+1:])
+	for _, allowed := range p.AllowedDomains {
+		allowed = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(allowed), "@"))
+		if suffix, ok := strings.CutPrefix(allowed, "*."); ok {
+			if strings.HasSuffix(domain, "."+suffix) {
+				return true
+			}
+			continue
+		}
+		if domain == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// Registration 读取当前的注册策略，缺少的字段取默认值
+func (m *Manager) Registration(ctx context.Context) RegistrationPolicy {
+	var policy RegistrationPolicy
+	def, _ := m.Definition(KeyRegistration)
+	_ = json.Unmarshal([]byte(def.Default), &policy)
+
+	if err := m.JSON(ctx, KeyRegistration, &policy); err != nil {
+		logrus.WithError(err).Warn("Invalid registration setting, using default")
+		policy = RegistrationPolicy{}
+		_ = json.Unmarshal([]byte(def.Default), &policy)
+	}
+	return policy
+}
+
+// AccessTokenTTL 读取访问令牌有效期
+func (m *Manager) AccessTokenTTL(ctx context.Context) time.Duration {
+	return time.Duration(m.Int(ctx, KeyAccessTokenTTL)) * time.Minute
+}
+
+// RefreshTokenTTL 读取刷新令牌有效期
+func (m *Manager) RefreshTokenTTL(ctx context.Context) time.Duration {
+	return time.Duration(m.Int(ctx, KeyRefreshTokenTTL)) * time.Hour
+}
+
+// Definitions 返回内置配置项，默认值取自配置文件
+func Definitions(cfg *config.Config) []Definition {
+	accessTTL := int64(cfg.JWT.ExpireHours) * 60
+	if accessTTL <= 0 {
+		accessTTL = 24 * 60
+	}
+	refreshTTL := int64(cfg.JWT.RefreshExpire)
+	if refreshTTL <= 0 {
+		refreshTTL = 7 * 24
+	}
+
+	return []Definition{
+		{
+			Key:         KeySiteName,
+			Group:       GroupGeneral,
+			Type:        TypeString,
+			Default:     "Stars Admin",
+			Description: "站点名称",
+			Schema:      Schema{MaxLength: 50},
+		},
+		{
+			Key:         KeyPasswordPolicy,
+			Group:       GroupSecurity,
+			Type:        TypeJSON,
+			Default:     `{"min_length":8,"max_length":72,"require_uppercase":false,"require_lowercase":true,"require_digit":true,"require_symbol":false,"disallow_common":true,"disallow_username":true,"history_count":5,"max_age_days":0}`,
+			Description: "密码策略，设置、修改和重置密码时校验",
+			Schema: Schema{
+				Properties: map[string]Property{
+					"min_length":        {Type: TypeInt, Schema: Schema{Min: Int64(6), Max: Int64(maxPasswordBytes)}},
+					"max_length":        {Type: TypeInt, Schema: Schema{Min: Int64(8), Max: Int64(maxPasswordBytes)}},
+					"require_uppercase": {Type: TypeBool},
+					"require_lowercase": {Type: TypeBool},
+					"require_digit":     {Type: TypeBool},
+					"require_symbol":    {Type: TypeBool},
+					"disallow_common":   {Type: TypeBool},
+					"disallow_username": {Type: TypeBool},
+					"history_count":     {Type: TypeInt, Schema: Schema{Min: Int64(0), Max: Int64(PasswordHistoryLimit)}},
+					"max_age_days":      {Type: TypeInt, Schema: Schema{Min: Int64(0), Max: Int64(3650)}},
+				},
+				Required: []string{"min_length"},
+			},
+			Validate: validatePasswordPolicy,
+		},
+		{
+			Key:         KeyRegistration,
+			Group:       GroupSecurity,
+			Type:        TypeJSON,
+			Default:     `{"enabled":false,"allowed_domains":[],"require_approval":true,"default_roles":["user"],"invitation_ttl":72,"verification_ttl":24}`,
+			Description: "用户注册策略，包括自助注册、邮箱域名白名单、管理员审核和邀请链接有效期",
+			Schema: Schema{
+				Properties: map[string]Property{
+					"enabled":          {Type: TypeBool},
+					"allowed_domains":  {Type: TypeJSON, Schema: Schema{Items: &Property{Type: TypeString, Schema: Schema{MaxLength: 255}}}},
+					"require_approval": {Type: TypeBool},
+					"default_roles":    {Type: TypeJSON, Schema: Schema{Items: &Property{Type: TypeString, Schema: Schema{MaxLength: 50, Exclude: []string{models.RoleAdmin}}}}},
+					"invitation_ttl":   {Type: TypeInt, Schema: Schema{Min: Int64(1), Max: Int64(30 * 24)}},
+					"verification_ttl": {Type: TypeInt, Schema: Schema{Min: Int64(1), Max: Int64(7 * 24)}},
+				},
+			},
+		},
+		{
+			Key:         KeyAccessTokenTTL,
+			Group:       GroupSession,
+			Type:        TypeInt,
+			Default:     strconv.FormatInt(accessTTL, 10),
+			Description: "访问令牌有效期（分钟），新签发的令牌生效",
+			Schema:      Schema{Min: Int64(5), Max: Int64(30 * 24 * 60)},
+		},
+		{
+			Key:         KeyRefreshTokenTTL,
+			Group:       GroupSession,
+			Type:        TypeInt,
+			Default:     strconv.FormatInt(refreshTTL, 10),
+			Description: "刷新令牌有效期（小时），新签发的令牌生效",
+			Schema:      Schema{Min: Int64(1), Max: Int64(365 * 24)},
+		},
+	}
+}
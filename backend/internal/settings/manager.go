@@ -0,0 +1,517 @@
+package settings
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"stars-admin/internal/apperrors"
+	"stars-admin/internal/database"
+	"stars-admin/internal/models"
+	"stars-admin/internal/store"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	// cacheKey 存储中缓存全部覆盖值的键
+	cacheKey = "settings:values"
+	// invalidateChannel 配置变更后通知其他实例的频道
+	invalidateChannel = "settings:invalidate"
+	// cacheTTL 存储中缓存的有效期
+	cacheTTL = 10 * time.Minute
+	// localTTL 本地缓存的有效期，未收到变更通知时最迟在该时间后重新读取
+	localTTL = time.Minute
+)
+
+// 变更历史的操作类型
+const (
+	ActionUpdate = "update"
+	ActionReset  = "reset"
+)
+
+// Operator 配置修改人
+type Operator struct {
+	ID       uint
+	Username string
+}
+
+// Item 配置项及其当前值
+type Item struct {
+	Definition
+	Value      interface{} `json:"value"`
+	Default    interface{} `json:"default"`
+	Overridden bool        `json:"overridden"`
+	UpdatedBy  uint        `json:"updated_by,omitempty"`
+	UpdatedAt  *time.Time  `json:"updated_at,omitempty"`
+}
+
+// Group 配置分组
+type Group struct {
+	Name  string `json:"name"`
+	Items []Item `json:"items"`
+}
+
+// Manager 系统配置管理器
+// 配置默认值来自配置文件，数据库只保存被覆盖的值；读取经过本地缓存和存储缓存，
+// 修改后刷新存储缓存并通过 Redis 发布订阅通知其他实例丢弃本地缓存
+type Manager struct {
+	db   *gorm.DB
+	st   store.Store
+	rdb  *redis.Client
+	keys []string
+
+	defsMu sync.RWMutex
+	defs   map[string]Definition
+
+	mu       sync.RWMutex
+	values   map[string]string
+	loadedAt time.Time
+
+	pubsub *redis.PubSub
+	done   chan struct{}
+}
+
+// NewManager 创建系统配置管理器，st 为 Redis 存储时启用跨实例失效通知
+// 配置项的默认值必须符合自身的校验规则
+func NewManager(db *gorm.DB, st store.Store, defs []Definition) (*Manager, error) {
+	m := &Manager{
+		db:   db,
+		st:   st,
+		rdb:  store.RedisClient(st),
+		defs: make(map[string]Definition, len(defs)),
+	}
+	for _, def := range defs {
+		if _, ok := m.defs[def.Key]; ok {
+			return nil, fmt.Errorf("setting %s defined twice", def.Key)
+		}
+		if _, err := def.Encode(storedJSON(def, def.Default)); err != nil {
+			return nil, fmt.Errorf("setting %s has invalid default %q: %w", def.Key, def.Default, err)
+		}
+		m.defs[def.Key] = def
+		m.keys = append(m.keys, def.Key)
+	}
+	return m, nil
+}
+
+// Name 组件名称
+func (m *Manager) Name() string {
+	return "settings"
+}
+
+// Start 订阅配置变更通知，订阅断开后由 Redis 客户端自动重连
+func (m *Manager) Start(context.Context) error {
+	if m.rdb == nil {
+		return nil
+	}
+
+	m.pubsub = m.rdb.Subscribe(context.Background(), invalidateChannel)
+	m.done = make(chan struct{})
+	go func() {
+		defer close(m.done)
+		for msg := range m.pubsub.Channel() {
+			logrus.WithField("key", msg.Payload).Debug("Settings changed on another instance")
+			m.invalidate()
+		}
+	}()
+
+	return nil
+}
+
+// Stop 取消订阅
+func (m *Manager) Stop(ctx context.Context) error {
+	if m.pubsub == nil {
+		return nil
+	}
+
+	err := m.pubsub.Close()
+	select {
+	case <-m.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return err
+}
+
+// Definition 返回配置项定义
+func (m *Manager) Definition(key string) (Definition, bool) {
+	m.defsMu.RLock()
+	defer m.defsMu.RUnlock()
+
+	def, ok := m.defs[key]
+	return def, ok
+}
+
+// SetDefaults 更新配置项的默认值，用于配置文件热更新，不符合校验规则的默认值会被忽略
+func (m *Manager) SetDefaults(defs []Definition) {
+	m.defsMu.Lock()
+	defer m.defsMu.Unlock()
+
+	for _, def := range defs {
+		current, ok := m.defs[def.Key]
+		if !ok || current.Default == def.Default {
+			continue
+		}
+		if _, err := current.Encode(storedJSON(current, def.Default)); err != nil {
+			logrus.WithError(err).WithField("key", def.Key).Warn("Ignoring invalid setting default from config")
+			continue
+		}
+		current.Default = def.Default
+		m.defs[def.Key] = current
+	}
+}
+
+// Get 读取配置的存储格式值，读取失败时返回默认值和错误
+func (m *Manager) Get(ctx context.Context, key string) (string, error) {
+	def, ok := m.Definition(key)
+	if !ok {
+		return "", apperrors.ErrSettingNotFound.WithDetails(map[string]string{"key": key})
+	}
+
+	values, err := m.load(ctx)
+	if err != nil {
+		return def.Default, err
+	}
+	if value, ok := values[key]; ok {
+		return value, nil
+	}
+	return def.Default, nil
+}
+
+// String 读取字符串配置，读取失败时返回默认值
+func (m *Manager) String(ctx context.Context, key string) string {
+	value, err := m.Get(ctx, key)
+	if err != nil {
+		logrus.WithError(err).WithField("key", key).Warn("Failed to read setting, using default")
+	}
+	return value
+}
+
+// Int 读取整数配置，读取失败时返回默认值
+func (m *Manager) Int(ctx context.Context, key string) int64 {
+	def, _ := m.Definition(key)
+	n, _ := def.Decode(m.String(ctx, key)).(int64)
+	return n
+}
+
+// Bool 读取布尔配置，读取失败时返回默认值
+func (m *Manager) Bool(ctx context.Context, key string) bool {
+	def, _ := m.Definition(key)
+	b, _ := def.Decode(m.String(ctx, key)).(bool)
+	return b
+}
+
+// JSON 读取 JSON 配置并解码到 dest，读取失败时解码默认值
+func (m *Manager) JSON(ctx context.Context, key string, dest interface{}) error {
+	return json.Unmarshal([]byte(m.String(ctx, key)), dest)
+}
+
+// load 返回全部覆盖值，依次读取本地缓存、存储缓存和数据库
+func (m *Manager) load(ctx context.Context) (map[string]string, error) {
+	m.mu.RLock()
+	values, loadedAt := m.values, m.loadedAt
+	m.mu.RUnlock()
+	if values != nil && time.Since(loadedAt) < localTTL {
+		return values, nil
+	}
+
+	raw, err := m.st.Get(ctx, cacheKey)
+	if err == nil {
+		var cached map[string]string
+		if err := json.Unmarshal([]byte(raw), &cached); err == nil {
+			m.setLocal(cached)
+			return cached, nil
+		}
+	} else if !errors.Is(err, store.ErrNotFound) {
+		logrus.WithError(err).Warn("Settings cache unavailable, reading from database")
+	}
+
+	fresh, err := m.fromDB(ctx)
+	if err != nil {
+		if values != nil {
+			logrus.WithError(err).Warn("Failed to reload settings, using stale values")
+			return values, nil
+		}
+		return nil, err
+	}
+
+	// 只在缓存不存在时写入，避免覆盖修改配置时刚写入的新值
+	if encoded, err := json.Marshal(fresh); err == nil {
+		if _, err := m.st.SetNX(ctx, cacheKey, string(encoded), cacheTTL); err != nil {
+			logrus.WithError(err).Debug("Failed to populate settings cache")
+		}
+	}
+	m.setLocal(fresh)
+
+	return fresh, nil
+}
+
+// fromDB 从主库读取全部覆盖值，忽略未定义或不再符合校验规则的值
+func (m *Manager) fromDB(ctx context.Context) (map[string]string, error) {
+	var rows []models.SystemSetting
+	if err := database.UsePrimary(m.db.WithContext(ctx)).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string, len(rows))
+	for _, row := range rows {
+		def, ok := m.Definition(row.Key)
+		if !ok {
+			continue
+		}
+		if _, err := def.Encode(storedJSON(def, row.Value)); err != nil {
+			logrus.WithError(err).WithField("key", row.Key).Warn("Stored setting no longer valid, using default")
+			continue
+		}
+		values[row.Key] = row.Value
+	}
+
+	return values, nil
+}
+
+// setLocal 更新本地缓存
+func (m *Manager) setLocal(values map[string]string) {
+	m.mu.Lock()
+	m.values = values
+	m.loadedAt = time.Now()
+	m.mu.Unlock()
+}
+
+// invalidate 丢弃本地缓存
+func (m *Manager) invalidate() {
+	m.mu.Lock()
+	m.values = nil
+	m.mu.Unlock()
+}
+
+// refresh 配置修改后重新读取数据库，写入存储缓存并通知其他实例
+func (m *Manager) refresh(ctx context.Context, key string) {
+	m.invalidate()
+
+	values, err := m.fromDB(ctx)
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to reload settings after change")
+		if err := m.st.Delete(ctx, cacheKey); err != nil {
+			logrus.WithError(err).Warn("Failed to drop settings cache")
+		}
+	} else {
+		encoded, _ := json.Marshal(values)
+		if err := m.st.Set(ctx, cacheKey, string(encoded), cacheTTL); err != nil {
+			logrus.WithError(err).Warn("Failed to update settings cache")
+		}
+		m.setLocal(values)
+	}
+
+	if m.rdb != nil {
+		err := store.Guard(ctx, m.st, func(ctx context.Context) error {
+			return m.rdb.Publish(ctx, invalidateChannel, key).Err()
+		})
+		if err != nil {
+			logrus.WithError(err).Warn("Failed to publish settings change, other instances refresh within the local cache TTL")
+		}
+	}
+}
+
+// List 返回配置项及当前值，group 为空时返回全部分组
+func (m *Manager) List(ctx context.Context, group string) ([]Group, error) {
+	var rows []models.SystemSetting
+	if err := database.UsePrimary(m.db.WithContext(ctx)).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	overrides := make(map[string]models.SystemSetting, len(rows))
+	for _, row := range rows {
+		overrides[row.Key] = row
+	}
+
+	var groups []Group
+	index := make(map[string]int)
+	for _, key := range m.keys {
+		def, _ := m.Definition(key)
+		if group != "" && def.Group != group {
+			continue
+		}
+
+		item := Item{
+			Definition: def,
+			Value:      def.Decode(def.Default),
+			Default:    def.Decode(def.Default),
+		}
+		if row, ok := overrides[key]; ok {
+			updatedAt := row.UpdatedAt
+			item.Value = def.Decode(row.Value)
+			item.Overridden = true
+			item.UpdatedBy = row.UpdatedBy
+			item.UpdatedAt = &updatedAt
+		}
+
+		i, ok := index[def.Group]
+		if !ok {
+			i = len(groups)
+			index[def.Group] = i
+			groups = append(groups, Group{Name: def.Group})
+		}
+		groups[i].Items = append(groups[i].Items, item)
+	}
+
+	return groups, nil
+}
+
+// Update 批量修改配置，全部校验通过后在同一事务中写入并记录变更历史
+func (m *Manager) Update(ctx context.Context, changes map[string]json.RawMessage, op Operator) error {
+	keys := make([]string, 0, len(changes))
+	for key := range changes {
+		if _, ok := m.Definition(key); !ok {
+			return apperrors.ErrSettingNotFound.WithDetails(map[string]string{"key": key})
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var fields []apperrors.FieldError
+	encoded := make(map[string]string, len(keys))
+	for _, key := range keys {
+		def, _ := m.Definition(key)
+		value, err := encode(def.Key, def.Type, def.Schema, changes[key], &fields)
+		if err != nil {
+			return err
+		}
+		encoded[key] = value
+	}
+	if len(fields) > 0 {
+		return apperrors.ErrInvalidSetting.WithDetails(fields)
+	}
+
+	var changed []string
+	err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, key := range keys {
+			def, _ := m.Definition(key)
+			value := encoded[key]
+
+			var row models.SystemSetting
+			err := tx.Where("setting_key = ?", key).First(&row).Error
+			found := err == nil
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+
+			old := def.Default
+			if found {
+				old = row.Value
+			}
+			if old == value {
+				continue
+			}
+
+			if found {
+				err = tx.Model(&row).Updates(map[string]interface{}{"value": value, "updated_by": op.ID}).Error
+			} else {
+				err = tx.Create(&models.SystemSetting{Key: key, Value: value, UpdatedBy: op.ID}).Error
+			}
+			if err != nil {
+				return err
+			}
+
+			if err := tx.Create(&models.SystemSettingHistory{
+				Key:        key,
+				Action:     ActionUpdate,
+				OldValue:   old,
+				NewValue:   value,
+				OperatorID: op.ID,
+				Operator:   op.Username,
+			}).Error; err != nil {
+				return err
+			}
+			changed = append(changed, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, key := range changed {
+		logrus.WithFields(logrus.Fields{"key": key, "operator": op.Username}).Info("Setting updated")
+	}
+	if len(changed) > 0 {
+		m.refresh(ctx, changed[0])
+	}
+
+	return nil
+}
+
+// Reset 删除覆盖值，恢复为默认值
+func (m *Manager) Reset(ctx context.Context, key string, op Operator) error {
+	def, ok := m.Definition(key)
+	if !ok {
+		return apperrors.ErrSettingNotFound.WithDetails(map[string]string{"key": key})
+	}
+
+	reset := false
+	err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var row models.SystemSetting
+		if err := tx.Where("setting_key = ?", key).First(&row).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+
+		if err := tx.Delete(&row).Error; err != nil {
+			return err
+		}
+		reset = true
+
+		return tx.Create(&models.SystemSettingHistory{
+			Key:        key,
+			Action:     ActionReset,
+			OldValue:   row.Value,
+			NewValue:   def.Default,
+			OperatorID: op.ID,
+			Operator:   op.Username,
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	if reset {
+		logrus.WithFields(logrus.Fields{"key": key, "operator": op.Username}).Info("Setting reset to default")
+		m.refresh(ctx, key)
+	}
+
+	return nil
+}
+
+// History 分页查询变更历史，key 为空时返回全部配置项的历史
+func (m *Manager) History(ctx context.Context, key string, page, pageSize int) ([]models.SystemSettingHistory, int64, error) {
+	query := m.db.WithContext(ctx).Model(&models.SystemSettingHistory{})
+	if key != "" {
+		query = query.Where("setting_key = ?", key)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var histories []models.SystemSettingHistory
+	if err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&histories).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return histories, total, nil
+}
+
+// storedJSON 将存储格式转换为 JSON，用于按校验规则重新检查
+func storedJSON(def Definition, value string) json.RawMessage {
+	if def.Type == TypeString {
+		raw, _ := json.Marshal(value)
+		return raw
+	}
+	return json.RawMessage(value)
+}
@@ -0,0 +1,219 @@
+package settings
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"stars-admin/internal/apperrors"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Type 配置值类型
+type Type string
+
+// 支持的配置值类型
+const (
+	TypeString Type = "string"
+	TypeInt    Type = "int"
+	TypeBool   Type = "bool"
+	TypeJSON   Type = "json"
+)
+
+// Schema 配置值校验规则，未设置的规则不校验
+type Schema struct {
+	Min       *int64   `json:"min,omitempty"`        // int 最小值
+	Max       *int64   `json:"max,omitempty"`        // int 最大值
+	MaxLength int      `json:"max_length,omitempty"` // string 最大长度（字符数）
+	Pattern   string   `json:"pattern,omitempty"`    // string 正则
+	Enum      []string `json:"enum,omitempty"`       // string 可选值
+	Exclude   []string `json:"exclude,omitempty"`    // string 不允许的值
+	// Properties json 对象的字段定义，设置后不允许出现未定义的字段
+	Properties map[string]Property `json:"properties,omitempty"`
+	// Required json 对象的必填字段
+	Required []string `json:"required,omitempty"`
+	// Items json 数组的元素定义，设置后值必须为数组
+	Items *Property `json:"items,omitempty"`
+}
+
+// Property json 对象的字段定义
+type Property struct {
+	Type   Type   `json:"type"`
+	Schema Schema `json:"schema"`
+}
+
+// Definition 配置项定义，默认值为编码后的字符串
+type Definition struct {
+	Key         string `json:"key"`
+	Group       string `json:"group"`
+	Type        Type   `json:"type"`
+	Default     string `json:"-"`
+	Description string `json:"description"`
+	Schema      Schema `json:"schema"`
+	// Validate 字段之间的约束校验，Schema 校验全部通过后对编码后的值执行，返回未满足的规则
+	Validate func(value string) []apperrors.FieldError `json:"-"`
+}
+
+// Int64 返回 int64 指针，用于填写 Schema 的 Min、Max
+func Int64(v int64) *int64 {
+	return &v
+}
+
+// Encode 将 JSON 请求值按配置类型校验并编码为存储格式
+func (d Definition) Encode(raw json.RawMessage) (string, error) {
+	var fields []apperrors.FieldError
+	value, err := encode(d.Key, d.Type, d.Schema, raw, &fields)
+	if err != nil {
+		return "", err
+	}
+	if len(fields) == 0 && d.Validate != nil {
+		fields = d.Validate(value)
+	}
+	if len(fields) > 0 {
+		return "", apperrors.ErrInvalidSetting.WithDetails(fields)
+	}
+	return value, nil
+}
+
+// Decode 将存储格式解码为对应的 Go 值，用于接口输出
+func (d Definition) Decode(value string) interface{} {
+	switch d.Type {
+	case TypeInt:
+		n, _ := strconv.ParseInt(value, 10, 64)
+		return n
+	case TypeBool:
+		b, _ := strconv.ParseBool(value)
+		return b
+	case TypeJSON:
+		return json.RawMessage(value)
+	default:
+		return value
+	}
+}
+
+// encode 校验单个值，校验失败的规则追加到 fields
+func encode(field string, typ Type, schema Schema, raw json.RawMessage, fields *[]apperrors.FieldError) (string, error) {
+	fail := func(rule, param string) {
+		*fields = append(*fields, apperrors.FieldError{Field: field, Rule: rule, Param: param})
+	}
+
+	switch typ {
+	case TypeString:
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			fail("string", "")
+			return "", nil
+		}
+		if schema.MaxLength > 0 && utf8.RuneCountInString(s) > schema.MaxLength {
+			fail("max", strconv.Itoa(schema.MaxLength))
+		}
+		if schema.Pattern != "" {
+			re, err := regexp.Compile(schema.Pattern)
+			if err != nil {
+				return "", fmt.Errorf("setting %s: invalid pattern: %w", field, err)
+			}
+			if !re.MatchString(s) {
+				fail("pattern", schema.Pattern)
+			}
+		}
+		if len(schema.Enum) > 0 && !contains(schema.Enum, s) {
+			fail("oneof", strings.Join(schema.Enum, " "))
+		}
+		if contains(schema.Exclude, s) {
+			fail("ne", s)
+		}
+		return s, nil
+
+	case TypeInt:
+		var n int64
+		if err := json.Unmarshal(raw, &n); err != nil {
+			fail("int", "")
+			return "", nil
+		}
+		if schema.Min != nil && n < *schema.Min {
+			fail("gte", strconv.FormatInt(*schema.Min, 10))
+		}
+		if schema.Max != nil && n > *schema.Max {
+			fail("lte", strconv.FormatInt(*schema.Max, 10))
+		}
+		return strconv.FormatInt(n, 10), nil
+
+	case TypeBool:
+		var b bool
+		if err := json.Unmarshal(raw, &b); err != nil {
+			fail("boolean", "")
+			return "", nil
+		}
+		return strconv.FormatBool(b), nil
+
+	case TypeJSON:
+		if !json.Valid(raw) {
+			fail("json", "")
+			return "", nil
+		}
+		if schema.Properties != nil {
+			var object map[string]json.RawMessage
+			if err := json.Unmarshal(raw, &object); err != nil || object == nil {
+				fail("object", "")
+				return "", nil
+			}
+			for _, name := range schema.Required {
+				if _, ok := object[name]; !ok {
+					*fields = append(*fields, apperrors.FieldError{Field: field + "." + name, Rule: "required"})
+				}
+			}
+			for _, name := range sortedKeys(object) {
+				property, ok := schema.Properties[name]
+				if !ok {
+					*fields = append(*fields, apperrors.FieldError{Field: field + "." + name, Rule: "unknown"})
+					continue
+				}
+				if _, err := encode(field+"."+name, property.Type, property.Schema, object[name], fields); err != nil {
+					return "", err
+				}
+			}
+		}
+		if schema.Items != nil {
+			var items []json.RawMessage
+			if err := json.Unmarshal(raw, &items); err != nil || items == nil {
+				fail("array", "")
+				return "", nil
+			}
+			for i, item := range items {
+				if _, err := encode(fmt.Sprintf("%s[%d]", field, i), schema.Items.Type, schema.Items.Schema, item, fields); err != nil {
+					return "", err
+				}
+			}
+		}
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, raw); err != nil {
+			return "", err
+		}
+		return compact.String(), nil
+	}
+
+	return "", fmt.Errorf("setting %s: unsupported type %q", field, typ)
+}
+
+// contains 判断列表中是否包含指定值
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedKeys 返回排序后的对象字段名，保证校验错误顺序稳定
+func sortedKeys(object map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(object))
+	for k := range object {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,89 @@
+package settings
+
+import (
+	"encoding/json"
+	"errors"
+	"stars-admin/internal/apperrors"
+	"stars-admin/internal/config"
+	"testing"
+)
+
+func definition(t *testing.T, key string) Definition {
+	t.Helper()
+	for _, d := range Definitions(&config.Config{}) {
+		if d.Key == key {
+			return d
+		}
+	}
+	t.Fatalf("setting %s not defined", key)
+	return Definition{}
+}
+
+// fieldErrors 返回校验失败的字段和规则
+func fieldErrors(t *testing.T, err error) map[string]string {
+	t.Helper()
+	var appErr *apperrors.Error
+	if !errors.As(err, &appErr) {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+	fields, _ := appErr.Details.([]apperrors.FieldError)
+	result := make(map[string]string, len(fields))
+	for _, f := range fields {
+		result[f.Field] = f.Rule
+	}
+	return result
+}
+
+func TestRegistrationArrays(t *testing.T) {
+	d := definition(t, KeyRegistration)
+
+	if _, err := d.Encode(json.RawMessage(`{"allowed_domains":["example.com"],"default_roles":["user"]}`)); err != nil {
+		t.Fatalf("valid registration policy rejected: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		value string
+		field string
+		rule  string
+	}{
+		{"domains not an array", `{"allowed_domains":"example.com"}`, KeyRegistration + ".allowed_domains", "array"},
+		{"roles not an array", `{"default_roles":{"0":"user"}}`, KeyRegistration + ".default_roles", "array"},
+		{"role not a string", `{"default_roles":[1]}`, KeyRegistration + ".default_roles[0]", "string"},
+		{"privileged role", `{"default_roles":["user","admin"]}`, KeyRegistration + ".default_roles[1]", "ne"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := d.Encode(json.RawMessage(tt.value))
+			if rule := fieldErrors(t, err)[tt.field]; rule != tt.rule {
+				t.Errorf("field %s: got rule %q, want %q", tt.field, rule, tt.rule)
+			}
+		})
+	}
+}
+
+func TestPasswordPolicyLengths(t *testing.T) {
+	d := definition(t, KeyPasswordPolicy)
+
+	for _, value := range []string{
+		`{"min_length":8,"max_length":72}`,
+		`{"min_length":12,"max_length":12}`,
+		`{"min_length":64}`,
+	} {
+		if _, err := d.Encode(json.RawMessage(value)); err != nil {
+			t.Errorf("%s rejected: %v", value, err)
+		}
+	}
+
+	// 最大长度小于最小长度时任何密码都无法通过，保存时拒绝
+	_, err := d.Encode(json.RawMessage(`{"min_length":20,"max_length":10}`))
+	if rule := fieldErrors(t, err)[KeyPasswordPolicy+".max_length"]; rule != "gtefield" {
+		t.Errorf("got rule %q, want gtefield", rule)
+	}
+
+	// 单个字段校验失败时不再执行字段间校验
+	_, err = d.Encode(json.RawMessage(`{"min_length":20,"max_length":100}`))
+	if fields := fieldErrors(t, err); len(fields) != 1 || fields[KeyPasswordPolicy+".max_length"] != "lte" {
+		t.Errorf("got %v", fields)
+	}
+}
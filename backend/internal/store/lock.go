@@ -0,0 +1,44 @@
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+// ErrLockNotAcquired 锁已被其他持有者占用
+var ErrLockNotAcquired = errors.New("store: lock not acquired")
+
+// Lock 基于 Store 的互斥锁
+type Lock struct {
+	store Store
+	key   string
+	token string
+}
+
+// AcquireLock 尝试获取锁，锁在 ttl 后自动释放
+func AcquireLock(ctx context.Context, s Store, key string, ttl time.Duration) (*Lock, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return nil, err
+	}
+	token := hex.EncodeToString(bytes)
+
+	ok, err := s.SetNX(ctx, "lock:"+key, token, ttl)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrLockNotAcquired
+	}
+
+	return &Lock{store: s, key: "lock:" + key, token: token}, nil
+}
+
+// Release 释放锁，只有持有者才能释放
+func (l *Lock) Release(ctx context.Context) error {
+	_, err := l.store.DeleteIfEqual(ctx, l.key, l.token)
+	return err
+}
@@ -0,0 +1,185 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// memoryCleanupInterval 过期键清理间隔
+const memoryCleanupInterval = time.Minute
+
+// memoryEntry 内存存储条目
+type memoryEntry struct {
+	value     string
+	expiresAt time.Time
+}
+
+// expired 判断条目是否过期
+func (e memoryEntry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && now.After(e.expiresAt)
+}
+
+// MemoryStore 进程内存储实现，适用于单节点部署和单元测试
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+	stop    chan struct{}
+	once    sync.Once
+}
+
+// NewMemoryStore 创建内存存储
+func NewMemoryStore() *MemoryStore {
+	s := &MemoryStore{
+		entries: make(map[string]memoryEntry),
+		stop:    make(chan struct{}),
+	}
+	go s.cleanup()
+	return s
+}
+
+// get 读取未过期的条目，调用方需持有锁
+func (s *MemoryStore) get(key string, now time.Time) (memoryEntry, bool) {
+	entry, ok := s.entries[key]
+	if !ok {
+		return memoryEntry{}, false
+	}
+	if entry.expired(now) {
+		delete(s.entries, key)
+		return memoryEntry{}, false
+	}
+	return entry, true
+}
+
+// newEntry 创建条目
+func newEntry(value string, ttl time.Duration, now time.Time) memoryEntry {
+	entry := memoryEntry{value: value}
+	if ttl > 0 {
+		entry.expiresAt = now.Add(ttl)
+	}
+	return entry
+}
+
+// Get 读取键值
+func (s *MemoryStore) Get(_ context.Context, key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.get(key, time.Now())
+	if !ok {
+		return "", ErrNotFound
+	}
+	return entry.value, nil
+}
+
+// Set 写入键值
+func (s *MemoryStore) Set(_ context.Context, key, value string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries[key] = newEntry(value, ttl, time.Now())
+	return nil
+}
+
+// SetNX 键不存在时写入
+func (s *MemoryStore) SetNX(_ context.Context, key, value string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if _, ok := s.get(key, now); ok {
+		return false, nil
+	}
+	s.entries[key] = newEntry(value, ttl, now)
+	return true, nil
+}
+
+// Delete 删除键
+func (s *MemoryStore) Delete(_ context.Context, keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		delete(s.entries, key)
+	}
+	return nil
+}
+
+// DeleteIfEqual 值匹配时删除
+func (s *MemoryStore) DeleteIfEqual(_ context.Context, key, value string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.get(key, time.Now())
+	if !ok || entry.value != value {
+		return false, nil
+	}
+	delete(s.entries, key)
+	return true, nil
+}
+
+// Exists 判断键是否存在
+func (s *MemoryStore) Exists(_ context.Context, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.get(key, time.Now())
+	return ok, nil
+}
+
+// Incr 计数器加一
+func (s *MemoryStore) Incr(_ context.Context, key string, ttl time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	entry, ok := s.get(key, now)
+	if !ok {
+		s.entries[key] = newEntry("1", ttl, now)
+		return 1, nil
+	}
+
+	n, err := strconv.ParseInt(entry.value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("store: value of %s is not an integer", key)
+	}
+	n++
+	entry.value = strconv.FormatInt(n, 10)
+	s.entries[key] = entry
+	return n, nil
+}
+
+// Ping 内存存储始终可用
+func (s *MemoryStore) Ping(context.Context) error {
+	return nil
+}
+
+// Close 停止过期清理
+func (s *MemoryStore) Close() error {
+	s.once.Do(func() { close(s.stop) })
+	return nil
+}
+
+// cleanup 定期清理过期键
+func (s *MemoryStore) cleanup() {
+	ticker := time.NewTicker(memoryCleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			now := time.Now()
+			s.mu.Lock()
+			for key, entry := range s.entries {
+				if entry.expired(now) {
+					delete(s.entries, key)
+				}
+			}
+			s.mu.Unlock()
+		case <-s.stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// incrScript 自增并在首次创建时设置过期时间
+var incrScript = redis.NewScript(`
+local n = redis.call("INCR", KEYS[1])
+if n == 1 and tonumber(ARGV[1]) > 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return n
+`)
+
+// deleteIfEqualScript 值匹配时删除
+var deleteIfEqualScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// RedisStore 基于Redis的存储实现
+type RedisStore struct {
+	rdb *redis.Client
+}
+
+// NewRedisStore 创建Redis存储
+func NewRedisStore(rdb *redis.Client) *RedisStore {
+	return &RedisStore{rdb: rdb}
+}
+
+// Client 返回底层Redis客户端，供需要Lua脚本等高级能力的组件使用
+func (s *RedisStore) Client() *redis.Client {
+	return s.rdb
+}
+
+// Get 读取键值
+func (s *RedisStore) Get(ctx context.Context, key string) (string, error) {
+	value, err := s.rdb.Get(ctx, key).Result()
+	if err == redis.Nil {
+		return "", ErrNotFound
+	}
+	return value, err
+}
+
+// Set 写入键值
+func (s *RedisStore) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	return s.rdb.Set(ctx, key, value, ttl).Err()
+}
+
+// SetNX 键不存在时写入
+func (s *RedisStore) SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	return s.rdb.SetNX(ctx, key, value, ttl).Result()
+}
+
+// Delete 删除键
+func (s *RedisStore) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return s.rdb.Del(ctx, keys...).Err()
+}
+
+// DeleteIfEqual 值匹配时删除
+func (s *RedisStore) DeleteIfEqual(ctx context.Context, key, value string) (bool, error) {
+	n, err := deleteIfEqualScript.Run(ctx, s.rdb, []string{key}, value).Int64()
+	return n > 0, err
+}
+
+// Exists 判断键是否存在
+func (s *RedisStore) Exists(ctx context.Context, key string) (bool, error) {
+	n, err := s.rdb.Exists(ctx, key).Result()
+	return n > 0, err
+}
+
+// Incr 计数器加一
+func (s *RedisStore) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	return incrScript.Run(ctx, s.rdb, []string{key}, ttl.Milliseconds()).Int64()
+}
+
+// Ping 检查连接
+func (s *RedisStore) Ping(ctx context.Context) error {
+	return s.rdb.Ping(ctx).Err()
+}
+
+// Close 关闭连接
+func (s *RedisStore) Close() error {
+	return s.rdb.Close()
+}
+
+// RedisClient 返回存储底层的Redis客户端，非Redis存储返回 nil
+func RedisClient(s Store) *redis.Client {
+	if r, ok := s.(*Resilient); ok {
+		s = r.Unwrap()
+	}
+	if r, ok := s.(*RedisStore); ok {
+		return r.Client()
+	}
+	return nil
+}
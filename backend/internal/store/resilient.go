@@ -0,0 +1,271 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrUnavailable 熔断器打开，存储暂不可用
+var ErrUnavailable = errors.New("store: unavailable (circuit open)")
+
+// 故障策略
+const (
+	FailOpen   = "open"   // 存储不可用时放行
+	FailClosed = "closed" // 存储不可用时拒绝
+)
+
+// 依赖存储的检查项
+const (
+	CheckBlacklist = "blacklist"
+	CheckRefresh   = "refresh"
+	CheckRateLimit = "rate_limit"
+)
+
+// 熔断器状态
+const (
+	StateClosed   = "closed"
+	StateOpen     = "open"
+	StateHalfOpen = "half_open"
+)
+
+// ResilientOptions 容错配置
+type ResilientOptions struct {
+	Timeout          time.Duration     // 单次调用超时
+	FailureThreshold int               // 连续失败多少次后熔断
+	Cooldown         time.Duration     // 熔断后多久进入半开状态
+	Policies         map[string]string // 各检查项的故障策略，未配置时为 FailClosed
+}
+
+// Health 存储健康状态
+type Health struct {
+	State     string     `json:"state"`
+	Failures  int        `json:"failures"`
+	LastError string     `json:"last_error,omitempty"`
+	OpenedAt  *time.Time `json:"opened_at,omitempty"`
+}
+
+// Degraded 熔断器非关闭状态即视为降级
+func (h Health) Degraded() bool {
+	return h.State != StateClosed
+}
+
+// Resilient 为存储调用增加超时和熔断
+type Resilient struct {
+	inner Store
+	opts  ResilientOptions
+
+	mu        sync.Mutex
+	state     string
+	failures  int
+	lastError string
+	openedAt  time.Time
+	probing   bool
+}
+
+// NewResilient 创建带超时和熔断的存储
+func NewResilient(inner Store, opts ResilientOptions) *Resilient {
+	if opts.FailureThreshold <= 0 {
+		opts.FailureThreshold = 5
+	}
+	if opts.Cooldown <= 0 {
+		opts.Cooldown = 10 * time.Second
+	}
+	return &Resilient{inner: inner, opts: opts, state: StateClosed}
+}
+
+// Unwrap 返回被包装的存储
+func (r *Resilient) Unwrap() Store {
+	return r.inner
+}
+
+// FailOpen 判断检查项在存储不可用时是否放行
+func (r *Resilient) FailOpen(check string) bool {
+	return r.opts.Policies[check] == FailOpen
+}
+
+// Health 返回熔断器状态
+func (r *Resilient) Health() Health {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	health := Health{
+		State:     r.state,
+		Failures:  r.failures,
+		LastError: r.lastError,
+	}
+	if r.state != StateClosed {
+		openedAt := r.openedAt
+		health.OpenedAt = &openedAt
+	}
+	return health
+}
+
+// allow 判断是否允许发起调用
+func (r *Resilient) allow() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch r.state {
+	case StateOpen:
+		if time.Since(r.openedAt) < r.opts.Cooldown {
+			return ErrUnavailable
+		}
+		r.state = StateHalfOpen
+		r.probing = true
+		return nil
+	case StateHalfOpen:
+		if r.probing {
+			return ErrUnavailable
+		}
+		r.probing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+// record 记录调用结果，键不存在不视为故障
+func (r *Resilient) record(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.probing = false
+	if err == nil || errors.Is(err, ErrNotFound) {
+		r.state = StateClosed
+		r.failures = 0
+		r.lastError = ""
+		return
+	}
+
+	r.failures++
+	r.lastError = err.Error()
+	if r.state == StateHalfOpen || r.failures >= r.opts.FailureThreshold {
+		r.state = StateOpen
+		r.openedAt = time.Now()
+	}
+}
+
+// call 在超时和熔断保护下执行调用
+func (r *Resilient) call(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := r.allow(); err != nil {
+		return err
+	}
+
+	callCtx := ctx
+	if r.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(ctx, r.opts.Timeout)
+		defer cancel()
+	}
+
+	err := fn(callCtx)
+	if err != nil && ctx.Err() != nil {
+		// 调用方主动取消不计入故障
+		r.mu.Lock()
+		r.probing = false
+		r.mu.Unlock()
+		return err
+	}
+	r.record(err)
+	return err
+}
+
+// Get 读取键值
+func (r *Resilient) Get(ctx context.Context, key string) (value string, err error) {
+	err = r.call(ctx, func(ctx context.Context) error {
+		value, err = r.inner.Get(ctx, key)
+		return err
+	})
+	return value, err
+}
+
+// Set 写入键值
+func (r *Resilient) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	return r.call(ctx, func(ctx context.Context) error {
+		return r.inner.Set(ctx, key, value, ttl)
+	})
+}
+
+// SetNX 键不存在时写入
+func (r *Resilient) SetNX(ctx context.Context, key, value string, ttl time.Duration) (ok bool, err error) {
+	err = r.call(ctx, func(ctx context.Context) error {
+		ok, err = r.inner.SetNX(ctx, key, value, ttl)
+		return err
+	})
+	return ok, err
+}
+
+// Delete 删除键
+func (r *Resilient) Delete(ctx context.Context, keys ...string) error {
+	return r.call(ctx, func(ctx context.Context) error {
+		return r.inner.Delete(ctx, keys...)
+	})
+}
+
+// DeleteIfEqual 值匹配时删除
+func (r *Resilient) DeleteIfEqual(ctx context.Context, key, value string) (ok bool, err error) {
+	err = r.call(ctx, func(ctx context.Context) error {
+		ok, err = r.inner.DeleteIfEqual(ctx, key, value)
+		return err
+	})
+	return ok, err
+}
+
+// Exists 判断键是否存在
+func (r *Resilient) Exists(ctx context.Context, key string) (ok bool, err error) {
+	err = r.call(ctx, func(ctx context.Context) error {
+		ok, err = r.inner.Exists(ctx, key)
+		return err
+	})
+	return ok, err
+}
+
+// Incr 计数器加一
+func (r *Resilient) Incr(ctx context.Context, key string, ttl time.Duration) (n int64, err error) {
+	err = r.call(ctx, func(ctx context.Context) error {
+		n, err = r.inner.Incr(ctx, key, ttl)
+		return err
+	})
+	return n, err
+}
+
+// Ping 检查存储是否可用，绕过熔断器以便探测恢复
+func (r *Resilient) Ping(ctx context.Context) error {
+	if r.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.opts.Timeout)
+		defer cancel()
+	}
+	return r.inner.Ping(ctx)
+}
+
+// Close 关闭被包装的存储
+func (r *Resilient) Close() error {
+	return r.inner.Close()
+}
+
+// FailOpenFor 判断检查项在存储故障时是否放行，未配置容错的存储一律拒绝
+func FailOpenFor(s Store, check string) bool {
+	if r, ok := s.(*Resilient); ok {
+		return r.FailOpen(check)
+	}
+	return false
+}
+
+// HealthOf 返回存储的健康状态，未配置容错的存储视为正常
+func HealthOf(s Store) Health {
+	if r, ok := s.(*Resilient); ok {
+		return r.Health()
+	}
+	return Health{State: StateClosed}
+}
+
+// Guard 在存储的超时和熔断保护下执行自定义调用，供直接使用底层客户端的组件复用
+func Guard(ctx context.Context, s Store, fn func(ctx context.Context) error) error {
+	if r, ok := s.(*Resilient); ok {
+		return r.call(ctx, fn)
+	}
+	return fn(ctx)
+}
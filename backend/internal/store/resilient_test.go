@@ -0,0 +1,314 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+var errBackend = errors.New("backend down")
+
+// flakyStore 可控制故障的存储，故障时所有调用返回 errBackend，hang 时调用阻塞到上下文结束
+type flakyStore struct {
+	*MemoryStore
+
+	mu    sync.Mutex
+	fail  bool
+	hang  bool
+	calls int
+}
+
+func newFlakyStore(t *testing.T) *flakyStore {
+	s := &flakyStore{MemoryStore: NewMemoryStore()}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func (s *flakyStore) setFail(fail bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fail = fail
+}
+
+func (s *flakyStore) setHang(hang bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hang = hang
+}
+
+// callCount 返回到达被包装存储的调用次数
+func (s *flakyStore) callCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls
+}
+
+func (s *flakyStore) check(ctx context.Context) error {
+	s.mu.Lock()
+	s.calls++
+	fail, hang := s.fail, s.hang
+	s.mu.Unlock()
+
+	if hang {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	if fail {
+		return errBackend
+	}
+	return nil
+}
+
+func (s *flakyStore) Get(ctx context.Context, key string) (string, error) {
+	if err := s.check(ctx); err != nil {
+		return "", err
+	}
+	return s.MemoryStore.Get(ctx, key)
+}
+
+func (s *flakyStore) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	if err := s.check(ctx); err != nil {
+		return err
+	}
+	return s.MemoryStore.Set(ctx, key, value, ttl)
+}
+
+func (s *flakyStore) Ping(ctx context.Context) error {
+	return s.check(ctx)
+}
+
+const testCooldown = 50 * time.Millisecond
+
+func newTestResilient(t *testing.T) (*Resilient, *flakyStore) {
+	inner := newFlakyStore(t)
+	r := NewResilient(inner, ResilientOptions{
+		Timeout:          20 * time.Millisecond,
+		FailureThreshold: 3,
+		Cooldown:         testCooldown,
+		Policies:         map[string]string{CheckBlacklist: FailOpen, CheckRefresh: FailClosed},
+	})
+	return r, inner
+}
+
+// tripBreaker 连续失败直到熔断器打开
+func tripBreaker(t *testing.T, r *Resilient, inner *flakyStore) {
+	t.Helper()
+	inner.setFail(true)
+	for i := 0; i < r.opts.FailureThreshold; i++ {
+		if err := r.Set(context.Background(), "k", "v", 0); !errors.Is(err, errBackend) {
+			t.Fatalf("call %d: got %v, want backend error", i+1, err)
+		}
+	}
+	if state := r.Health().State; state != StateOpen {
+		t.Fatalf("breaker should be open, got %s", state)
+	}
+}
+
+func TestResilientBreaker(t *testing.T) {
+	ctx := context.Background()
+	r, inner := newTestResilient(t)
+
+	// 失败次数未达阈值时保持关闭
+	inner.setFail(true)
+	r.Set(ctx, "k", "v", 0)
+	r.Set(ctx, "k", "v", 0)
+	if h := r.Health(); h.State != StateClosed || h.Failures != 2 || h.LastError != errBackend.Error() || h.Degraded() {
+		t.Errorf("unexpected health %+v", h)
+	}
+	// 成功的调用清零失败计数
+	inner.setFail(false)
+	if err := r.Set(ctx, "k", "v", 0); err != nil {
+		t.Fatal(err)
+	}
+	if h := r.Health(); h.Failures != 0 || h.LastError != "" {
+		t.Errorf("success should reset failures, got %+v", h)
+	}
+
+	tripBreaker(t, r, inner)
+	h := r.Health()
+	if !h.Degraded() || h.OpenedAt == nil || h.Failures != 3 {
+		t.Errorf("unexpected open health %+v", h)
+	}
+
+	// 熔断期间不调用被包装的存储
+	calls := inner.callCount()
+	if _, err := r.Get(ctx, "k"); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("open breaker: got %v, want ErrUnavailable", err)
+	}
+	if inner.callCount() != calls {
+		t.Error("open breaker should not call the backend")
+	}
+
+	// 冷却后进入半开状态，只放行一次探测，探测失败重新打开
+	time.Sleep(testCooldown + 10*time.Millisecond)
+	if err := r.Set(ctx, "k", "v", 0); !errors.Is(err, errBackend) {
+		t.Errorf("half-open probe: got %v, want backend error", err)
+	}
+	if state := r.Health().State; state != StateOpen {
+		t.Errorf("failed probe should reopen the breaker, got %s", state)
+	}
+	if err := r.Set(ctx, "k", "v", 0); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("reopened breaker: got %v, want ErrUnavailable", err)
+	}
+
+	// 探测成功后关闭
+	inner.setFail(false)
+	time.Sleep(testCooldown + 10*time.Millisecond)
+	if err := r.Set(ctx, "k", "v", 0); err != nil {
+		t.Errorf("half-open probe: %v", err)
+	}
+	if h := r.Health(); h.State != StateClosed || h.Failures != 0 || h.OpenedAt != nil {
+		t.Errorf("successful probe should close the breaker, got %+v", h)
+	}
+}
+
+func TestResilientHalfOpenSingleProbe(t *testing.T) {
+	ctx := context.Background()
+	r, inner := newTestResilient(t)
+	tripBreaker(t, r, inner)
+	time.Sleep(testCooldown + 10*time.Millisecond)
+
+	// 探测进行中时其他调用被拒绝
+	inner.setFail(false)
+	inner.setHang(true)
+	done := make(chan error)
+	go func() { done <- r.Set(ctx, "k", "v", 0) }()
+	for inner.callCount() == r.opts.FailureThreshold {
+		time.Sleep(time.Millisecond)
+	}
+	if state := r.Health().State; state != StateHalfOpen {
+		t.Errorf("got state %s, want half_open", state)
+	}
+	if _, err := r.Get(ctx, "k"); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("concurrent call during probe: got %v, want ErrUnavailable", err)
+	}
+
+	// 探测超时视为失败
+	if err := <-done; !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("probe: got %v, want deadline exceeded", err)
+	}
+	if state := r.Health().State; state != StateOpen {
+		t.Errorf("timed out probe should reopen the breaker, got %s", state)
+	}
+}
+
+func TestResilientTimeout(t *testing.T) {
+	r, inner := newTestResilient(t)
+	inner.setHang(true)
+
+	start := time.Now()
+	if err := r.Set(context.Background(), "k", "v", 0); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want deadline exceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("call should time out after %s, took %s", r.opts.Timeout, elapsed)
+	}
+	if h := r.Health(); h.Failures != 1 {
+		t.Errorf("timeout should count as a failure, got %+v", h)
+	}
+
+	// 调用方取消不计入故障
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := r.Set(ctx, "k", "v", 0); !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want canceled", err)
+	}
+	if h := r.Health(); h.Failures != 1 {
+		t.Errorf("caller cancellation should not count, got %+v", h)
+	}
+}
+
+func TestResilientNotFound(t *testing.T) {
+	r, _ := newTestResilient(t)
+	for i := 0; i < 5; i++ {
+		if _, err := r.Get(context.Background(), "missing"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("got %v, want ErrNotFound", err)
+		}
+	}
+	if h := r.Health(); h.State != StateClosed || h.Failures != 0 {
+		t.Errorf("missing keys should not count as failures, got %+v", h)
+	}
+}
+
+func TestResilientPing(t *testing.T) {
+	r, inner := newTestResilient(t)
+	tripBreaker(t, r, inner)
+
+	// Ping 绕过熔断器以便探测恢复
+	if err := r.Ping(context.Background()); !errors.Is(err, errBackend) {
+		t.Errorf("Ping: got %v, want backend error", err)
+	}
+	inner.setFail(false)
+	if err := r.Ping(context.Background()); err != nil {
+		t.Errorf("Ping: %v", err)
+	}
+	if state := r.Health().State; state != StateOpen {
+		t.Errorf("Ping should not change the breaker state, got %s", state)
+	}
+}
+
+func TestFailOpenFor(t *testing.T) {
+	r, _ := newTestResilient(t)
+	plain := NewMemoryStore()
+	defer plain.Close()
+
+	tests := []struct {
+		name  string
+		store Store
+		check string
+		want  bool
+	}{
+		{"fail open", r, CheckBlacklist, true},
+		{"fail closed", r, CheckRefresh, false},
+		{"not configured", r, CheckRateLimit, false},
+		{"store without breaker", plain, CheckBlacklist, false},
+	}
+	for _, tt := range tests {
+		if got := FailOpenFor(tt.store, tt.check); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if h := HealthOf(plain); h.State != StateClosed || h.Degraded() {
+		t.Errorf("store without breaker should be healthy, got %+v", h)
+	}
+}
+
+func TestGuard(t *testing.T) {
+	ctx := context.Background()
+	r, inner := newTestResilient(t)
+
+	// 自定义调用的失败同样计入熔断
+	for i := 0; i < r.opts.FailureThreshold; i++ {
+		if err := Guard(ctx, r, func(context.Context) error { return errBackend }); !errors.Is(err, errBackend) {
+			t.Fatalf("got %v", err)
+		}
+	}
+	if state := r.Health().State; state != StateOpen {
+		t.Fatalf("got state %s, want open", state)
+	}
+
+	called := false
+	if err := Guard(ctx, r, func(context.Context) error { called = true; return nil }); !errors.Is(err, ErrUnavailable) || called {
+		t.Errorf("open breaker: got %v, called %v", err, called)
+	}
+
+	// 自定义调用受超时保护
+	inner.setFail(false)
+	time.Sleep(testCooldown + 10*time.Millisecond)
+	err := Guard(ctx, r, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want deadline exceeded", err)
+	}
+
+	// 未配置容错的存储直接执行
+	called = false
+	if err := Guard(ctx, inner.MemoryStore, func(context.Context) error { called = true; return nil }); err != nil || !called {
+		t.Errorf("plain store: got %v, called %v", err, called)
+	}
+}
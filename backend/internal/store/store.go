@@ -0,0 +1,39 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// 支持的存储驱动
+const (
+	DriverRedis  = "redis"
+	DriverMemory = "memory"
+)
+
+// ErrNotFound 键不存在或已过期
+var ErrNotFound = errors.New("store: key not found")
+
+// Store 令牌与缓存存储，覆盖令牌黑名单、刷新令牌、计数器和分布式锁等场景
+// 过期时间为 0 表示永不过期
+type Store interface {
+	// Get 读取键值，不存在时返回 ErrNotFound
+	Get(ctx context.Context, key string) (string, error)
+	// Set 写入键值
+	Set(ctx context.Context, key, value string, ttl time.Duration) error
+	// SetNX 键不存在时写入，返回是否写入成功
+	SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error)
+	// Delete 删除键，不存在的键会被忽略
+	Delete(ctx context.Context, keys ...string) error
+	// DeleteIfEqual 值等于 value 时删除，返回是否删除
+	DeleteIfEqual(ctx context.Context, key, value string) (bool, error)
+	// Exists 判断键是否存在
+	Exists(ctx context.Context, key string) (bool, error)
+	// Incr 计数器加一，键首次创建时设置过期时间
+	Incr(ctx context.Context, key string, ttl time.Duration) (int64, error)
+	// Ping 检查存储是否可用
+	Ping(ctx context.Context) error
+	// Close 释放存储资源
+	Close() error
+}
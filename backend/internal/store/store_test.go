@@ -0,0 +1,228 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+// testTTL 测试用的过期时间，内存存储按真实时间过期
+const testTTL = 50 * time.Millisecond
+
+// backend 被测的存储实现，advance 让时间前进 d 使键按 TTL 过期
+type backend struct {
+	name string
+	open func(t *testing.T) (s Store, advance func(d time.Duration))
+}
+
+// backends 内存存储与 Redis 存储（miniredis）运行相同的用例，保证行为一致
+var backends = []backend{
+	{"memory", func(t *testing.T) (Store, func(time.Duration)) {
+		s := NewMemoryStore()
+		t.Cleanup(func() { s.Close() })
+		return s, time.Sleep
+	}},
+	{"redis", func(t *testing.T) (Store, func(time.Duration)) {
+		m := miniredis.RunT(t)
+		s := NewRedisStore(redis.NewClient(&redis.Options{Addr: m.Addr()}))
+		t.Cleanup(func() { s.Close() })
+		return s, m.FastForward
+	}},
+}
+
+// eachBackend 对每种存储实现运行用例
+func eachBackend(t *testing.T, fn func(t *testing.T, s Store, advance func(time.Duration))) {
+	for _, b := range backends {
+		t.Run(b.name, func(t *testing.T) {
+			s, advance := b.open(t)
+			fn(t, s, advance)
+		})
+	}
+}
+
+func TestGetSet(t *testing.T) {
+	ctx := context.Background()
+	eachBackend(t, func(t *testing.T, s Store, advance func(time.Duration)) {
+		if _, err := s.Get(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("missing key: got %v, want ErrNotFound", err)
+		}
+		if ok, err := s.Exists(ctx, "missing"); err != nil || ok {
+			t.Errorf("Exists missing: got %v, %v", ok, err)
+		}
+
+		if err := s.Set(ctx, "forever", "a", 0); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Set(ctx, "short", "b", testTTL); err != nil {
+			t.Fatal(err)
+		}
+		if v, err := s.Get(ctx, "short"); err != nil || v != "b" {
+			t.Errorf("Get: got %q, %v", v, err)
+		}
+		// 覆盖写入替换值
+		if err := s.Set(ctx, "forever", "c", 0); err != nil {
+			t.Fatal(err)
+		}
+
+		advance(2 * testTTL)
+		if _, err := s.Get(ctx, "short"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expired key: got %v, want ErrNotFound", err)
+		}
+		if ok, _ := s.Exists(ctx, "short"); ok {
+			t.Error("expired key should not exist")
+		}
+		if v, err := s.Get(ctx, "forever"); err != nil || v != "c" {
+			t.Errorf("key without TTL: got %q, %v", v, err)
+		}
+	})
+}
+
+func TestSetNX(t *testing.T) {
+	ctx := context.Background()
+	eachBackend(t, func(t *testing.T, s Store, advance func(time.Duration)) {
+		tests := []struct {
+			value string
+			want  bool
+		}{
+			{"first", true},
+			{"second", false},
+		}
+		for _, tt := range tests {
+			if ok, err := s.SetNX(ctx, "k", tt.value, testTTL); err != nil || ok != tt.want {
+				t.Errorf("SetNX %s: got %v, %v, want %v", tt.value, ok, err, tt.want)
+			}
+		}
+		if v, _ := s.Get(ctx, "k"); v != "first" {
+			t.Errorf("SetNX should keep the first value, got %q", v)
+		}
+
+		// 过期后可以再次写入
+		advance(2 * testTTL)
+		if ok, err := s.SetNX(ctx, "k", "third", 0); err != nil || !ok {
+			t.Errorf("SetNX after expiry: got %v, %v", ok, err)
+		}
+	})
+}
+
+func TestDelete(t *testing.T) {
+	ctx := context.Background()
+	eachBackend(t, func(t *testing.T, s Store, advance func(time.Duration)) {
+		s.Set(ctx, "a", "1", 0)
+		s.Set(ctx, "b", "2", 0)
+		if err := s.Delete(ctx, "a", "b", "missing"); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if err := s.Delete(ctx); err != nil {
+			t.Errorf("Delete without keys: %v", err)
+		}
+		for _, key := range []string{"a", "b"} {
+			if ok, _ := s.Exists(ctx, key); ok {
+				t.Errorf("%s should be deleted", key)
+			}
+		}
+
+		s.Set(ctx, "k", "token", 0)
+		tests := []struct {
+			name  string
+			key   string
+			value string
+			want  bool
+		}{
+			{"value mismatch", "k", "other", false},
+			{"missing key", "missing", "token", false},
+			{"value match", "k", "token", true},
+			{"already deleted", "k", "token", false},
+		}
+		for _, tt := range tests {
+			if ok, err := s.DeleteIfEqual(ctx, tt.key, tt.value); err != nil || ok != tt.want {
+				t.Errorf("%s: got %v, %v, want %v", tt.name, ok, err, tt.want)
+			}
+		}
+	})
+}
+
+func TestIncr(t *testing.T) {
+	ctx := context.Background()
+	eachBackend(t, func(t *testing.T, s Store, advance func(time.Duration)) {
+		window := 4 * testTTL
+		for want := int64(1); want <= 3; want++ {
+			if n, err := s.Incr(ctx, "counter", window); err != nil || n != want {
+				t.Errorf("Incr: got %d, %v, want %d", n, err, want)
+			}
+		}
+
+		// 过期时间只在首次创建时设置，之后的自增不会延长窗口
+		advance(3 * testTTL)
+		if n, _ := s.Incr(ctx, "counter", window); n != 4 {
+			t.Errorf("Incr within window: got %d, want 4", n)
+		}
+		advance(2 * testTTL)
+		if n, _ := s.Incr(ctx, "counter", window); n != 1 {
+			t.Errorf("Incr after window: got %d, want 1", n)
+		}
+
+		// TTL 为 0 时不过期
+		s.Incr(ctx, "forever", 0)
+		advance(2 * testTTL)
+		if n, _ := s.Incr(ctx, "forever", 0); n != 2 {
+			t.Errorf("Incr without TTL: got %d, want 2", n)
+		}
+
+		s.Set(ctx, "text", "abc", 0)
+		if _, err := s.Incr(ctx, "text", 0); err == nil {
+			t.Error("Incr on a non-integer value should fail")
+		}
+	})
+}
+
+func TestLock(t *testing.T) {
+	ctx := context.Background()
+	eachBackend(t, func(t *testing.T, s Store, advance func(time.Duration)) {
+		ttl := 4 * testTTL
+		lock, err := AcquireLock(ctx, s, "job", ttl)
+		if err != nil {
+			t.Fatalf("AcquireLock: %v", err)
+		}
+		if _, err := AcquireLock(ctx, s, "job", ttl); !errors.Is(err, ErrLockNotAcquired) {
+			t.Errorf("held lock: got %v, want ErrLockNotAcquired", err)
+		}
+		if other, err := AcquireLock(ctx, s, "other-job", ttl); err != nil {
+			t.Errorf("different key: %v", err)
+		} else {
+			other.Release(ctx)
+		}
+
+		// 非持有者不能释放锁
+		stranger := &Lock{store: s, key: lock.key, token: "stranger"}
+		if err := stranger.Release(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := AcquireLock(ctx, s, "job", ttl); !errors.Is(err, ErrLockNotAcquired) {
+			t.Errorf("lock released by a stranger: got %v", err)
+		}
+
+		if err := lock.Release(ctx); err != nil {
+			t.Fatalf("Release: %v", err)
+		}
+		relocked, err := AcquireLock(ctx, s, "job", ttl)
+		if err != nil {
+			t.Fatalf("AcquireLock after release: %v", err)
+		}
+
+		// 持有者未释放时锁在 TTL 后自动释放，原持有者之后释放不影响新的持有者
+		advance(5 * testTTL)
+		latest, err := AcquireLock(ctx, s, "job", ttl)
+		if err != nil {
+			t.Fatalf("AcquireLock after expiry: %v", err)
+		}
+		relocked.Release(ctx)
+		if _, err := AcquireLock(ctx, s, "job", ttl); !errors.Is(err, ErrLockNotAcquired) {
+			t.Errorf("stale holder released the new lock: got %v", err)
+		}
+		latest.Release(ctx)
+	})
+}
@@ -5,11 +5,16 @@ import (
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"math/big"
+	"stars-admin/internal/config"
+	"stars-admin/internal/store"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/go-redis/redis/v8"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -19,33 +24,58 @@ type JWTClaims struct {
 	Username    string   `json:"username"`
 	Roles       []string `json:"roles"`
 	Permissions []string `json:"permissions"`
+	// MustChangePassword 为 true 时只允许访问修改密码等少数接口
+	MustChangePassword bool `json:"must_change_password,omitempty"`
+	// Locale 用户偏好语言，为空时按 Accept-Language 协商
+	Locale string `json:"locale,omitempty"`
+	// AllowedIPs 用户允许访问的 IP 或网段，为空时不限制
+	AllowedIPs []string `json:"allowed_ips,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// refreshAudience 刷新令牌的受众，用于和访问令牌区分
+const refreshAudience = "refresh"
+
+// errInvalidTokenTTL 签发令牌时未指定有效期
+var errInvalidTokenTTL = errors.New("token ttl must be positive")
+
 var (
-	// JWT密钥，应该从配置文件中读取
+	// JWT密钥，启动时由 InitJWT 从配置文件中读取
 	jwtSecret = []byte("your-secret-key-here")
+	// 本地吊销缓存时长，存储不可用时仍能识别本节点最近吊销的令牌
+	revocationCacheTTL = 5 * time.Minute
+	revocations        = newRevocationCache()
 )
 
-// GenerateJWT 生成JWT token
-func GenerateJWT(userID uint, username string, roles []string, permissions []string) (string, error) {
-	claims := &JWTClaims{
-		UserID:      userID,
-		Username:    username,
-		Roles:       roles,
-		Permissions: permissions,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
-		},
+// InitJWT 从配置初始化令牌参数
+func InitJWT(cfg *config.Config) {
+	if cfg.JWT.SecretKey != "" {
+		jwtSecret = []byte(cfg.JWT.SecretKey)
+	}
+	if cfg.Store.RevocationCacheTTL > 0 {
+		revocationCacheTTL = time.Duration(cfg.Store.RevocationCacheTTL) * time.Second
+	}
+}
+
+// GenerateJWT 生成JWT token，签发和过期时间由 ttl 决定
+// 有效期由调用方从系统设置读取，保证热更新后只有一个来源
+func GenerateJWT(claims JWTClaims, ttl time.Duration) (string, error) {
+	if ttl <= 0 {
+		return "", errInvalidTokenTTL
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	now := time.Now()
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &claims)
 	return token.SignedString(jwtSecret)
 }
 
-// ValidateJWT 验证JWT token
+// ValidateJWT 验证JWT token，刷新令牌不能作为访问令牌使用
 func ValidateJWT(tokenString string) (*JWTClaims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
 		return jwtSecret, nil
@@ -56,6 +86,11 @@ func ValidateJWT(tokenString string) (*JWTClaims, error) {
 	}
 
 	if claims, ok := token.Claims.(*JWTClaims); ok && token.Valid {
+		for _, aud := range claims.Audience {
+			if aud == refreshAudience {
+				return nil, fmt.Errorf("invalid token")
+			}
+		}
 		return claims, nil
 	}
 
@@ -83,6 +118,23 @@ func GenerateRandomString(length int) (string, error) {
 	return hex.EncodeToString(bytes), nil
 }
 
+// passwordAlphabet 随机密码字符集，去掉了容易混淆的字符
+const passwordAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz23456789!@#$%^&*"
+
+// GenerateRandomPassword 生成随机密码
+func GenerateRandomPassword(length int) (string, error) {
+	bytes := make([]byte, length)
+	max := big.NewInt(int64(len(passwordAlphabet)))
+	for i := range bytes {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		bytes[i] = passwordAlphabet[n.Int64()]
+	}
+	return string(bytes), nil
+}
+
 // GetTokenHash 获取token哈希值
 func GetTokenHash(token string) string {
 	hasher := sha256.New()
@@ -90,49 +142,147 @@ func GetTokenHash(token string) string {
 	return hex.EncodeToString(hasher.Sum(nil))
 }
 
-// BlacklistToken 将token加入黑名单
-func BlacklistToken(rdb *redis.Client, token string, expiration time.Duration) error {
-	ctx := context.Background()
+// BlacklistToken 将token加入黑名单，同时记录到本地吊销缓存
+func BlacklistToken(ctx context.Context, st store.Store, token string, expiration time.Duration) error {
 	tokenHash := GetTokenHash(token)
-	return rdb.Set(ctx, fmt.Sprintf("blacklist:%s", tokenHash), "1", expiration).Err()
+	revocations.add(tokenHash, expiration)
+	return st.Set(ctx, fmt.Sprintf("blacklist:%s", tokenHash), "1", expiration)
 }
 
 // IsTokenBlacklisted 检查token是否在黑名单中
-func IsTokenBlacklisted(rdb *redis.Client, token string) bool {
-	ctx := context.Background()
+// 存储不可用时返回错误，由调用方根据故障策略决定是否放行
+func IsTokenBlacklisted(ctx context.Context, st store.Store, token string) (bool, error) {
 	tokenHash := GetTokenHash(token)
-	result := rdb.Get(ctx, fmt.Sprintf("blacklist:%s", tokenHash))
-	return result.Err() == nil
+	if revocations.contains(tokenHash) {
+		return true, nil
+	}
+	return st.Exists(ctx, fmt.Sprintf("blacklist:%s", tokenHash))
+}
+
+// RevokeUserTokens 吊销用户此前签发的所有访问令牌，用于重置密码等需要让已登录会话全部失效的场景
+// 记录的保留时间应不短于访问令牌有效期，之后此前签发的令牌均已过期
+func RevokeUserTokens(ctx context.Context, st store.Store, userID uint, expiration time.Duration) error {
+	key := fmt.Sprintf("tokens_revoked:%d", userID)
+	return st.Set(ctx, key, strconv.FormatInt(time.Now().Unix(), 10), expiration)
+}
+
+// IsUserTokenRevoked 检查访问令牌是否签发于用户令牌被整体吊销之前
+// 签发时间精确到秒，与吊销同一秒签发的令牌也视为已吊销；存储不可用时返回错误，由调用方根据故障策略决定是否放行
+func IsUserTokenRevoked(ctx context.Context, st store.Store, claims *JWTClaims) (bool, error) {
+	key := fmt.Sprintf("tokens_revoked:%d", claims.UserID)
+	value, err := st.Get(ctx, key)
+	if errors.Is(err, store.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	revokedAt, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return false, err
+	}
+	return claims.IssuedAt == nil || claims.IssuedAt.Unix() <= revokedAt, nil
+}
+
+// GenerateRefreshToken 生成刷新token，有效期由调用方从系统设置读取
+func GenerateRefreshToken(userID uint, ttl time.Duration) (string, error) {
+	if ttl <= 0 {
+		return "", errInvalidTokenTTL
+	}
+
+	jti, err := GenerateRandomString(16)
+	if err != nil {
+		return "", err
+	}
+
+	claims := &jwt.RegisteredClaims{
+		ID:        jti,
+		Subject:   strconv.FormatUint(uint64(userID), 10),
+		Audience:  jwt.ClaimStrings{refreshAudience},
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(jwtSecret)
 }
 
-// GenerateRefreshToken 生成刷新token
-func GenerateRefreshToken() string {
-	bytes := make([]byte, 32)
-	rand.Read(bytes)
-	return hex.EncodeToString(bytes)
+// ParseRefreshToken 校验刷新token的签名和有效期，返回用户ID
+func ParseRefreshToken(tokenString string) (uint, error) {
+	claims := &jwt.RegisteredClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return jwtSecret, nil
+	}, jwt.WithAudience(refreshAudience))
+	if err != nil {
+		return 0, err
+	}
+
+	userID, err := strconv.ParseUint(claims.Subject, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid refresh token subject")
+	}
+	return uint(userID), nil
 }
 
-// StoreRefreshToken 存储刷新token
-func StoreRefreshToken(rdb *redis.Client, userID uint, refreshToken string, expiration time.Duration) error {
-	ctx := context.Background()
+// StoreRefreshToken 存储刷新token，每个用户只保留最新的一个
+func StoreRefreshToken(ctx context.Context, st store.Store, userID uint, refreshToken string, expiration time.Duration) error {
 	key := fmt.Sprintf("refresh_token:%d", userID)
-	return rdb.Set(ctx, key, refreshToken, expiration).Err()
+	return st.Set(ctx, key, GetTokenHash(refreshToken), expiration)
 }
 
-// ValidateRefreshToken 验证刷新token
-func ValidateRefreshToken(rdb *redis.Client, userID uint, refreshToken string) bool {
-	ctx := context.Background()
+// ValidateRefreshToken 验证刷新token是否为用户当前有效的token
+func ValidateRefreshToken(ctx context.Context, st store.Store, userID uint, refreshToken string) (bool, error) {
 	key := fmt.Sprintf("refresh_token:%d", userID)
-	result := rdb.Get(ctx, key)
-	if result.Err() != nil {
-		return false
+	value, err := st.Get(ctx, key)
+	if errors.Is(err, store.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
 	}
-	return result.Val() == refreshToken
+	return value == GetTokenHash(refreshToken), nil
 }
 
 // DeleteRefreshToken 删除刷新token
-func DeleteRefreshToken(rdb *redis.Client, userID uint) error {
-	ctx := context.Background()
+func DeleteRefreshToken(ctx context.Context, st store.Store, userID uint) error {
 	key := fmt.Sprintf("refresh_token:%d", userID)
-	return rdb.Del(ctx, key).Err()
-}
\ No newline at end of file
+	return st.Delete(ctx, key)
+}
+
+// revocationCache 本地吊销缓存
+type revocationCache struct {
+	mu      sync.Mutex
+	entries map[string]time.Time
+}
+
+// newRevocationCache 创建本地吊销缓存
+func newRevocationCache() *revocationCache {
+	return &revocationCache{entries: make(map[string]time.Time)}
+}
+
+// add 记录吊销的token，保留时间不超过 revocationCacheTTL
+func (c *revocationCache) add(tokenHash string, expiration time.Duration) {
+	if expiration <= 0 || expiration > revocationCacheTTL {
+		expiration = revocationCacheTTL
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for hash, expiresAt := range c.entries {
+		if now.After(expiresAt) {
+			delete(c.entries, hash)
+		}
+	}
+	c.entries[tokenHash] = now.Add(expiration)
+}
+
+// contains 判断token是否在本地吊销缓存中
+func (c *revocationCache) contains(tokenHash string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt, ok := c.entries[tokenHash]
+	return ok && time.Now().Before(expiresAt)
+}
@@ -2,6 +2,9 @@ package utils
 
 import (
 	"net/http"
+	"stars-admin/internal/apperrors"
+	"stars-admin/internal/i18n"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -24,7 +27,7 @@ type PageResponse struct {
 func Success(c *gin.Context, data interface{}) {
 	c.JSON(http.StatusOK, Response{
 		Code:    200,
-		Message: "success",
+		Message: i18n.T(c.Request.Context(), "message.success", nil),
 		Data:    data,
 	})
 }
@@ -38,15 +41,26 @@ func SuccessWithMessage(c *gin.Context, message string, data interface{}) {
 	})
 }
 
-// Error 错误响应
+// Error 错误响应，code 为HTTP状态码
+// 新代码应使用 Fail 返回应用错误
 func Error(c *gin.Context, code int, message string) {
-	c.JSON(http.StatusOK, Response{
+	status := code
+	if http.StatusText(status) == "" || status < http.StatusBadRequest {
+		status = http.StatusInternalServerError
+	}
+	c.JSON(status, Response{
 		Code:    code,
 		Message: message,
 		Data:    nil,
 	})
 }
 
+// Fail 记录错误并中止请求，由 ErrorHandler 中间件统一按错误码输出响应
+func Fail(c *gin.Context, err error) {
+	_ = c.Error(err)
+	c.Abort()
+}
+
 // BadRequest 请求参数错误
 func BadRequest(c *gin.Context, message string) {
 	c.JSON(http.StatusBadRequest, Response{
@@ -96,7 +110,7 @@ func InternalServerError(c *gin.Context, message string) {
 func PageSuccess(c *gin.Context, list interface{}, total int64, page int, pageSize int) {
 	c.JSON(http.StatusOK, Response{
 		Code:    200,
-		Message: "success",
+		Message: i18n.T(c.Request.Context(), "message.success", nil),
 		Data: PageResponse{
 			List:     list,
 			Total:    total,
@@ -106,11 +120,7 @@ func PageSuccess(c *gin.Context, list interface{}, total int64, page int, pageSi
 	})
 }
 
-// ValidateError 验证错误响应
+// ValidateError 请求绑定或校验失败，字段错误放在 data 中返回
 func ValidateError(c *gin.Context, err error) {
-	c.JSON(http.StatusBadRequest, Response{
-		Code:    400,
-		Message: err.Error(),
-		Data:    nil,
-	})
+	Fail(c, apperrors.FromBinding(err))
 }
\ No newline at end of file
@@ -95,7 +95,7 @@ const LoginPage: React.FC = () => {
         
         <div className="login-tips">
           <p>默认账号：admin</p>
-          <p>默认密码：admin123</p>
+          <p>初始密码见数据库迁移日志，首次登录后需修改</p>
         </div>
       </Card>
     </div>
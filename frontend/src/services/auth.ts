@@ -4,6 +4,16 @@ import type {
   LoginResponse, 
   RefreshTokenRequest, 
   UpdatePasswordRequest,
+  ForgotPasswordRequest,
+  ResetPasswordRequest,
+  RegisterRequest,
+  VerifyEmailResponse,
+  AcceptInvitationRequest,
+  APIKey,
+  CreateAPIKeyRequest,
+  APIKeyResponse,
+  OIDCProvider,
+  PasswordPolicy,
   User 
 } from '../types'
 
@@ -25,4 +35,44 @@ export const authAPI = {
   
   // 更新密码
   updatePassword: (data: UpdatePasswordRequest) => api.put('/auth/password', data),
+
+  // 获取密码策略
+  getPasswordPolicy: () => api.get<PasswordPolicy>('/auth/password-policy'),
+
+  // 申请重置密码
+  forgotPassword: (data: ForgotPasswordRequest) => api.post('/auth/forgot-password', data),
+
+  // 使用邮件中的令牌重置密码
+  resetPassword: (data: ResetPasswordRequest) => api.post('/auth/reset-password', data),
+
+  // 自助注册
+  register: (data: RegisterRequest) => api.post('/auth/register', data),
+
+  // 使用邮件中的令牌验证邮箱
+  verifyEmail: (token: string) => api.post<VerifyEmailResponse>('/auth/verify-email', { token }),
+
+  // 重新发送验证邮件
+  resendVerification: (email: string) => api.post('/auth/verify-email/resend', { email }),
+
+  // 接受邀请并创建账号
+  acceptInvitation: (data: AcceptInvitationRequest) => api.post('/auth/invitations/accept', data),
+
+  // 获取我的 API 密钥
+  getAPIKeys: () => api.get<APIKey[]>('/auth/api-keys'),
+
+  // 创建 API 密钥，密钥只在响应中返回一次
+  createAPIKey: (data: CreateAPIKeyRequest) => api.post<APIKeyResponse>('/auth/api-keys', data),
+
+  // 吊销 API 密钥
+  revokeAPIKey: (id: number) => api.delete(`/auth/api-keys/${id}`),
+
+  // 获取 OpenID Connect 身份提供方
+  getOIDCProviders: () => api.get<OIDCProvider[]>('/auth/oidc/providers'),
+
+  // 跳转到身份提供方登录的地址，用于整页跳转
+  oidcAuthorizeURL: (provider: string) =>
+    `${import.meta.env.VITE_API_BASE_URL || '/api/v1'}/auth/oidc/${encodeURIComponent(provider)}/authorize`,
+
+  // 使用登录回调页面收到的一次性 code 换取令牌
+  exchangeOIDCCode: (code: string) => api.post<LoginResponse>('/auth/oidc/exchange', { code }),
 }
\ No newline at end of file
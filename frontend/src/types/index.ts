@@ -21,7 +21,13 @@ export interface User {
   phone?: string
   nickname?: string
   avatar?: string
-  status: number
+  status: number // 0:禁用 1:正常 2:待审核 3:未验证邮箱
+  must_change_password?: boolean
+  password_changed_at?: string
+  locale?: string
+  allowed_ips?: string
+  email_verified_at?: string
+  service_account?: boolean
   last_login_at?: string
   created_at: string
   updated_at: string
@@ -109,6 +115,109 @@ export interface UpdatePasswordRequest {
   new_password: string
 }
 
+// 重置密码相关类型
+export interface ForgotPasswordRequest {
+  email: string
+}
+
+export interface ResetPasswordRequest {
+  token: string
+  new_password: string
+}
+
+// 注册与邀请相关类型
+export interface RegisterRequest {
+  username: string
+  email: string
+  password: string
+  nickname?: string
+}
+
+export interface VerifyEmailResponse {
+  pending_approval: boolean
+}
+
+export interface AcceptInvitationRequest {
+  token: string
+  username: string
+  password: string
+  nickname?: string
+}
+
+export interface Invitation {
+  id: number
+  email: string
+  roles: string // 逗号分隔的角色编码
+  invited_by: number
+  expires_at: string
+  accepted_at?: string
+  user_id?: number
+  revoked_at?: string
+  status: 'pending' | 'accepted' | 'revoked' | 'expired'
+  created_at: string
+  updated_at: string
+}
+
+export interface InviteRequest {
+  email: string
+  roles: string[]
+}
+
+export interface InvitationResponse {
+  invitation: Invitation
+  url: string
+  email_sent: boolean
+}
+
+// API 密钥相关类型
+export interface APIKey {
+  id: number
+  name: string
+  prefix: string
+  user_id: number
+  roles: string // 逗号分隔的角色编码
+  permissions: string // 逗号分隔的权限
+  expires_at?: string
+  last_used_at?: string
+  last_used_ip?: string
+  revoked_at?: string
+  created_by: number
+  created_at: string
+  updated_at: string
+}
+
+export interface CreateAPIKeyRequest {
+  name: string
+  roles?: string[]
+  permissions?: string[]
+  expires_in_days?: number // 不填则不过期
+}
+
+export interface APIKeyResponse {
+  api_key: APIKey
+  key: string // 只在创建时返回一次
+}
+
+// OpenID Connect 身份提供方
+export interface OIDCProvider {
+  name: string
+  display_name: string
+}
+
+// 密码策略类型
+export interface PasswordPolicy {
+  min_length: number
+  max_length: number
+  require_uppercase: boolean
+  require_lowercase: boolean
+  require_digit: boolean
+  require_symbol: boolean
+  disallow_common: boolean
+  disallow_username: boolean
+  history_count: number
+  max_age_days: number
+}
+
 // 分页请求参数
 export interface PageRequest {
   page?: number
@@ -47,29 +47,32 @@ const createAxiosInstance = (): AxiosInstance => {
     (error: AxiosError) => {
       const { response } = error
 
-      // 处理HTTP错误状态码
+      // 处理HTTP错误状态码，优先使用后端返回的提示信息，错误码含义见 /api/v1/errors
       if (response) {
         const { status, data } = response
+        const msg = (data as any)?.message
 
         switch (status) {
           case 401:
-            // 未授权，清除token并跳转到登录页
-            localStorage.removeItem('access_token')
-            localStorage.removeItem('refresh_token')
-            store.dispatch(logout())
-            message.error('登录已过期，请重新登录')
+            // 携带令牌的请求未通过认证，清除token并跳转到登录页
+            if (error.config?.headers?.Authorization) {
+              localStorage.removeItem('access_token')
+              localStorage.removeItem('refresh_token')
+              store.dispatch(logout())
+            }
+            message.error(msg || '登录已过期，请重新登录')
             break
           case 403:
-            message.error('没有权限访问该资源')
+            message.error(msg || '没有权限访问该资源')
             break
           case 404:
-            message.error('请求的资源不存在')
+            message.error(msg || '请求的资源不存在')
             break
           case 500:
-            message.error('服务器内部错误')
+            message.error(msg || '服务器内部错误')
             break
           default:
-            message.error((data as any)?.message || '请求失败')
+            message.error(msg || '请求失败')
         }
       } else {
         // 网络错误或超时